@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+// Action is the module return value of an hcloud.Action
+type Action struct {
+	ID        int              `json:"id"`
+	Command   string           `json:"command"`
+	Status    string           `json:"status"`
+	Progress  int              `json:"progress"`
+	Started   time.Time        `json:"started"`
+	Finished  *time.Time       `json:"finished"`
+	Error     *ActionError     `json:"error"`
+	Resources []ActionResource `json:"resources"`
+}
+
+// ActionError is the module return value of a failed hcloud.Action
+type ActionError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ActionResource is the module return value of an hcloud.ActionResource
+type ActionResource struct {
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+}
+
+type arguments struct {
+	Token string `json:"token"`
+
+	ID   interface{} `json:"id"`
+	Wait *bool       `json:"wait"`
+}
+
+var argSpec = ansible.ArgSpec{
+	Args: []ansible.Arg{
+		ansible.TokenArg(),
+		{Name: "id", Type: ansible.TypeRaw, Required: true,
+			Description: "A single action id or list of action ids."},
+		{Name: "wait", Type: ansible.TypeBool, Default: true,
+			Description: "Wait until all actions completed. When `false`, only the current status and progress is reported."},
+	},
+}
+
+var doc = ansible.Doc{
+	Module:           "hcloud_action",
+	ShortDescription: "Wait for or report Hetzner Cloud Actions",
+	Description: "Reports the status of Hetzner Cloud actions or waits for their completion. " +
+		"Use it together with `wait: false` of the other modules to start long running operations and check on them later.",
+	Returns: []ansible.Return{
+		{
+			Name:        "actions",
+			Description: "The actions with their status and the resources they affect.",
+			Returned:    "always",
+			Sample: []Action{{
+				ID:        123,
+				Command:   "start_server",
+				Status:    "success",
+				Progress:  100,
+				Started:   time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC),
+				Finished:  timePtr(time.Date(2018, 1, 1, 12, 0, 12, 0, time.UTC)),
+				Resources: []ActionResource{{ID: 42, Type: "server"}},
+			}},
+		},
+	},
+	Examples: `
+# start a server without waiting
+- hcloud_server:
+    name: example-server
+    state: running
+    wait: false
+  register: hcloud_server
+
+# ... do something else ...
+
+# wait for the server to be started
+- hcloud_action:
+    id: "{{ hcloud_server.action_ids }}"
+    timeout: 10m
+
+# poll the progress until done
+- hcloud_action:
+    id: "{{ hcloud_server.action_ids }}"
+    wait: false
+  register: hcloud_actions
+  until: hcloud_actions.actions | rejectattr('status', 'equalto', 'running') | list | length == hcloud_actions.actions | length
+  retries: 30
+  delay: 10
+`,
+}
+
+// statusTimeout is the maximum time to fetch the status of the actions after the module timed out
+const statusTimeout = 10 * time.Second
+
+type module struct {
+	args   arguments
+	client *hcloud.Client
+	waiter util.ActionWaiter
+}
+
+func (m *module) Args() interface{} {
+	return &m.args
+}
+
+func (m *module) ArgSpec() ansible.ArgSpec {
+	return argSpec
+}
+
+func (m *module) Doc() ansible.Doc {
+	return doc
+}
+
+func (m *module) Run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	m.client, err = hcloud.BuildClient(m.args.Token)
+	if err != nil {
+		return
+	}
+	m.waiter = util.NewActionWatcher(m.client.Action)
+	return m.run(ctx)
+}
+
+func (m *module) run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var actions []*hcloud.Action
+	if actions, err = m.actions(ctx); err != nil {
+		return
+	}
+
+	if m.args.Wait == nil || *m.args.Wait {
+		waitErr := m.waiter.WaitForActions(ctx, actions...)
+		// report the latest status, even if waiting failed,
+		// the status is fetched with a fresh context when the module timed out or was cancelled
+		statusCtx := ctx
+		if ctx.Err() != nil {
+			var cancel context.CancelFunc
+			statusCtx, cancel = context.WithTimeout(context.Background(), statusTimeout)
+			defer cancel()
+		}
+		if latest, statusErr := m.actions(statusCtx); statusErr == nil {
+			actions = latest
+		} else if waitErr == nil {
+			err = statusErr
+			return
+		}
+		resp.Set("actions", toActions(actions))
+		if waitErr != nil {
+			err = waitErr
+			return
+		}
+		resp.Msg(fmt.Sprintf("Actions %s completed", joinIDs(actions)))
+		return
+	}
+
+	resp.
+		Msg(fmt.Sprintf("Actions %s status reported", joinIDs(actions))).
+		Set("actions", toActions(actions))
+	return
+}
+
+func (m *module) actions(ctx context.Context) (actions []*hcloud.Action, err error) {
+	for _, id := range util.GetIDs(m.args.ID) {
+		var action *hcloud.Action
+		if action, _, err = m.client.Action.GetByID(ctx, id); err != nil {
+			return
+		}
+		if action == nil {
+			err = fmt.Errorf("Action %d not found", id)
+			return
+		}
+		actions = append(actions, action)
+	}
+	return
+}
+
+func joinIDs(actions []*hcloud.Action) string {
+	var ids []string
+	for _, action := range actions {
+		ids = append(ids, fmt.Sprintf("%d", action.ID))
+	}
+	return strings.Join(ids, ", ")
+}
+
+func toActions(actions []*hcloud.Action) []Action {
+	list := []Action{}
+	for _, action := range actions {
+		list = append(list, toAction(action))
+	}
+	return list
+}
+
+func toAction(action *hcloud.Action) Action {
+	data := Action{
+		ID:        action.ID,
+		Command:   action.Command,
+		Status:    string(action.Status),
+		Progress:  action.Progress,
+		Started:   action.Started,
+		Resources: []ActionResource{},
+	}
+	if !action.Finished.IsZero() {
+		data.Finished = &action.Finished
+	}
+	if action.ErrorCode != "" || action.ErrorMessage != "" {
+		data.Error = &ActionError{
+			Code:    action.ErrorCode,
+			Message: action.ErrorMessage,
+		}
+	}
+	for _, r := range action.Resources {
+		data.Resources = append(data.Resources, ActionResource{
+			ID:   r.ID,
+			Type: string(r.Type),
+		})
+	}
+	return data
+}
+
+// CheckArgs checks the constraints of the arguments the spec cannot express
+func (m *module) CheckArgs() (errs []string) {
+	if m.args.ID != nil && len(util.GetIDs(m.args.ID)) == 0 {
+		errs = append(errs, "'id' must be an id or a list of ids")
+	}
+	return
+}
+
+var flags = pflag.NewFlagSet("hcloud_action", pflag.ContinueOnError)
+
+func init() {
+	flags.BoolP("version", "v", false, "Print version and exit")
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func main() {
+	ansible.RunModule(&module{}, flags)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud/hcloudtest"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+var (
+	nilAction   *hcloud.Action
+	nilResponse *hcloud.Response
+
+	runningAction = &hcloud.Action{
+		ID:       123,
+		Command:  "create_image",
+		Status:   hcloud.ActionStatusRunning,
+		Progress: 42,
+		Started:  time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+		Resources: []*hcloud.ActionResource{
+			{ID: 1, Type: "server"},
+		},
+	}
+	finishedAction = &hcloud.Action{
+		ID:       123,
+		Command:  "create_image",
+		Status:   hcloud.ActionStatusSuccess,
+		Progress: 100,
+		Started:  time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+		Finished: time.Date(2018, 1, 1, 0, 5, 0, 0, time.UTC),
+		Resources: []*hcloud.ActionResource{
+			{ID: 1, Type: "server"},
+		},
+	}
+)
+
+func TestStatus(t *testing.T) {
+	client := hcloud.NewClient()
+	client.Action = hcloudtest.NewActionClientMock()
+
+	m := module{
+		client: client,
+		args: arguments{
+			ID:   []interface{}{float64(123)},
+			Wait: hcloud.Bool(false),
+		},
+		waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+			t.Error("should not wait for actions")
+			return nil
+		}),
+	}
+
+	actionClientMock := client.Action.(*hcloudtest.ActionClientMock)
+	actionClientMock.On("GetByID", mock.Anything, 123).Return(runningAction, nilResponse, nil)
+
+	resp, err := m.run(context.Background())
+	if assert.NoError(t, err) {
+		assert.False(t, resp.HasChanged(), "module should not have changed")
+		assert.Equal(t, map[string]interface{}{
+			"actions": []Action{toAction(runningAction)},
+		}, resp.Data())
+	}
+}
+
+func TestWait(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Action = hcloudtest.NewActionClientMock()
+
+		var waited []*hcloud.Action
+		m := module{
+			client: client,
+			args: arguments{
+				ID: float64(123),
+			},
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				waited = actions
+				return nil
+			}),
+		}
+
+		actionClientMock := client.Action.(*hcloudtest.ActionClientMock)
+		actionClientMock.On("GetByID", mock.Anything, 123).Return(runningAction, nilResponse, nil).Once()
+		actionClientMock.On("GetByID", mock.Anything, 123).Return(finishedAction, nilResponse, nil)
+
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.Equal(t, []*hcloud.Action{runningAction}, waited)
+			assert.Equal(t, map[string]interface{}{
+				"actions": []Action{toAction(finishedAction)},
+			}, resp.Data())
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Action = hcloudtest.NewActionClientMock()
+
+		m := module{
+			client: client,
+			args: arguments{
+				ID: float64(123),
+			},
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				return errors.New("Action failed (action_failed)")
+			}),
+		}
+
+		actionClientMock := client.Action.(*hcloudtest.ActionClientMock)
+		actionClientMock.On("GetByID", mock.Anything, 123).Return(runningAction, nilResponse, nil)
+
+		resp, err := m.run(context.Background())
+		assert.EqualError(t, err, "Action failed (action_failed)")
+		assert.Equal(t, map[string]interface{}{
+			"actions": []Action{toAction(runningAction)},
+		}, resp.Data())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Action = hcloudtest.NewActionClientMock()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		m := module{
+			client: client,
+			args: arguments{
+				ID: float64(123),
+			},
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				cancel()
+				return ctx.Err()
+			}),
+		}
+
+		// the status is fetched again after the module context is done
+		var ctxErrs []error
+		actionClientMock := client.Action.(*hcloudtest.ActionClientMock)
+		actionClientMock.On("GetByID", mock.Anything, 123).Return(runningAction, nilResponse, nil).Once()
+		actionClientMock.On("GetByID", mock.Anything, 123).Run(func(args mock.Arguments) {
+			ctxErrs = append(ctxErrs, args.Get(0).(context.Context).Err())
+		}).Return(finishedAction, nilResponse, nil).Once()
+
+		resp, err := m.run(ctx)
+		assert.EqualError(t, err, "context canceled")
+		assert.Equal(t, []error{nil}, ctxErrs)
+		assert.Equal(t, map[string]interface{}{
+			"actions": []Action{toAction(finishedAction)},
+		}, resp.Data())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Action = hcloudtest.NewActionClientMock()
+
+		m := module{
+			client: client,
+			args: arguments{
+				ID: float64(123),
+			},
+		}
+
+		actionClientMock := client.Action.(*hcloudtest.ActionClientMock)
+		actionClientMock.On("GetByID", mock.Anything, 123).Return(nilAction, nilResponse, nil)
+
+		_, err := m.run(context.Background())
+		assert.EqualError(t, err, "Action 123 not found")
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	assert.EqualError(t, ansible.ValidateArgs(&module{args: arguments{}}), "'id' is required")
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{ID: "123"}}))
+}
+
+func TestDoc(t *testing.T) {
+	data, err := ioutil.ReadFile("../../docs/hcloud_action.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(data), doc.Markdown(argSpec), "docs/hcloud_action.md is outdated, run make docs")
+	}
+}
@@ -0,0 +1,549 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+const (
+	stateAbsent  = "absent"
+	statePresent = "present"
+	stateList    = "list"
+
+	defaultExpiryWarningDays = 30
+)
+
+// Certificate is the module return value of an hcloud.Certificate
+type Certificate struct {
+	ID             int      `json:"id"`
+	Name           string   `json:"name"`
+	Type           string   `json:"type"`
+	DomainNames    []string `json:"domain_names"`
+	Fingerprint    string   `json:"fingerprint"`
+	NotValidBefore string   `json:"not_valid_before"`
+	NotValidAfter  string   `json:"not_valid_after"`
+	Status         *Status  `json:"status"`
+	UsedBy         []int    `json:"used_by"`
+}
+
+// Status is the issuance and renewal status of a managed certificate
+type Status struct {
+	Issuance string `json:"issuance"`
+	Renewal  string `json:"renewal"`
+	Error    string `json:"error,omitempty"`
+}
+
+type arguments struct {
+	Token string `json:"token"`
+	State string `json:"state"`
+
+	ID                interface{} `json:"id"`
+	Name              string      `json:"name"`
+	Type              string      `json:"type"`
+	Certificate       string      `json:"certificate"`
+	PrivateKey        string      `json:"private_key"`
+	DomainNames       []string    `json:"domain_names"`
+	ExpiryWarningDays *int        `json:"expiry_warning_days"`
+	Wait              *bool       `json:"wait"`
+}
+
+var argSpec = ansible.ArgSpec{
+	Args: []ansible.Arg{
+		ansible.TokenArg(),
+		{Name: "state", Type: ansible.TypeStr, Default: statePresent, Choices: []string{statePresent, stateAbsent, stateList},
+			Description: "`list` lists all existing certificates."},
+		{Name: "id", Type: ansible.TypeRaw,
+			Description: "ID of the certificate.\n\n`id` or `name` is required when `state=present` or `state=absent`."},
+		{Name: "name", Type: ansible.TypeStr,
+			Description: "Name of the certificate, used to find the certificate when `id` is not specified. The certificate is renamed when both are given."},
+		{Name: "type", Type: ansible.TypeStr,
+			Choices: []string{string(hcloud.CertificateTypeUploaded), string(hcloud.CertificateTypeManaged)},
+			Description: "Type of the certificate, `uploaded` when a new certificate is created without it. " +
+				"An existing certificate of a different type is only replaced when `type` is set."},
+		{Name: "certificate", Type: ansible.TypeStr,
+			Description: "PEM encoded certificate, intermediate certificates may follow. Required to create an uploaded certificate."},
+		{Name: "private_key", Type: ansible.TypeStr, NoLog: true,
+			Description: "PEM encoded private key of the certificate. Required to create an uploaded certificate."},
+		{Name: "domain_names", Type: ansible.TypeList, Elements: ansible.TypeStr,
+			Description: "Domains of a managed certificate. Required to create a managed certificate."},
+		{Name: "expiry_warning_days", Type: ansible.TypeInt, Default: defaultExpiryWarningDays,
+			Description: "Warn when an uploaded certificate expires within this number of days."},
+		{Name: "wait", Type: ansible.TypeBool, Default: true,
+			Description: "Wait for the issuance of managed certificates. When `false`, the module returns immediately with `action_ids`, see `hcloud_action`."},
+	},
+	RequiredIf: []ansible.RequiredIf{
+		{Arg: "state", Value: statePresent, Required: []string{"id", "name"}, OneOf: true},
+		{Arg: "state", Value: stateAbsent, Required: []string{"id", "name"}, OneOf: true},
+	},
+}
+
+var doc = ansible.Doc{
+	Module:           "hcloud_certificate",
+	ShortDescription: "Manage TLS certificates for load balancers",
+	Description: "Manages Hetzner Cloud TLS certificates for load balancers. " +
+		"Uploaded certificates are created from a PEM encoded certificate and private key, " +
+		"managed certificates are issued and renewed by Hetzner Cloud through Let's Encrypt for domains with DNS zones at Hetzner. " +
+		"Reference the returned `id` in the `certificates` of an `hcloud_load_balancer` `https` service.\n\n" +
+		"Certificates cannot be changed after creation. " +
+		"An uploaded certificate is replaced when the fingerprint of `certificate` differs and a managed certificate when `domain_names` differ. " +
+		"Certificates used by a load balancer cannot be replaced or deleted, remove them from the load balancer first.",
+	Returns: []ansible.Return{
+		{
+			Name: "certificates",
+			Description: "The certificate, or all certificates with `state=list`.\n\n" +
+				"`status` is only set for managed certificates, `error` describes a failed issuance or renewal. " +
+				"`used_by` lists the IDs of the load balancers using the certificate.",
+			Returned: "unless `state=absent`",
+			Sample: []Certificate{{
+				ID:             897,
+				Name:           "example.com",
+				Type:           "managed",
+				DomainNames:    []string{"example.com", "www.example.com"},
+				Fingerprint:    "03:c7:55:9b:2a:d1:04:17:09:f6:d0:7f:18:34:63:d4:3e:5f:17:58:52:d8:4b:3d:76:cb:47:09:26:4d:84:96",
+				NotValidBefore: "2018-06-01T10:00:00Z",
+				NotValidAfter:  "2018-08-30T10:00:00Z",
+				Status:         &Status{Issuance: "completed", Renewal: "unavailable"},
+				UsedBy:         []int{42},
+			}},
+		},
+		{
+			Name:        "action_ids",
+			Description: "IDs of the pending actions, see `hcloud_action`.",
+			Returned:    "when `wait` is `false`",
+			Sample:      []int{4711},
+		},
+	},
+	Examples: `
+# upload a certificate
+- hcloud_certificate:
+    name: example.com
+    certificate: "{{ lookup('file', 'example.com.crt') }}"
+    private_key: "{{ lookup('file', 'example.com.key') }}"
+  register: certificate
+
+# let Hetzner Cloud issue and renew a certificate
+- hcloud_certificate:
+    name: example.com
+    type: managed
+    domain_names:
+    - example.com
+    - www.example.com
+  register: certificate
+
+# terminate TLS on a load balancer
+- hcloud_load_balancer:
+    name: web
+    load_balancer_type: lb11
+    location: fsn1
+    services:
+    - protocol: https
+      listen_port: 443
+      destination_port: 80
+      http:
+        certificates:
+        - "{{ certificate.certificates[0].id }}"
+
+# delete the certificate
+- hcloud_certificate:
+    name: example.com
+    state: absent
+
+# list all certificates
+- hcloud_certificate:
+    state: list
+  register: certificates
+`,
+}
+
+type module struct {
+	args   arguments
+	client *hcloud.Client
+	waiter util.ActionWaiter
+	now    func() time.Time
+}
+
+func (m *module) Args() interface{} {
+	return &m.args
+}
+
+func (m *module) ArgSpec() ansible.ArgSpec {
+	return argSpec
+}
+
+func (m *module) Doc() ansible.Doc {
+	return doc
+}
+
+func (m *module) Run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	m.client, err = hcloud.BuildClient(m.args.Token)
+	if err != nil {
+		return
+	}
+	m.waiter = util.NewActionWatcher(m.client.Action)
+	m.now = time.Now
+	return m.run(ctx)
+}
+
+func (m *module) run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	if m.args.Wait != nil && !*m.args.Wait {
+		defer util.RecordActions(&m.waiter).Report(&resp, &err)
+	}
+
+	switch m.args.State {
+	case stateList:
+		return m.list(ctx)
+	case stateAbsent:
+		return m.absent(ctx)
+	case statePresent:
+		return m.present(ctx)
+	default:
+		err = errors.New("invalid state")
+		return
+	}
+}
+
+func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var certificate *hcloud.Certificate
+	if certificate, err = m.certificate(ctx); err != nil {
+		return
+	}
+
+	var (
+		msg    []string
+		reason string
+	)
+	if certificate != nil {
+		reason = m.recreateReason(certificate)
+	} else if m.args.ID != nil {
+		err = fmt.Errorf("Certificate %v not found", m.args.ID)
+		return
+	}
+
+	if certificate == nil || reason != "" {
+		name := m.args.Name
+		if certificate != nil {
+			if len(certificate.UsedBy) > 0 {
+				err = fmt.Errorf("Certificate %d %s, but cannot be replaced while it is used by %s",
+					certificate.ID, reason, usedBy(certificate))
+				return
+			}
+			if name == "" {
+				name = certificate.Name
+			}
+		}
+		// the arguments are checked before an existing certificate is deleted,
+		// incomplete arguments must not lose it
+		var opts hcloud.CertificateCreateOpts
+		if opts, err = m.createOpts(name); err != nil {
+			return
+		}
+		if certificate != nil {
+			if _, err = m.client.Certificate.Delete(ctx, certificate); err != nil {
+				return
+			}
+			msg = append(msg, fmt.Sprintf("Certificate %d deleted (%s)", certificate.ID, reason))
+			resp.Changed()
+		}
+		if certificate, err = m.create(ctx, opts); err != nil {
+			return
+		}
+		msg = append(msg, fmt.Sprintf("Certificate %d created", certificate.ID))
+		resp.Changed()
+	} else {
+		if m.args.ID != nil && m.args.Name != "" && certificate.Name != m.args.Name {
+			if certificate, _, err = m.client.Certificate.Update(ctx, certificate, hcloud.CertificateUpdateOpts{
+				Name: m.args.Name,
+			}); err != nil {
+				return
+			}
+			msg = append(msg, fmt.Sprintf("Certificate %d renamed", certificate.ID))
+			resp.Changed()
+		}
+		if certificate.Status != nil && certificate.Status.Issuance == hcloud.CertificateStatusTypeFailed {
+			if certificate, err = m.retry(ctx, certificate); err != nil {
+				return
+			}
+			msg = append(msg, fmt.Sprintf("Certificate %d issuance retried", certificate.ID))
+			resp.Changed()
+		}
+	}
+
+	if len(msg) == 0 {
+		msg = append(msg, fmt.Sprintf("Certificate %d already exists, nothing to do", certificate.ID))
+	}
+	if warning := m.expiryWarning(certificate); warning != "" {
+		resp.Warn(warning)
+	}
+	resp.
+		Msg(strings.Join(msg, ", ")).
+		Set("certificates", []Certificate{toCertificate(certificate)})
+	return
+}
+
+// recreateReason returns why the certificate has to be replaced or an empty string,
+// certificates cannot be changed after creation
+func (m *module) recreateReason(certificate *hcloud.Certificate) string {
+	if m.args.Type != "" && string(certificate.Type) != m.args.Type {
+		return fmt.Sprintf("is of type %s", certificate.Type)
+	}
+	switch certificate.Type {
+	case hcloud.CertificateTypeUploaded:
+		if m.args.Certificate != "" && !strings.EqualFold(fingerprint(m.args.Certificate), certificate.Fingerprint) {
+			return "has a different fingerprint"
+		}
+	case hcloud.CertificateTypeManaged:
+		if m.args.DomainNames != nil && !sameDomainNames(m.args.DomainNames, certificate.DomainNames) {
+			return "has different domain names"
+		}
+	}
+	return ""
+}
+
+// createOpts returns the options to create the certificate,
+// the type defaults to uploaded when it is not set
+func (m *module) createOpts(name string) (opts hcloud.CertificateCreateOpts, err error) {
+	opts = hcloud.CertificateCreateOpts{
+		Name: name,
+		Type: hcloud.CertificateType(m.args.Type),
+	}
+	if opts.Type == "" {
+		opts.Type = hcloud.CertificateTypeUploaded
+	}
+	if opts.Type == hcloud.CertificateTypeManaged {
+		if len(m.args.DomainNames) == 0 {
+			err = fmt.Errorf("Cannot create certificate '%s': 'domain_names' is required", name)
+			return
+		}
+		opts.DomainNames = m.args.DomainNames
+	} else {
+		if m.args.Certificate == "" || m.args.PrivateKey == "" {
+			err = fmt.Errorf("Cannot create certificate '%s': 'certificate' and 'private_key' are required", name)
+			return
+		}
+		opts.Certificate = m.args.Certificate
+		opts.PrivateKey = m.args.PrivateKey
+	}
+	return
+}
+
+func (m *module) create(ctx context.Context, opts hcloud.CertificateCreateOpts) (*hcloud.Certificate, error) {
+	result, _, err := m.client.Certificate.Create(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return m.waitForIssuance(ctx, result.Certificate, result.Action)
+}
+
+// retry retries the failed issuance of a managed certificate
+func (m *module) retry(ctx context.Context, certificate *hcloud.Certificate) (*hcloud.Certificate, error) {
+	action, _, err := m.client.Certificate.RetryIssuance(ctx, certificate)
+	if err != nil {
+		return nil, err
+	}
+	return m.waitForIssuance(ctx, certificate, action)
+}
+
+// waitForIssuance waits for the issuance action of a managed certificate and returns
+// the issued certificate, the error of a failed issuance includes the reason
+func (m *module) waitForIssuance(ctx context.Context, certificate *hcloud.Certificate, action *hcloud.Action) (*hcloud.Certificate, error) {
+	if action == nil {
+		return certificate, nil
+	}
+	waitErr := m.waiter.WaitForActions(ctx, action)
+	if _, recorded := m.waiter.(*util.ActionRecorder); recorded && waitErr == nil {
+		return certificate, nil
+	}
+	current, _, err := m.client.Certificate.GetByID(ctx, certificate.ID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, fmt.Errorf("Certificate %d not found", certificate.ID)
+	}
+	if current.Status != nil && current.Status.Issuance == hcloud.CertificateStatusTypeFailed {
+		if current.Status.Error != nil {
+			return nil, fmt.Errorf("Certificate %d issuance failed: %s", current.ID, current.Status.Error.Message)
+		}
+		return nil, fmt.Errorf("Certificate %d issuance failed", current.ID)
+	}
+	if waitErr != nil {
+		return nil, waitErr
+	}
+	return current, nil
+}
+
+// expiryWarning warns about uploaded certificates expiring within the configured window,
+// managed certificates are renewed automatically
+func (m *module) expiryWarning(certificate *hcloud.Certificate) string {
+	if certificate.Type != hcloud.CertificateTypeUploaded || certificate.NotValidAfter.IsZero() {
+		return ""
+	}
+	days := defaultExpiryWarningDays
+	if m.args.ExpiryWarningDays != nil {
+		days = *m.args.ExpiryWarningDays
+	}
+	remaining := certificate.NotValidAfter.Sub(m.now())
+	if remaining < 0 {
+		return fmt.Sprintf("Certificate %d expired on %s", certificate.ID, certificate.NotValidAfter.Format(time.RFC3339))
+	}
+	if remaining < time.Duration(days)*24*time.Hour {
+		return fmt.Sprintf("Certificate %d expires in %d days on %s",
+			certificate.ID, int(remaining.Hours()/24), certificate.NotValidAfter.Format(time.RFC3339))
+	}
+	return ""
+}
+
+func (m *module) absent(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var certificate *hcloud.Certificate
+	if certificate, err = m.certificate(ctx); err != nil {
+		return
+	}
+	if certificate == nil {
+		resp.Msg("No certificate found, nothing to do")
+		return
+	}
+	if len(certificate.UsedBy) > 0 {
+		err = fmt.Errorf("Certificate %d is used by %s", certificate.ID, usedBy(certificate))
+		return
+	}
+	if _, err = m.client.Certificate.Delete(ctx, certificate); err != nil {
+		return
+	}
+	resp.Msg(fmt.Sprintf("Certificate %d deleted", certificate.ID)).Changed()
+	return
+}
+
+func (m *module) list(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var certificates []*hcloud.Certificate
+	if certificates, err = m.client.Certificate.All(ctx); err != nil {
+		return
+	}
+	list := []Certificate{}
+	for _, certificate := range certificates {
+		list = append(list, toCertificate(certificate))
+	}
+	resp.Msg("Certificates listed").Set("certificates", list)
+	return
+}
+
+// certificate looks up the certificate by id or name
+func (m *module) certificate(ctx context.Context) (certificate *hcloud.Certificate, err error) {
+	if id := util.GetID(m.args.ID); id != 0 {
+		certificate, _, err = m.client.Certificate.GetByID(ctx, id)
+		return
+	}
+	certificate, _, err = m.client.Certificate.GetByName(ctx, m.args.Name)
+	return
+}
+
+// fingerprint returns the SHA256 fingerprint of the first certificate in the PEM data
+// in the format of the API, or an empty string if it contains no certificate
+func fingerprint(data string) string {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return ""
+	}
+	sum := sha256.Sum256(block.Bytes)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// sameDomainNames checks if both lists contain the same domains, ignoring the order
+func sameDomainNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func usedBy(certificate *hcloud.Certificate) string {
+	var refs []string
+	for _, ref := range certificate.UsedBy {
+		refs = append(refs, fmt.Sprintf("%s %d", strings.Replace(ref.Type, "_", " ", -1), ref.ID))
+	}
+	return strings.Join(refs, ", ")
+}
+
+func toCertificate(certificate *hcloud.Certificate) Certificate {
+	data := Certificate{
+		ID:          certificate.ID,
+		Name:        certificate.Name,
+		Type:        string(certificate.Type),
+		DomainNames: certificate.DomainNames,
+		Fingerprint: certificate.Fingerprint,
+		UsedBy:      []int{},
+	}
+	if !certificate.NotValidBefore.IsZero() {
+		data.NotValidBefore = certificate.NotValidBefore.Format(time.RFC3339)
+	}
+	if !certificate.NotValidAfter.IsZero() {
+		data.NotValidAfter = certificate.NotValidAfter.Format(time.RFC3339)
+	}
+	if certificate.Status != nil {
+		data.Status = &Status{
+			Issuance: string(certificate.Status.Issuance),
+			Renewal:  string(certificate.Status.Renewal),
+		}
+		if certificate.Status.Error != nil {
+			data.Status.Error = certificate.Status.Error.Message
+		}
+	}
+	for _, ref := range certificate.UsedBy {
+		data.UsedBy = append(data.UsedBy, ref.ID)
+	}
+	return data
+}
+
+// CheckArgs checks the constraints of the arguments the spec cannot express
+func (m *module) CheckArgs() (errs []string) {
+	switch hcloud.CertificateType(m.args.Type) {
+	case hcloud.CertificateTypeUploaded:
+		if len(m.args.DomainNames) > 0 {
+			errs = append(errs, "'domain_names' is only supported for managed certificates")
+		}
+	case hcloud.CertificateTypeManaged:
+		if m.args.Certificate != "" || m.args.PrivateKey != "" {
+			errs = append(errs, "'certificate' and 'private_key' are only supported for uploaded certificates")
+		}
+	}
+	if m.args.Certificate != "" && fingerprint(m.args.Certificate) == "" {
+		errs = append(errs, "'certificate' must be PEM encoded")
+	}
+	if m.args.ExpiryWarningDays != nil && *m.args.ExpiryWarningDays < 0 {
+		errs = append(errs, "'expiry_warning_days' must not be negative")
+	}
+	return
+}
+
+var flags = pflag.NewFlagSet("hcloud_certificate", pflag.ContinueOnError)
+
+func init() {
+	flags.BoolP("version", "v", false, "Print version and exit")
+}
+
+func main() {
+	ansible.RunModule(&module{}, flags)
+}
@@ -0,0 +1,524 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+const (
+	groupByInventory   = "inventory"
+	groupByLabelPrefix = "label:"
+
+	// ungrouped holds all items without a group
+	ungrouped = "ungrouped"
+
+	// hoursPerMonth converts monthly-only prices into hourly costs
+	hoursPerMonth = 30 * 24
+
+	itemServer       = "server"
+	itemServerBackup = "server_backup"
+	itemFloatingIP   = "floating_ip"
+	itemImage        = "image"
+
+	stateAbsent = "absent"
+)
+
+// Cost is a net and gross amount of money
+type Cost = util.Cost
+
+// Costs are the monthly and hourly costs
+type Costs = util.Costs
+
+// Item is the cost of a single resource
+type Item struct {
+	Type    string `json:"type"`
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Monthly Cost   `json:"monthly"`
+	Hourly  Cost   `json:"hourly"`
+
+	groups map[string][]string
+}
+
+func (i Item) costs() Costs {
+	return Costs{Monthly: i.Monthly, Hourly: i.Hourly}
+}
+
+// Report is the module return value of the cost calculation
+type Report struct {
+	Currency string                      `json:"currency"`
+	Total    Costs                       `json:"total"`
+	Items    []Item                      `json:"items"`
+	Groups   map[string]map[string]Costs `json:"groups"`
+}
+
+// Estimate is the module return value of a planned change
+type Estimate struct {
+	Total Costs  `json:"total"`
+	Delta Costs  `json:"delta"`
+	Items []Item `json:"items"`
+}
+
+// plan describes a planned hcloud_server change using the same arguments
+type plan struct {
+	State      string      `json:"state"`
+	Name       interface{} `json:"name"`
+	ServerType string      `json:"server_type"`
+	Datacenter string      `json:"datacenter"`
+	Location   string      `json:"location"`
+}
+
+type arguments struct {
+	Token string `json:"token"`
+
+	GroupBy interface{} `json:"group_by"`
+	Plan    []plan      `json:"plan"`
+}
+
+var argSpec = ansible.ArgSpec{
+	Args: []ansible.Arg{
+		ansible.TokenArg(),
+		{Name: "group_by", Type: ansible.TypeList, Elements: ansible.TypeStr,
+			Description: "A single or list of breakdowns of the total. " +
+				"`inventory` groups by the groups of `hcloud_inventory`, `label:<key>` by the value of a label. " +
+				"Items without a group are summed up as `ungrouped`."},
+		{Name: "plan", Type: ansible.TypeList, Elements: ansible.TypeDict,
+			Description: "List of planned server changes with the `hcloud_server` options `name`, `state`, `server_type`, `datacenter` and `location`. " +
+				"Options that are not set are taken from the existing server."},
+	},
+}
+
+var doc = ansible.Doc{
+	Module:           "hcloud_cost",
+	ShortDescription: "Calculate the cost of a Hetzner Cloud project",
+	Description: "Calculates the monthly and hourly cost of the current Hetzner Cloud project from the current prices. " +
+		"Servers are billed by server type and location, backups as a percentage of the server price, floating IPs and snapshots by month. " +
+		"The module never changes anything and can run in check mode, " +
+		"planned `hcloud_server` changes can be passed as `plan` to estimate their cost before applying them.",
+	Returns: []ansible.Return{
+		{
+			Name: "cost",
+			Description: "The cost of the project. " +
+				"The `type` of an item is `server`, `server_backup`, `floating_ip` or `image`.",
+			Returned: "always",
+			Sample: Report{
+				Currency: "EUR",
+				Total: Costs{
+					Monthly: Cost{Net: 10.7, Gross: 12.733},
+					Hourly:  Cost{Net: 0.0175, Gross: 0.0209},
+				},
+				Items: []Item{{
+					Type:    "server",
+					ID:      1,
+					Name:    "web1",
+					Monthly: Cost{Net: 3, Gross: 3.57},
+					Hourly:  Cost{Net: 0.005, Gross: 0.006},
+				}},
+				Groups: map[string]map[string]Costs{
+					"label:env": {
+						"prod": {
+							Monthly: Cost{Net: 3.6, Gross: 4.284},
+							Hourly:  Cost{Net: 0.006, Gross: 0.0072},
+						},
+						ungrouped: {
+							Monthly: Cost{Net: 7.1, Gross: 8.449},
+							Hourly:  Cost{Net: 0.0115, Gross: 0.0137},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:        "plan",
+			Description: "The cost of the project after the planned changes, the difference to the current cost and the planned servers.",
+			Returned:    "when `plan` is set",
+			Sample: Estimate{
+				Total: Costs{
+					Monthly: Cost{Net: 14.3, Gross: 17.017},
+					Hourly:  Cost{Net: 0.0235, Gross: 0.0281},
+				},
+				Delta: Costs{
+					Monthly: Cost{Net: 3.6, Gross: 4.284},
+					Hourly:  Cost{Net: 0.006, Gross: 0.0072},
+				},
+				Items: []Item{{
+					Type:    "server",
+					Name:    "web2",
+					Monthly: Cost{Net: 3, Gross: 3.57},
+					Hourly:  Cost{Net: 0.005, Gross: 0.006},
+				}},
+			},
+		},
+	},
+	Examples: `
+- name: cost by environment
+  hcloud_cost:
+    group_by: label:env
+  register: cost
+
+- debug:
+    msg: "{{ cost.cost.groups['label:env'] }}"
+
+- name: estimate scaling out the web servers
+  hcloud_cost:
+    plan:
+    - name: [web1, web2, web3]
+      server_type: cx21
+      location: fsn1
+  register: estimate
+
+- name: fail if the change is too expensive
+  fail:
+    msg: "Scaling out costs {{ estimate.plan.delta.monthly.gross }} per month"
+  when: estimate.plan.delta.monthly.gross > 50
+`,
+}
+
+type module struct {
+	args   arguments
+	client *hcloud.Client
+
+	pricing hcloud.Pricing
+	labels  map[string]map[int]hcloud.Labels
+}
+
+func (m *module) Args() interface{} {
+	return &m.args
+}
+
+func (m *module) ArgSpec() ansible.ArgSpec {
+	return argSpec
+}
+
+func (m *module) Doc() ansible.Doc {
+	return doc
+}
+
+func (m *module) Run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	m.client, err = hcloud.BuildClient(m.args.Token)
+	if err != nil {
+		return
+	}
+	return m.run(ctx)
+}
+
+func (m *module) run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	if m.pricing, _, err = m.client.Pricing.Get(ctx); err != nil {
+		return
+	}
+	if err = m.loadLabels(ctx); err != nil {
+		return
+	}
+
+	var servers []*hcloud.Server
+	if servers, err = m.client.Server.All(ctx); err != nil {
+		return
+	}
+	var floatingIPs []*hcloud.FloatingIP
+	if floatingIPs, err = m.client.FloatingIP.All(ctx); err != nil {
+		return
+	}
+	var images []*hcloud.Image
+	if images, err = m.client.Image.All(ctx); err != nil {
+		return
+	}
+
+	var items []Item
+	serversByID := map[int]*hcloud.Server{}
+	for _, server := range servers {
+		serversByID[server.ID] = server
+		var serverItems []Item
+		if serverItems, err = m.serverItems(server); err != nil {
+			return
+		}
+		items = append(items, serverItems...)
+	}
+	for _, floatingIP := range floatingIPs {
+		var item Item
+		if item, err = m.floatingIPItem(floatingIP, serversByID); err != nil {
+			return
+		}
+		items = append(items, item)
+	}
+	for _, image := range images {
+		if image.Type != hcloud.ImageTypeSnapshot {
+			// system images are free and backups are billed with the server
+			continue
+		}
+		var item Item
+		if item, err = m.imageItem(image); err != nil {
+			return
+		}
+		items = append(items, item)
+	}
+
+	report := m.report(items)
+	resp.
+		Msg(fmt.Sprintf("Monthly cost %.2f %s (gross)", report.Total.Monthly.Gross, report.Currency)).
+		Set("cost", report)
+
+	if len(m.args.Plan) > 0 {
+		var estimate Estimate
+		if estimate, err = m.estimate(ctx, report.Total, servers); err != nil {
+			return
+		}
+		resp.Set("plan", estimate)
+	}
+	return
+}
+
+func (m *module) groupBy() []string {
+	return util.GetNames(m.args.GroupBy)
+}
+
+func (m *module) loadLabels(ctx context.Context) (err error) {
+	m.labels = map[string]map[int]hcloud.Labels{}
+	for _, groupBy := range m.groupBy() {
+		if !strings.HasPrefix(groupBy, groupByLabelPrefix) {
+			continue
+		}
+		for _, resource := range []string{
+			hcloud.LabelResourceServers,
+			hcloud.LabelResourceFloatingIPs,
+			hcloud.LabelResourceImages,
+		} {
+			if m.labels[resource], err = m.client.Label.All(ctx, resource); err != nil {
+				return
+			}
+		}
+		return
+	}
+	return
+}
+
+// groups returns the groups of a resource for all group_by options
+func (m *module) groups(resource string, id int, inventoryGroups []string) map[string][]string {
+	groups := map[string][]string{}
+	for _, groupBy := range m.groupBy() {
+		if groupBy == groupByInventory {
+			groups[groupBy] = inventoryGroups
+			continue
+		}
+		key := strings.TrimPrefix(groupBy, groupByLabelPrefix)
+		if value, ok := m.labels[resource][id][key]; ok {
+			groups[groupBy] = []string{value}
+		}
+	}
+	return groups
+}
+
+func (m *module) serverItems(server *hcloud.Server) (items []Item, err error) {
+	var costs Costs
+	if costs, err = util.ServerCosts(m.pricing, server.ServerType.Name, server.Datacenter.Location.Name); err != nil {
+		return
+	}
+	groups := m.groups(hcloud.LabelResourceServers, server.ID, util.InventoryGroups(server))
+	items = append(items, Item{
+		Type:    itemServer,
+		ID:      server.ID,
+		Name:    server.Name,
+		Monthly: costs.Monthly,
+		Hourly:  costs.Hourly,
+		groups:  groups,
+	})
+
+	if server.BackupWindow != "" {
+		var backup Costs
+		if backup, err = util.BackupCosts(m.pricing, costs); err != nil {
+			return
+		}
+		items = append(items, Item{
+			Type:    itemServerBackup,
+			ID:      server.ID,
+			Name:    server.Name,
+			Monthly: backup.Monthly,
+			Hourly:  backup.Hourly,
+			groups:  groups,
+		})
+	}
+	return
+}
+
+func (m *module) floatingIPItem(floatingIP *hcloud.FloatingIP, servers map[int]*hcloud.Server) (item Item, err error) {
+	var monthly Cost
+	if monthly, err = util.ParsePrice(m.pricing.FloatingIP.Monthly); err != nil {
+		return
+	}
+	inventoryGroups := []string{floatingIP.HomeLocation.Name}
+	if floatingIP.Server != nil {
+		if server, ok := servers[floatingIP.Server.ID]; ok {
+			inventoryGroups = util.InventoryGroups(server)
+		}
+	}
+	item = Item{
+		Type:    itemFloatingIP,
+		ID:      floatingIP.ID,
+		Name:    floatingIP.IP.String(),
+		Monthly: monthly,
+		Hourly:  monthly.Scale(1.0 / hoursPerMonth),
+		groups:  m.groups(hcloud.LabelResourceFloatingIPs, floatingIP.ID, inventoryGroups),
+	}
+	return
+}
+
+func (m *module) imageItem(image *hcloud.Image) (item Item, err error) {
+	var perGB Cost
+	if perGB, err = util.ParsePrice(m.pricing.Image.PerGBMonth); err != nil {
+		return
+	}
+	monthly := perGB.Scale(float64(image.ImageSize))
+	name := image.Name
+	if name == "" {
+		name = image.Description
+	}
+	item = Item{
+		Type:    itemImage,
+		ID:      image.ID,
+		Name:    name,
+		Monthly: monthly,
+		Hourly:  monthly.Scale(1.0 / hoursPerMonth),
+		groups:  m.groups(hcloud.LabelResourceImages, image.ID, nil),
+	}
+	return
+}
+
+func (m *module) report(items []Item) Report {
+	report := Report{
+		Currency: m.pricing.Image.PerGBMonth.Currency,
+		Items:    []Item{},
+		Groups:   map[string]map[string]Costs{},
+	}
+	for _, groupBy := range m.groupBy() {
+		report.Groups[groupBy] = map[string]Costs{}
+	}
+
+	for _, item := range items {
+		report.Total = report.Total.Add(item.costs())
+		for _, groupBy := range m.groupBy() {
+			groups := item.groups[groupBy]
+			if len(groups) == 0 {
+				groups = []string{ungrouped}
+			}
+			for _, group := range groups {
+				report.Groups[groupBy][group] = report.Groups[groupBy][group].Add(item.costs())
+			}
+		}
+		item.Monthly, item.Hourly = item.Monthly.Round(), item.Hourly.Round()
+		report.Items = append(report.Items, item)
+	}
+
+	report.Total = report.Total.Round()
+	for _, groups := range report.Groups {
+		for group, costs := range groups {
+			groups[group] = costs.Round()
+		}
+	}
+	return report
+}
+
+// estimate calculates the total costs after the planned changes are applied
+func (m *module) estimate(ctx context.Context, total Costs, servers []*hcloud.Server) (estimate Estimate, err error) {
+	serversByName := map[string]*hcloud.Server{}
+	for _, server := range servers {
+		serversByName[server.Name] = server
+	}
+
+	estimate.Items = []Item{}
+	estimate.Total = total
+	for _, p := range m.args.Plan {
+		for _, name := range util.GetNames(p.Name) {
+			existing := serversByName[name]
+			if existing != nil {
+				var items []Item
+				if items, err = m.serverItems(existing); err != nil {
+					return
+				}
+				for _, item := range items {
+					estimate.Total = estimate.Total.Sub(item.costs())
+				}
+			}
+			if p.State == stateAbsent {
+				continue
+			}
+
+			server := &hcloud.Server{
+				Name:       name,
+				ServerType: &hcloud.ServerType{Name: p.ServerType},
+				Datacenter: &hcloud.Datacenter{Location: &hcloud.Location{Name: p.Location}},
+				Image:      &hcloud.Image{},
+			}
+			if existing != nil {
+				server.ID = existing.ID
+				server.BackupWindow = existing.BackupWindow
+				server.Image = existing.Image
+				if p.ServerType == "" {
+					server.ServerType = existing.ServerType
+				}
+				if p.Location == "" && p.Datacenter == "" {
+					server.Datacenter = existing.Datacenter
+				}
+			}
+			if p.Datacenter != "" {
+				if server.Datacenter, _, err = m.client.Datacenter.Get(ctx, p.Datacenter); err != nil {
+					return
+				}
+				if server.Datacenter == nil {
+					err = fmt.Errorf("datacenter '%s' not found", p.Datacenter)
+					return
+				}
+			}
+			if server.ServerType.Name == "" || server.Datacenter.Location.Name == "" {
+				err = fmt.Errorf("Cannot estimate cost of server '%s': 'server_type' and 'location' or 'datacenter' are required", name)
+				return
+			}
+
+			var items []Item
+			if items, err = m.serverItems(server); err != nil {
+				return
+			}
+			for _, item := range items {
+				estimate.Total = estimate.Total.Add(item.costs())
+				item.Monthly, item.Hourly = item.Monthly.Round(), item.Hourly.Round()
+				estimate.Items = append(estimate.Items, item)
+			}
+		}
+	}
+	estimate.Delta = estimate.Total.Sub(total).Round()
+	estimate.Total = estimate.Total.Round()
+	return
+}
+
+// CheckArgs checks the constraints of the arguments the spec cannot express
+func (m *module) CheckArgs() (errs []string) {
+	for _, groupBy := range util.GetNames(m.args.GroupBy) {
+		if groupBy != groupByInventory &&
+			(!strings.HasPrefix(groupBy, groupByLabelPrefix) || groupBy == groupByLabelPrefix) {
+			errs = append(errs, fmt.Sprintf("'group_by' must be inventory or label:<key>, got %q", groupBy))
+		}
+	}
+	for i, p := range m.args.Plan {
+		if len(util.GetNames(p.Name)) == 0 {
+			errs = append(errs, fmt.Sprintf("'plan[%d].name' is required", i))
+		}
+		if p.Datacenter != "" && p.Location != "" {
+			errs = append(errs, fmt.Sprintf("'plan[%d].datacenter' and 'plan[%d].location' are mutually exclusive", i, i))
+		}
+	}
+	return
+}
+
+var flags = pflag.NewFlagSet("hcloud_cost", pflag.ContinueOnError)
+
+func init() {
+	flags.BoolP("version", "v", false, "Print version and exit")
+}
+
+func main() {
+	ansible.RunModule(&module{}, flags)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud/hcloudtest"
+)
+
+var (
+	fsn1 = &hcloud.Location{Name: "fsn1"}
+
+	pricing = hcloud.Pricing{
+		Image: hcloud.ImagePricing{
+			PerGBMonth: hcloud.Price{Currency: "EUR", Net: "0.0100", Gross: "0.0119"},
+		},
+		FloatingIP: hcloud.FloatingIPPricing{
+			Monthly: hcloud.Price{Currency: "EUR", Net: "1.0000", Gross: "1.1900"},
+		},
+		ServerBackup: hcloud.ServerBackupPricing{Percentage: "20"},
+		ServerTypes: []hcloud.ServerTypePricing{
+			{
+				ServerType: &hcloud.ServerType{Name: "cx11"},
+				Pricings: []hcloud.ServerTypeLocationPricing{{
+					Location: fsn1,
+					Hourly:   hcloud.Price{Net: "0.0050", Gross: "0.0060"},
+					Monthly:  hcloud.Price{Net: "3.0000", Gross: "3.5700"},
+				}},
+			},
+			{
+				ServerType: &hcloud.ServerType{Name: "cx21"},
+				Pricings: []hcloud.ServerTypeLocationPricing{{
+					Location: fsn1,
+					Hourly:   hcloud.Price{Net: "0.0100", Gross: "0.0119"},
+					Monthly:  hcloud.Price{Net: "6.0000", Gross: "7.1400"},
+				}},
+			},
+		},
+	}
+
+	servers = []*hcloud.Server{
+		{
+			ID:           1,
+			Name:         "web1",
+			Status:       hcloud.ServerStatusRunning,
+			ServerType:   &hcloud.ServerType{Name: "cx11"},
+			Datacenter:   &hcloud.Datacenter{Name: "fsn1-dc8", Location: fsn1},
+			Image:        &hcloud.Image{Name: "debian-9"},
+			BackupWindow: "22-02",
+		},
+		{
+			ID:         2,
+			Name:       "db1",
+			Status:     hcloud.ServerStatusRunning,
+			ServerType: &hcloud.ServerType{Name: "cx21"},
+			Datacenter: &hcloud.Datacenter{Name: "fsn1-dc8", Location: fsn1},
+			Image:      &hcloud.Image{Name: "debian-9"},
+		},
+	}
+	floatingIPs = []*hcloud.FloatingIP{
+		{ID: 1, IP: net.ParseIP("192.168.1.1"), HomeLocation: fsn1, Server: &hcloud.Server{ID: 1}},
+	}
+	images = []*hcloud.Image{
+		{ID: 1, Name: "debian-9", Type: hcloud.ImageTypeSystem},
+		{ID: 2, Description: "snapshot", Type: hcloud.ImageTypeSnapshot, ImageSize: 10},
+	}
+)
+
+func newClient() *hcloud.Client {
+	client := hcloud.NewClient()
+	client.Pricing = hcloudtest.NewPricingClientMock()
+	client.Server = hcloudtest.NewServerClientMock()
+	client.FloatingIP = hcloudtest.NewFloatingIPClientMock()
+	client.Image = hcloudtest.NewImageClientMock()
+	client.Label = hcloudtest.NewLabelClientMock()
+
+	var nilResponse *hcloud.Response
+	client.Pricing.(*hcloudtest.PricingClientMock).On("Get", mock.Anything).Return(pricing, nilResponse, nil)
+	client.Server.(*hcloudtest.ServerClientMock).On("All", mock.Anything).Return(servers, nil)
+	client.FloatingIP.(*hcloudtest.FloatingIPClientMock).On("All", mock.Anything).Return(floatingIPs, nil)
+	client.Image.(*hcloudtest.ImageClientMock).On("All", mock.Anything).Return(images, nil)
+
+	labelClientMock := client.Label.(*hcloudtest.LabelClientMock)
+	labelClientMock.On("All", mock.Anything, hcloud.LabelResourceServers).Return(map[int]hcloud.Labels{
+		1: {"env": "prod"},
+		2: {"env": "staging"},
+	}, nil)
+	labelClientMock.On("All", mock.Anything, mock.Anything).Return(map[int]hcloud.Labels{}, nil)
+	return client
+}
+
+func TestCost(t *testing.T) {
+	m := module{
+		client: newClient(),
+		args: arguments{
+			GroupBy: []interface{}{"inventory", "label:env"},
+		},
+	}
+
+	resp, err := m.run(context.Background())
+	if assert.NoError(t, err) {
+		assert.False(t, resp.HasChanged(), "module should not have changed")
+		report := resp.Data()["cost"].(Report)
+		assert.Equal(t, "EUR", report.Currency)
+		// 3.00 web1 + 0.60 backup + 6.00 db1 + 1.00 floating ip + 0.10 snapshot
+		assert.Equal(t, Cost{Net: 10.7, Gross: 12.733}, report.Total.Monthly)
+		assert.Equal(t, Cost{Net: 0.0175, Gross: 0.0209}, report.Total.Hourly)
+		assert.Len(t, report.Items, 5)
+
+		assert.Equal(t, Cost{Net: 10.6, Gross: 12.614}, report.Groups["inventory"]["fsn1"].Monthly)
+		assert.Equal(t, Cost{Net: 0.1, Gross: 0.119}, report.Groups["inventory"]["ungrouped"].Monthly)
+		assert.Equal(t, Cost{Net: 3.6, Gross: 4.284}, report.Groups["label:env"]["prod"].Monthly)
+		assert.Equal(t, Cost{Net: 6, Gross: 7.14}, report.Groups["label:env"]["staging"].Monthly)
+		assert.Equal(t, Cost{Net: 1.1, Gross: 1.309}, report.Groups["label:env"]["ungrouped"].Monthly)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	t.Run("resize and add", func(t *testing.T) {
+		m := module{
+			client: newClient(),
+			args: arguments{
+				Plan: []plan{
+					{Name: "web1", ServerType: "cx21"},
+					{Name: []interface{}{"web2", "web3"}, ServerType: "cx11", Location: "fsn1"},
+				},
+			},
+		}
+
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			estimate := resp.Data()["plan"].(Estimate)
+			// web1: 3.60 -> 7.20, web2 + web3: 2 * 3.00
+			assert.Equal(t, Cost{Net: 9.6, Gross: 11.424}, estimate.Delta.Monthly)
+			assert.Equal(t, Cost{Net: 20.3, Gross: 24.157}, estimate.Total.Monthly)
+			assert.Len(t, estimate.Items, 4)
+		}
+	})
+
+	t.Run("absent", func(t *testing.T) {
+		m := module{
+			client: newClient(),
+			args: arguments{
+				Plan: []plan{
+					{Name: "db1", State: "absent"},
+				},
+			},
+		}
+
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			estimate := resp.Data()["plan"].(Estimate)
+			assert.Equal(t, Cost{Net: -6, Gross: -7.14}, estimate.Delta.Monthly)
+			assert.Equal(t, []Item{}, estimate.Items)
+		}
+	})
+
+	t.Run("missing server type", func(t *testing.T) {
+		m := module{
+			client: newClient(),
+			args: arguments{
+				Plan: []plan{
+					{Name: "new", Location: "fsn1"},
+				},
+			},
+		}
+
+		_, err := m.run(context.Background())
+		assert.EqualError(t, err, "Cannot estimate cost of server 'new': 'server_type' and 'location' or 'datacenter' are required")
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{GroupBy: []interface{}{"inventory", "label:env"}}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{GroupBy: "label:"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{GroupBy: "location"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{Plan: []plan{{ServerType: "cx11"}}}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{Plan: []plan{{Name: "a", Location: "fsn1", Datacenter: "fsn1-dc8"}}}}))
+}
+
+func TestDoc(t *testing.T) {
+	data, err := ioutil.ReadFile("../../docs/hcloud_cost.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(data), doc.Markdown(argSpec), "docs/hcloud_cost.md is outdated, run make docs")
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hetznerdns"
+)
+
+const (
+	stateAbsent  = "absent"
+	statePresent = "present"
+	stateList    = "list"
+)
+
+// Record is the module return value of a hetznerdns.Record
+type Record struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	FQDN  string `json:"fqdn"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	// TTL is 0 if the record uses the TTL of the zone
+	TTL int `json:"ttl"`
+}
+
+// Zone is the module return value of a hetznerdns.Zone
+type Zone struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	TTL  int    `json:"ttl"`
+}
+
+type arguments struct {
+	DNSToken string `json:"dns_token"`
+	State    string `json:"state"`
+
+	Zone      string   `json:"zone"`
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Value     string   `json:"value"`
+	Values    []string `json:"values"`
+	TTL       *int     `json:"ttl"`
+	Exclusive *bool    `json:"exclusive"`
+}
+
+var argSpec = ansible.ArgSpec{
+	Args: []ansible.Arg{
+		{Name: "dns_token", Type: ansible.TypeStr, NoLog: true,
+			Description: "Hetzner DNS API Token. Can also be specified with `HETZNER_DNS_TOKEN` environment variable."},
+		{Name: "state", Type: ansible.TypeStr, Default: statePresent, Choices: []string{statePresent, stateAbsent, stateList},
+			Description: "`list` lists the records of the zone, filtered by `name` and `type` if set."},
+		{Name: "zone", Type: ansible.TypeStr,
+			Description: "Name of the zone, e.g. `example.com`. The zone with the longest matching name is used if not set, `name` must be fully qualified then."},
+		{Name: "name", Type: ansible.TypeStr,
+			Description: "Name of the record, relative to the zone or fully qualified. `@` is the zone apex.\n\nRequired when `state=present` or `state=absent`."},
+		{Name: "type", Type: ansible.TypeStr,
+			Description: "Record type like `A`, `AAAA`, `CNAME`, `MX` or `TXT`.\n\nRequired when `state=present` or `state=absent`."},
+		{Name: "value", Type: ansible.TypeStr,
+			Description: "Value of the record, added to `values`."},
+		{Name: "values", Type: ansible.TypeList, Elements: ansible.TypeStr,
+			Description: "Values of the records. One of `value` or `values` is required when `state=present`. " +
+				"With `state=absent` only records with these values are deleted, all records of the name and type otherwise."},
+		{Name: "ttl", Type: ansible.TypeInt,
+			Description: "TTL of the records in seconds, the TTL of the zone is used if not set. Updates the TTL of existing records."},
+		{Name: "exclusive", Type: ansible.TypeBool, Default: true,
+			Description: "Delete records of the name and type with other values."},
+	},
+	RequiredIf: []ansible.RequiredIf{
+		{Arg: "state", Value: statePresent, Required: []string{"type"}},
+		{Arg: "state", Value: stateAbsent, Required: []string{"type"}},
+		{Arg: "state", Value: statePresent, Required: []string{"value", "values"}, OneOf: true},
+	},
+}
+
+var doc = ansible.Doc{
+	Module:           "hcloud_dns_record",
+	ShortDescription: "Manage Hetzner DNS records",
+	Description: "Manages records in [Hetzner DNS](https://dns.hetzner.com) zones. " +
+		"Hetzner DNS has its own API and token, the zones are managed in the DNS console. " +
+		"To point a name at servers, see the `dns_name` option of `hcloud_server`.\n\n" +
+		"Records are identified by their name and type. " +
+		"By default the given values are the exact set of records of the name and type, records with other values are deleted. " +
+		"With `exclusive: false` missing values are added and other records are kept.",
+	Returns: []ansible.Return{
+		{
+			Name:        "zone",
+			Description: "The zone of the records.",
+			Returned:    "always",
+			Sample:      Zone{ID: "2oPe1C4wPhvkQFRJcTe5qs", Name: "example.com", TTL: 86400},
+		},
+		{
+			Name: "records",
+			Description: "The records of the name and type after the change, or the records of the zone with `state=list`.\n\n" +
+				"`ttl` is `0` for records using the TTL of the zone.",
+			Returned: "unless `state=absent`",
+			Sample: []Record{{
+				ID:    "7f1e4a0e1d1a6a21c1d2e2b9b0f3ddc8",
+				Name:  "web",
+				FQDN:  "web.example.com",
+				Type:  "A",
+				Value: "203.0.113.1",
+				TTL:   300,
+			}},
+		},
+	},
+	Examples: `
+# round-robin A records, other A records of web are deleted
+- hcloud_dns_record:
+    zone: example.com
+    name: web
+    type: A
+    values:
+    - 203.0.113.1
+    - 203.0.113.2
+    ttl: 300
+
+# point a name at a floating IP
+- hcloud_floating_ip:
+    id: 123
+  register: floating_ip
+- hcloud_dns_record:
+    name: www.example.com
+    type: A
+    value: "{{ floating_ip.floating_ips[0].ip }}"
+
+# add a TXT record to the zone apex, keeping existing ones
+- hcloud_dns_record:
+    zone: example.com
+    name: "@"
+    type: TXT
+    value: "google-site-verification=abc"
+    exclusive: false
+
+# delete all AAAA records of web
+- hcloud_dns_record:
+    zone: example.com
+    name: web
+    type: AAAA
+    state: absent
+
+# list the records of a zone
+- hcloud_dns_record:
+    zone: example.com
+    state: list
+  register: dns
+`,
+}
+
+type module struct {
+	args   arguments
+	client *hetznerdns.Client
+}
+
+func (m *module) Args() interface{} {
+	return &m.args
+}
+
+func (m *module) ArgSpec() ansible.ArgSpec {
+	return argSpec
+}
+
+func (m *module) Doc() ansible.Doc {
+	return doc
+}
+
+func (m *module) Run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	m.client, err = hetznerdns.BuildClient(m.args.DNSToken)
+	if err != nil {
+		return
+	}
+	return m.run(ctx)
+}
+
+func (m *module) run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	m.args.Type = strings.ToUpper(m.args.Type)
+	if m.args.Value != "" {
+		m.args.Values = append([]string{m.args.Value}, m.args.Values...)
+	}
+
+	switch m.args.State {
+	case stateList:
+		return m.list(ctx)
+	case stateAbsent:
+		return m.absent(ctx)
+	case statePresent:
+		return m.present(ctx)
+	default:
+		err = errors.New("invalid state")
+		return
+	}
+}
+
+func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var (
+		zone *hetznerdns.Zone
+		name string
+	)
+	if zone, name, err = m.zone(ctx); err != nil {
+		return
+	}
+
+	var result hetznerdns.RecordSetResult
+	if result, err = hetznerdns.EnsureRecordSet(ctx, m.client.Record, zone, hetznerdns.RecordSet{
+		Name:      name,
+		Type:      m.args.Type,
+		Values:    m.args.Values,
+		TTL:       m.args.TTL,
+		Exclusive: m.args.Exclusive == nil || *m.args.Exclusive,
+	}); err != nil {
+		return
+	}
+
+	if result.Changed() {
+		resp.Changed().Msg(fmt.Sprintf("%s records of %s: %d created, %d updated, %d deleted",
+			m.args.Type, fqdn(zone, name), len(result.Created), len(result.Updated), len(result.Deleted)))
+	} else {
+		resp.Msg(fmt.Sprintf("%s records of %s are up to date, nothing to do", m.args.Type, fqdn(zone, name)))
+	}
+	resp.
+		Set("zone", toZone(zone)).
+		Set("records", toRecords(zone, result.Records))
+	return
+}
+
+func (m *module) absent(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var (
+		zone *hetznerdns.Zone
+		name string
+	)
+	if zone, name, err = m.zone(ctx); err != nil {
+		return
+	}
+
+	var deleted []*hetznerdns.Record
+	if deleted, err = hetznerdns.DeleteRecords(ctx, m.client.Record, zone, name, m.args.Type, m.args.Values); err != nil {
+		return
+	}
+	if len(deleted) > 0 {
+		resp.Changed().Msg(fmt.Sprintf("%d %s records of %s deleted", len(deleted), m.args.Type, fqdn(zone, name)))
+	} else {
+		resp.Msg(fmt.Sprintf("No %s records of %s found, nothing to do", m.args.Type, fqdn(zone, name)))
+	}
+	resp.Set("zone", toZone(zone))
+	return
+}
+
+func (m *module) list(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var (
+		zone *hetznerdns.Zone
+		name string
+	)
+	if zone, name, err = m.zone(ctx); err != nil {
+		return
+	}
+
+	var records []*hetznerdns.Record
+	if records, err = hetznerdns.MatchingRecords(ctx, m.client.Record, zone, name, m.args.Type); err != nil {
+		return
+	}
+	resp.
+		Msg("Records listed").
+		Set("zone", toZone(zone)).
+		Set("records", toRecords(zone, records))
+	return
+}
+
+// zone looks up the zone by name or by the fully qualified record name,
+// and returns the record name relative to the zone
+func (m *module) zone(ctx context.Context) (zone *hetznerdns.Zone, name string, err error) {
+	if m.args.Zone == "" {
+		return hetznerdns.FindZone(ctx, m.client.Zone, m.args.Name)
+	}
+
+	zoneName := strings.TrimSuffix(strings.ToLower(m.args.Zone), ".")
+	if zone, err = m.client.Zone.GetByName(ctx, zoneName); err != nil {
+		return
+	}
+	if zone == nil {
+		err = fmt.Errorf("DNS zone %q not found", zoneName)
+		return
+	}
+	if m.args.Name != "" {
+		name = hetznerdns.RelativeName(zone, m.args.Name)
+	}
+	return
+}
+
+func fqdn(zone *hetznerdns.Zone, name string) string {
+	if name == "" || name == "@" {
+		return zone.Name
+	}
+	return name + "." + zone.Name
+}
+
+func toZone(zone *hetznerdns.Zone) Zone {
+	return Zone{ID: zone.ID, Name: zone.Name, TTL: zone.TTL}
+}
+
+func toRecords(zone *hetznerdns.Zone, records []*hetznerdns.Record) []Record {
+	r := []Record{}
+	for _, record := range records {
+		data := Record{
+			ID:    record.ID,
+			Name:  record.Name,
+			FQDN:  fqdn(zone, record.Name),
+			Type:  record.Type,
+			Value: record.Value,
+		}
+		if record.TTL != nil {
+			data.TTL = *record.TTL
+		}
+		r = append(r, data)
+	}
+	return r
+}
+
+// CheckArgs checks the constraints of the arguments the spec cannot express
+func (m *module) CheckArgs() (errs []string) {
+	if m.args.Zone == "" && m.args.Name == "" {
+		errs = append(errs, "'zone' or a fully qualified 'name' is required")
+	}
+	if m.args.State != stateList && m.args.Name == "" {
+		errs = append(errs, "'name' is required, use '@' for the zone apex")
+	}
+	if m.args.TTL != nil && *m.args.TTL <= 0 {
+		errs = append(errs, "'ttl' must be positive")
+	}
+	return
+}
+
+var flags = pflag.NewFlagSet("hcloud_dns_record", pflag.ContinueOnError)
+
+func init() {
+	flags.BoolP("version", "v", false, "Print version and exit")
+}
+
+func main() {
+	ansible.RunModule(&module{}, flags)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hetznerdns"
+	"github.com/thetechnick/hcloud-ansible/pkg/hetznerdns/hetznerdnstest"
+)
+
+var (
+	nilZone = (*hetznerdns.Zone)(nil)
+	zone    = &hetznerdns.Zone{ID: "z1", Name: "example.com", TTL: 86400}
+)
+
+func newClient(records ...*hetznerdns.Record) *hetznerdns.Client {
+	client := &hetznerdns.Client{
+		Zone:   hetznerdnstest.NewZoneClientMock(),
+		Record: hetznerdnstest.NewRecordClientMock(),
+	}
+	client.Zone.(*hetznerdnstest.ZoneClientMock).On("GetByName", mock.Anything, "example.com").Return(zone, nil)
+	client.Zone.(*hetznerdnstest.ZoneClientMock).On("All", mock.Anything).Return([]*hetznerdns.Zone{zone}, nil)
+	client.Record.(*hetznerdnstest.RecordClientMock).On("List", mock.Anything, zone).Return(records, nil)
+	return client
+}
+
+func TestPresent(t *testing.T) {
+	t.Run("exclusive", func(t *testing.T) {
+		old := &hetznerdns.Record{ID: "r1", ZoneID: "z1", Name: "web", Type: "A", Value: "203.0.113.1"}
+		kept := &hetznerdns.Record{ID: "r2", ZoneID: "z1", Name: "web", Type: "A", Value: "203.0.113.2"}
+		other := &hetznerdns.Record{ID: "r3", ZoneID: "z1", Name: "www", Type: "A", Value: "203.0.113.1"}
+		client := newClient(old, kept, other)
+		recordMock := client.Record.(*hetznerdnstest.RecordClientMock)
+		recordMock.On("Delete", mock.Anything, old).Return(nil)
+		recordMock.On("Create", mock.Anything, hetznerdns.RecordOpts{
+			ZoneID: "z1", Name: "web", Type: "A", Value: "203.0.113.3",
+		}).Return(&hetznerdns.Record{ID: "r4", ZoneID: "z1", Name: "web", Type: "A", Value: "203.0.113.3"}, nil)
+
+		m := module{
+			client: client,
+			args: arguments{
+				State:  statePresent,
+				Name:   "web.example.com",
+				Type:   "a",
+				Values: []string{"203.0.113.2", "203.0.113.3"},
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			assert.Equal(t, []Record{
+				{ID: "r2", Name: "web", FQDN: "web.example.com", Type: "A", Value: "203.0.113.2"},
+				{ID: "r4", Name: "web", FQDN: "web.example.com", Type: "A", Value: "203.0.113.3"},
+			}, resp.Data()["records"])
+			recordMock.AssertNotCalled(t, "Delete", mock.Anything, other)
+		}
+	})
+
+	t.Run("upsert", func(t *testing.T) {
+		existing := &hetznerdns.Record{ID: "r1", ZoneID: "z1", Name: "@", Type: "TXT", Value: "v=spf1 -all"}
+		client := newClient(existing)
+		client.Record.(*hetznerdnstest.RecordClientMock).On("Create", mock.Anything, hetznerdns.RecordOpts{
+			ZoneID: "z1", Name: "@", Type: "TXT", Value: "verification",
+		}).Return(&hetznerdns.Record{ID: "r2", ZoneID: "z1", Name: "@", Type: "TXT", Value: "verification"}, nil)
+
+		exclusive := false
+		m := module{
+			client: client,
+			args: arguments{
+				State:     statePresent,
+				Zone:      "example.com",
+				Name:      "@",
+				Type:      "TXT",
+				Value:     "verification",
+				Exclusive: &exclusive,
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			assert.Len(t, resp.Data()["records"], 2)
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		client := newClient(&hetznerdns.Record{ID: "r1", ZoneID: "z1", Name: "web", Type: "AAAA", Value: "2001:db8:0::1"})
+
+		m := module{
+			client: client,
+			args:   arguments{State: statePresent, Zone: "example.com", Name: "web", Type: "AAAA", Value: "2001:db8::1"},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.False(t, resp.HasChanged(), "module should not have changed")
+		}
+	})
+
+	t.Run("zone not found", func(t *testing.T) {
+		client := newClient()
+		client.Zone.(*hetznerdnstest.ZoneClientMock).On("GetByName", mock.Anything, "example.org").Return(nilZone, nil)
+
+		m := module{
+			client: client,
+			args:   arguments{State: statePresent, Zone: "example.org", Name: "web", Type: "A", Value: "203.0.113.1"},
+		}
+		_, err := m.run(context.Background())
+		assert.EqualError(t, err, `DNS zone "example.org" not found`)
+	})
+}
+
+func TestAbsent(t *testing.T) {
+	record := &hetznerdns.Record{ID: "r1", ZoneID: "z1", Name: "web", Type: "A", Value: "203.0.113.1"}
+	client := newClient(record, &hetznerdns.Record{ID: "r2", ZoneID: "z1", Name: "web", Type: "AAAA", Value: "2001:db8::1"})
+	client.Record.(*hetznerdnstest.RecordClientMock).On("Delete", mock.Anything, record).Return(nil)
+
+	m := module{
+		client: client,
+		args:   arguments{State: stateAbsent, Zone: "example.com", Name: "web", Type: "A"},
+	}
+	resp, err := m.run(context.Background())
+	if assert.NoError(t, err) {
+		assert.True(t, resp.HasChanged(), "module should have changed")
+	}
+}
+
+func TestList(t *testing.T) {
+	ttl := 300
+	client := newClient(
+		&hetznerdns.Record{ID: "r1", ZoneID: "z1", Name: "web", Type: "A", Value: "203.0.113.1", TTL: &ttl},
+		&hetznerdns.Record{ID: "r2", ZoneID: "z1", Name: "@", Type: "NS", Value: "hydrogen.ns.hetzner.com."},
+	)
+
+	m := module{client: client, args: arguments{State: stateList, Zone: "example.com"}}
+	resp, err := m.run(context.Background())
+	if assert.NoError(t, err) {
+		assert.Equal(t, Zone{ID: "z1", Name: "example.com", TTL: 86400}, resp.Data()["zone"])
+		assert.Equal(t, []Record{
+			{ID: "r1", Name: "web", FQDN: "web.example.com", Type: "A", Value: "203.0.113.1", TTL: 300},
+			{ID: "r2", Name: "@", FQDN: "example.com", Type: "NS", Value: "hydrogen.ns.hetzner.com."},
+		}, resp.Data()["records"])
+	}
+}
+
+func TestValidateArgs(t *testing.T) {
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web.example.com", Type: "A", Values: []string{"203.0.113.1"}}}))
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: stateAbsent, Zone: "example.com", Name: "web", Type: "A"}}))
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: stateList, Zone: "example.com"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: stateList}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Zone: "example.com", Name: "web", Type: "A"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Zone: "example.com", Type: "A", Values: []string{"203.0.113.1"}}}))
+}
+
+func TestDoc(t *testing.T) {
+	data, err := ioutil.ReadFile("../../docs/hcloud_dns_record.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(data), doc.Markdown(argSpec), "docs/hcloud_dns_record.md is outdated, run make docs")
+	}
+}
@@ -0,0 +1,603 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+const (
+	subsetAll         = "all"
+	subsetDatacenters = "datacenters"
+	subsetLocations   = "locations"
+	subsetServerTypes = "server_types"
+	subsetImages      = "images"
+	subsetISOs        = "isos"
+	subsetPricing     = "pricing"
+)
+
+var subsets = []string{
+	subsetDatacenters,
+	subsetLocations,
+	subsetServerTypes,
+	subsetImages,
+	subsetISOs,
+	subsetPricing,
+}
+
+// Datacenter is the fact value of an hcloud.Datacenter
+type Datacenter struct {
+	ID                   int      `json:"id"`
+	Name                 string   `json:"name"`
+	Description          string   `json:"description"`
+	Location             string   `json:"location"`
+	ServerTypesSupported []string `json:"server_types_supported"`
+	ServerTypesAvailable []string `json:"server_types_available"`
+}
+
+// Location is the fact value of an hcloud.Location
+type Location struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Country     string  `json:"country"`
+	City        string  `json:"city"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+}
+
+// ServerType is the fact value of an hcloud.ServerType
+type ServerType struct {
+	ID          int               `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Cores       int               `json:"cores"`
+	Memory      float32           `json:"memory"`
+	Disk        int               `json:"disk"`
+	StorageType string            `json:"storage_type"`
+	Prices      []ServerTypePrice `json:"prices"`
+}
+
+// ServerTypePrice is the fact value of an hcloud.ServerTypeLocationPricing
+type ServerTypePrice struct {
+	Location     string `json:"location"`
+	PriceHourly  Price  `json:"price_hourly"`
+	PriceMonthly Price  `json:"price_monthly"`
+}
+
+// Price is the fact value of an hcloud.Price
+type Price struct {
+	Net   string `json:"net"`
+	Gross string `json:"gross"`
+}
+
+// Image is the fact value of an hcloud.Image
+type Image struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	Status      string    `json:"status"`
+	Description string    `json:"description"`
+	ImageSize   float32   `json:"image_size"`
+	DiskSize    float32   `json:"disk_size"`
+	Created     time.Time `json:"created"`
+	OSFlavor    string    `json:"os_flavor"`
+	OSVersion   string    `json:"os_version"`
+	RapidDeploy bool      `json:"rapid_deploy"`
+	CreatedFrom *int      `json:"created_from"`
+	BoundTo     *int      `json:"bound_to"`
+}
+
+// ISO is the fact value of an hcloud.ISO
+type ISO struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Deprecated  bool   `json:"deprecated"`
+}
+
+// Pricing is the fact value of an hcloud.Pricing, the prices of
+// server types are part of their facts
+type Pricing struct {
+	Currency               string `json:"currency"`
+	VATRate                string `json:"vat_rate"`
+	FloatingIPPriceMonthly Price  `json:"floating_ip_price_monthly"`
+	ImagePricePerGBMonth   Price  `json:"image_price_per_gb_month"`
+	TrafficPricePerTB      Price  `json:"traffic_price_per_tb"`
+	ServerBackupPercentage string `json:"server_backup_percentage"`
+}
+
+// filter matches fact fields against the expected values
+type filter map[string]interface{}
+
+type arguments struct {
+	Token string `json:"token"`
+
+	GatherSubset interface{}       `json:"gather_subset"`
+	Location     string            `json:"location"`
+	Filters      map[string]filter `json:"filters"`
+}
+
+var argSpec = ansible.ArgSpec{
+	Args: []ansible.Arg{
+		ansible.TokenArg(),
+		{Name: "gather_subset", Type: ansible.TypeList, Elements: ansible.TypeStr, Default: subsetAll, Choices: append([]string{subsetAll}, subsets...),
+			Description: "A single subset or list of subsets to gather."},
+		{Name: "location", Type: ansible.TypeStr,
+			Description: "Only return datacenters and locations with this location name and server type prices for this location."},
+		{Name: "filters", Type: ansible.TypeDict,
+			Description: "Dict of subset name to a dict of field filters, `pricing` cannot be filtered. " +
+				"An item is returned if every field equals the value, or one of the values if a list is given. " +
+				"List fields match if they contain the value."},
+	},
+}
+
+var doc = ansible.Doc{
+	Module:           "hcloud_facts",
+	ShortDescription: "Gather facts about Hetzner Cloud datacenters, locations, server types, images, ISOs and prices",
+	Description: "Gathers facts about the read-only resources of Hetzner Cloud: datacenters, locations, server types (including their prices), images, ISOs and the prices of other resources. " +
+		"The module never changes anything, the results are returned as `ansible_facts` and can be used in templates and conditionals.",
+	Returns: []ansible.Return{
+		{
+			Name:        "hcloud_datacenters",
+			Description: "Host fact of the datacenters and the server types they support.",
+			Returned:    "when `datacenters` are gathered",
+			Sample: []Datacenter{{
+				ID:                   2,
+				Name:                 "nbg1-dc3",
+				Description:          "Nuremberg 1 DC 3",
+				Location:             "nbg1",
+				ServerTypesSupported: []string{"cx11", "cx21"},
+				ServerTypesAvailable: []string{"cx11", "cx21"},
+			}},
+		},
+		{
+			Name:        "hcloud_locations",
+			Description: "Host fact of the locations.",
+			Returned:    "when `locations` are gathered",
+			Sample: []Location{{
+				ID:          2,
+				Name:        "nbg1",
+				Description: "Nuremberg DC Park 1",
+				Country:     "DE",
+				City:        "Nuremberg",
+				Latitude:    49.452102,
+				Longitude:   11.076665,
+			}},
+		},
+		{
+			Name:        "hcloud_server_types",
+			Description: "Host fact of the server types and their prices per location.",
+			Returned:    "when `server_types` are gathered",
+			Sample: []ServerType{{
+				ID:          1,
+				Name:        "cx11",
+				Description: "CX11",
+				Cores:       1,
+				Memory:      2,
+				Disk:        20,
+				StorageType: "local",
+				Prices: []ServerTypePrice{{
+					Location:     "nbg1",
+					PriceHourly:  Price{Net: "0.0040000000", Gross: "0.0047600000000000"},
+					PriceMonthly: Price{Net: "2.4900000000", Gross: "2.9631000000000000"},
+				}},
+			}},
+		},
+		{
+			Name:        "hcloud_images",
+			Description: "Host fact of the images.",
+			Returned:    "when `images` are gathered",
+			Sample: []Image{{
+				ID:          1,
+				Name:        "debian-9",
+				Type:        "system",
+				Status:      "available",
+				Description: "Debian 9",
+				DiskSize:    5,
+				Created:     time.Date(2018, 1, 15, 11, 34, 45, 0, time.UTC),
+				OSFlavor:    "debian",
+				OSVersion:   "9",
+				RapidDeploy: true,
+			}},
+		},
+		{
+			Name:        "hcloud_isos",
+			Description: "Host fact of the ISOs.",
+			Returned:    "when `isos` are gathered",
+			Sample: []ISO{{
+				ID:          1,
+				Name:        "ubuntu-17.10.1-server-amd64.iso",
+				Description: "Ubuntu 17.10.1 (amd64)",
+				Type:        "public",
+			}},
+		},
+		{
+			Name:        "hcloud_pricing",
+			Description: "Host fact of the prices of floating IPs, images, traffic and server backups. Server backups cost a percentage of the server price.",
+			Returned:    "when `pricing` is gathered",
+			Sample: Pricing{
+				Currency:               "EUR",
+				VATRate:                "19.000000",
+				FloatingIPPriceMonthly: Price{Net: "1.0000000000", Gross: "1.1900000000000000"},
+				ImagePricePerGBMonth:   Price{Net: "0.0100000000", Gross: "0.0119000000000000"},
+				TrafficPricePerTB:      Price{Net: "1.0000000000", Gross: "1.1900000000000000"},
+				ServerBackupPercentage: "20.0000000000",
+			},
+		},
+	},
+	Examples: `
+# gather all facts
+- hcloud_facts:
+
+# show the monthly prices of server types with 4 or 8 cores in fsn1
+- hcloud_facts:
+    gather_subset: server_types
+    location: fsn1
+    filters:
+      server_types:
+        cores: [4, 8]
+
+- debug:
+    msg: "{{ item.name }}: {{ item.prices[0].price_monthly.gross }}"
+  with_items: "{{ hcloud_server_types }}"
+
+# show the monthly price of a floating IP
+- hcloud_facts:
+    gather_subset: pricing
+
+- debug:
+    msg: "{{ hcloud_pricing.floating_ip_price_monthly.gross }} {{ hcloud_pricing.currency }}"
+
+# list all debian snapshots
+- hcloud_facts:
+    gather_subset: images
+    filters:
+      images:
+        type: snapshot
+        os_flavor: debian
+`,
+}
+
+type module struct {
+	args   arguments
+	client *hcloud.Client
+}
+
+func (m *module) Args() interface{} {
+	return &m.args
+}
+
+func (m *module) ArgSpec() ansible.ArgSpec {
+	return argSpec
+}
+
+func (m *module) Doc() ansible.Doc {
+	return doc
+}
+
+func (m *module) Run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	m.client, err = hcloud.BuildClient(m.args.Token)
+	if err != nil {
+		return
+	}
+	return m.run(ctx)
+}
+
+func (m *module) run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	facts := map[string]interface{}{}
+	for _, subset := range gatherSubsets(m.args.GatherSubset) {
+		var value interface{}
+		switch subset {
+		case subsetDatacenters:
+			value, err = m.datacenters(ctx)
+		case subsetLocations:
+			value, err = m.locations(ctx)
+		case subsetServerTypes:
+			value, err = m.serverTypes(ctx)
+		case subsetImages:
+			value, err = m.images(ctx)
+		case subsetISOs:
+			value, err = m.isos(ctx)
+		case subsetPricing:
+			value, err = m.pricing(ctx)
+		}
+		if err != nil {
+			return
+		}
+		facts["hcloud_"+subset] = value
+	}
+	resp.Set("ansible_facts", facts)
+	return
+}
+
+func (m *module) datacenters(ctx context.Context) (list []Datacenter, err error) {
+	var datacenters []*hcloud.Datacenter
+	if datacenters, err = m.client.Datacenter.All(ctx); err != nil {
+		return
+	}
+	list = []Datacenter{}
+	for _, datacenter := range datacenters {
+		if m.args.Location != "" && datacenter.Location.Name != m.args.Location {
+			continue
+		}
+		d := toDatacenter(datacenter)
+		if m.args.Filters[subsetDatacenters].matches(d) {
+			list = append(list, d)
+		}
+	}
+	return
+}
+
+func (m *module) locations(ctx context.Context) (list []Location, err error) {
+	var locations []*hcloud.Location
+	if locations, err = m.client.Location.All(ctx); err != nil {
+		return
+	}
+	list = []Location{}
+	for _, location := range locations {
+		if m.args.Location != "" && location.Name != m.args.Location {
+			continue
+		}
+		l := toLocation(location)
+		if m.args.Filters[subsetLocations].matches(l) {
+			list = append(list, l)
+		}
+	}
+	return
+}
+
+func (m *module) serverTypes(ctx context.Context) (list []ServerType, err error) {
+	var serverTypes []*hcloud.ServerType
+	if serverTypes, err = m.client.ServerType.All(ctx); err != nil {
+		return
+	}
+	list = []ServerType{}
+	for _, serverType := range serverTypes {
+		s := toServerType(serverType, m.args.Location)
+		if m.args.Filters[subsetServerTypes].matches(s) {
+			list = append(list, s)
+		}
+	}
+	return
+}
+
+func (m *module) images(ctx context.Context) (list []Image, err error) {
+	var images []*hcloud.Image
+	if images, err = m.client.Image.All(ctx); err != nil {
+		return
+	}
+	list = []Image{}
+	for _, image := range images {
+		i := toImage(image)
+		if m.args.Filters[subsetImages].matches(i) {
+			list = append(list, i)
+		}
+	}
+	return
+}
+
+func (m *module) isos(ctx context.Context) (list []ISO, err error) {
+	var isos []*hcloud.ISO
+	if isos, err = m.client.ISO.All(ctx); err != nil {
+		return
+	}
+	list = []ISO{}
+	for _, iso := range isos {
+		i := toISO(iso)
+		if m.args.Filters[subsetISOs].matches(i) {
+			list = append(list, i)
+		}
+	}
+	return
+}
+
+func (m *module) pricing(ctx context.Context) (p Pricing, err error) {
+	var pricing hcloud.Pricing
+	if pricing, _, err = m.client.Pricing.Get(ctx); err != nil {
+		return
+	}
+	return toPricing(pricing), nil
+}
+
+// matches checks if all filter fields match the field of the fact.
+// A list of values in the filter matches any of them,
+// a list field of the fact matches if it contains the value.
+func (f filter) matches(fact interface{}) bool {
+	if len(f) == 0 {
+		return true
+	}
+	var fields map[string]interface{}
+	b, _ := json.Marshal(fact)
+	json.Unmarshal(b, &fields)
+
+	for key, expected := range f {
+		field, ok := fields[key]
+		if !ok {
+			return false
+		}
+		if !valueMatches(field, expected) {
+			return false
+		}
+	}
+	return true
+}
+
+func valueMatches(field, expected interface{}) bool {
+	if values, ok := expected.([]interface{}); ok {
+		for _, v := range values {
+			if valueMatches(field, v) {
+				return true
+			}
+		}
+		return false
+	}
+	if values, ok := field.([]interface{}); ok {
+		for _, v := range values {
+			if valueMatches(v, expected) {
+				return true
+			}
+		}
+		return false
+	}
+	return fmt.Sprint(field) == fmt.Sprint(expected)
+}
+
+func gatherSubsets(value interface{}) []string {
+	names := util.GetNames(value)
+	if len(names) == 0 {
+		return subsets
+	}
+	for _, name := range names {
+		if name == subsetAll {
+			return subsets
+		}
+	}
+	return names
+}
+
+func toDatacenter(datacenter *hcloud.Datacenter) Datacenter {
+	d := Datacenter{
+		ID:                   datacenter.ID,
+		Name:                 datacenter.Name,
+		Description:          datacenter.Description,
+		Location:             datacenter.Location.Name,
+		ServerTypesSupported: []string{},
+		ServerTypesAvailable: []string{},
+	}
+	for _, serverType := range datacenter.ServerTypes.Supported {
+		d.ServerTypesSupported = append(d.ServerTypesSupported, serverType.Name)
+	}
+	for _, serverType := range datacenter.ServerTypes.Available {
+		d.ServerTypesAvailable = append(d.ServerTypesAvailable, serverType.Name)
+	}
+	return d
+}
+
+func toLocation(location *hcloud.Location) Location {
+	return Location{
+		ID:          location.ID,
+		Name:        location.Name,
+		Description: location.Description,
+		Country:     location.Country,
+		City:        location.City,
+		Latitude:    location.Latitude,
+		Longitude:   location.Longitude,
+	}
+}
+
+func toServerType(serverType *hcloud.ServerType, location string) ServerType {
+	s := ServerType{
+		ID:          serverType.ID,
+		Name:        serverType.Name,
+		Description: serverType.Description,
+		Cores:       serverType.Cores,
+		Memory:      serverType.Memory,
+		Disk:        serverType.Disk,
+		StorageType: string(serverType.StorageType),
+		Prices:      []ServerTypePrice{},
+	}
+	for _, pricing := range serverType.Pricings {
+		if location != "" && pricing.Location.Name != location {
+			continue
+		}
+		s.Prices = append(s.Prices, ServerTypePrice{
+			Location:     pricing.Location.Name,
+			PriceHourly:  Price{Net: pricing.Hourly.Net, Gross: pricing.Hourly.Gross},
+			PriceMonthly: Price{Net: pricing.Monthly.Net, Gross: pricing.Monthly.Gross},
+		})
+	}
+	return s
+}
+
+func toImage(image *hcloud.Image) Image {
+	i := Image{
+		ID:          image.ID,
+		Name:        image.Name,
+		Type:        string(image.Type),
+		Status:      string(image.Status),
+		Description: image.Description,
+		ImageSize:   image.ImageSize,
+		DiskSize:    image.DiskSize,
+		Created:     image.Created,
+		OSFlavor:    image.OSFlavor,
+		OSVersion:   image.OSVersion,
+		RapidDeploy: image.RapidDeploy,
+	}
+	if image.CreatedFrom != nil {
+		i.CreatedFrom = &image.CreatedFrom.ID
+	}
+	if image.BoundTo != nil {
+		i.BoundTo = &image.BoundTo.ID
+	}
+	return i
+}
+
+func toISO(iso *hcloud.ISO) ISO {
+	return ISO{
+		ID:          iso.ID,
+		Name:        iso.Name,
+		Description: iso.Description,
+		Type:        string(iso.Type),
+		Deprecated:  iso.IsDeprecated(),
+	}
+}
+
+func toPricing(pricing hcloud.Pricing) Pricing {
+	return Pricing{
+		Currency:               pricing.FloatingIP.Monthly.Currency,
+		VATRate:                pricing.FloatingIP.Monthly.VATRate,
+		FloatingIPPriceMonthly: Price{Net: pricing.FloatingIP.Monthly.Net, Gross: pricing.FloatingIP.Monthly.Gross},
+		ImagePricePerGBMonth:   Price{Net: pricing.Image.PerGBMonth.Net, Gross: pricing.Image.PerGBMonth.Gross},
+		TrafficPricePerTB:      Price{Net: pricing.Traffic.PerTB.Net, Gross: pricing.Traffic.PerTB.Gross},
+		ServerBackupPercentage: pricing.ServerBackup.Percentage,
+	}
+}
+
+// filterSubsets returns the subsets that can be filtered, all but the single pricing fact
+func filterSubsets() []string {
+	var names []string
+	for _, subset := range subsets {
+		if subset != subsetPricing {
+			names = append(names, subset)
+		}
+	}
+	return names
+}
+
+func isFilterSubset(name string) bool {
+	for _, subset := range filterSubsets() {
+		if subset == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckArgs checks the constraints of the arguments the spec cannot express
+func (m *module) CheckArgs() (errs []string) {
+	for name := range m.args.Filters {
+		if !isFilterSubset(name) {
+			errs = append(errs, fmt.Sprintf("'filters' keys must be one of %s, got %q", strings.Join(filterSubsets(), ", "), name))
+		}
+	}
+	return
+}
+
+var flags = pflag.NewFlagSet("hcloud_facts", pflag.ContinueOnError)
+
+func init() {
+	flags.BoolP("version", "v", false, "Print version and exit")
+}
+
+func main() {
+	ansible.RunModule(&module{}, flags)
+}
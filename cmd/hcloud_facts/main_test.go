@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud/hcloudtest"
+)
+
+var (
+	fsn1 = &hcloud.Location{ID: 1, Name: "fsn1", City: "Falkenstein"}
+	nbg1 = &hcloud.Location{ID: 2, Name: "nbg1", City: "Nuremberg"}
+
+	cx11 = &hcloud.ServerType{
+		ID:          1,
+		Name:        "cx11",
+		Cores:       1,
+		Memory:      2,
+		StorageType: "local",
+		Pricings: []hcloud.ServerTypeLocationPricing{
+			{
+				Location: fsn1,
+				Hourly:   hcloud.Price{Net: "0.0040", Gross: "0.0048"},
+				Monthly:  hcloud.Price{Net: "2.4900", Gross: "2.9631"},
+			},
+			{
+				Location: nbg1,
+				Hourly:   hcloud.Price{Net: "0.0040", Gross: "0.0048"},
+				Monthly:  hcloud.Price{Net: "2.4900", Gross: "2.9631"},
+			},
+		},
+	}
+	cx21 = &hcloud.ServerType{ID: 2, Name: "cx21", Cores: 2, Memory: 4, StorageType: "local"}
+
+	datacenters = []*hcloud.Datacenter{
+		{ID: 1, Name: "fsn1-dc8", Location: fsn1},
+		{ID: 2, Name: "nbg1-dc3", Location: nbg1},
+	}
+	images = []*hcloud.Image{
+		{ID: 1, Name: "debian-9", Type: hcloud.ImageTypeSystem, OSFlavor: "debian"},
+		{ID: 2, Name: "ubuntu-16.04", Type: hcloud.ImageTypeSystem, OSFlavor: "ubuntu"},
+		{ID: 3, Description: "my snapshot", Type: hcloud.ImageTypeSnapshot, OSFlavor: "debian"},
+	}
+	pricing = hcloud.Pricing{
+		FloatingIP:   hcloud.FloatingIPPricing{Monthly: hcloud.Price{Currency: "EUR", VATRate: "19.00", Net: "1.00", Gross: "1.19"}},
+		Image:        hcloud.ImagePricing{PerGBMonth: hcloud.Price{Currency: "EUR", VATRate: "19.00", Net: "0.01", Gross: "0.0119"}},
+		Traffic:      hcloud.TrafficPricing{PerTB: hcloud.Price{Currency: "EUR", VATRate: "19.00", Net: "1.00", Gross: "1.19"}},
+		ServerBackup: hcloud.ServerBackupPricing{Percentage: "20.00"},
+	}
+)
+
+func TestFacts(t *testing.T) {
+	t.Run("all", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Datacenter = hcloudtest.NewDatacenterClientMock()
+		client.Location = hcloudtest.NewLocationClientMock()
+		client.ServerType = hcloudtest.NewServerTypeClientMock()
+		client.Image = hcloudtest.NewImageClientMock()
+		client.ISO = hcloudtest.NewISOClientMock()
+		client.Pricing = hcloudtest.NewPricingClientMock()
+
+		client.Datacenter.(*hcloudtest.DatacenterClientMock).On("All", mock.Anything).Return(datacenters, nil)
+		client.Location.(*hcloudtest.LocationClientMock).On("All", mock.Anything).Return([]*hcloud.Location{fsn1, nbg1}, nil)
+		client.ServerType.(*hcloudtest.ServerTypeClientMock).On("All", mock.Anything).Return([]*hcloud.ServerType{cx11}, nil)
+		client.Image.(*hcloudtest.ImageClientMock).On("All", mock.Anything).Return(images, nil)
+		client.ISO.(*hcloudtest.ISOClientMock).On("All", mock.Anything).Return([]*hcloud.ISO{}, nil)
+		client.Pricing.(*hcloudtest.PricingClientMock).On("Get", mock.Anything).Return(pricing, (*hcloud.Response)(nil), nil)
+
+		m := module{client: client}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.False(t, resp.HasChanged(), "module should not have changed")
+			facts := resp.Data()["ansible_facts"].(map[string]interface{})
+			assert.Len(t, facts["hcloud_datacenters"], 2)
+			assert.Len(t, facts["hcloud_locations"], 2)
+			assert.Equal(t, []ServerType{toServerType(cx11, "")}, facts["hcloud_server_types"])
+			assert.Len(t, facts["hcloud_images"], 3)
+			assert.Equal(t, []ISO{}, facts["hcloud_isos"])
+			assert.Equal(t, Pricing{
+				Currency:               "EUR",
+				VATRate:                "19.00",
+				FloatingIPPriceMonthly: Price{Net: "1.00", Gross: "1.19"},
+				ImagePricePerGBMonth:   Price{Net: "0.01", Gross: "0.0119"},
+				TrafficPricePerTB:      Price{Net: "1.00", Gross: "1.19"},
+				ServerBackupPercentage: "20.00",
+			}, facts["hcloud_pricing"])
+		}
+	})
+
+	t.Run("subset with location", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Datacenter = hcloudtest.NewDatacenterClientMock()
+		client.ServerType = hcloudtest.NewServerTypeClientMock()
+
+		client.Datacenter.(*hcloudtest.DatacenterClientMock).On("All", mock.Anything).Return(datacenters, nil)
+		client.ServerType.(*hcloudtest.ServerTypeClientMock).On("All", mock.Anything).Return([]*hcloud.ServerType{cx11}, nil)
+
+		m := module{
+			client: client,
+			args: arguments{
+				GatherSubset: []interface{}{"datacenters", "server_types"},
+				Location:     "nbg1",
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.Equal(t, map[string]interface{}{
+				"hcloud_datacenters": []Datacenter{toDatacenter(datacenters[1])},
+				"hcloud_server_types": []ServerType{{
+					ID:          1,
+					Name:        "cx11",
+					Cores:       1,
+					Memory:      2,
+					StorageType: "local",
+					Prices: []ServerTypePrice{{
+						Location:     "nbg1",
+						PriceHourly:  Price{Net: "0.0040", Gross: "0.0048"},
+						PriceMonthly: Price{Net: "2.4900", Gross: "2.9631"},
+					}},
+				}},
+			}, resp.Data()["ansible_facts"])
+		}
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Image = hcloudtest.NewImageClientMock()
+		client.ServerType = hcloudtest.NewServerTypeClientMock()
+
+		client.Image.(*hcloudtest.ImageClientMock).On("All", mock.Anything).Return(images, nil)
+		client.ServerType.(*hcloudtest.ServerTypeClientMock).On("All", mock.Anything).Return([]*hcloud.ServerType{cx11, cx21}, nil)
+
+		m := module{
+			client: client,
+			args: arguments{
+				GatherSubset: []interface{}{"images", "server_types"},
+				Filters: map[string]filter{
+					"images":       {"type": "system", "os_flavor": []interface{}{"debian", "centos"}},
+					"server_types": {"cores": float64(2)},
+				},
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.Equal(t, map[string]interface{}{
+				"hcloud_images":       []Image{toImage(images[0])},
+				"hcloud_server_types": []ServerType{toServerType(cx21, "")},
+			}, resp.Data()["ansible_facts"])
+		}
+	})
+}
+
+func TestFilterMatches(t *testing.T) {
+	d := Datacenter{Name: "fsn1-dc8", ServerTypesAvailable: []string{"cx11", "cx21"}}
+	assert.True(t, filter(nil).matches(d))
+	assert.True(t, filter{"server_types_available": "cx21"}.matches(d))
+	assert.False(t, filter{"server_types_available": "cx51"}.matches(d))
+	assert.False(t, filter{"unknown": "value"}.matches(d))
+}
+
+func TestValidateArgs(t *testing.T) {
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{GatherSubset: "all"}}))
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{GatherSubset: []interface{}{"images"}}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{GatherSubset: "volumes"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{Filters: map[string]filter{"servers": nil}}}))
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{GatherSubset: "pricing"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{Filters: map[string]filter{"pricing": nil}}}))
+}
+
+func TestDoc(t *testing.T) {
+	data, err := ioutil.ReadFile("../../docs/hcloud_facts.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(data), doc.Markdown(argSpec), "docs/hcloud_facts.md is outdated, run make docs")
+	}
+}
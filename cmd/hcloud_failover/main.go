@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+	"github.com/thetechnick/hcloud-ansible/pkg/version"
+)
+
+// Exit codes, keepalived logs the exit code of failed notify scripts
+const (
+	exitOK = 0
+	// exitFailed is returned if a floating IP could not be assigned after all retries
+	exitFailed = 1
+	// exitUsage is returned for invalid flags or arguments
+	exitUsage = 2
+	// exitNoServer is returned if the local server could not be detected
+	exitNoServer = 3
+)
+
+// Server detection methods
+const (
+	detectAuto     = "auto"
+	detectHostname = "hostname"
+	detectMetadata = "metadata"
+)
+
+const defaultMetadataURL = "http://169.254.169.254/hetzner/v1/metadata"
+
+var flags = pflag.NewFlagSet("hcloud_failover", pflag.ContinueOnError)
+
+func init() {
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: hcloud_failover [flags] FLOATING_IP...\n\n")
+		fmt.Fprintf(os.Stderr, "Assigns the floating IPs (id or address) to the local server, e.g. as keepalived notify_master script.\n\n")
+		flags.PrintDefaults()
+	}
+	flags.BoolP("version", "v", false, "Print version and exit")
+	flags.String("token", "", "Hetzner Cloud API token, defaults to HCLOUD_TOKEN")
+	flags.String("server", "", "ID or name of the server to assign the floating IPs to, detected if not set")
+	flags.String("detect", detectAuto, "How to detect the local server: auto (hostname, then metadata), hostname or metadata")
+	flags.String("metadata-url", defaultMetadataURL, "URL of the metadata service")
+	flags.Int("retries", 5, "Number of retries per floating IP")
+	flags.Duration("retry-interval", 2*time.Second, "Interval between retries")
+	flags.Duration("timeout", time.Minute, "Maximum run time")
+	flags.String("install", "", "Install a keepalived notify script at this path instead of assigning, prints the result as JSON")
+	flags.String("binary", "", "Path of hcloud_failover in the notify script, defaults to the running binary")
+}
+
+// failover assigns floating IPs to the local server
+type failover struct {
+	client   *hcloud.Client
+	waiter   util.ActionWaiter
+	out      io.Writer
+	hostname func() (string, error)
+
+	server        string
+	detect        string
+	metadataURL   string
+	retries       int
+	retryInterval time.Duration
+}
+
+// localServer returns the server the floating IPs are assigned to
+func (f *failover) localServer(ctx context.Context) (*hcloud.Server, error) {
+	if f.server != "" {
+		return f.lookupServer(ctx, f.server)
+	}
+
+	var errs []string
+	if f.detect == detectAuto || f.detect == detectHostname {
+		hostname, err := f.hostname()
+		if err == nil {
+			// servers are often named by their FQDN or short hostname
+			for _, name := range []string{hostname, strings.SplitN(hostname, ".", 2)[0]} {
+				var server *hcloud.Server
+				if server, _, err = f.client.Server.GetByName(ctx, name); err != nil {
+					return nil, err
+				}
+				if server != nil {
+					return server, nil
+				}
+			}
+			err = fmt.Errorf("no server named %q", hostname)
+		}
+		errs = append(errs, fmt.Sprintf("hostname: %v", err))
+	}
+	if f.detect == detectAuto || f.detect == detectMetadata {
+		id, err := f.metadataInstanceID(ctx)
+		if err == nil {
+			var server *hcloud.Server
+			if server, _, err = f.client.Server.GetByID(ctx, id); err != nil {
+				return nil, err
+			}
+			if server != nil {
+				return server, nil
+			}
+			err = fmt.Errorf("no server with id %d", id)
+		}
+		errs = append(errs, fmt.Sprintf("metadata: %v", err))
+	}
+	return nil, fmt.Errorf("cannot detect local server (%s)", strings.Join(errs, ", "))
+}
+
+func (f *failover) lookupServer(ctx context.Context, idOrName string) (*hcloud.Server, error) {
+	server, _, err := f.client.Server.Get(ctx, idOrName)
+	if err != nil {
+		return nil, err
+	}
+	if server == nil {
+		return nil, fmt.Errorf("server %q not found", idOrName)
+	}
+	return server, nil
+}
+
+// metadataInstanceID returns the id of the local server from the metadata service
+func (f *failover) metadataInstanceID(ctx context.Context) (int, error) {
+	req, err := http.NewRequest("GET", f.metadataURL+"/instance-id", nil)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("metadata service returned %s", resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(body)))
+}
+
+// floatingIP finds a floating IP by id or address
+func (f *failover) floatingIP(ctx context.Context, arg string) (*hcloud.FloatingIP, error) {
+	if id, err := strconv.Atoi(arg); err == nil {
+		floatingIP, _, err := f.client.FloatingIP.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if floatingIP == nil {
+			return nil, fmt.Errorf("floating IP %d not found", id)
+		}
+		return floatingIP, nil
+	}
+
+	ip := net.ParseIP(arg)
+	if ip == nil {
+		return nil, fmt.Errorf("%q is neither a floating IP id nor an address", arg)
+	}
+	floatingIPs, err := f.client.FloatingIP.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, floatingIP := range floatingIPs {
+		if floatingIP.IP.Equal(ip) || floatingIP.Network != nil && floatingIP.Network.Contains(ip) {
+			return floatingIP, nil
+		}
+	}
+	return nil, fmt.Errorf("floating IP %s not found", arg)
+}
+
+// assign assigns the floating IP to the server, retrying failed attempts
+func (f *failover) assign(ctx context.Context, arg string, server *hcloud.Server) (err error) {
+	for attempt := 0; attempt <= f.retries; attempt++ {
+		if attempt > 0 {
+			fmt.Fprintf(f.out, "%s: %v, retrying in %s\n", arg, err, f.retryInterval)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(f.retryInterval):
+			}
+		}
+
+		var floatingIP *hcloud.FloatingIP
+		if floatingIP, err = f.floatingIP(ctx, arg); err != nil {
+			continue
+		}
+		if floatingIP.Server != nil && floatingIP.Server.ID == server.ID {
+			fmt.Fprintf(f.out, "Floating IP %d (%s) already assigned to server %d\n", floatingIP.ID, floatingIP.IP, server.ID)
+			return nil
+		}
+		var action *hcloud.Action
+		if action, _, err = f.client.FloatingIP.Assign(ctx, floatingIP, server); err != nil {
+			continue
+		}
+		if err = f.waiter.WaitForActions(ctx, action); err != nil {
+			continue
+		}
+		fmt.Fprintf(f.out, "Floating IP %d (%s) assigned to server %d\n", floatingIP.ID, floatingIP.IP, server.ID)
+		return nil
+	}
+	return err
+}
+
+// run assigns all floating IPs and returns the exit code
+func (f *failover) run(ctx context.Context, floatingIPs []string) int {
+	server, err := f.localServer(ctx)
+	if err != nil {
+		fmt.Fprintf(f.out, "Error: %v\n", err)
+		return exitNoServer
+	}
+
+	code := exitOK
+	for _, arg := range floatingIPs {
+		if err := f.assign(ctx, arg, server); err != nil {
+			fmt.Fprintf(f.out, "Error assigning floating IP %s to server %d: %v\n", arg, server.ID, err)
+			code = exitFailed
+		}
+	}
+	return code
+}
+
+var notifyScript = template.Must(template.New("notify").Funcs(template.FuncMap{"quote": shellQuote}).Parse(`#!/bin/sh
+# Generated by hcloud_failover, assigns the floating IPs to this server.
+# Use as notify_master, or as notify script receiving TYPE NAME STATE.
+if [ "$#" -ge 3 ] && [ "$3" != "MASTER" ]; then
+  exit 0
+fi
+{{- if .Token }}
+HCLOUD_TOKEN={{ quote .Token }}
+export HCLOUD_TOKEN
+{{- end }}
+exec {{ quote .Binary }}{{ range .Args }} {{ quote . }}{{ end }}
+`))
+
+// shellQuote quotes a string for sh
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// installResult is printed as JSON by the install mode
+type installResult struct {
+	Changed bool   `json:"changed"`
+	Path    string `json:"path"`
+	// NotifyMaster is the keepalived configuration line calling the script
+	NotifyMaster string `json:"notify_master"`
+}
+
+// install writes the notify script if its content changed
+func install(path string, data interface{}) (result installResult, err error) {
+	var script bytes.Buffer
+	if err = notifyScript.Execute(&script, data); err != nil {
+		return
+	}
+	result.Path = path
+	result.NotifyMaster = fmt.Sprintf("notify_master %q", path)
+
+	current, err := ioutil.ReadFile(path)
+	if err == nil && bytes.Equal(current, script.Bytes()) {
+		// the script contains the token
+		err = os.Chmod(path, 0700)
+		return
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return
+	}
+	if err = ioutil.WriteFile(path, script.Bytes(), 0700); err != nil {
+		return
+	}
+	result.Changed = true
+	err = os.Chmod(path, 0700)
+	return
+}
+
+// installArgs returns the arguments of the notify script call,
+// the flags of this call except install, binary and token
+func installArgs() []string {
+	var args []string
+	flags.Visit(func(flag *pflag.Flag) {
+		switch flag.Name {
+		case "install", "binary", "token", "version":
+			return
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", flag.Name, flag.Value.String()))
+	})
+	return append(args, flags.Args()...)
+}
+
+func main() {
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	if v, _ := flags.GetBool("version"); v {
+		version.PrintText()
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+	token, _ := flags.GetString("token")
+
+	if path, _ := flags.GetString("install"); path != "" {
+		// the notify script runs without the environment of this call
+		if token == "" {
+			token = os.Getenv("HCLOUD_TOKEN")
+		}
+		if token == "" {
+			fmt.Fprintf(os.Stderr, "--token or HCLOUD_TOKEN is required for --install\n")
+			os.Exit(exitUsage)
+		}
+		binary, _ := flags.GetString("binary")
+		if binary == "" {
+			var err error
+			if binary, err = os.Executable(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error detecting binary path: %v\n", err)
+				os.Exit(exitUsage)
+			}
+		}
+		result, err := install(path, struct {
+			Token, Binary string
+			Args          []string
+		}{token, binary, installArgs()})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error installing notify script: %v\n", err)
+			os.Exit(exitFailed)
+		}
+		json.NewEncoder(os.Stdout).Encode(result)
+		os.Exit(exitOK)
+	}
+
+	detect, _ := flags.GetString("detect")
+	if detect != detectAuto && detect != detectHostname && detect != detectMetadata {
+		fmt.Fprintf(os.Stderr, "--detect must be auto, hostname or metadata\n")
+		os.Exit(exitUsage)
+	}
+
+	client, err := hcloud.BuildClient(token)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Hetzner Cloud client: %v\n", err)
+		os.Exit(exitUsage)
+	}
+	f := &failover{
+		client:   client,
+		waiter:   util.NewActionWatcher(client.Action),
+		out:      os.Stdout,
+		hostname: os.Hostname,
+		detect:   detect,
+	}
+	f.server, _ = flags.GetString("server")
+	f.metadataURL, _ = flags.GetString("metadata-url")
+	f.retries, _ = flags.GetInt("retries")
+	f.retryInterval, _ = flags.GetDuration("retry-interval")
+	timeout, _ := flags.GetDuration("timeout")
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	os.Exit(f.run(ctx, flags.Args()))
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud/hcloudtest"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+var (
+	nilResponse *hcloud.Response
+	nilServer   *hcloud.Server
+
+	noWait = util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+		return nil
+	})
+)
+
+// metadataService is a stand-in for the metadata service of the server with the id
+func metadataService(id int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/hetzner/v1/metadata/instance-id" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "%d", id)
+	}))
+}
+
+func newFailover(client *hcloud.Client) *failover {
+	return &failover{
+		client:      client,
+		waiter:      noWait,
+		out:         ioutil.Discard,
+		hostname:    func() (string, error) { return "lb1.example.com", nil },
+		detect:      detectAuto,
+		metadataURL: "http://127.0.0.1:0",
+	}
+}
+
+func TestLocalServer(t *testing.T) {
+	server := &hcloud.Server{ID: 42, Name: "lb1"}
+
+	t.Run("short hostname", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Server = hcloudtest.NewServerClientMock()
+		serverMock := client.Server.(*hcloudtest.ServerClientMock)
+		serverMock.On("GetByName", mock.Anything, "lb1.example.com").Return(nilServer, nilResponse, nil)
+		serverMock.On("GetByName", mock.Anything, "lb1").Return(server, nilResponse, nil)
+
+		s, err := newFailover(client).localServer(context.Background())
+		if assert.NoError(t, err) {
+			assert.Equal(t, server, s)
+		}
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		metadata := metadataService(42)
+		defer metadata.Close()
+
+		client := hcloud.NewClient()
+		client.Server = hcloudtest.NewServerClientMock()
+		client.Server.(*hcloudtest.ServerClientMock).On("GetByID", mock.Anything, 42).Return(server, nilResponse, nil)
+
+		f := newFailover(client)
+		f.detect = detectMetadata
+		f.metadataURL = metadata.URL + "/hetzner/v1/metadata"
+		s, err := f.localServer(context.Background())
+		if assert.NoError(t, err) {
+			assert.Equal(t, server, s)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Server = hcloudtest.NewServerClientMock()
+		client.Server.(*hcloudtest.ServerClientMock).On("GetByName", mock.Anything, mock.Anything).Return(nilServer, nilResponse, nil)
+
+		f := newFailover(client)
+		f.detect = detectHostname
+		assert.Equal(t, exitNoServer, f.run(context.Background(), []string{"1"}))
+	})
+}
+
+func TestRun(t *testing.T) {
+	server := &hcloud.Server{ID: 42, Name: "lb1"}
+	_, network, _ := net.ParseCIDR("2001:db8::/64")
+	ipv4 := &hcloud.FloatingIP{ID: 1, IP: net.ParseIP("203.0.113.1"), Server: &hcloud.Server{ID: 43}}
+	ipv6 := &hcloud.FloatingIP{ID: 2, IP: network.IP, Network: network, Server: server}
+
+	t.Run("assign with retry", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.FloatingIP = hcloudtest.NewFloatingIPClientMock()
+		floatingIPMock := client.FloatingIP.(*hcloudtest.FloatingIPClientMock)
+		floatingIPMock.On("GetByID", mock.Anything, 1).Return(ipv4, nilResponse, nil)
+		floatingIPMock.On("All", mock.Anything).Return([]*hcloud.FloatingIP{ipv4, ipv6}, nil)
+		floatingIPMock.On("Assign", mock.Anything, ipv4, server).Return((*hcloud.Action)(nil), nilResponse, errors.New("locked")).Once()
+		floatingIPMock.On("Assign", mock.Anything, ipv4, server).Return(&hcloud.Action{ID: 1}, nilResponse, nil)
+
+		f := newFailover(client)
+		f.server = "42"
+		f.retries = 2
+		f.retryInterval = time.Millisecond
+		client.Server = hcloudtest.NewServerClientMock()
+		client.Server.(*hcloudtest.ServerClientMock).On("Get", mock.Anything, "42").Return(server, nilResponse, nil)
+
+		assert.Equal(t, exitOK, f.run(context.Background(), []string{"1", "2001:db8::1"}))
+		floatingIPMock.AssertNumberOfCalls(t, "Assign", 2)
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.FloatingIP = hcloudtest.NewFloatingIPClientMock()
+		client.FloatingIP.(*hcloudtest.FloatingIPClientMock).On("GetByID", mock.Anything, 1).Return(ipv4, nilResponse, nil)
+		client.FloatingIP.(*hcloudtest.FloatingIPClientMock).On("Assign", mock.Anything, ipv4, server).
+			Return((*hcloud.Action)(nil), nilResponse, errors.New("locked"))
+		client.Server = hcloudtest.NewServerClientMock()
+		client.Server.(*hcloudtest.ServerClientMock).On("Get", mock.Anything, "lb1").Return(server, nilResponse, nil)
+
+		f := newFailover(client)
+		f.server = "lb1"
+		f.retries = 1
+		f.retryInterval = time.Millisecond
+		assert.Equal(t, exitFailed, f.run(context.Background(), []string{"1"}))
+		client.FloatingIP.(*hcloudtest.FloatingIPClientMock).AssertNumberOfCalls(t, "Assign", 2)
+	})
+}
+
+func TestInstall(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcloud_failover")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "notify.sh")
+	data := struct {
+		Token, Binary string
+		Args          []string
+	}{"it's-secret", "/usr/local/bin/hcloud_failover", []string{"--retries=3", "1"}}
+
+	result, err := install(path, data)
+	if assert.NoError(t, err) {
+		assert.True(t, result.Changed)
+		assert.Equal(t, `notify_master "`+path+`"`, result.NotifyMaster)
+		script, _ := ioutil.ReadFile(path)
+		assert.Contains(t, string(script), `HCLOUD_TOKEN='it'\''s-secret'`)
+		assert.Contains(t, string(script), `exec '/usr/local/bin/hcloud_failover' '--retries=3' '1'`)
+		info, _ := os.Stat(path)
+		assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	}
+
+	result, err = install(path, data)
+	if assert.NoError(t, err) {
+		assert.False(t, result.Changed)
+	}
+}
@@ -0,0 +1,665 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+const (
+	stateAbsent  = "absent"
+	statePresent = "present"
+	stateList    = "list"
+)
+
+var protocols = []string{
+	string(hcloud.FirewallRuleProtocolTCP),
+	string(hcloud.FirewallRuleProtocolUDP),
+	string(hcloud.FirewallRuleProtocolICMP),
+	string(hcloud.FirewallRuleProtocolESP),
+	string(hcloud.FirewallRuleProtocolGRE),
+}
+
+// Firewall is the module return value of an hcloud.Firewall
+type Firewall struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Rules     []Rule     `json:"rules"`
+	AppliedTo []Resource `json:"applied_to"`
+}
+
+// Rule is the module argument and return value of an hcloud.FirewallRule
+type Rule struct {
+	Direction      string   `json:"direction"`
+	Protocol       string   `json:"protocol"`
+	Port           string   `json:"port,omitempty"`
+	SourceIPs      []string `json:"source_ips,omitempty"`
+	DestinationIPs []string `json:"destination_ips,omitempty"`
+	Description    string   `json:"description,omitempty"`
+}
+
+// Resource is the module return value of an hcloud.FirewallResource
+type Resource struct {
+	Type          string `json:"type"`
+	Server        int    `json:"server,omitempty"`
+	LabelSelector string `json:"label_selector,omitempty"`
+	// Servers are the servers matched by the label selector
+	Servers []int `json:"servers,omitempty"`
+}
+
+type applyTo struct {
+	Server        interface{} `json:"server"`
+	LabelSelector string      `json:"label_selector"`
+}
+
+type arguments struct {
+	Token string `json:"token"`
+	State string `json:"state"`
+
+	ID      interface{} `json:"id"`
+	Name    string      `json:"name"`
+	Rules   []Rule      `json:"rules"`
+	ApplyTo []applyTo   `json:"apply_to"`
+	Wait    *bool       `json:"wait"`
+}
+
+var argSpec = ansible.ArgSpec{
+	Args: []ansible.Arg{
+		ansible.TokenArg(),
+		{Name: "state", Type: ansible.TypeStr, Default: statePresent, Choices: []string{statePresent, stateAbsent, stateList},
+			Description: "`list` lists all existing firewalls. `absent` removes the firewall from all resources before deleting it."},
+		{Name: "id", Type: ansible.TypeRaw,
+			Description: "ID of the firewall.\n\n`id` or `name` is required when `state=present` or `state=absent`."},
+		{Name: "name", Type: ansible.TypeStr,
+			Description: "Name of the firewall, used to find the firewall when `id` is not specified. The firewall is renamed when both are given."},
+		{Name: "rules", Type: ansible.TypeList, Elements: ansible.TypeDict,
+			Description: "List of rules, see below.\n\n" +
+				"When set, the rules of the firewall are replaced if they differ, the order of rules and IPs is ignored. Existing rules are kept if not set.",
+			Options: []ansible.Arg{
+				{Name: "direction", Type: ansible.TypeStr, Required: true, Choices: []string{"in", "out"}},
+				{Name: "protocol", Type: ansible.TypeStr, Required: true, Choices: protocols},
+				{Name: "port", Type: ansible.TypeStr, Description: "Port or port range, e.g. `22` or `1024-5000`, only for `tcp` and `udp`."},
+				{Name: "source_ips", Type: ansible.TypeList, Elements: ansible.TypeStr, Description: "List of CIDRs of inbound rules."},
+				{Name: "destination_ips", Type: ansible.TypeList, Elements: ansible.TypeStr, Description: "List of CIDRs of outbound rules."},
+				{Name: "description", Type: ansible.TypeStr},
+			}},
+		{Name: "apply_to", Type: ansible.TypeList, Elements: ansible.TypeDict,
+			Description: "List of resources with either `server` or `label_selector`.\n\n" +
+				"When set, the firewall is removed from resources not in the list. Existing resources are kept if not set.",
+			Options: []ansible.Arg{
+				{Name: "server", Type: ansible.TypeRaw, Description: "ID or name of a server."},
+				{Name: "label_selector", Type: ansible.TypeStr, Description: "Label selector of servers, e.g. `role=web`."},
+			}},
+		{Name: "wait", Type: ansible.TypeBool, Default: true,
+			Description: "Wait for all actions to complete. When `false`, the module returns immediately with `action_ids`, see `hcloud_action`. " +
+				"The firewall is locked while an action runs, so with multiple changes to a firewall only the last actions are not awaited."},
+	},
+	RequiredIf: []ansible.RequiredIf{
+		{Arg: "state", Value: statePresent, Required: []string{"id", "name"}, OneOf: true},
+		{Arg: "state", Value: stateAbsent, Required: []string{"id", "name"}, OneOf: true},
+	},
+}
+
+var doc = ansible.Doc{
+	Module:           "hcloud_firewall",
+	ShortDescription: "Manage Hetzner Cloud Firewalls",
+	Description: "Manages Hetzner Cloud firewalls. " +
+		"This module can be used to create, modify and delete firewalls and to apply them to servers directly or by label selector.",
+	Returns: []ansible.Return{
+		{
+			Name:        "firewalls",
+			Description: "The firewall, or all firewalls with `state=list`. `servers` of a label selector lists the servers currently matched by it.",
+			Returned:    "unless `state=absent`",
+			Sample: []Firewall{{
+				ID:   123,
+				Name: "web",
+				Rules: []Rule{
+					{Direction: "in", Protocol: "tcp", Port: "443", SourceIPs: []string{"0.0.0.0/0", "::/0"}},
+					{Direction: "out", Protocol: "icmp", DestinationIPs: []string{"0.0.0.0/0"}},
+				},
+				AppliedTo: []Resource{
+					{Type: "server", Server: 42},
+					{Type: "label_selector", LabelSelector: "role=web", Servers: []int{43, 44}},
+				},
+			}},
+		},
+		{
+			Name:        "action_ids",
+			Description: "IDs of the pending actions, see `hcloud_action`.",
+			Returned:    "when `wait` is `false`",
+			Sample:      []int{4711},
+		},
+	},
+	Examples: `
+# allow HTTPS from everywhere and SSH from the office on all web servers
+- hcloud_firewall:
+    name: web
+    rules:
+    - direction: in
+      protocol: tcp
+      port: "443"
+      source_ips: [0.0.0.0/0, ::/0]
+    - direction: in
+      protocol: tcp
+      port: "22"
+      source_ips: [203.0.113.0/24]
+      description: office
+    apply_to:
+    - label_selector: role=web
+
+# apply the firewall to a single server, keeping the rules
+- hcloud_firewall:
+    name: web
+    apply_to:
+    - server: web1
+
+# delete the firewall
+- hcloud_firewall:
+    name: web
+    state: absent
+`,
+}
+
+type module struct {
+	args   arguments
+	client *hcloud.Client
+	waiter util.ActionWaiter
+}
+
+func (m *module) Args() interface{} {
+	return &m.args
+}
+
+func (m *module) ArgSpec() ansible.ArgSpec {
+	return argSpec
+}
+
+func (m *module) Doc() ansible.Doc {
+	return doc
+}
+
+func (m *module) Run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	m.client, err = hcloud.BuildClient(m.args.Token)
+	if err != nil {
+		return
+	}
+	m.waiter = util.NewActionWatcher(m.client.Action)
+	return m.run(ctx)
+}
+
+func (m *module) run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	// a firewall must be removed from its resources before it can be deleted, so absent always waits
+	if m.args.Wait != nil && !*m.args.Wait && m.args.State != stateAbsent {
+		defer util.RecordActions(&m.waiter).Report(&resp, &err)
+	}
+
+	switch m.args.State {
+	case stateList:
+		return m.list(ctx)
+	case stateAbsent:
+		return m.absent(ctx)
+	case statePresent:
+		return m.present(ctx)
+	default:
+		err = errors.New("invalid state")
+		return
+	}
+}
+
+func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var firewall *hcloud.Firewall
+	if firewall, err = m.firewall(ctx); err != nil {
+		return
+	}
+
+	var resources []hcloud.FirewallResource
+	if resources, err = m.resources(ctx); err != nil {
+		return
+	}
+
+	if firewall == nil {
+		if m.args.ID != nil {
+			err = fmt.Errorf("Firewall %v not found", m.args.ID)
+			return
+		}
+		opts := hcloud.FirewallCreateOpts{
+			Name:    m.args.Name,
+			ApplyTo: resources,
+		}
+		for _, r := range m.args.Rules {
+			opts.Rules = append(opts.Rules, toFirewallRule(r))
+		}
+		var result hcloud.FirewallCreateResult
+		if result, _, err = m.client.Firewall.Create(ctx, opts); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, result.Actions...); err != nil {
+			return
+		}
+		resp.
+			Msg(fmt.Sprintf("Firewall %d created", result.Firewall.ID)).
+			Set("firewalls", []Firewall{toFirewall(result.Firewall)}).
+			Changed()
+		return
+	}
+
+	var msg []string
+	if m.args.ID != nil && m.args.Name != "" && firewall.Name != m.args.Name {
+		if firewall, _, err = m.client.Firewall.Update(ctx, firewall, hcloud.FirewallUpdateOpts{
+			Name: m.args.Name,
+		}); err != nil {
+			return
+		}
+		msg = append(msg, fmt.Sprintf("Firewall %d renamed", firewall.ID))
+		resp.Changed()
+	}
+
+	var actions []*hcloud.Action
+	if m.args.Rules != nil {
+		rules := []hcloud.FirewallRule{}
+		for _, r := range m.args.Rules {
+			rules = append(rules, toFirewallRule(r))
+		}
+		if !sameRules(rules, firewall.Rules) {
+			var ruleActions []*hcloud.Action
+			if ruleActions, _, err = m.client.Firewall.SetRules(ctx, firewall, rules); err != nil {
+				return
+			}
+			if err = m.waiter.WaitForActions(ctx, ruleActions...); err != nil {
+				return
+			}
+			actions = append(actions, ruleActions...)
+			msg = append(msg, fmt.Sprintf("Firewall %d rules updated", firewall.ID))
+		}
+	}
+
+	if m.args.ApplyTo != nil {
+		apply, remove := resourceChanges(resources, firewall.AppliedTo)
+		if len(remove) > 0 {
+			if err = m.waitForPending(ctx, firewall); err != nil {
+				return
+			}
+			var removeActions []*hcloud.Action
+			if removeActions, _, err = m.client.Firewall.RemoveResources(ctx, firewall, remove); err != nil {
+				return
+			}
+			if err = m.waiter.WaitForActions(ctx, removeActions...); err != nil {
+				return
+			}
+			actions = append(actions, removeActions...)
+			msg = append(msg, fmt.Sprintf("Firewall %d removed from %s", firewall.ID, resourceNames(remove)))
+		}
+		if len(apply) > 0 {
+			if err = m.waitForPending(ctx, firewall); err != nil {
+				return
+			}
+			var applyActions []*hcloud.Action
+			if applyActions, _, err = m.client.Firewall.ApplyResources(ctx, firewall, apply); err != nil {
+				return
+			}
+			if err = m.waiter.WaitForActions(ctx, applyActions...); err != nil {
+				return
+			}
+			actions = append(actions, applyActions...)
+			msg = append(msg, fmt.Sprintf("Firewall %d applied to %s", firewall.ID, resourceNames(apply)))
+		}
+	}
+
+	if len(actions) > 0 {
+		resp.Changed()
+		if firewall, _, err = m.client.Firewall.GetByID(ctx, firewall.ID); err != nil {
+			return
+		}
+	}
+
+	resp.
+		Msg(strings.Join(msg, ", ")).
+		Set("firewalls", []Firewall{toFirewall(firewall)})
+	return
+}
+
+func (m *module) absent(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var firewall *hcloud.Firewall
+	if firewall, err = m.firewall(ctx); err != nil {
+		return
+	}
+	if firewall == nil {
+		resp.Msg("No Firewall found, nothing to do")
+		return
+	}
+
+	if len(firewall.AppliedTo) > 0 {
+		var actions []*hcloud.Action
+		if actions, _, err = m.client.Firewall.RemoveResources(ctx, firewall, firewall.AppliedTo); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, actions...); err != nil {
+			return
+		}
+	}
+	if _, err = m.client.Firewall.Delete(ctx, firewall); err != nil {
+		return
+	}
+	resp.Msg(fmt.Sprintf("Firewall %d deleted", firewall.ID)).Changed()
+	return
+}
+
+func (m *module) list(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var firewalls []*hcloud.Firewall
+	if firewalls, err = m.client.Firewall.All(ctx); err != nil {
+		return
+	}
+
+	list := []Firewall{}
+	for _, firewall := range firewalls {
+		list = append(list, toFirewall(firewall))
+	}
+	resp.Msg("Firewalls listed").Set("firewalls", list)
+	return
+}
+
+// waitForPending waits for the recorded actions on the firewall with `wait: false`,
+// the firewall is locked until they complete
+func (m *module) waitForPending(ctx context.Context, firewall *hcloud.Firewall) error {
+	return util.WaitForPending(ctx, m.waiter, hcloud.ActionResource{ID: firewall.ID, Type: hcloud.ActionResourceTypeFirewall})
+}
+
+// firewall looks up the firewall by id or name
+func (m *module) firewall(ctx context.Context) (firewall *hcloud.Firewall, err error) {
+	if id := util.GetID(m.args.ID); id != 0 {
+		firewall, _, err = m.client.Firewall.GetByID(ctx, id)
+		return
+	}
+	firewall, _, err = m.client.Firewall.GetByName(ctx, m.args.Name)
+	return
+}
+
+// resources resolves the apply_to argument
+func (m *module) resources(ctx context.Context) (resources []hcloud.FirewallResource, err error) {
+	for _, a := range m.args.ApplyTo {
+		if a.LabelSelector != "" {
+			resources = append(resources, hcloud.FirewallResource{
+				Type:          hcloud.FirewallResourceTypeLabelSelector,
+				LabelSelector: a.LabelSelector,
+			})
+			continue
+		}
+		var server *hcloud.Server
+		if server, err = m.server(ctx, a.Server); err != nil {
+			return
+		}
+		resources = append(resources, hcloud.FirewallResource{
+			Type:   hcloud.FirewallResourceTypeServer,
+			Server: server,
+		})
+	}
+	return
+}
+
+func (m *module) server(ctx context.Context, serverArg interface{}) (server *hcloud.Server, err error) {
+	id := util.GetID(serverArg)
+	name := util.GetName(serverArg)
+
+	if id != 0 {
+		server, _, err = m.client.Server.GetByID(ctx, id)
+	}
+
+	if server == nil {
+		if name != "" {
+			server, _, err = m.client.Server.GetByName(ctx, name)
+		}
+	}
+
+	if err != nil {
+		return
+	}
+	if server == nil {
+		err = fmt.Errorf("Server '%v' not found", serverArg)
+	}
+	return
+}
+
+// resourceChanges returns the resources to apply the firewall to and to remove it from
+func resourceChanges(desired, existing []hcloud.FirewallResource) (apply, remove []hcloud.FirewallResource) {
+	desiredKeys := map[string]bool{}
+	for _, resource := range desired {
+		desiredKeys[resourceKey(resource)] = true
+	}
+	existingKeys := map[string]bool{}
+	for _, resource := range existing {
+		existingKeys[resourceKey(resource)] = true
+		if !desiredKeys[resourceKey(resource)] {
+			remove = append(remove, resource)
+		}
+	}
+	for _, resource := range desired {
+		if !existingKeys[resourceKey(resource)] {
+			apply = append(apply, resource)
+			existingKeys[resourceKey(resource)] = true
+		}
+	}
+	return
+}
+
+func resourceKey(resource hcloud.FirewallResource) string {
+	if resource.Type == hcloud.FirewallResourceTypeLabelSelector {
+		return "label_selector " + resource.LabelSelector
+	}
+	if resource.Server == nil {
+		return "server"
+	}
+	return "server " + strconv.Itoa(resource.Server.ID)
+}
+
+func resourceNames(resources []hcloud.FirewallResource) string {
+	names := []string{}
+	for _, resource := range resources {
+		names = append(names, resourceKey(resource))
+	}
+	return strings.Join(names, ", ")
+}
+
+// sameRules compares two rule sets independent of the order of rules and IPs
+func sameRules(a, b []hcloud.FirewallRule) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	keys := map[string]int{}
+	for _, rule := range a {
+		keys[ruleKey(rule)]++
+	}
+	for _, rule := range b {
+		keys[ruleKey(rule)]--
+	}
+	for _, n := range keys {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func ruleKey(rule hcloud.FirewallRule) string {
+	return strings.Join([]string{
+		string(rule.Direction),
+		string(rule.Protocol),
+		normalizePort(rule.Port),
+		strings.Join(sortedCIDRs(rule.SourceIPs), ","),
+		strings.Join(sortedCIDRs(rule.DestinationIPs), ","),
+		rule.Description,
+	}, " ")
+}
+
+// normalizePort returns a single port for ranges like 80-80
+func normalizePort(port string) string {
+	parts := strings.SplitN(port, "-", 2)
+	if len(parts) == 2 && parts[0] == parts[1] {
+		return parts[0]
+	}
+	return port
+}
+
+func sortedCIDRs(ipNets []net.IPNet) []string {
+	s := []string{}
+	for _, ipNet := range ipNets {
+		s = append(s, ipNet.String())
+	}
+	sort.Strings(s)
+	return s
+}
+
+// parseCIDRs parses CIDR strings, a plain IP is treated as a single host
+func parseCIDRs(s []string) (ipNets []net.IPNet, err error) {
+	for _, cidr := range s {
+		if ip := net.ParseIP(cidr); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			ipNets = append(ipNets, net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		var ipNet *net.IPNet
+		if _, ipNet, err = net.ParseCIDR(cidr); err != nil {
+			return
+		}
+		ipNets = append(ipNets, *ipNet)
+	}
+	return
+}
+
+func toFirewallRule(r Rule) hcloud.FirewallRule {
+	sourceIPs, _ := parseCIDRs(r.SourceIPs)
+	destinationIPs, _ := parseCIDRs(r.DestinationIPs)
+	return hcloud.FirewallRule{
+		Direction:      hcloud.FirewallRuleDirection(r.Direction),
+		Protocol:       hcloud.FirewallRuleProtocol(r.Protocol),
+		Port:           r.Port,
+		SourceIPs:      sourceIPs,
+		DestinationIPs: destinationIPs,
+		Description:    r.Description,
+	}
+}
+
+func toFirewall(firewall *hcloud.Firewall) Firewall {
+	data := Firewall{
+		ID:        firewall.ID,
+		Name:      firewall.Name,
+		Rules:     []Rule{},
+		AppliedTo: []Resource{},
+	}
+	for _, rule := range firewall.Rules {
+		r := Rule{
+			Direction:   string(rule.Direction),
+			Protocol:    string(rule.Protocol),
+			Port:        rule.Port,
+			Description: rule.Description,
+		}
+		for _, ipNet := range rule.SourceIPs {
+			r.SourceIPs = append(r.SourceIPs, ipNet.String())
+		}
+		for _, ipNet := range rule.DestinationIPs {
+			r.DestinationIPs = append(r.DestinationIPs, ipNet.String())
+		}
+		data.Rules = append(data.Rules, r)
+	}
+	for _, resource := range firewall.AppliedTo {
+		r := Resource{
+			Type:          string(resource.Type),
+			LabelSelector: resource.LabelSelector,
+		}
+		if resource.Server != nil {
+			r.Server = resource.Server.ID
+		}
+		for _, server := range resource.Servers {
+			r.Servers = append(r.Servers, server.ID)
+		}
+		data.AppliedTo = append(data.AppliedTo, r)
+	}
+	return data
+}
+
+// CheckArgs checks the constraints of the arguments the spec cannot express
+func (m *module) CheckArgs() (errs []string) {
+	for i, r := range m.args.Rules {
+		errs = append(errs, validateRule(fmt.Sprintf("rules[%d]", i), r)...)
+	}
+	for i, a := range m.args.ApplyTo {
+		if (a.Server == nil) == (a.LabelSelector == "") {
+			errs = append(errs, fmt.Sprintf("'apply_to[%d]' requires exactly one of 'server' or 'label_selector'", i))
+		}
+	}
+	return
+}
+
+func validateRule(field string, r Rule) (errs []string) {
+	switch hcloud.FirewallRuleDirection(r.Direction) {
+	case hcloud.FirewallRuleDirectionIn:
+		if len(r.SourceIPs) == 0 {
+			errs = append(errs, fmt.Sprintf("'%s.source_ips' is required for inbound rules", field))
+		}
+		if len(r.DestinationIPs) > 0 {
+			errs = append(errs, fmt.Sprintf("'%s.destination_ips' is not allowed for inbound rules", field))
+		}
+	case hcloud.FirewallRuleDirectionOut:
+		if len(r.DestinationIPs) == 0 {
+			errs = append(errs, fmt.Sprintf("'%s.destination_ips' is required for outbound rules", field))
+		}
+		if len(r.SourceIPs) > 0 {
+			errs = append(errs, fmt.Sprintf("'%s.source_ips' is not allowed for outbound rules", field))
+		}
+	}
+
+	switch hcloud.FirewallRuleProtocol(r.Protocol) {
+	case hcloud.FirewallRuleProtocolTCP, hcloud.FirewallRuleProtocolUDP:
+		if !isPort(r.Port) {
+			errs = append(errs, fmt.Sprintf("'%s.port' must be a port or port range like 1024-5000, got %q", field, r.Port))
+		}
+	case hcloud.FirewallRuleProtocolICMP, hcloud.FirewallRuleProtocolESP, hcloud.FirewallRuleProtocolGRE:
+		if r.Port != "" {
+			errs = append(errs, fmt.Sprintf("'%s.port' is only allowed for tcp and udp", field))
+		}
+	}
+
+	if _, err := parseCIDRs(r.SourceIPs); err != nil {
+		errs = append(errs, fmt.Sprintf("'%s.source_ips' must be CIDRs: %v", field, err))
+	}
+	if _, err := parseCIDRs(r.DestinationIPs); err != nil {
+		errs = append(errs, fmt.Sprintf("'%s.destination_ips' must be CIDRs: %v", field, err))
+	}
+	return
+}
+
+// isPort checks for a port or a port range like 1024-5000
+func isPort(s string) bool {
+	parts := strings.SplitN(s, "-", 2)
+	ports := []int{}
+	for _, part := range parts {
+		port, err := strconv.Atoi(part)
+		if err != nil || port < 1 || port > 65535 {
+			return false
+		}
+		ports = append(ports, port)
+	}
+	return len(ports) == 1 || ports[0] <= ports[1]
+}
+
+var flags = pflag.NewFlagSet("hcloud_firewall", pflag.ContinueOnError)
+
+func init() {
+	flags.BoolP("version", "v", false, "Print version and exit")
+}
+
+func main() {
+	ansible.RunModule(&module{}, flags)
+}
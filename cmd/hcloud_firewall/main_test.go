@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud/hcloudtest"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+var (
+	nilResponse *hcloud.Response
+	nilFirewall *hcloud.Firewall
+
+	noWait = util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+		return nil
+	})
+)
+
+func ipNets(cidrs ...string) []net.IPNet {
+	ipNets, _ := parseCIDRs(cidrs)
+	return ipNets
+}
+
+func newFirewall() *hcloud.Firewall {
+	return &hcloud.Firewall{
+		ID:   1,
+		Name: "web",
+		Rules: []hcloud.FirewallRule{
+			{Direction: "in", Protocol: "tcp", Port: "443", SourceIPs: ipNets("0.0.0.0/0", "::/0")},
+			{Direction: "in", Protocol: "tcp", Port: "22", SourceIPs: ipNets("10.0.0.0/8")},
+		},
+		AppliedTo: []hcloud.FirewallResource{
+			{Type: "server", Server: &hcloud.Server{ID: 42}},
+			{Type: "label_selector", LabelSelector: "role=web", Servers: []*hcloud.Server{{ID: 43}}},
+		},
+	}
+}
+
+func TestList(t *testing.T) {
+	client := hcloud.NewClient()
+	client.Firewall = hcloudtest.NewFirewallClientMock()
+	client.Firewall.(*hcloudtest.FirewallClientMock).On("All", mock.Anything).Return([]*hcloud.Firewall{newFirewall()}, nil)
+
+	m := module{client: client}
+	resp, err := m.list(context.Background())
+	if assert.NoError(t, err) {
+		assert.False(t, resp.HasChanged(), "module should not have changed")
+		assert.Equal(t, []Firewall{{
+			ID:   1,
+			Name: "web",
+			Rules: []Rule{
+				{Direction: "in", Protocol: "tcp", Port: "443", SourceIPs: []string{"0.0.0.0/0", "::/0"}},
+				{Direction: "in", Protocol: "tcp", Port: "22", SourceIPs: []string{"10.0.0.0/8"}},
+			},
+			AppliedTo: []Resource{
+				{Type: "server", Server: 42},
+				{Type: "label_selector", LabelSelector: "role=web", Servers: []int{43}},
+			},
+		}}, resp.Data()["firewalls"])
+	}
+}
+
+func TestPresent(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Firewall = hcloudtest.NewFirewallClientMock()
+		client.Server = hcloudtest.NewServerClientMock()
+		firewallMock := client.Firewall.(*hcloudtest.FirewallClientMock)
+		server := &hcloud.Server{ID: 42, Name: "web1"}
+		client.Server.(*hcloudtest.ServerClientMock).On("GetByName", mock.Anything, "web1").Return(server, nilResponse, nil)
+		firewallMock.On("GetByName", mock.Anything, "web").Return(nilFirewall, nilResponse, nil)
+		firewallMock.On("Create", mock.Anything, hcloud.FirewallCreateOpts{
+			Name: "web",
+			Rules: []hcloud.FirewallRule{
+				{Direction: "in", Protocol: "tcp", Port: "443", SourceIPs: ipNets("0.0.0.0/0")},
+				{Direction: "out", Protocol: "icmp", DestinationIPs: ipNets("10.0.0.1/32")},
+			},
+			ApplyTo: []hcloud.FirewallResource{
+				{Type: "server", Server: server},
+				{Type: "label_selector", LabelSelector: "role=web"},
+			},
+		}).Return(hcloud.FirewallCreateResult{
+			Firewall: newFirewall(),
+			Actions:  []*hcloud.Action{{ID: 1}},
+		}, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args: arguments{
+				State: statePresent,
+				Name:  "web",
+				Rules: []Rule{
+					{Direction: "in", Protocol: "tcp", Port: "443", SourceIPs: []string{"0.0.0.0/0"}},
+					{Direction: "out", Protocol: "icmp", DestinationIPs: []string{"10.0.0.1"}},
+				},
+				ApplyTo: []applyTo{{Server: "web1"}, {LabelSelector: "role=web"}},
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+		}
+	})
+
+	t.Run("unchanged in different order", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Firewall = hcloudtest.NewFirewallClientMock()
+		client.Server = hcloudtest.NewServerClientMock()
+		client.Server.(*hcloudtest.ServerClientMock).On("GetByID", mock.Anything, 42).Return(&hcloud.Server{ID: 42}, nilResponse, nil)
+		firewallMock := client.Firewall.(*hcloudtest.FirewallClientMock)
+		firewallMock.On("GetByName", mock.Anything, "web").Return(newFirewall(), nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args: arguments{
+				State: statePresent,
+				Name:  "web",
+				Rules: []Rule{
+					{Direction: "in", Protocol: "tcp", Port: "22-22", SourceIPs: []string{"10.0.0.0/8"}},
+					{Direction: "in", Protocol: "tcp", Port: "443", SourceIPs: []string{"::/0", "0.0.0.0/0"}},
+				},
+				ApplyTo: []applyTo{{LabelSelector: "role=web"}, {Server: float64(42)}},
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.False(t, resp.HasChanged(), "module should not have changed")
+		}
+	})
+
+	t.Run("rule drift and resources", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Firewall = hcloudtest.NewFirewallClientMock()
+		client.Server = hcloudtest.NewServerClientMock()
+		server := &hcloud.Server{ID: 44}
+		client.Server.(*hcloudtest.ServerClientMock).On("GetByID", mock.Anything, 44).Return(server, nilResponse, nil)
+		firewall := newFirewall()
+		firewallMock := client.Firewall.(*hcloudtest.FirewallClientMock)
+		firewallMock.On("GetByID", mock.Anything, 1).Return(firewall, nilResponse, nil)
+		// the firewall is locked while an action runs, so the actions
+		// are awaited before the next one and only the last one is left pending
+		var waited []int
+		firewallAction := func(id int) *hcloud.Action {
+			return &hcloud.Action{
+				ID:        id,
+				Resources: []*hcloud.ActionResource{{ID: firewall.ID, Type: hcloud.ActionResourceTypeFirewall}},
+			}
+		}
+		firewallMock.On("SetRules", mock.Anything, firewall, []hcloud.FirewallRule{
+			{Direction: "in", Protocol: "tcp", Port: "443", SourceIPs: ipNets("0.0.0.0/0")},
+		}).Return([]*hcloud.Action{firewallAction(1), firewallAction(2)}, nilResponse, nil)
+		firewallMock.On("RemoveResources", mock.Anything, firewall, []hcloud.FirewallResource{firewall.AppliedTo[0]}).
+			Run(func(args mock.Arguments) {
+				assert.Equal(t, []int{1, 2}, waited, "resources removed before the rules were set")
+			}).
+			Return([]*hcloud.Action{firewallAction(3)}, nilResponse, nil)
+		firewallMock.On("ApplyResources", mock.Anything, firewall, []hcloud.FirewallResource{{Type: "server", Server: server}}).
+			Run(func(args mock.Arguments) {
+				assert.Equal(t, []int{1, 2, 3}, waited, "resources applied before the removal finished")
+			}).
+			Return([]*hcloud.Action{firewallAction(4)}, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				for _, action := range actions {
+					waited = append(waited, action.ID)
+				}
+				return nil
+			}),
+			args: arguments{
+				State:   statePresent,
+				ID:      float64(1),
+				Rules:   []Rule{{Direction: "in", Protocol: "tcp", Port: "443", SourceIPs: []string{"0.0.0.0/0"}}},
+				ApplyTo: []applyTo{{LabelSelector: "role=web"}, {Server: float64(44)}},
+				Wait:    hcloud.Bool(false),
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			assert.Equal(t, []int{4}, resp.Data()["action_ids"])
+		}
+	})
+
+	t.Run("unmanaged rules", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Firewall = hcloudtest.NewFirewallClientMock()
+		firewallMock := client.Firewall.(*hcloudtest.FirewallClientMock)
+		firewallMock.On("GetByName", mock.Anything, "web").Return(newFirewall(), nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: statePresent, Name: "web"},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.False(t, resp.HasChanged(), "module should not have changed")
+			firewallMock.AssertNotCalled(t, "SetRules", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+}
+
+func TestAbsent(t *testing.T) {
+	client := hcloud.NewClient()
+	client.Firewall = hcloudtest.NewFirewallClientMock()
+	firewall := newFirewall()
+	firewallMock := client.Firewall.(*hcloudtest.FirewallClientMock)
+	firewallMock.On("GetByName", mock.Anything, "web").Return(firewall, nilResponse, nil)
+	firewallMock.On("RemoveResources", mock.Anything, firewall, firewall.AppliedTo).Return([]*hcloud.Action{{ID: 1}}, nilResponse, nil)
+	firewallMock.On("Delete", mock.Anything, firewall).Return(nilResponse, nil)
+
+	m := module{
+		client: client,
+		waiter: noWait,
+		args:   arguments{State: stateAbsent, Name: "web"},
+	}
+	resp, err := m.run(context.Background())
+	if assert.NoError(t, err) {
+		assert.True(t, resp.HasChanged(), "module should have changed")
+		firewallMock.AssertCalled(t, "Delete", mock.Anything, firewall)
+	}
+}
+
+func TestValidateArgs(t *testing.T) {
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: stateList}}))
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", Rules: []Rule{
+		{Direction: "in", Protocol: "tcp", Port: "1024-5000", SourceIPs: []string{"0.0.0.0/0"}},
+		{Direction: "out", Protocol: "gre", DestinationIPs: []string{"2001:db8::/32"}},
+	}}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", Rules: []Rule{
+		{Direction: "in", Protocol: "tcp", SourceIPs: []string{"0.0.0.0/0"}},
+	}}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", Rules: []Rule{
+		{Direction: "in", Protocol: "tcp", Port: "5000-1024", SourceIPs: []string{"0.0.0.0/0"}},
+	}}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", Rules: []Rule{
+		{Direction: "in", Protocol: "icmp", Port: "22", SourceIPs: []string{"0.0.0.0/0"}},
+	}}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", Rules: []Rule{
+		{Direction: "out", Protocol: "udp", Port: "53", SourceIPs: []string{"0.0.0.0/0"}},
+	}}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", Rules: []Rule{
+		{Direction: "in", Protocol: "tcp", Port: "22", SourceIPs: []string{"10.0.0.0/33"}},
+	}}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", ApplyTo: []applyTo{{}}}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", ApplyTo: []applyTo{
+		{Server: "web1", LabelSelector: "role=web"},
+	}}}))
+}
+
+func TestDoc(t *testing.T) {
+	data, err := ioutil.ReadFile("../../docs/hcloud_firewall.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(data), doc.Markdown(argSpec), "docs/hcloud_firewall.md is outdated, run make docs")
+	}
+}
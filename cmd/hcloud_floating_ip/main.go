@@ -1,10 +1,14 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"net"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
@@ -28,42 +32,221 @@ type FloatingIP struct {
 	HomeLocation string `json:"home_location"`
 }
 
+// OSConfig holds snippets configuring floating IPs on the public interface of a server
+type OSConfig struct {
+	// Netplan is a file for /etc/netplan/
+	Netplan string `json:"netplan"`
+	// Ifupdown is a file for /etc/network/interfaces.d/
+	Ifupdown string `json:"ifupdown"`
+	// Networkd is a drop-in for the .network file of the interface
+	Networkd string `json:"networkd"`
+}
+
 type arguments struct {
 	Token string `json:"token"`
 	State string `json:"state"`
 
-	ID           interface{} `json:"id"`
-	Description  string      `json:"description"`
-	Type         string      `json:"type"`
-	Server       interface{} `json:"server"`
-	HomeLocation string      `json:"home_location"`
+	ID          interface{} `json:"id"`
+	Description string      `json:"description"`
+	// LabelSelector and IP identify existing floating IPs like Description
+	LabelSelector string      `json:"label_selector"`
+	IP            string      `json:"ip"`
+	Type          string      `json:"type"`
+	Server        interface{} `json:"server"`
+	HomeLocation  string      `json:"home_location"`
+	// AssignToOneOf lists candidate servers, the floating IP stays at a healthy assignee
+	// or moves to the first healthy candidate
+	AssignToOneOf      []interface{} `json:"assign_to_one_of"`
+	HealthCheckPort    int           `json:"health_check_port"`
+	HealthCheckTimeout interface{}   `json:"health_check_timeout"`
+	// Interface is the public interface of the server in the rendered os_config
+	Interface string `json:"interface"`
+	Wait      *bool  `json:"wait"`
+}
+
+var argSpec = ansible.ArgSpec{
+	Args: []ansible.Arg{
+		ansible.TokenArg(),
+		{Name: "state", Type: ansible.TypeStr, Default: statePresent, Choices: []string{statePresent, stateAbsent, stateList},
+			Description: "`list` lists all existing floating ips, or the floating ips assigned to `server`.\n\n" +
+				"**NOTICE:** `present` without `id`, `description`, `label_selector` or `ip` creates a new floating ip on every run."},
+		{Name: "id", Type: ansible.TypeRaw,
+			Description: "ID of the floating ip.\n\n`id`, `description`, `label_selector` or `ip` is required when `state=absent`."},
+		{Name: "description", Type: ansible.TypeStr,
+			Description: "Description of the floating ip. Identifies the floating ip when `id` is not specified, it is changed otherwise."},
+		{Name: "label_selector", Type: ansible.TypeStr,
+			Description: "Label selector identifying an existing floating ip, e.g. `role=loadbalancer`."},
+		{Name: "ip", Type: ansible.TypeStr,
+			Description: "Address identifying an existing floating ip. IPv6 floating ips are matched by their network or any address in it."},
+		{Name: "type", Type: ansible.TypeStr, Default: string(hcloud.FloatingIPTypeIPv4),
+			Choices:     []string{string(hcloud.FloatingIPTypeIPv4), string(hcloud.FloatingIPTypeIPv6)},
+			Description: "Type of a new floating ip."},
+		{Name: "server", Type: ansible.TypeRaw,
+			Description: "Server to assign the floating ip to, the floating ip is unassigned if not specified. " +
+				"With `state=list` only floating ips assigned to the server are listed.\n\n" +
+				"Required to create a floating ip when `home_location` or `assign_to_one_of` is not specified.\n\n" +
+				"Mutually exclusive with `home_location` and `assign_to_one_of`."},
+		{Name: "home_location", Type: ansible.TypeStr,
+			Description: "Home location of the floating ip.\n\n" +
+				"Required to create a floating ip when `server` or `assign_to_one_of` is not specified.\n\n" +
+				"Mutually exclusive with `server` and `assign_to_one_of`."},
+		{Name: "assign_to_one_of", Type: ansible.TypeList,
+			Description: "List of candidate servers by id or name. " +
+				"The floating ip stays at its server if it is a healthy candidate, otherwise it is assigned to the first healthy candidate. " +
+				"A server is healthy when it is running and passes the health check, candidates that do not exist are skipped with a warning.\n\n" +
+				"Mutually exclusive with `server` and `home_location`."},
+		{Name: "health_check_port", Type: ansible.TypeInt,
+			Description: "TCP port on the public IP of the candidate servers that has to accept connections for a server to be healthy. " +
+				"Servers without public IPv4 are checked on the first address of their IPv6 network."},
+		{Name: "health_check_timeout", Type: ansible.TypeRaw, Default: defaultHealthCheckTimeout.String(),
+			Description: "Timeout of the TCP health check as seconds or duration string."},
+		{Name: "interface", Type: ansible.TypeStr, Default: defaultInterface,
+			Description: "Public network interface of the server in `os_config`, e.g. `enp1s0` on newer images."},
+		{Name: "wait", Type: ansible.TypeBool, Default: true,
+			Description: "Wait for all actions to complete. When `false`, the module returns immediately with `action_ids`, see `hcloud_action`."},
+	},
+	RequiredIf: []ansible.RequiredIf{
+		{Arg: "state", Value: stateAbsent, Required: []string{"id", "description", "label_selector", "ip"}, OneOf: true},
+	},
+	MutuallyExclusive: [][]string{{"home_location", "server", "assign_to_one_of"}},
 }
 
+var doc = ansible.Doc{
+	Module:           "hcloud_floating_ip",
+	ShortDescription: "Manage Hetzner Cloud Floating IPs",
+	Description: "Manages Hetzner Cloud floating ips. This module can be used to create, modify, assign and delete floating ips.\n\n" +
+		"Without `id`, existing floating ips are identified by `description`, `label_selector` and `ip`; a floating ip has to match all of them. " +
+		"The module fails if several floating ips match. " +
+		"A new floating ip is only created when none matches `description`, floating ips identified by `label_selector` or `ip` are never created.",
+	Returns: []ansible.Return{
+		{
+			Name:        "floating_ips",
+			Description: "The floating ip, or all floating ips with `state=list`.",
+			Returned:    "unless `state=absent`",
+			Sample: []FloatingIP{{
+				ID:           123,
+				Description:  "Loadbalancer IP",
+				IP:           "131.232.99.1",
+				Type:         string(hcloud.FloatingIPTypeIPv4),
+				ServerID:     hcloud.Int(123),
+				HomeLocation: "fsn1",
+			}},
+		},
+		{
+			Name: "os_config",
+			Description: "The configuration of the floating ips on the server. " +
+				"IPv4 floating ips are configured as `/32`, IPv6 floating ips with the first address of their `/64` network. " +
+				"`ifupdown` configures each IPv4 floating ip on its own alias of the interface, e.g. `eth0:1`, and IPv6 floating ips on the interface itself. " +
+				"The snippets are empty when no floating ip is assigned to the server.\n\n" +
+				"`netplan` is a file for `/etc/netplan/`, `ifupdown` a file for `/etc/network/interfaces.d/` " +
+				"and `networkd` a drop-in for the `.network` file of the interface, " +
+				"e.g. `/etc/systemd/network/10-eth0.network.d/60-floating-ip.conf`.",
+			Returned: "when the floating ip is assigned to a server, or `state=list` is used with `server`",
+			Sample: renderOSConfig(defaultInterface, []*hcloud.FloatingIP{
+				{IP: net.ParseIP("131.232.99.1"), Type: hcloud.FloatingIPTypeIPv4},
+			}),
+		},
+		{
+			Name:        "action_ids",
+			Description: "IDs of the pending actions, see `hcloud_action`.",
+			Returned:    "when `wait` is `false`",
+			Sample:      []int{4711},
+		},
+	},
+	Examples: `
+# create a floating ip and assign it to a server,
+# the floating ip is found again by its description on the next run
+- hcloud_floating_ip:
+    description: Loadbalancer IP
+    type: ipv4
+    server: 123 # by id
+
+# assign the floating ip labeled role=loadbalancer to server "lb2"
+- hcloud_floating_ip:
+    label_selector: role=loadbalancer
+    server: lb2
+
+# delete a floating ip by its address
+- hcloud_floating_ip:
+    ip: 131.232.99.1
+    state: absent
+
+# list all floating ips in the Hetzner Cloud Project and
+# assign all of them to a server
+- hcloud_floating_ip:
+    state: list
+  register: hcloud_floating_ips
+
+- hcloud_floating_ip:
+    id: "{{item.id}}"
+    server: 123
+  with_items: {{hcloud_floating_ips.floating_ips}}
+
+# assign floating ip 123 to server "loadbalancer"
+- hcloud_floating_ip:
+    id: 123
+    server: "loadbalancer" # by name
+
+# keep the floating ip at a server accepting connections on port 443,
+# e.g. when run from cron
+- hcloud_floating_ip:
+    description: Loadbalancer IP
+    assign_to_one_of: [lb1, lb2, lb3]
+    health_check_port: 443
+    health_check_timeout: 5s
+
+# configure all floating ips of the server on the server
+- hcloud_floating_ip:
+    state: list
+    server: "{{ inventory_hostname }}"
+  register: floating_ips
+  delegate_to: localhost
+
+- copy:
+    content: "{{ floating_ips.os_config.netplan }}"
+    dest: /etc/netplan/60-floating-ip.yaml
+  notify: netplan apply
+`,
+}
+
+const (
+	defaultHealthCheckTimeout = 2 * time.Second
+	defaultInterface          = "eth0"
+)
+
 type module struct {
 	args   arguments
 	client *hcloud.Client
-	waitFn util.WaitFn
+	waiter util.ActionWaiter
+	// dial connects to candidate servers for the health check
+	dial func(ctx context.Context, network, address string) (net.Conn, error)
 }
 
 func (m *module) Args() interface{} {
 	return &m.args
 }
 
-func (m *module) Run() (resp ansible.ModuleResponse, err error) {
+func (m *module) ArgSpec() ansible.ArgSpec {
+	return argSpec
+}
+
+func (m *module) Doc() ansible.Doc {
+	return doc
+}
+
+func (m *module) Run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
 	m.client, err = hcloud.BuildClient(m.args.Token)
 	if err != nil {
 		return
 	}
-	if m.args.State == "" {
-		m.args.State = statePresent
-	}
-	return m.run()
+	m.waiter = util.NewActionWatcher(m.client.Action)
+	m.dial = (&net.Dialer{}).DialContext
+	return m.run(ctx)
 }
 
-func (m *module) run() (resp ansible.ModuleResponse, err error) {
-	ctx := context.Background()
-	if err = validateArgs(m.args); err != nil {
-		return
+func (m *module) run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	if m.args.Wait != nil && !*m.args.Wait {
+		defer util.RecordActions(&m.waiter).Report(&resp, &err)
 	}
 
 	switch m.args.State {
@@ -81,20 +264,45 @@ func (m *module) run() (resp ansible.ModuleResponse, err error) {
 
 func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err error) {
 	var floatingIP *hcloud.FloatingIP
-	id := util.GetID(m.args.ID)
-	if id != 0 {
-		if floatingIP, _, err = m.client.FloatingIP.GetByID(ctx, id); err != nil {
+	if floatingIP, err = m.floatingIP(ctx); err != nil {
+		return
+	}
+	if floatingIP == nil && m.args.ID == nil {
+		switch {
+		case m.args.IP != "":
+			err = fmt.Errorf("No FloatingIP with IP %s found", m.args.IP)
+			return
+		case m.args.LabelSelector != "":
+			// created floating IPs have no labels and would not be found again
+			err = fmt.Errorf("No FloatingIP matching label selector %q found, floating IPs can only be created by 'description'", m.args.LabelSelector)
 			return
+		case m.args.Description == "":
+			resp.Warn("No 'id' or 'description' set, a new FloatingIP is created on every run")
 		}
 	}
 
-	var server *hcloud.Server
-	if server, err = m.server(ctx, m.args.Server); err != nil {
+	var (
+		server *hcloud.Server
+		msg    []string
+	)
+	if m.args.AssignToOneOf != nil {
+		var reason string
+		if server, reason, err = m.healthyServer(ctx, &resp, floatingIP); err != nil {
+			return
+		}
+		if reason != "" {
+			msg = append(msg, reason)
+		}
+	} else if server, err = m.server(ctx, m.args.Server); err != nil {
 		return
 	}
 
-	var msg []string
 	if floatingIP == nil {
+		// the location of an existing floating IP is known, it is only required to create one
+		if m.args.HomeLocation == "" && server == nil {
+			err = errors.New("'home_location', 'server' or 'assign_to_one_of' is required to create a FloatingIP")
+			return
+		}
 		opts := hcloud.FloatingIPCreateOpts{
 			Description: hcloud.String(m.args.Description),
 			Server:      server,
@@ -113,7 +321,7 @@ func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err
 			return
 		}
 		if r.Action != nil {
-			err = util.WaitForAction(ctx, m.client, r.Action)
+			err = m.waiter.WaitForActions(ctx, r.Action)
 			if err != nil {
 				return
 			}
@@ -123,7 +331,8 @@ func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err
 		resp.Changed()
 	}
 
-	if floatingIP.Description != m.args.Description {
+	// without id the description identifies the floating IP and is not changed
+	if m.args.ID != nil && floatingIP.Description != m.args.Description {
 		floatingIP, _, err = m.client.FloatingIP.Update(ctx, floatingIP, hcloud.FloatingIPUpdateOpts{
 			Description: m.args.Description,
 		})
@@ -141,7 +350,7 @@ func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err
 			return
 		}
 
-		if err = m.waitFn(ctx, m.client, action); err != nil {
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
 			return
 		}
 
@@ -157,7 +366,7 @@ func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err
 			return
 		}
 
-		if err = m.waitFn(ctx, m.client, action); err != nil {
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
 			return
 		}
 
@@ -168,14 +377,16 @@ func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err
 	resp.
 		Msg(strings.Join(msg, ", ")).
 		Set("floating_ips", []FloatingIP{toFloatingIP(floatingIP)})
+	if server != nil {
+		resp.Set("os_config", renderOSConfig(m.args.Interface, []*hcloud.FloatingIP{floatingIP}))
+	}
 
 	return
 }
 
 func (m *module) absent(ctx context.Context) (resp ansible.ModuleResponse, err error) {
-	id := util.GetID(m.args.ID)
 	var floatingIP *hcloud.FloatingIP
-	if floatingIP, _, err = m.client.FloatingIP.GetByID(ctx, id); err != nil {
+	if floatingIP, err = m.floatingIP(ctx); err != nil {
 		return
 	}
 	if floatingIP == nil {
@@ -199,14 +410,184 @@ func (m *module) list(ctx context.Context) (resp ansible.ModuleResponse, err err
 		return
 	}
 
+	// with a server only its floating IPs are listed and configured
+	var server *hcloud.Server
+	if server, err = m.server(ctx, m.args.Server); err != nil {
+		return
+	}
+	var assigned []*hcloud.FloatingIP
 	for _, floatingIP := range floatingIPs {
+		if server != nil && (floatingIP.Server == nil || floatingIP.Server.ID != server.ID) {
+			continue
+		}
+		assigned = append(assigned, floatingIP)
 		list = append(list, toFloatingIP(floatingIP))
 	}
 	resp.Msg("FloatingIPs listed").Set("floating_ips", list)
+	if server != nil {
+		resp.Set("os_config", renderOSConfig(m.args.Interface, assigned))
+	}
+	return
+}
+
+// floatingIP returns the floating IP with the id or the only floating IP
+// matching description, label selector and IP, ambiguous matches are an error
+func (m *module) floatingIP(ctx context.Context) (floatingIP *hcloud.FloatingIP, err error) {
+	if m.args.ID != nil {
+		floatingIP, _, err = m.client.FloatingIP.GetByID(ctx, util.GetID(m.args.ID))
+		return
+	}
+	if m.args.Description == "" && m.args.LabelSelector == "" && m.args.IP == "" {
+		return
+	}
+
+	var floatingIPs []*hcloud.FloatingIP
+	if floatingIPs, err = m.client.FloatingIP.All(ctx); err != nil {
+		return
+	}
+	var labeled map[int]hcloud.Labels
+	if m.args.LabelSelector != "" {
+		if labeled, err = m.client.Label.List(ctx, hcloud.LabelResourceFloatingIPs, m.args.LabelSelector); err != nil {
+			return
+		}
+	}
+
+	var matches []string
+	for _, f := range floatingIPs {
+		if m.args.Description != "" && f.Description != m.args.Description {
+			continue
+		}
+		if _, ok := labeled[f.ID]; m.args.LabelSelector != "" && !ok {
+			continue
+		}
+		if m.args.IP != "" && !matchesIP(f, m.args.IP) {
+			continue
+		}
+		floatingIP = f
+		matches = append(matches, fmt.Sprint(f.ID))
+	}
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("FloatingIPs %s match, use 'id' or a unique 'description', 'label_selector' or 'ip'",
+			strings.Join(matches, ", "))
+	}
+	return
+}
+
+// matchesIP checks if the floating IP is the address,
+// IPv6 floating IPs match their network and any address in it
+func matchesIP(floatingIP *hcloud.FloatingIP, value string) bool {
+	if ip, network, err := net.ParseCIDR(value); err == nil {
+		return floatingIP.Network != nil && floatingIP.Network.String() == network.String() ||
+			floatingIP.IP.Equal(ip)
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return false
+	}
+	return floatingIP.IP.Equal(ip) || floatingIP.Network != nil && floatingIP.Network.Contains(ip)
+}
+
+// healthyServer returns the assignee of the floating IP if it is a healthy candidate,
+// the first healthy candidate otherwise and why the assignee was not kept.
+// Candidates that do not exist are skipped with a warning, e.g. a deleted server.
+func (m *module) healthyServer(ctx context.Context, resp *ansible.ModuleResponse, floatingIP *hcloud.FloatingIP) (server *hcloud.Server, reason string, err error) {
+	var candidates []*hcloud.Server
+	for _, arg := range m.args.AssignToOneOf {
+		var candidate *hcloud.Server
+		if candidate, err = m.findServer(ctx, arg); err != nil {
+			return
+		}
+		if candidate == nil {
+			resp.Warn(fmt.Sprintf("Server '%v' in 'assign_to_one_of' not found", arg))
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+	if len(candidates) == 0 {
+		err = errors.New("No server in 'assign_to_one_of' found")
+		return
+	}
+
+	var unhealthy []string
+	if floatingIP != nil && floatingIP.Server != nil {
+		for _, candidate := range candidates {
+			if candidate.ID != floatingIP.Server.ID {
+				continue
+			}
+			if err := m.checkHealth(ctx, candidate); err != nil {
+				reason = fmt.Sprintf("Server %d unhealthy: %v", candidate.ID, err)
+				unhealthy = append(unhealthy, reason)
+				break
+			}
+			return candidate, "", nil
+		}
+		if reason == "" {
+			reason = fmt.Sprintf("Server %d is no candidate", floatingIP.Server.ID)
+		}
+	}
+
+	for _, candidate := range candidates {
+		if floatingIP != nil && floatingIP.Server != nil && candidate.ID == floatingIP.Server.ID {
+			continue
+		}
+		if err := m.checkHealth(ctx, candidate); err != nil {
+			unhealthy = append(unhealthy, fmt.Sprintf("Server %d unhealthy: %v", candidate.ID, err))
+			continue
+		}
+		return candidate, reason, nil
+	}
+	err = fmt.Errorf("No healthy server in 'assign_to_one_of': %s", strings.Join(unhealthy, ", "))
 	return
 }
 
+// checkHealth checks if the server is running and accepts TCP connections
+// on its public IP if a health check port is configured
+func (m *module) checkHealth(ctx context.Context, server *hcloud.Server) error {
+	if server.Status != hcloud.ServerStatusRunning {
+		return fmt.Errorf("status is %s", server.Status)
+	}
+	if m.args.HealthCheckPort == 0 {
+		return nil
+	}
+
+	var ip net.IP
+	if ipv4 := server.PublicNet.IPv4.IP; ipv4 != nil {
+		ip = ipv4
+	} else if ipv6 := server.PublicNet.IPv6.IP; ipv6 != nil {
+		ip = make(net.IP, len(ipv6))
+		copy(ip, ipv6)
+		ip[len(ip)-1]++
+	} else {
+		return errors.New("no public IP to check")
+	}
+
+	timeout, _ := ansible.ParseTimeout(m.args.HealthCheckTimeout)
+	if timeout == 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	address := net.JoinHostPort(ip.String(), strconv.Itoa(m.args.HealthCheckPort))
+	conn, err := m.dial(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("health check failed: %v", err)
+	}
+	conn.Close()
+	return nil
+}
+
 func (m *module) server(ctx context.Context, serverArg interface{}) (server *hcloud.Server, err error) {
+	if server, err = m.findServer(ctx, serverArg); err != nil {
+		return
+	}
+	if server == nil && (util.GetID(serverArg) != 0 || util.GetName(serverArg) != "") {
+		err = fmt.Errorf("Server '%v' not found", serverArg)
+	}
+	return
+}
+
+// findServer returns the server by id or name, nil if it does not exist
+func (m *module) findServer(ctx context.Context, serverArg interface{}) (server *hcloud.Server, err error) {
 	id := util.GetID(serverArg)
 	name := util.GetName(serverArg)
 
@@ -223,14 +604,55 @@ func (m *module) server(ctx context.Context, serverArg interface{}) (server *hcl
 			server, _, err = m.client.Server.GetByName(ctx, name)
 		}
 	}
+	return
+}
 
-	if err != nil {
-		return
+// address returns the address of the floating IP with prefix length,
+// IPv6 floating IPs are configured with the first address of their network
+func address(floatingIP *hcloud.FloatingIP) string {
+	if floatingIP.Network == nil {
+		return floatingIP.IP.String() + "/32"
 	}
-	if server == nil {
-		err = fmt.Errorf("Server '%v' not found", serverArg)
+	ip := make(net.IP, len(floatingIP.Network.IP))
+	copy(ip, floatingIP.Network.IP)
+	ip[len(ip)-1]++
+	ones, _ := floatingIP.Network.Mask.Size()
+	return fmt.Sprintf("%s/%d", ip, ones)
+}
+
+// renderOSConfig renders the configuration of the floating IPs on the interface
+// for netplan, ifupdown and systemd-networkd, it is empty without floating IPs.
+// ifupdown configures each IPv4 address on its own alias of the interface
+// and IPv6 addresses on the interface itself.
+func renderOSConfig(iface string, floatingIPs []*hcloud.FloatingIP) OSConfig {
+	var netplan, ifupdown, networkd bytes.Buffer
+	alias := 0
+	for _, floatingIP := range floatingIPs {
+		if floatingIP.IP == nil {
+			continue
+		}
+		addr := address(floatingIP)
+		ip, network, _ := net.ParseCIDR(addr)
+		ones, _ := network.Mask.Size()
+
+		fmt.Fprintf(&netplan, "      - %s\n", addr)
+		if ip.To4() != nil {
+			alias++
+			fmt.Fprintf(&ifupdown, "auto %s:%d\niface %s:%d inet static\n    address %s\n    netmask %d\n",
+				iface, alias, iface, alias, ip, ones)
+		} else {
+			fmt.Fprintf(&ifupdown, "iface %s inet6 static\n    address %s\n    netmask %d\n", iface, ip, ones)
+		}
+		fmt.Fprintf(&networkd, "Address=%s\n", addr)
+	}
+	if netplan.Len() == 0 {
+		return OSConfig{}
+	}
+	return OSConfig{
+		Netplan:  fmt.Sprintf("network:\n  version: 2\n  ethernets:\n    %s:\n      addresses:\n", iface) + netplan.String(),
+		Ifupdown: ifupdown.String(),
+		Networkd: "[Network]\n" + networkd.String(),
 	}
-	return
 }
 
 func toFloatingIP(ip *hcloud.FloatingIP) FloatingIP {
@@ -247,29 +669,23 @@ func toFloatingIP(ip *hcloud.FloatingIP) FloatingIP {
 	return data
 }
 
-func validateArgs(args arguments) error {
-	errs := []string{}
-	if args.State != stateAbsent &&
-		args.State != statePresent &&
-		args.State != stateList {
-		errs = append(errs, "'state' must be present, absent or list")
+// CheckArgs checks the constraints of the arguments the spec cannot express
+func (m *module) CheckArgs() (errs []string) {
+	if m.args.AssignToOneOf != nil && len(m.args.AssignToOneOf) == 0 {
+		errs = append(errs, "'assign_to_one_of' must not be empty")
 	}
-	if args.State == statePresent && args.ID == nil {
-		if args.HomeLocation == "" && args.Server == nil {
-			errs = append(errs, "'home_location' or 'server' must be set")
-		}
-		if args.HomeLocation != "" && args.Server != nil {
-			errs = append(errs, "'home_location' and 'server' are mutually exclusive")
-		}
+	if m.args.HealthCheckPort < 0 || m.args.HealthCheckPort > 65535 {
+		errs = append(errs, "'health_check_port' must be a port number")
 	}
-	if args.State == stateAbsent &&
-		args.ID == nil {
-		errs = append(errs, "'id' is required")
+	if _, err := ansible.ParseTimeout(m.args.HealthCheckTimeout); err != nil {
+		errs = append(errs, "'health_check_timeout' must be a positive number of seconds or a duration")
 	}
-	if len(errs) > 0 {
-		return fmt.Errorf(strings.Join(errs, ", "))
+	if m.args.IP != "" && net.ParseIP(m.args.IP) == nil {
+		if _, _, err := net.ParseCIDR(m.args.IP); err != nil {
+			errs = append(errs, fmt.Sprintf("'ip' %q is not an IP address", m.args.IP))
+		}
 	}
-	return nil
+	return
 }
 
 var flags = pflag.NewFlagSet("hcloud_floating_ip", pflag.ContinueOnError)
@@ -279,7 +695,5 @@ func init() {
 }
 
 func main() {
-	ansible.RunModule(&module{
-		waitFn: util.WaitForAction,
-	}, flags)
+	ansible.RunModule(&module{}, flags)
 }
@@ -2,17 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
+	"io/ioutil"
 	"net"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
 	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
 	"github.com/thetechnick/hcloud-ansible/pkg/hcloud/hcloudtest"
 	"github.com/thetechnick/hcloud-ansible/pkg/util"
 )
 
 var (
+	nilResponse *hcloud.Response
+
 	floatingIP = &hcloud.FloatingIP{
 		ID:           123,
 		IP:           net.ParseIP("192.168.1.1"),
@@ -42,6 +47,102 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestListServer(t *testing.T) {
+	client := hcloud.NewClient()
+	client.FloatingIP = hcloudtest.NewFloatingIPClientMock()
+	client.Server = hcloudtest.NewServerClientMock()
+
+	m := module{
+		client: client,
+		args: arguments{
+			Server:    "lb1",
+			Interface: "eth0",
+		},
+	}
+
+	var r *hcloud.Response
+	_, network, _ := net.ParseCIDR("2001:db8:1::/64")
+	assignedIPv4 := &hcloud.FloatingIP{ID: 1, IP: net.ParseIP("192.168.1.1"), Type: hcloud.FloatingIPTypeIPv4,
+		HomeLocation: &hcloud.Location{}, Server: &hcloud.Server{ID: 1}}
+	assignedIPv6 := &hcloud.FloatingIP{ID: 2, IP: network.IP, Network: network, Type: hcloud.FloatingIPTypeIPv6,
+		HomeLocation: &hcloud.Location{}, Server: &hcloud.Server{ID: 1}}
+	other := &hcloud.FloatingIP{ID: 3, IP: net.ParseIP("192.168.1.3"), Type: hcloud.FloatingIPTypeIPv4,
+		HomeLocation: &hcloud.Location{}, Server: &hcloud.Server{ID: 2}}
+
+	floatingIPMock := client.FloatingIP.(*hcloudtest.FloatingIPClientMock)
+	floatingIPMock.On("All", mock.Anything).Return([]*hcloud.FloatingIP{assignedIPv4, other, assignedIPv6}, nil)
+	serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+	serverClientMock.On("GetByName", mock.Anything, "lb1").Return(&hcloud.Server{ID: 1, Name: "lb1"}, r, nil)
+
+	resp, err := m.list(context.Background())
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]interface{}{
+			"floating_ips": []FloatingIP{toFloatingIP(assignedIPv4), toFloatingIP(assignedIPv6)},
+			"os_config": OSConfig{
+				Netplan: `network:
+  version: 2
+  ethernets:
+    eth0:
+      addresses:
+      - 192.168.1.1/32
+      - 2001:db8:1::1/64
+`,
+				Ifupdown: `auto eth0:1
+iface eth0:1 inet static
+    address 192.168.1.1
+    netmask 32
+iface eth0 inet6 static
+    address 2001:db8:1::1
+    netmask 64
+`,
+				Networkd: `[Network]
+Address=192.168.1.1/32
+Address=2001:db8:1::1/64
+`,
+			},
+		}, resp.Data())
+	}
+}
+
+func TestRenderOSConfig(t *testing.T) {
+	_, network, _ := net.ParseCIDR("2001:db8:1::/64")
+	config := renderOSConfig("enp1s0", []*hcloud.FloatingIP{
+		{IP: net.ParseIP("192.168.1.1"), Type: hcloud.FloatingIPTypeIPv4},
+		{IP: network.IP, Network: network, Type: hcloud.FloatingIPTypeIPv6},
+		{IP: net.ParseIP("192.168.1.2"), Type: hcloud.FloatingIPTypeIPv4},
+	})
+	assert.Equal(t, OSConfig{
+		Netplan: `network:
+  version: 2
+  ethernets:
+    enp1s0:
+      addresses:
+      - 192.168.1.1/32
+      - 2001:db8:1::1/64
+      - 192.168.1.2/32
+`,
+		Ifupdown: `auto enp1s0:1
+iface enp1s0:1 inet static
+    address 192.168.1.1
+    netmask 32
+iface enp1s0 inet6 static
+    address 2001:db8:1::1
+    netmask 64
+auto enp1s0:2
+iface enp1s0:2 inet static
+    address 192.168.1.2
+    netmask 32
+`,
+		Networkd: `[Network]
+Address=192.168.1.1/32
+Address=2001:db8:1::1/64
+Address=192.168.1.2/32
+`,
+	}, config)
+
+	assert.Equal(t, OSConfig{}, renderOSConfig("eth0", nil), "no floating IPs")
+}
+
 func TestAbsent(t *testing.T) {
 	t.Run("floatingip exists", func(t *testing.T) {
 		client := hcloud.NewClient()
@@ -212,7 +313,7 @@ func TestPresent(t *testing.T) {
 
 		m := module{
 			client: client,
-			waitFn: util.WaitFn(func(ctx context.Context, client *hcloud.Client, action *hcloud.Action) error {
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
 				return nil
 			}),
 			args: arguments{
@@ -241,7 +342,7 @@ func TestPresent(t *testing.T) {
 		serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
 		serverClientMock.On("GetByID", mock.Anything, mock.Anything).Return(rServer, r, nil)
 
-		resp, err := m.run()
+		resp, err := m.run(context.Background())
 		assert.NoError(t, err)
 		assert.True(t, resp.HasChanged(), "should have changed")
 
@@ -257,7 +358,7 @@ func TestPresent(t *testing.T) {
 
 		m := module{
 			client: client,
-			waitFn: util.WaitFn(func(ctx context.Context, client *hcloud.Client, action *hcloud.Action) error {
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
 				return nil
 			}),
 			args: arguments{
@@ -287,7 +388,7 @@ func TestPresent(t *testing.T) {
 		serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
 		serverClientMock.On("GetByID", mock.Anything, mock.Anything).Return(rServer, r, nil)
 
-		resp, err := m.run()
+		resp, err := m.run(context.Background())
 		assert.NoError(t, err)
 		assert.True(t, resp.HasChanged(), "should have changed")
 
@@ -303,7 +404,7 @@ func TestPresent(t *testing.T) {
 
 		m := module{
 			client: client,
-			waitFn: util.WaitFn(func(ctx context.Context, client *hcloud.Client, action *hcloud.Action) error {
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
 				return nil
 			}),
 			args: arguments{
@@ -326,6 +427,54 @@ func TestPresent(t *testing.T) {
 
 		floatingIPMock := client.FloatingIP.(*hcloudtest.FloatingIPClientMock)
 		floatingIPMock.On("GetByID", mock.Anything, mock.Anything).Return(rFloatingIP, r, nil)
+		floatingIPMock.On("All", mock.Anything).Return([]*hcloud.FloatingIP{}, nil)
+		floatingIPMock.On("Create", mock.Anything, mock.Anything).Return(hcloud.FloatingIPCreateResult{
+			FloatingIP: rFloatingIP,
+		}, r, nil)
+		floatingIPMock.On("Unassign", mock.Anything, mock.Anything, mock.Anything).Return(rAction, r, nil)
+
+		serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+		serverClientMock.On("GetByID", mock.Anything, mock.Anything).Return(rServer, r, nil)
+
+		resp, err := m.run(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, resp.HasChanged(), "should have changed")
+
+		assert.Empty(t, resp.Warnings())
+		floatingIPMock.AssertCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("create without description", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.FloatingIP = hcloudtest.NewFloatingIPClientMock()
+		client.Server = hcloudtest.NewServerClientMock()
+		client.Action = hcloudtest.NewActionClientMock()
+
+		m := module{
+			client: client,
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				return nil
+			}),
+			args: arguments{
+				Token: "--token--",
+
+				State:        "present",
+				HomeLocation: "fsn1",
+			},
+		}
+
+		var r *hcloud.Response
+		rServer := &hcloud.Server{ID: 123}
+		rFloatingIP := &hcloud.FloatingIP{
+			ID: 123, Description: "test",
+			HomeLocation: &hcloud.Location{},
+			Server:       &hcloud.Server{ID: 456},
+		}
+		rAction := &hcloud.Action{ID: 123, Status: hcloud.ActionStatusSuccess}
+
+		floatingIPMock := client.FloatingIP.(*hcloudtest.FloatingIPClientMock)
+		floatingIPMock.On("GetByID", mock.Anything, mock.Anything).Return(rFloatingIP, r, nil)
+		floatingIPMock.On("All", mock.Anything).Return([]*hcloud.FloatingIP{}, nil)
 		floatingIPMock.On("Create", mock.Anything, mock.Anything).Return(hcloud.FloatingIPCreateResult{
 			FloatingIP: rFloatingIP,
 		}, r, nil)
@@ -334,53 +483,322 @@ func TestPresent(t *testing.T) {
 		serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
 		serverClientMock.On("GetByID", mock.Anything, mock.Anything).Return(rServer, r, nil)
 
-		resp, err := m.run()
+		resp, err := m.run(context.Background())
 		assert.NoError(t, err)
 		assert.True(t, resp.HasChanged(), "should have changed")
 
+		assert.Equal(t, []string{"No 'id' or 'description' set, a new FloatingIP is created on every run"}, resp.Warnings())
 		floatingIPMock.AssertCalled(t, "Create", mock.Anything, mock.Anything)
 	})
+
+	t.Run("unassign found by description", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.FloatingIP = hcloudtest.NewFloatingIPClientMock()
+
+		m := module{
+			client: client,
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				return nil
+			}),
+			args: arguments{State: "present", Description: "test"},
+		}
+
+		rFloatingIP := &hcloud.FloatingIP{
+			ID: 123, Description: "test",
+			HomeLocation: &hcloud.Location{},
+			Server:       &hcloud.Server{ID: 456},
+		}
+		floatingIPMock := client.FloatingIP.(*hcloudtest.FloatingIPClientMock)
+		floatingIPMock.On("All", mock.Anything).Return([]*hcloud.FloatingIP{rFloatingIP}, nil)
+		floatingIPMock.On("Unassign", mock.Anything, rFloatingIP).Return(&hcloud.Action{ID: 1}, nilResponse, nil)
+
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "should have changed")
+			floatingIPMock.AssertCalled(t, "Unassign", mock.Anything, rFloatingIP)
+		}
+	})
+
+	t.Run("create without location", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.FloatingIP = hcloudtest.NewFloatingIPClientMock()
+
+		m := module{
+			client: client,
+			args:   arguments{State: "present", Description: "test"},
+		}
+
+		floatingIPMock := client.FloatingIP.(*hcloudtest.FloatingIPClientMock)
+		floatingIPMock.On("All", mock.Anything).Return([]*hcloud.FloatingIP{}, nil)
+
+		_, err := m.run(context.Background())
+		assert.EqualError(t, err, "'home_location', 'server' or 'assign_to_one_of' is required to create a FloatingIP")
+		floatingIPMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAssignToOneOf(t *testing.T) {
+	newModule := func(healthy map[string]bool) (module, *hcloudtest.FloatingIPClientMock) {
+		client := hcloud.NewClient()
+		client.FloatingIP = hcloudtest.NewFloatingIPClientMock()
+		client.Server = hcloudtest.NewServerClientMock()
+
+		var r *hcloud.Response
+		serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+		for i, name := range []string{"lb1", "lb2", "lb3"} {
+			serverClientMock.On("GetByName", mock.Anything, name).Return(&hcloud.Server{
+				ID:     i + 1,
+				Name:   name,
+				Status: hcloud.ServerStatusRunning,
+				PublicNet: hcloud.ServerPublicNet{
+					IPv4: hcloud.ServerPublicNetIPv4{IP: net.IPv4(10, 0, 0, byte(i+1))},
+				},
+			}, r, nil)
+		}
+
+		return module{
+			client: client,
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				return nil
+			}),
+			dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				if !healthy[address] {
+					return nil, errors.New("connection refused")
+				}
+				client, server := net.Pipe()
+				server.Close()
+				return client, nil
+			},
+			args: arguments{
+				Token: "--token--",
+
+				State:           "present",
+				ID:              123,
+				AssignToOneOf:   []interface{}{"lb1", "lb2", "lb3"},
+				HealthCheckPort: 443,
+			},
+		}, client.FloatingIP.(*hcloudtest.FloatingIPClientMock)
+	}
+
+	t.Run("healthy assignee is kept", func(t *testing.T) {
+		m, floatingIPMock := newModule(map[string]bool{"10.0.0.1:443": true, "10.0.0.2:443": true})
+		var r *hcloud.Response
+		floatingIPMock.On("GetByID", mock.Anything, 123).Return(&hcloud.FloatingIP{
+			ID: 123, HomeLocation: &hcloud.Location{}, Server: &hcloud.Server{ID: 2},
+		}, r, nil)
+
+		resp, err := m.run(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, resp.HasChanged(), "should not have changed")
+		floatingIPMock.AssertNotCalled(t, "Assign", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unhealthy assignee is replaced", func(t *testing.T) {
+		m, floatingIPMock := newModule(map[string]bool{"10.0.0.3:443": true})
+		var r *hcloud.Response
+		rFloatingIP := &hcloud.FloatingIP{
+			ID: 123, HomeLocation: &hcloud.Location{}, Server: &hcloud.Server{ID: 1},
+		}
+		floatingIPMock.On("GetByID", mock.Anything, 123).Return(rFloatingIP, r, nil)
+		floatingIPMock.On("Assign", mock.Anything, mock.Anything, mock.Anything).
+			Return(&hcloud.Action{ID: 1, Status: hcloud.ActionStatusSuccess}, r, nil)
+
+		resp, err := m.run(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, resp.HasChanged(), "should have changed")
+		floatingIPMock.AssertCalled(t, "Assign", mock.Anything, rFloatingIP, mock.MatchedBy(func(server *hcloud.Server) bool {
+			return server.ID == 3
+		}))
+	})
+
+	t.Run("no healthy candidate", func(t *testing.T) {
+		m, floatingIPMock := newModule(nil)
+		var r *hcloud.Response
+		floatingIPMock.On("GetByID", mock.Anything, 123).Return(&hcloud.FloatingIP{
+			ID: 123, HomeLocation: &hcloud.Location{}, Server: &hcloud.Server{ID: 1},
+		}, r, nil)
+
+		_, err := m.run(context.Background())
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "No healthy server in 'assign_to_one_of'")
+		}
+		floatingIPMock.AssertNotCalled(t, "Assign", mock.Anything, mock.Anything, mock.Anything)
+		floatingIPMock.AssertNotCalled(t, "Unassign", mock.Anything, mock.Anything)
+	})
+
+	t.Run("missing candidate is skipped", func(t *testing.T) {
+		m, floatingIPMock := newModule(map[string]bool{"10.0.0.2:443": true})
+		m.args.AssignToOneOf = []interface{}{"lb4", "lb2"}
+		m.client.Server.(*hcloudtest.ServerClientMock).On("GetByName", mock.Anything, "lb4").Return((*hcloud.Server)(nil), nilResponse, nil)
+		rFloatingIP := &hcloud.FloatingIP{ID: 123, HomeLocation: &hcloud.Location{}}
+		floatingIPMock.On("GetByID", mock.Anything, 123).Return(rFloatingIP, nilResponse, nil)
+		floatingIPMock.On("Assign", mock.Anything, mock.Anything, mock.Anything).
+			Return(&hcloud.Action{ID: 1, Status: hcloud.ActionStatusSuccess}, nilResponse, nil)
+
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.Equal(t, []string{"Server 'lb4' in 'assign_to_one_of' not found"}, resp.Warnings())
+			floatingIPMock.AssertCalled(t, "Assign", mock.Anything, rFloatingIP, mock.MatchedBy(func(server *hcloud.Server) bool {
+				return server.ID == 2
+			}))
+		}
+	})
+
+	t.Run("no candidate found", func(t *testing.T) {
+		m, floatingIPMock := newModule(nil)
+		m.args.AssignToOneOf = []interface{}{"lb4"}
+		m.client.Server.(*hcloudtest.ServerClientMock).On("GetByName", mock.Anything, "lb4").Return((*hcloud.Server)(nil), nilResponse, nil)
+		floatingIPMock.On("GetByID", mock.Anything, 123).Return(&hcloud.FloatingIP{
+			ID: 123, HomeLocation: &hcloud.Location{}, Server: &hcloud.Server{ID: 1},
+		}, nilResponse, nil)
+
+		_, err := m.run(context.Background())
+		assert.EqualError(t, err, "No server in 'assign_to_one_of' found")
+		floatingIPMock.AssertNotCalled(t, "Unassign", mock.Anything, mock.Anything)
+	})
+}
+
+func TestIdentify(t *testing.T) {
+	_, network, _ := net.ParseCIDR("2001:db8::/64")
+	web := &hcloud.FloatingIP{ID: 1, Description: "web", IP: net.ParseIP("192.168.1.1"), Type: hcloud.FloatingIPTypeIPv4, HomeLocation: &hcloud.Location{Name: "fsn1"}}
+	mail := &hcloud.FloatingIP{ID: 2, Description: "mail", IP: net.ParseIP("192.168.1.2"), Type: hcloud.FloatingIPTypeIPv4, HomeLocation: &hcloud.Location{Name: "fsn1"}}
+	mailV6 := &hcloud.FloatingIP{ID: 3, Description: "mail", IP: network.IP, Network: network, Type: hcloud.FloatingIPTypeIPv6, HomeLocation: &hcloud.Location{Name: "fsn1"}}
+
+	newClient := func() *hcloud.Client {
+		client := hcloud.NewClient()
+		client.FloatingIP = hcloudtest.NewFloatingIPClientMock()
+		client.Label = hcloudtest.NewLabelClientMock()
+		client.FloatingIP.(*hcloudtest.FloatingIPClientMock).On("All", mock.Anything).Return([]*hcloud.FloatingIP{web, mail, mailV6}, nil)
+		client.Label.(*hcloudtest.LabelClientMock).On("List", mock.Anything, hcloud.LabelResourceFloatingIPs, "role=mail").
+			Return(map[int]hcloud.Labels{2: {"role": "mail"}, 3: {"role": "mail"}}, nil)
+		client.Label.(*hcloudtest.LabelClientMock).On("List", mock.Anything, hcloud.LabelResourceFloatingIPs, "role=db").
+			Return(map[int]hcloud.Labels{}, nil)
+		return client
+	}
+
+	t.Run("by description", func(t *testing.T) {
+		client := newClient()
+		m := module{
+			client: client,
+			args:   arguments{State: statePresent, Description: "web", HomeLocation: "fsn1"},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.False(t, resp.HasChanged(), "should not have changed")
+			assert.Equal(t, 1, resp.Data()["floating_ips"].([]FloatingIP)[0].ID)
+			client.FloatingIP.(*hcloudtest.FloatingIPClientMock).AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("ambiguous description", func(t *testing.T) {
+		m := module{
+			client: newClient(),
+			args:   arguments{State: statePresent, Description: "mail", HomeLocation: "fsn1"},
+		}
+		_, err := m.run(context.Background())
+		assert.EqualError(t, err, "FloatingIPs 2, 3 match, use 'id' or a unique 'description', 'label_selector' or 'ip'")
+	})
+
+	t.Run("by label selector and ip", func(t *testing.T) {
+		m := module{
+			client: newClient(),
+			args:   arguments{State: statePresent, LabelSelector: "role=mail", IP: "2001:db8::1"},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.Equal(t, 3, resp.Data()["floating_ips"].([]FloatingIP)[0].ID)
+		}
+	})
+
+	t.Run("label selector without match", func(t *testing.T) {
+		m := module{
+			client: newClient(),
+			args:   arguments{State: statePresent, LabelSelector: "role=db"},
+		}
+		_, err := m.run(context.Background())
+		assert.Error(t, err)
+	})
+
+	t.Run("absent by ip", func(t *testing.T) {
+		client := newClient()
+		client.FloatingIP.(*hcloudtest.FloatingIPClientMock).On("Delete", mock.Anything, mail).Return(nilResponse, nil)
+		m := module{
+			client: client,
+			args:   arguments{State: stateAbsent, IP: "192.168.1.2"},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "should have changed")
+		}
+	})
 }
 
 func TestValidateArgs(t *testing.T) {
 	t.Run("invalid state", func(t *testing.T) {
-		err := validateArgs(arguments{
+		err := ansible.ValidateArgs(&module{args: arguments{
 			State: "not-a-valid-state",
-		})
+		}})
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "'state' must be present, absent or list")
 	})
 
 	t.Run("absent", func(t *testing.T) {
 		t.Run("id missing", func(t *testing.T) {
-			err := validateArgs(arguments{
+			err := ansible.ValidateArgs(&module{args: arguments{
 				State: "absent",
-			})
+			}})
 			if assert.Error(t, err) {
-				assert.Contains(t, err.Error(), "'id' is required")
+				assert.Contains(t, err.Error(), "'id', 'description', 'label_selector' or 'ip' is required")
 			}
 		})
 	})
 
 	t.Run("present", func(t *testing.T) {
 		t.Run("home_location and server missing", func(t *testing.T) {
-			err := validateArgs(arguments{
-				State: "present",
-			})
-			if assert.Error(t, err) {
-				assert.Contains(t, err.Error(), "'home_location' or 'server' must be set")
-			}
+			err := ansible.ValidateArgs(&module{args: arguments{
+				State:       "present",
+				Description: "test",
+			}})
+			assert.NoError(t, err, "floating IPs found by description need no location")
 		})
 
 		t.Run("home_location and server specified", func(t *testing.T) {
-			err := validateArgs(arguments{
+			err := ansible.ValidateArgs(&module{args: arguments{
 				State:        "present",
 				HomeLocation: "nbg1",
 				Server:       "test",
-			})
+			}})
 			if assert.Error(t, err) {
 				assert.Contains(t, err.Error(), "'home_location' and 'server' are mutually exclusive")
 			}
 		})
+
+		t.Run("assign_to_one_of and server specified", func(t *testing.T) {
+			err := ansible.ValidateArgs(&module{args: arguments{
+				State:         "present",
+				Server:        "test",
+				AssignToOneOf: []interface{}{"test"},
+			}})
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "'server' and 'assign_to_one_of' are mutually exclusive")
+			}
+		})
+
+		t.Run("assign_to_one_of empty", func(t *testing.T) {
+			err := ansible.ValidateArgs(&module{args: arguments{
+				State:         "present",
+				AssignToOneOf: []interface{}{},
+			}})
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "'assign_to_one_of' must not be empty")
+			}
+		})
 	})
 }
+
+func TestDoc(t *testing.T) {
+	data, err := ioutil.ReadFile("../../docs/hcloud_floating_ip.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(data), doc.Markdown(argSpec), "docs/hcloud_floating_ip.md is outdated, run make docs")
+	}
+}
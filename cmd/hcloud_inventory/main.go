@@ -4,14 +4,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
+	"strconv"
 
 	"github.com/spf13/pflag"
 	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
 	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
 	"github.com/thetechnick/hcloud-ansible/pkg/version"
 )
 
+// ansible_host address selected with HCLOUD_INVENTORY_HOST,
+// any other value selects the private IP in the network with that name or id
+const (
+	hostPublic  = "public"
+	hostPrivate = "private"
+)
+
 var flags = pflag.NewFlagSet("hcloud_inventory", pflag.ContinueOnError)
 
 func init() {
@@ -46,18 +56,33 @@ func main() {
 		os.Exit(1)
 	}
 
+	privateNets, err := client.ServerNetwork.All(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing private networks of servers: %v\n", err)
+		os.Exit(1)
+	}
+
+	networks, err := client.Network.All(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing networks: %v\n", err)
+		os.Exit(1)
+	}
+	networkNames := map[int]string{}
+	for _, network := range networks {
+		networkNames[network.ID] = network.Name
+	}
+
+	host := os.Getenv("HCLOUD_INVENTORY_HOST")
+	if host == "" {
+		host = hostPublic
+	}
+
 	inventory := ansible.NewInventory()
 	for _, server := range servers {
 		inventory.AddHost(ansible.InventoryHost{
-			Host: server.Name,
-			Vars: varsForServer(server),
-			Groups: []string{
-				server.Datacenter.Name,
-				server.Datacenter.Location.Name,
-				server.ServerType.Name,
-				fmt.Sprintf("status_%s", string(server.Status)),
-				imageTag(server),
-			},
+			Host:   server.Name,
+			Vars:   varsForServer(server, privateNets[server.ID], networkNames, host),
+			Groups: util.InventoryGroups(server),
 		})
 	}
 
@@ -65,25 +90,29 @@ func main() {
 	fmt.Println(string(json))
 }
 
-func imageTag(server *hcloud.Server) string {
-	if server.Image.Name != "" {
-		return server.Image.Name
-	}
-	return fmt.Sprintf("image_%d", server.Image.ID)
-}
-
-func varsForServer(server *hcloud.Server) map[string]interface{} {
+func varsForServer(server *hcloud.Server, privateNets []hcloud.ServerPrivateNet, networkNames map[int]string, host string) map[string]interface{} {
 	vars := map[string]interface{}{
 		"hcloud_id":          server.ID,
 		"hcloud_name":        server.Name,
-		"hcloud_public_ipv4": server.PublicNet.IPv4.IP.String(),
-		"hcloud_public_ipv6": server.PublicNet.IPv6.IP.String(),
+		"hcloud_public_ipv4": "",
+		"hcloud_public_ipv6": "",
 		"hcloud_location":    server.Datacenter.Location.Name,
 		"hcloud_datacenter":  server.Datacenter.Name,
 		"hcloud_status":      string(server.Status),
 		"hcloud_server_type": server.ServerType.Name,
+	}
 
-		"ansible_host": server.PublicNet.IPv4.IP.String(),
+	// servers without public IPv4 are reached by the first address of their IPv6 network
+	if ipv6 := server.PublicNet.IPv6.IP; ipv6 != nil {
+		vars["hcloud_public_ipv6"] = ipv6.String()
+		host := make(net.IP, len(ipv6))
+		copy(host, ipv6)
+		host[len(host)-1]++
+		vars["ansible_host"] = host.String()
+	}
+	if ipv4 := server.PublicNet.IPv4.IP; ipv4 != nil {
+		vars["hcloud_public_ipv4"] = ipv4.String()
+		vars["ansible_host"] = ipv4.String()
 	}
 
 	if server.Image.Name != "" {
@@ -92,5 +121,26 @@ func varsForServer(server *hcloud.Server) map[string]interface{} {
 		vars["hcloud_image"] = server.Image.ID
 	}
 
+	privateIPs := map[string]string{}
+	for i, privateNet := range privateNets {
+		name := networkNames[privateNet.Network.ID]
+		if name == "" {
+			name = strconv.Itoa(privateNet.Network.ID)
+		}
+		privateIPs[name] = privateNet.IP.String()
+
+		// servers without a private IP in the selected network keep the public IP
+		if host == hostPrivate && i == 0 ||
+			host == name || host == strconv.Itoa(privateNet.Network.ID) {
+			vars["ansible_host"] = privateNet.IP.String()
+		}
+	}
+	vars["hcloud_private_ips"] = privateIPs
+
+	// servers without public IPs are only reachable by their private IP
+	if _, ok := vars["ansible_host"]; !ok && len(privateNets) > 0 {
+		vars["ansible_host"] = privateNets[0].IP.String()
+	}
+
 	return vars
 }
@@ -0,0 +1,1040 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+const (
+	stateAbsent  = "absent"
+	statePresent = "present"
+	stateList    = "list"
+
+	defaultLoadBalancerType = "lb11"
+)
+
+// defaults of the API for services and health checks,
+// applied to the arguments so unchanged services are detected
+const (
+	defaultCookieName          = "HCLBSTICKY"
+	defaultCookieLifetime      = 300
+	defaultHealthCheckInterval = 15
+	defaultHealthCheckTimeout  = 10
+	defaultHealthCheckRetries  = 3
+	defaultHealthCheckPath     = "/"
+)
+
+var (
+	protocols = []string{
+		string(hcloud.LoadBalancerServiceProtocolTCP),
+		string(hcloud.LoadBalancerServiceProtocolHTTP),
+		string(hcloud.LoadBalancerServiceProtocolHTTPS),
+	}
+	algorithms = []string{
+		string(hcloud.LoadBalancerAlgorithmTypeRoundRobin),
+		string(hcloud.LoadBalancerAlgorithmTypeLeastConnections),
+	}
+	defaultHealthCheckStatusCodes = []string{"2??", "3??"}
+)
+
+// LoadBalancer is the module return value of an hcloud.LoadBalancer
+type LoadBalancer struct {
+	ID               int         `json:"id"`
+	Name             string      `json:"name"`
+	LoadBalancerType string      `json:"load_balancer_type"`
+	Location         string      `json:"location"`
+	Algorithm        string      `json:"algorithm"`
+	PublicIPv4       string      `json:"public_ipv4"`
+	PublicIPv6       string      `json:"public_ipv6"`
+	PrivateIPs       []PrivateIP `json:"private_ips"`
+	Services         []Service   `json:"services"`
+	Targets          []Target    `json:"targets"`
+}
+
+// PrivateIP is the module return value of an hcloud.LoadBalancerPrivateNet
+type PrivateIP struct {
+	NetworkID int    `json:"network_id"`
+	IP        string `json:"ip"`
+}
+
+// Service is the module argument and return value of an hcloud.LoadBalancerService
+type Service struct {
+	Protocol        string       `json:"protocol"`
+	ListenPort      int          `json:"listen_port"`
+	DestinationPort int          `json:"destination_port"`
+	Proxyprotocol   bool         `json:"proxyprotocol"`
+	HTTP            *ServiceHTTP `json:"http,omitempty"`
+	HealthCheck     *HealthCheck `json:"health_check,omitempty"`
+}
+
+// ServiceHTTP holds the options of http and https services
+type ServiceHTTP struct {
+	StickySessions bool   `json:"sticky_sessions"`
+	CookieName     string `json:"cookie_name,omitempty"`
+	CookieLifetime int    `json:"cookie_lifetime,omitempty"`
+	RedirectHTTP   bool   `json:"redirect_http"`
+	Certificates   []int  `json:"certificates,omitempty"`
+}
+
+// HealthCheck is the health check of a service
+type HealthCheck struct {
+	Protocol string           `json:"protocol,omitempty"`
+	Port     int              `json:"port,omitempty"`
+	Interval int              `json:"interval,omitempty"`
+	Timeout  int              `json:"timeout,omitempty"`
+	Retries  int              `json:"retries,omitempty"`
+	HTTP     *HealthCheckHTTP `json:"http,omitempty"`
+}
+
+// HealthCheckHTTP holds the options of http health checks
+type HealthCheckHTTP struct {
+	Domain      string   `json:"domain,omitempty"`
+	Path        string   `json:"path,omitempty"`
+	Response    string   `json:"response,omitempty"`
+	StatusCodes []string `json:"status_codes,omitempty"`
+	TLS         bool     `json:"tls"`
+}
+
+// Target is the module return value of an hcloud.LoadBalancerTarget
+type Target struct {
+	Type          string         `json:"type"`
+	Server        int            `json:"server,omitempty"`
+	LabelSelector string         `json:"label_selector,omitempty"`
+	IP            string         `json:"ip,omitempty"`
+	UsePrivateIP  bool           `json:"use_private_ip"`
+	HealthStatus  []HealthStatus `json:"health_status"`
+	// Targets are the servers matched by a label selector
+	Targets []Target `json:"targets,omitempty"`
+}
+
+// HealthStatus is the health of a target for the service on ListenPort
+type HealthStatus struct {
+	ListenPort int    `json:"listen_port"`
+	Status     string `json:"status"`
+}
+
+type network struct {
+	Network interface{} `json:"network"`
+	IP      string      `json:"ip"`
+}
+
+type target struct {
+	Server        interface{} `json:"server"`
+	LabelSelector string      `json:"label_selector"`
+	IP            string      `json:"ip"`
+	UsePrivateIP  bool        `json:"use_private_ip"`
+}
+
+type arguments struct {
+	Token string `json:"token"`
+	State string `json:"state"`
+
+	ID               interface{} `json:"id"`
+	Name             string      `json:"name"`
+	LoadBalancerType string      `json:"load_balancer_type"`
+	Location         string      `json:"location"`
+	Algorithm        string      `json:"algorithm"`
+	Networks         []network   `json:"networks"`
+	Services         []Service   `json:"services"`
+	Targets          []target    `json:"targets"`
+	Wait             *bool       `json:"wait"`
+}
+
+var argSpec = ansible.ArgSpec{
+	Args: []ansible.Arg{
+		ansible.TokenArg(),
+		{Name: "state", Type: ansible.TypeStr, Default: statePresent, Choices: []string{statePresent, stateAbsent, stateList},
+			Description: "`list` lists all existing load balancers."},
+		{Name: "id", Type: ansible.TypeRaw,
+			Description: "ID of the load balancer.\n\n`id` or `name` is required when `state=present` or `state=absent`."},
+		{Name: "name", Type: ansible.TypeStr,
+			Description: "Name of the load balancer, used to find the load balancer when `id` is not specified. The load balancer is renamed when both are given."},
+		{Name: "load_balancer_type", Type: ansible.TypeStr,
+			Description: "Type of the load balancer, `lb11` for new load balancers if not set. The type of an existing load balancer is changed if it differs."},
+		{Name: "location", Type: ansible.TypeStr,
+			Description: "Location of the load balancer, e.g. `fsn1`.\n\nRequired to create a load balancer, a load balancer cannot be moved."},
+		{Name: "algorithm", Type: ansible.TypeStr, Choices: algorithms,
+			Description: "Algorithm used to distribute requests to the targets, `round_robin` for new load balancers if not set."},
+		{Name: "networks", Type: ansible.TypeList, Elements: ansible.TypeDict,
+			Description: "List of private networks, see below.\n\n" +
+				"When set, the load balancer is detached from networks not in the list. Existing attachments are kept if not set.",
+			Options: []ansible.Arg{
+				{Name: "network", Type: ansible.TypeRaw, Required: true, Description: "ID or name of the network."},
+				{Name: "ip", Type: ansible.TypeStr, Description: "IP of the load balancer in the network."},
+			}},
+		{Name: "services", Type: ansible.TypeList, Elements: ansible.TypeDict,
+			Description: "List of services, see below. Omitted options default to the values of the API, " +
+				"so a service is only updated when one of the given options differs.\n\n" +
+				"When set, services not in the list are deleted and changed services are updated, services are identified by `listen_port`. " +
+				"Existing services are kept if not set.",
+			Options: []ansible.Arg{
+				{Name: "protocol", Type: ansible.TypeStr, Required: true, Choices: protocols},
+				{Name: "listen_port", Type: ansible.TypeInt,
+					Description: "Port the load balancer listens on, required for `tcp`. 80 for http, 443 for https if not set."},
+				{Name: "destination_port", Type: ansible.TypeInt,
+					Description: "Port the traffic is sent to on the targets, `listen_port` for tcp and 80 otherwise if not set."},
+				{Name: "proxyprotocol", Type: ansible.TypeBool, Default: false, Description: "Enable the PROXY protocol."},
+				{Name: "http", Type: ansible.TypeDict, Description: "HTTP options for `http` and `https`.",
+					Options: []ansible.Arg{
+						{Name: "sticky_sessions", Type: ansible.TypeBool, Default: false},
+						{Name: "cookie_name", Type: ansible.TypeStr, Default: defaultCookieName},
+						{Name: "cookie_lifetime", Type: ansible.TypeInt, Default: defaultCookieLifetime, Description: "Lifetime of the cookie in seconds."},
+						{Name: "redirect_http", Type: ansible.TypeBool, Default: false},
+						{Name: "certificates", Type: ansible.TypeList, Elements: ansible.TypeInt, Description: "List of certificate IDs."},
+					}},
+				{Name: "health_check", Type: ansible.TypeDict,
+					Options: []ansible.Arg{
+						{Name: "protocol", Type: ansible.TypeStr, Choices: protocols, Description: "`tcp` for tcp services and `http` otherwise if not set."},
+						{Name: "port", Type: ansible.TypeInt, Description: "`destination_port` if not set."},
+						{Name: "interval", Type: ansible.TypeInt, Default: defaultHealthCheckInterval, Description: "Interval of the checks in seconds."},
+						{Name: "timeout", Type: ansible.TypeInt, Default: defaultHealthCheckTimeout, Description: "Timeout of a check in seconds."},
+						{Name: "retries", Type: ansible.TypeInt, Default: defaultHealthCheckRetries},
+						{Name: "http", Type: ansible.TypeDict, Description: "Options of http checks.",
+							Options: []ansible.Arg{
+								{Name: "domain", Type: ansible.TypeStr},
+								{Name: "path", Type: ansible.TypeStr, Default: defaultHealthCheckPath},
+								{Name: "response", Type: ansible.TypeStr},
+								{Name: "status_codes", Type: ansible.TypeList, Elements: ansible.TypeStr,
+									Description: "`[\"2??\", \"3??\"]` if not set."},
+								{Name: "tls", Type: ansible.TypeBool, Default: false},
+							}},
+					}},
+			}},
+		{Name: "targets", Type: ansible.TypeList, Elements: ansible.TypeDict,
+			Description: "List of targets with either `server`, `label_selector` or `ip`, see below.\n\n" +
+				"When set, targets not in the list are removed. Existing targets are kept if not set.",
+			Options: []ansible.Arg{
+				{Name: "server", Type: ansible.TypeRaw, Description: "ID or name of a server."},
+				{Name: "label_selector", Type: ansible.TypeStr, Description: "Label selector of servers, e.g. `role=web`."},
+				{Name: "ip", Type: ansible.TypeStr, Description: "IP of a target outside of Hetzner Cloud, e.g. a dedicated server."},
+				{Name: "use_private_ip", Type: ansible.TypeBool, Default: false, Description: "Send the traffic to the private IP of servers."},
+			}},
+		{Name: "wait", Type: ansible.TypeBool, Default: true,
+			Description: "Wait for all actions to complete. When `false`, the module returns immediately with `action_ids`, see `hcloud_action`. " +
+				"The load balancer is locked while an action runs, so with multiple changes to a load balancer only the last action is not awaited."},
+	},
+	RequiredIf: []ansible.RequiredIf{
+		{Arg: "state", Value: statePresent, Required: []string{"id", "name"}, OneOf: true},
+		{Arg: "state", Value: stateAbsent, Required: []string{"id", "name"}, OneOf: true},
+	},
+}
+
+var doc = ansible.Doc{
+	Module:           "hcloud_load_balancer",
+	ShortDescription: "Manage Hetzner Cloud Load Balancers",
+	Description: "Manages Hetzner Cloud load balancers. " +
+		"This module can be used to create, modify and delete load balancers including their network attachments, services and targets.",
+	Returns: []ansible.Return{
+		{
+			Name: "load_balancers",
+			Description: "The load balancer, or all load balancers with `state=list`. " +
+				"`targets` of a label selector lists the servers currently matched by it.",
+			Returned: "unless `state=absent`",
+			Sample: []LoadBalancer{{
+				ID:               123,
+				Name:             "web",
+				LoadBalancerType: "lb11",
+				Location:         "fsn1",
+				Algorithm:        "round_robin",
+				PublicIPv4:       "203.0.113.1",
+				PublicIPv6:       "2001:db8::1",
+				PrivateIPs:       []PrivateIP{{NetworkID: 4, IP: "10.0.0.2"}},
+				Services: []Service{{
+					Protocol:        "http",
+					ListenPort:      80,
+					DestinationPort: 8080,
+					HTTP:            &ServiceHTTP{CookieName: defaultCookieName, CookieLifetime: defaultCookieLifetime},
+					HealthCheck: &HealthCheck{
+						Protocol: "http",
+						Port:     8080,
+						Interval: defaultHealthCheckInterval,
+						Timeout:  defaultHealthCheckTimeout,
+						Retries:  defaultHealthCheckRetries,
+						HTTP:     &HealthCheckHTTP{Path: "/healthz", StatusCodes: defaultHealthCheckStatusCodes},
+					},
+				}},
+				Targets: []Target{{
+					Type:          "label_selector",
+					LabelSelector: "role=web",
+					UsePrivateIP:  true,
+					HealthStatus:  []HealthStatus{},
+					Targets: []Target{{
+						Type:         "server",
+						Server:       42,
+						UsePrivateIP: true,
+						HealthStatus: []HealthStatus{{ListenPort: 80, Status: "healthy"}},
+					}},
+				}},
+			}},
+		},
+		{
+			Name:        "action_ids",
+			Description: "IDs of the pending actions, see `hcloud_action`.",
+			Returned:    "when `wait` is `false`",
+			Sample:      []int{4711},
+		},
+	},
+	Examples: `
+# balance HTTP traffic to all web servers over the private network
+- hcloud_load_balancer:
+    name: web
+    location: fsn1
+    networks:
+    - network: internal
+    services:
+    - protocol: http
+      destination_port: 8080
+      http:
+        sticky_sessions: true
+      health_check:
+        http:
+          path: /healthz
+    targets:
+    - label_selector: role=web
+      use_private_ip: true
+  register: lb
+
+- debug:
+    msg: "{{ lb.load_balancers[0].public_ipv4 }}"
+
+# delete the load balancer
+- hcloud_load_balancer:
+    name: web
+    state: absent
+`,
+}
+
+// loadBalancerNetwork is the desired attachment of the load balancer to a private network
+type loadBalancerNetwork struct {
+	Network *hcloud.Network
+	IP      net.IP
+}
+
+type module struct {
+	args   arguments
+	client *hcloud.Client
+	waiter util.ActionWaiter
+}
+
+func (m *module) Args() interface{} {
+	return &m.args
+}
+
+func (m *module) ArgSpec() ansible.ArgSpec {
+	return argSpec
+}
+
+func (m *module) Doc() ansible.Doc {
+	return doc
+}
+
+func (m *module) Run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	m.client, err = hcloud.BuildClient(m.args.Token)
+	if err != nil {
+		return
+	}
+	m.waiter = util.NewActionWatcher(m.client.Action)
+	return m.run(ctx)
+}
+
+func (m *module) run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	if m.args.Wait != nil && !*m.args.Wait {
+		defer util.RecordActions(&m.waiter).Report(&resp, &err)
+	}
+
+	switch m.args.State {
+	case stateList:
+		return m.list(ctx)
+	case stateAbsent:
+		return m.absent(ctx)
+	case statePresent:
+		return m.present(ctx)
+	default:
+		err = errors.New("invalid state")
+		return
+	}
+}
+
+func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var lb *hcloud.LoadBalancer
+	if lb, err = m.loadBalancer(ctx); err != nil {
+		return
+	}
+
+	var networks []loadBalancerNetwork
+	if networks, err = m.networks(ctx); err != nil {
+		return
+	}
+	var targets []hcloud.LoadBalancerTarget
+	if targets, err = m.targets(ctx); err != nil {
+		return
+	}
+	var services []hcloud.LoadBalancerService
+	if m.args.Services != nil {
+		services = []hcloud.LoadBalancerService{}
+		for _, s := range m.args.Services {
+			services = append(services, toLoadBalancerService(s))
+		}
+	}
+
+	var msg []string
+	if lb == nil {
+		if m.args.ID != nil {
+			err = fmt.Errorf("Load balancer %v not found", m.args.ID)
+			return
+		}
+		if m.args.Location == "" {
+			err = errors.New("'location' is required to create a load balancer")
+			return
+		}
+		opts := hcloud.LoadBalancerCreateOpts{
+			Name:             m.args.Name,
+			LoadBalancerType: &hcloud.LoadBalancerType{Name: defaultLoadBalancerType},
+			Location:         &hcloud.Location{Name: m.args.Location},
+			Algorithm:        hcloud.LoadBalancerAlgorithmType(m.args.Algorithm),
+			Services:         services,
+		}
+		if m.args.LoadBalancerType != "" {
+			opts.LoadBalancerType.Name = m.args.LoadBalancerType
+		}
+		// the first network is attached on creation, so targets can use private IPs right away,
+		// all other networks and targets are added afterwards
+		if len(networks) > 0 && networks[0].IP == nil {
+			opts.Network = networks[0].Network
+		}
+		if opts.Network != nil || !usePrivateIP(targets) {
+			opts.Targets = targets
+		}
+		var result hcloud.LoadBalancerCreateResult
+		if result, _, err = m.client.LoadBalancer.Create(ctx, opts); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, result.Action); err != nil {
+			return
+		}
+		lb = result.LoadBalancer
+		msg = append(msg, fmt.Sprintf("Load balancer %d created", lb.ID))
+		resp.Changed()
+	} else {
+		if m.args.Location != "" && lb.Location.Name != m.args.Location {
+			err = fmt.Errorf("Load balancer %d is located in %s and cannot be moved to %s", lb.ID, lb.Location.Name, m.args.Location)
+			return
+		}
+		if m.args.ID != nil && m.args.Name != "" && lb.Name != m.args.Name {
+			if lb, _, err = m.client.LoadBalancer.Update(ctx, lb, hcloud.LoadBalancerUpdateOpts{
+				Name: m.args.Name,
+			}); err != nil {
+				return
+			}
+			msg = append(msg, fmt.Sprintf("Load balancer %d renamed", lb.ID))
+			resp.Changed()
+		}
+	}
+
+	// the load balancer is locked while an action runs, so the pending actions
+	// of `wait: false` are awaited before the next action is started
+	var actions []*hcloud.Action
+	wait := func(start func() (*hcloud.Action, *hcloud.Response, error), message string) error {
+		if err := m.waitForPending(ctx, lb); err != nil {
+			return err
+		}
+		action, _, err := start()
+		if err != nil {
+			return err
+		}
+		if err := m.waiter.WaitForActions(ctx, action); err != nil {
+			return err
+		}
+		actions = append(actions, action)
+		msg = append(msg, message)
+		return nil
+	}
+
+	if m.args.LoadBalancerType != "" && lb.LoadBalancerType.Name != m.args.LoadBalancerType {
+		if err = wait(func() (*hcloud.Action, *hcloud.Response, error) {
+			return m.client.LoadBalancer.ChangeType(ctx, lb, &hcloud.LoadBalancerType{Name: m.args.LoadBalancerType})
+		}, fmt.Sprintf("type changed to %s", m.args.LoadBalancerType)); err != nil {
+			return
+		}
+	}
+	if m.args.Algorithm != "" && string(lb.Algorithm) != m.args.Algorithm {
+		if err = wait(func() (*hcloud.Action, *hcloud.Response, error) {
+			return m.client.LoadBalancer.ChangeAlgorithm(ctx, lb, hcloud.LoadBalancerAlgorithmType(m.args.Algorithm))
+		}, fmt.Sprintf("algorithm changed to %s", m.args.Algorithm)); err != nil {
+			return
+		}
+	}
+
+	// targets and services are removed before networks are detached,
+	// because targets using private IPs depend on them
+	addTargets, removeTargets := targetChanges(targets, lb.Targets)
+	for _, t := range removeTargets {
+		if err = wait(func() (*hcloud.Action, *hcloud.Response, error) {
+			return m.client.LoadBalancer.RemoveTarget(ctx, lb, t)
+		}, fmt.Sprintf("target %s removed", targetKey(t))); err != nil {
+			return
+		}
+	}
+
+	addServices, updateServices, deleteServices := serviceChanges(services, lb.Services)
+	for _, s := range deleteServices {
+		if err = wait(func() (*hcloud.Action, *hcloud.Response, error) {
+			return m.client.LoadBalancer.DeleteService(ctx, lb, s.ListenPort)
+		}, fmt.Sprintf("service %d deleted", s.ListenPort)); err != nil {
+			return
+		}
+	}
+
+	if networks != nil {
+		desired := map[int]loadBalancerNetwork{}
+		for _, n := range networks {
+			desired[n.Network.ID] = n
+		}
+		attached := map[int]bool{}
+		for _, privateNet := range lb.PrivateNet {
+			n, ok := desired[privateNet.Network.ID]
+			if ok && (n.IP == nil || n.IP.Equal(privateNet.IP)) {
+				attached[privateNet.Network.ID] = true
+				continue
+			}
+			if err = wait(func() (*hcloud.Action, *hcloud.Response, error) {
+				return m.client.LoadBalancer.DetachFromNetwork(ctx, lb, privateNet.Network)
+			}, fmt.Sprintf("detached from network %d", privateNet.Network.ID)); err != nil {
+				return
+			}
+		}
+		for _, n := range networks {
+			if attached[n.Network.ID] {
+				continue
+			}
+			if err = wait(func() (*hcloud.Action, *hcloud.Response, error) {
+				return m.client.LoadBalancer.AttachToNetwork(ctx, lb, hcloud.LoadBalancerAttachToNetworkOpts{
+					Network: n.Network,
+					IP:      n.IP,
+				})
+			}, fmt.Sprintf("attached to network %d", n.Network.ID)); err != nil {
+				return
+			}
+		}
+	}
+
+	for _, s := range updateServices {
+		if err = wait(func() (*hcloud.Action, *hcloud.Response, error) {
+			return m.client.LoadBalancer.UpdateService(ctx, lb, s)
+		}, fmt.Sprintf("service %d updated", s.ListenPort)); err != nil {
+			return
+		}
+	}
+	for _, s := range addServices {
+		if err = wait(func() (*hcloud.Action, *hcloud.Response, error) {
+			return m.client.LoadBalancer.AddService(ctx, lb, s)
+		}, fmt.Sprintf("service %d added", s.ListenPort)); err != nil {
+			return
+		}
+	}
+	for _, t := range addTargets {
+		if err = wait(func() (*hcloud.Action, *hcloud.Response, error) {
+			return m.client.LoadBalancer.AddTarget(ctx, lb, t)
+		}, fmt.Sprintf("target %s added", targetKey(t))); err != nil {
+			return
+		}
+	}
+
+	if len(actions) > 0 {
+		resp.Changed()
+		if lb, _, err = m.client.LoadBalancer.GetByID(ctx, lb.ID); err != nil {
+			return
+		}
+	}
+
+	resp.
+		Msg(strings.Join(msg, ", ")).
+		Set("load_balancers", []LoadBalancer{toLoadBalancer(lb)})
+	return
+}
+
+func (m *module) absent(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var lb *hcloud.LoadBalancer
+	if lb, err = m.loadBalancer(ctx); err != nil {
+		return
+	}
+	if lb == nil {
+		resp.Msg("No Load balancer found, nothing to do")
+		return
+	}
+	if _, err = m.client.LoadBalancer.Delete(ctx, lb); err != nil {
+		return
+	}
+	resp.Msg(fmt.Sprintf("Load balancer %d deleted", lb.ID)).Changed()
+	return
+}
+
+func (m *module) list(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var lbs []*hcloud.LoadBalancer
+	if lbs, err = m.client.LoadBalancer.All(ctx); err != nil {
+		return
+	}
+
+	list := []LoadBalancer{}
+	for _, lb := range lbs {
+		list = append(list, toLoadBalancer(lb))
+	}
+	resp.Msg("Load balancers listed").Set("load_balancers", list)
+	return
+}
+
+// waitForPending waits for the recorded actions on the load balancer with `wait: false`
+func (m *module) waitForPending(ctx context.Context, lb *hcloud.LoadBalancer) error {
+	return util.WaitForPending(ctx, m.waiter, hcloud.ActionResource{ID: lb.ID, Type: hcloud.ActionResourceTypeLoadBalancer})
+}
+
+// loadBalancer looks up the load balancer by id or name
+func (m *module) loadBalancer(ctx context.Context) (lb *hcloud.LoadBalancer, err error) {
+	if id := util.GetID(m.args.ID); id != 0 {
+		lb, _, err = m.client.LoadBalancer.GetByID(ctx, id)
+		return
+	}
+	lb, _, err = m.client.LoadBalancer.GetByName(ctx, m.args.Name)
+	return
+}
+
+// networks resolves the networks argument, nil leaves the networks unchanged
+func (m *module) networks(ctx context.Context) (networks []loadBalancerNetwork, err error) {
+	if m.args.Networks == nil {
+		return
+	}
+	networks = []loadBalancerNetwork{}
+	for _, n := range m.args.Networks {
+		idOrName := util.GetIdentifier(n.Network)
+		var lbNetwork loadBalancerNetwork
+		if lbNetwork.Network, _, err = m.client.Network.Get(ctx, idOrName); err != nil {
+			return
+		}
+		if lbNetwork.Network == nil {
+			err = fmt.Errorf("network '%s' not found", idOrName)
+			return
+		}
+		if n.IP != "" {
+			lbNetwork.IP = net.ParseIP(n.IP)
+		}
+		networks = append(networks, lbNetwork)
+	}
+	return
+}
+
+// targets resolves the targets argument, nil leaves the targets unchanged
+func (m *module) targets(ctx context.Context) (targets []hcloud.LoadBalancerTarget, err error) {
+	if m.args.Targets == nil {
+		return
+	}
+	targets = []hcloud.LoadBalancerTarget{}
+	for _, t := range m.args.Targets {
+		switch {
+		case t.LabelSelector != "":
+			targets = append(targets, hcloud.LoadBalancerTarget{
+				Type:          hcloud.LoadBalancerTargetTypeLabelSelector,
+				LabelSelector: t.LabelSelector,
+				UsePrivateIP:  t.UsePrivateIP,
+			})
+		case t.IP != "":
+			targets = append(targets, hcloud.LoadBalancerTarget{
+				Type: hcloud.LoadBalancerTargetTypeIP,
+				IP:   t.IP,
+			})
+		default:
+			var server *hcloud.Server
+			if server, err = m.server(ctx, t.Server); err != nil {
+				return
+			}
+			targets = append(targets, hcloud.LoadBalancerTarget{
+				Type:         hcloud.LoadBalancerTargetTypeServer,
+				Server:       server,
+				UsePrivateIP: t.UsePrivateIP,
+			})
+		}
+	}
+	return
+}
+
+func (m *module) server(ctx context.Context, serverArg interface{}) (server *hcloud.Server, err error) {
+	id := util.GetID(serverArg)
+	name := util.GetName(serverArg)
+
+	if id != 0 {
+		server, _, err = m.client.Server.GetByID(ctx, id)
+	}
+
+	if server == nil {
+		if name != "" {
+			server, _, err = m.client.Server.GetByName(ctx, name)
+		}
+	}
+
+	if err != nil {
+		return
+	}
+	if server == nil {
+		err = fmt.Errorf("Server '%v' not found", serverArg)
+	}
+	return
+}
+
+func usePrivateIP(targets []hcloud.LoadBalancerTarget) bool {
+	for _, t := range targets {
+		if t.UsePrivateIP {
+			return true
+		}
+	}
+	return false
+}
+
+// targetChanges returns the targets to add and remove,
+// targets with a changed use_private_ip are removed and added again,
+// nil desired targets leave the targets unchanged
+func targetChanges(desired, existing []hcloud.LoadBalancerTarget) (add, remove []hcloud.LoadBalancerTarget) {
+	if desired == nil {
+		return
+	}
+	desiredKeys := map[string]bool{}
+	for _, t := range desired {
+		desiredKeys[targetKey(t)] = true
+	}
+	existingKeys := map[string]bool{}
+	for _, t := range existing {
+		existingKeys[targetKey(t)] = true
+		if !desiredKeys[targetKey(t)] {
+			remove = append(remove, t)
+		}
+	}
+	for _, t := range desired {
+		if !existingKeys[targetKey(t)] {
+			add = append(add, t)
+			existingKeys[targetKey(t)] = true
+		}
+	}
+	return
+}
+
+func targetKey(t hcloud.LoadBalancerTarget) string {
+	var key string
+	switch t.Type {
+	case hcloud.LoadBalancerTargetTypeServer:
+		key = "server " + strconv.Itoa(t.Server.ID)
+	case hcloud.LoadBalancerTargetTypeLabelSelector:
+		key = "label_selector " + t.LabelSelector
+	default:
+		key = "ip " + t.IP
+	}
+	if t.UsePrivateIP {
+		key += " (private IP)"
+	}
+	return key
+}
+
+// serviceChanges returns the services to add, update and delete by listen port,
+// nil desired services leave the services unchanged
+func serviceChanges(desired, existing []hcloud.LoadBalancerService) (add, update, del []hcloud.LoadBalancerService) {
+	if desired == nil {
+		return
+	}
+	existingByPort := map[int]hcloud.LoadBalancerService{}
+	for _, s := range existing {
+		existingByPort[s.ListenPort] = s
+	}
+	desiredByPort := map[int]bool{}
+	for _, s := range desired {
+		desiredByPort[s.ListenPort] = true
+		e, ok := existingByPort[s.ListenPort]
+		switch {
+		case !ok:
+			add = append(add, s)
+		case !sameService(s, e):
+			update = append(update, s)
+		}
+	}
+	for _, s := range existing {
+		if !desiredByPort[s.ListenPort] {
+			del = append(del, s)
+		}
+	}
+	return
+}
+
+// sameService compares two services, ignoring options of other protocols
+// and the order of certificates and status codes
+func sameService(a, b hcloud.LoadBalancerService) bool {
+	return reflect.DeepEqual(normalizeService(a), normalizeService(b))
+}
+
+func normalizeService(s hcloud.LoadBalancerService) hcloud.LoadBalancerService {
+	if s.Protocol == hcloud.LoadBalancerServiceProtocolTCP {
+		s.HTTP = hcloud.LoadBalancerServiceHTTP{}
+	}
+	if s.HealthCheck.Protocol == hcloud.LoadBalancerServiceProtocolTCP {
+		s.HealthCheck.HTTP = hcloud.LoadBalancerServiceHealthCheckHTTP{}
+	}
+	if len(s.HTTP.Certificates) == 0 {
+		s.HTTP.Certificates = nil
+	} else {
+		certificates := append([]int{}, s.HTTP.Certificates...)
+		sort.Ints(certificates)
+		s.HTTP.Certificates = certificates
+	}
+	if len(s.HealthCheck.HTTP.StatusCodes) == 0 {
+		s.HealthCheck.HTTP.StatusCodes = nil
+	} else {
+		statusCodes := append([]string{}, s.HealthCheck.HTTP.StatusCodes...)
+		sort.Strings(statusCodes)
+		s.HealthCheck.HTTP.StatusCodes = statusCodes
+	}
+	return s
+}
+
+// toLoadBalancerService converts the argument and applies the defaults of the API
+func toLoadBalancerService(s Service) hcloud.LoadBalancerService {
+	service := hcloud.LoadBalancerService{
+		Protocol:        hcloud.LoadBalancerServiceProtocol(s.Protocol),
+		ListenPort:      s.ListenPort,
+		DestinationPort: s.DestinationPort,
+		Proxyprotocol:   s.Proxyprotocol,
+	}
+	if service.ListenPort == 0 {
+		switch service.Protocol {
+		case hcloud.LoadBalancerServiceProtocolHTTP:
+			service.ListenPort = 80
+		case hcloud.LoadBalancerServiceProtocolHTTPS:
+			service.ListenPort = 443
+		}
+	}
+	if service.DestinationPort == 0 {
+		if service.Protocol == hcloud.LoadBalancerServiceProtocolTCP {
+			service.DestinationPort = service.ListenPort
+		} else {
+			service.DestinationPort = 80
+		}
+	}
+
+	if service.Protocol != hcloud.LoadBalancerServiceProtocolTCP {
+		service.HTTP = hcloud.LoadBalancerServiceHTTP{
+			CookieName:     defaultCookieName,
+			CookieLifetime: defaultCookieLifetime * time.Second,
+		}
+		if h := s.HTTP; h != nil {
+			service.HTTP.StickySessions = h.StickySessions
+			service.HTTP.RedirectHTTP = h.RedirectHTTP
+			service.HTTP.Certificates = h.Certificates
+			if h.CookieName != "" {
+				service.HTTP.CookieName = h.CookieName
+			}
+			if h.CookieLifetime != 0 {
+				service.HTTP.CookieLifetime = time.Duration(h.CookieLifetime) * time.Second
+			}
+		}
+	}
+
+	healthCheck := HealthCheck{}
+	if s.HealthCheck != nil {
+		healthCheck = *s.HealthCheck
+	}
+	service.HealthCheck = hcloud.LoadBalancerServiceHealthCheck{
+		Protocol: hcloud.LoadBalancerServiceProtocol(healthCheck.Protocol),
+		Port:     healthCheck.Port,
+		Interval: time.Duration(healthCheck.Interval) * time.Second,
+		Timeout:  time.Duration(healthCheck.Timeout) * time.Second,
+		Retries:  healthCheck.Retries,
+	}
+	if service.HealthCheck.Protocol == "" {
+		service.HealthCheck.Protocol = hcloud.LoadBalancerServiceProtocolTCP
+		if service.Protocol != hcloud.LoadBalancerServiceProtocolTCP {
+			service.HealthCheck.Protocol = hcloud.LoadBalancerServiceProtocolHTTP
+		}
+	}
+	if service.HealthCheck.Port == 0 {
+		service.HealthCheck.Port = service.DestinationPort
+	}
+	if service.HealthCheck.Interval == 0 {
+		service.HealthCheck.Interval = defaultHealthCheckInterval * time.Second
+	}
+	if service.HealthCheck.Timeout == 0 {
+		service.HealthCheck.Timeout = defaultHealthCheckTimeout * time.Second
+	}
+	if service.HealthCheck.Retries == 0 {
+		service.HealthCheck.Retries = defaultHealthCheckRetries
+	}
+	if service.HealthCheck.Protocol != hcloud.LoadBalancerServiceProtocolTCP {
+		service.HealthCheck.HTTP = hcloud.LoadBalancerServiceHealthCheckHTTP{
+			Path:        defaultHealthCheckPath,
+			StatusCodes: defaultHealthCheckStatusCodes,
+		}
+		if h := healthCheck.HTTP; h != nil {
+			service.HealthCheck.HTTP.Domain = h.Domain
+			service.HealthCheck.HTTP.Response = h.Response
+			service.HealthCheck.HTTP.TLS = h.TLS
+			if h.Path != "" {
+				service.HealthCheck.HTTP.Path = h.Path
+			}
+			if h.StatusCodes != nil {
+				service.HealthCheck.HTTP.StatusCodes = h.StatusCodes
+			}
+		}
+	}
+	return service
+}
+
+func toService(service hcloud.LoadBalancerService) Service {
+	s := Service{
+		Protocol:        string(service.Protocol),
+		ListenPort:      service.ListenPort,
+		DestinationPort: service.DestinationPort,
+		Proxyprotocol:   service.Proxyprotocol,
+		HealthCheck: &HealthCheck{
+			Protocol: string(service.HealthCheck.Protocol),
+			Port:     service.HealthCheck.Port,
+			Interval: int(service.HealthCheck.Interval / time.Second),
+			Timeout:  int(service.HealthCheck.Timeout / time.Second),
+			Retries:  service.HealthCheck.Retries,
+		},
+	}
+	if service.Protocol != hcloud.LoadBalancerServiceProtocolTCP {
+		s.HTTP = &ServiceHTTP{
+			StickySessions: service.HTTP.StickySessions,
+			CookieName:     service.HTTP.CookieName,
+			CookieLifetime: int(service.HTTP.CookieLifetime / time.Second),
+			RedirectHTTP:   service.HTTP.RedirectHTTP,
+			Certificates:   service.HTTP.Certificates,
+		}
+	}
+	if service.HealthCheck.Protocol != hcloud.LoadBalancerServiceProtocolTCP {
+		h := service.HealthCheck.HTTP
+		s.HealthCheck.HTTP = &HealthCheckHTTP{
+			Domain:      h.Domain,
+			Path:        h.Path,
+			Response:    h.Response,
+			StatusCodes: h.StatusCodes,
+			TLS:         h.TLS,
+		}
+	}
+	return s
+}
+
+func toTarget(t hcloud.LoadBalancerTarget) Target {
+	data := Target{
+		Type:          string(t.Type),
+		LabelSelector: t.LabelSelector,
+		IP:            t.IP,
+		UsePrivateIP:  t.UsePrivateIP,
+		HealthStatus:  []HealthStatus{},
+	}
+	if t.Server != nil {
+		data.Server = t.Server.ID
+	}
+	for _, status := range t.HealthStatus {
+		data.HealthStatus = append(data.HealthStatus, HealthStatus{
+			ListenPort: status.ListenPort,
+			Status:     string(status.Status),
+		})
+	}
+	for _, matched := range t.Targets {
+		data.Targets = append(data.Targets, toTarget(matched))
+	}
+	return data
+}
+
+func toLoadBalancer(lb *hcloud.LoadBalancer) LoadBalancer {
+	data := LoadBalancer{
+		ID:         lb.ID,
+		Name:       lb.Name,
+		Algorithm:  string(lb.Algorithm),
+		PrivateIPs: []PrivateIP{},
+		Services:   []Service{},
+		Targets:    []Target{},
+	}
+	if lb.LoadBalancerType != nil {
+		data.LoadBalancerType = lb.LoadBalancerType.Name
+	}
+	if lb.Location != nil {
+		data.Location = lb.Location.Name
+	}
+	if lb.PublicNet.IPv4 != nil {
+		data.PublicIPv4 = lb.PublicNet.IPv4.String()
+	}
+	if lb.PublicNet.IPv6 != nil {
+		data.PublicIPv6 = lb.PublicNet.IPv6.String()
+	}
+	for _, privateNet := range lb.PrivateNet {
+		data.PrivateIPs = append(data.PrivateIPs, PrivateIP{
+			NetworkID: privateNet.Network.ID,
+			IP:        privateNet.IP.String(),
+		})
+	}
+	for _, service := range lb.Services {
+		data.Services = append(data.Services, toService(service))
+	}
+	for _, t := range lb.Targets {
+		data.Targets = append(data.Targets, toTarget(t))
+	}
+	return data
+}
+
+// CheckArgs checks the constraints of the arguments the spec cannot express
+func (m *module) CheckArgs() (errs []string) {
+	for i, n := range m.args.Networks {
+		if n.IP != "" && net.ParseIP(n.IP) == nil {
+			errs = append(errs, fmt.Sprintf("'networks[%d].ip' must be an IP, got %q", i, n.IP))
+		}
+	}
+	listenPorts := map[int]bool{}
+	for i, s := range m.args.Services {
+		if s.Protocol == string(hcloud.LoadBalancerServiceProtocolTCP) && s.ListenPort == 0 {
+			errs = append(errs, fmt.Sprintf("'services[%d].listen_port' is required for tcp", i))
+		}
+		if s.HTTP != nil && s.Protocol == string(hcloud.LoadBalancerServiceProtocolTCP) {
+			errs = append(errs, fmt.Sprintf("'services[%d].http' is only allowed for http and https", i))
+		}
+		if listenPort := toLoadBalancerService(s).ListenPort; listenPorts[listenPort] {
+			errs = append(errs, fmt.Sprintf("'services[%d].listen_port' %d is used by multiple services", i, listenPort))
+		} else {
+			listenPorts[listenPort] = true
+		}
+	}
+	for i, t := range m.args.Targets {
+		n := 0
+		for _, set := range []bool{t.Server != nil, t.LabelSelector != "", t.IP != ""} {
+			if set {
+				n++
+			}
+		}
+		if n != 1 {
+			errs = append(errs, fmt.Sprintf("'targets[%d]' requires exactly one of 'server', 'label_selector' or 'ip'", i))
+		}
+		if t.IP != "" && net.ParseIP(t.IP) == nil {
+			errs = append(errs, fmt.Sprintf("'targets[%d].ip' must be an IP, got %q", i, t.IP))
+		}
+		if t.IP != "" && t.UsePrivateIP {
+			errs = append(errs, fmt.Sprintf("'targets[%d].use_private_ip' is not allowed for ip targets", i))
+		}
+	}
+	return
+}
+
+var flags = pflag.NewFlagSet("hcloud_load_balancer", pflag.ContinueOnError)
+
+func init() {
+	flags.BoolP("version", "v", false, "Print version and exit")
+}
+
+func main() {
+	ansible.RunModule(&module{}, flags)
+}
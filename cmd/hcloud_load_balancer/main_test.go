@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud/hcloudtest"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+var (
+	nilResponse     *hcloud.Response
+	nilLoadBalancer *hcloud.LoadBalancer
+
+	noWait = util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+		return nil
+	})
+)
+
+// httpService is an http service with the defaults the API returns
+func httpService() hcloud.LoadBalancerService {
+	return hcloud.LoadBalancerService{
+		Protocol:        hcloud.LoadBalancerServiceProtocolHTTP,
+		ListenPort:      80,
+		DestinationPort: 8080,
+		HTTP: hcloud.LoadBalancerServiceHTTP{
+			CookieName:     "HCLBSTICKY",
+			CookieLifetime: 300 * time.Second,
+			Certificates:   []int{},
+		},
+		HealthCheck: hcloud.LoadBalancerServiceHealthCheck{
+			Protocol: hcloud.LoadBalancerServiceProtocolHTTP,
+			Port:     8080,
+			Interval: 15 * time.Second,
+			Timeout:  10 * time.Second,
+			Retries:  3,
+			HTTP: hcloud.LoadBalancerServiceHealthCheckHTTP{
+				Path:        "/healthz",
+				StatusCodes: []string{"3??", "2??"},
+			},
+		},
+	}
+}
+
+func newLoadBalancer() *hcloud.LoadBalancer {
+	return &hcloud.LoadBalancer{
+		ID:               1,
+		Name:             "web",
+		LoadBalancerType: &hcloud.LoadBalancerType{ID: 1, Name: "lb11"},
+		Location:         &hcloud.Location{Name: "fsn1"},
+		Algorithm:        hcloud.LoadBalancerAlgorithmTypeRoundRobin,
+		PublicNet: hcloud.LoadBalancerPublicNet{
+			Enabled: true,
+			IPv4:    net.ParseIP("203.0.113.1"),
+			IPv6:    net.ParseIP("2001:db8::1"),
+		},
+		PrivateNet: []hcloud.LoadBalancerPrivateNet{
+			{Network: &hcloud.Network{ID: 4}, IP: net.ParseIP("10.0.0.2")},
+		},
+		Services: []hcloud.LoadBalancerService{httpService()},
+		Targets: []hcloud.LoadBalancerTarget{
+			{
+				Type:         hcloud.LoadBalancerTargetTypeServer,
+				Server:       &hcloud.Server{ID: 42},
+				UsePrivateIP: true,
+				HealthStatus: []hcloud.LoadBalancerTargetHealthStatus{{ListenPort: 80, Status: "healthy"}},
+			},
+		},
+	}
+}
+
+func httpServiceArg() Service {
+	return Service{
+		Protocol:        "http",
+		DestinationPort: 8080,
+		HealthCheck:     &HealthCheck{HTTP: &HealthCheckHTTP{Path: "/healthz"}},
+	}
+}
+
+func TestList(t *testing.T) {
+	client := hcloud.NewClient()
+	client.LoadBalancer = hcloudtest.NewLoadBalancerClientMock()
+	client.LoadBalancer.(*hcloudtest.LoadBalancerClientMock).On("All", mock.Anything).Return([]*hcloud.LoadBalancer{newLoadBalancer()}, nil)
+
+	m := module{client: client}
+	resp, err := m.list(context.Background())
+	if assert.NoError(t, err) {
+		assert.False(t, resp.HasChanged(), "module should not have changed")
+		assert.Equal(t, []LoadBalancer{{
+			ID:               1,
+			Name:             "web",
+			LoadBalancerType: "lb11",
+			Location:         "fsn1",
+			Algorithm:        "round_robin",
+			PublicIPv4:       "203.0.113.1",
+			PublicIPv6:       "2001:db8::1",
+			PrivateIPs:       []PrivateIP{{NetworkID: 4, IP: "10.0.0.2"}},
+			Services: []Service{{
+				Protocol:        "http",
+				ListenPort:      80,
+				DestinationPort: 8080,
+				HTTP:            &ServiceHTTP{CookieName: "HCLBSTICKY", CookieLifetime: 300, Certificates: []int{}},
+				HealthCheck: &HealthCheck{
+					Protocol: "http",
+					Port:     8080,
+					Interval: 15,
+					Timeout:  10,
+					Retries:  3,
+					HTTP:     &HealthCheckHTTP{Path: "/healthz", StatusCodes: []string{"3??", "2??"}},
+				},
+			}},
+			Targets: []Target{{
+				Type:         "server",
+				Server:       42,
+				UsePrivateIP: true,
+				HealthStatus: []HealthStatus{{ListenPort: 80, Status: "healthy"}},
+			}},
+		}}, resp.Data()["load_balancers"])
+	}
+}
+
+func TestPresent(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.LoadBalancer = hcloudtest.NewLoadBalancerClientMock()
+		client.Network = hcloudtest.NewNetworkClientMock()
+		client.Server = hcloudtest.NewServerClientMock()
+		internal := &hcloud.Network{ID: 4}
+		server := &hcloud.Server{ID: 42}
+		client.Network.(*hcloudtest.NetworkClientMock).On("Get", mock.Anything, "internal").Return(internal, nilResponse, nil)
+		client.Server.(*hcloudtest.ServerClientMock).On("GetByName", mock.Anything, "web1").Return(server, nilResponse, nil)
+		lbMock := client.LoadBalancer.(*hcloudtest.LoadBalancerClientMock)
+		lbMock.On("GetByName", mock.Anything, "web").Return(nilLoadBalancer, nilResponse, nil)
+		lbMock.On("Create", mock.Anything, hcloud.LoadBalancerCreateOpts{
+			Name:             "web",
+			LoadBalancerType: &hcloud.LoadBalancerType{Name: "lb11"},
+			Location:         &hcloud.Location{Name: "fsn1"},
+			Network:          internal,
+			Services: []hcloud.LoadBalancerService{
+				func() hcloud.LoadBalancerService {
+					s := httpService()
+					s.HTTP.Certificates = nil
+					s.HealthCheck.HTTP.StatusCodes = []string{"2??", "3??"}
+					return s
+				}(),
+			},
+			Targets: []hcloud.LoadBalancerTarget{
+				{Type: hcloud.LoadBalancerTargetTypeServer, Server: server, UsePrivateIP: true},
+			},
+		}).Return(hcloud.LoadBalancerCreateResult{
+			LoadBalancer: newLoadBalancer(),
+			Action:       &hcloud.Action{ID: 1},
+		}, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args: arguments{
+				State:    statePresent,
+				Name:     "web",
+				Location: "fsn1",
+				Networks: []network{{Network: "internal"}},
+				Services: []Service{httpServiceArg()},
+				Targets:  []target{{Server: "web1", UsePrivateIP: true}},
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			lbs := resp.Data()["load_balancers"].([]LoadBalancer)
+			assert.Equal(t, "203.0.113.1", lbs[0].PublicIPv4)
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.LoadBalancer = hcloudtest.NewLoadBalancerClientMock()
+		client.Network = hcloudtest.NewNetworkClientMock()
+		client.Server = hcloudtest.NewServerClientMock()
+		client.Network.(*hcloudtest.NetworkClientMock).On("Get", mock.Anything, "4").Return(&hcloud.Network{ID: 4}, nilResponse, nil)
+		client.Server.(*hcloudtest.ServerClientMock).On("GetByID", mock.Anything, 42).Return(&hcloud.Server{ID: 42}, nilResponse, nil)
+		lbMock := client.LoadBalancer.(*hcloudtest.LoadBalancerClientMock)
+		lbMock.On("GetByName", mock.Anything, "web").Return(newLoadBalancer(), nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args: arguments{
+				State:            statePresent,
+				Name:             "web",
+				LoadBalancerType: "lb11",
+				Location:         "fsn1",
+				Algorithm:        "round_robin",
+				Networks:         []network{{Network: float64(4), IP: "10.0.0.2"}},
+				Services:         []Service{httpServiceArg()},
+				Targets:          []target{{Server: float64(42), UsePrivateIP: true}},
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.False(t, resp.HasChanged(), "module should not have changed")
+		}
+	})
+
+	t.Run("reconcile", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.LoadBalancer = hcloudtest.NewLoadBalancerClientMock()
+		client.Network = hcloudtest.NewNetworkClientMock()
+		lb := newLoadBalancer()
+		lbMock := client.LoadBalancer.(*hcloudtest.LoadBalancerClientMock)
+		lbMock.On("GetByID", mock.Anything, 1).Return(lb, nilResponse, nil)
+		// the load balancer is locked while an action runs, so each action
+		// is awaited before the next one and only the last one is left pending
+		var waited []int
+		lbAction := func(id int) *hcloud.Action {
+			return &hcloud.Action{
+				ID:        id,
+				Resources: []*hcloud.ActionResource{{ID: lb.ID, Type: hcloud.ActionResourceTypeLoadBalancer}},
+			}
+		}
+		lbMock.On("ChangeAlgorithm", mock.Anything, lb, hcloud.LoadBalancerAlgorithmTypeLeastConnections).Return(lbAction(1), nilResponse, nil)
+		lbMock.On("RemoveTarget", mock.Anything, lb, lb.Targets[0]).Return(lbAction(2), nilResponse, nil)
+		lbMock.On("DetachFromNetwork", mock.Anything, lb, lb.PrivateNet[0].Network).Return(lbAction(3), nilResponse, nil)
+		updated := httpService()
+		updated.HTTP.StickySessions = true
+		updated.HTTP.Certificates = nil
+		updated.HealthCheck.HTTP.StatusCodes = []string{"2??", "3??"}
+		lbMock.On("UpdateService", mock.Anything, lb, updated).Return(lbAction(4), nilResponse, nil)
+		lbMock.On("AddService", mock.Anything, lb, hcloud.LoadBalancerService{
+			Protocol:        hcloud.LoadBalancerServiceProtocolTCP,
+			ListenPort:      5432,
+			DestinationPort: 5432,
+			HealthCheck: hcloud.LoadBalancerServiceHealthCheck{
+				Protocol: hcloud.LoadBalancerServiceProtocolTCP,
+				Port:     5432,
+				Interval: 15 * time.Second,
+				Timeout:  10 * time.Second,
+				Retries:  3,
+			},
+		}).Return(lbAction(5), nilResponse, nil)
+		lbMock.On("AddTarget", mock.Anything, lb, hcloud.LoadBalancerTarget{
+			Type:          hcloud.LoadBalancerTargetTypeLabelSelector,
+			LabelSelector: "role=web",
+		}).Run(func(args mock.Arguments) {
+			assert.Equal(t, []int{1, 2, 3, 4, 5}, waited, "target added before the earlier actions finished")
+		}).Return(lbAction(6), nilResponse, nil)
+
+		service := httpServiceArg()
+		service.HTTP = &ServiceHTTP{StickySessions: true}
+		m := module{
+			client: client,
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				for _, action := range actions {
+					waited = append(waited, action.ID)
+				}
+				return nil
+			}),
+			args: arguments{
+				State:     statePresent,
+				ID:        float64(1),
+				Algorithm: "least_connections",
+				Networks:  []network{},
+				Services:  []Service{service, {Protocol: "tcp", ListenPort: 5432}},
+				Targets:   []target{{LabelSelector: "role=web"}},
+				Wait:      hcloud.Bool(false),
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			assert.Equal(t, []int{6}, resp.Data()["action_ids"])
+		}
+	})
+
+	t.Run("location cannot change", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.LoadBalancer = hcloudtest.NewLoadBalancerClientMock()
+		client.LoadBalancer.(*hcloudtest.LoadBalancerClientMock).On("GetByName", mock.Anything, "web").Return(newLoadBalancer(), nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: statePresent, Name: "web", Location: "nbg1"},
+		}
+		_, err := m.run(context.Background())
+		assert.Error(t, err)
+	})
+}
+
+func TestAbsent(t *testing.T) {
+	client := hcloud.NewClient()
+	client.LoadBalancer = hcloudtest.NewLoadBalancerClientMock()
+	lb := newLoadBalancer()
+	lbMock := client.LoadBalancer.(*hcloudtest.LoadBalancerClientMock)
+	lbMock.On("GetByName", mock.Anything, "web").Return(lb, nilResponse, nil)
+	lbMock.On("Delete", mock.Anything, lb).Return(nilResponse, nil)
+
+	m := module{
+		client: client,
+		waiter: noWait,
+		args:   arguments{State: stateAbsent, Name: "web"},
+	}
+	resp, err := m.run(context.Background())
+	if assert.NoError(t, err) {
+		assert.True(t, resp.HasChanged(), "module should have changed")
+		lbMock.AssertCalled(t, "Delete", mock.Anything, lb)
+	}
+}
+
+func TestValidateArgs(t *testing.T) {
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: stateList}}))
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{
+		State:    statePresent,
+		Name:     "web",
+		Services: []Service{{Protocol: "https"}, {Protocol: "http"}, {Protocol: "tcp", ListenPort: 22}},
+		Targets:  []target{{Server: "web1"}, {LabelSelector: "role=web"}, {IP: "203.0.113.5"}},
+	}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", Algorithm: "random"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", Services: []Service{{Protocol: "udp", ListenPort: 53}}}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", Services: []Service{{Protocol: "tcp"}}}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", Services: []Service{{Protocol: "http"}, {Protocol: "tcp", ListenPort: 80}}}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", Targets: []target{{Server: "web1", IP: "203.0.113.5"}}}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", Targets: []target{{IP: "203.0.113.5", UsePrivateIP: true}}}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", Networks: []network{{Network: "internal", IP: "10.0.0"}}}}))
+	assert.EqualError(t, ansible.ValidateArgs(&module{args: arguments{
+		State:    statePresent,
+		Name:     "web",
+		Networks: []network{{IP: "10.0.0.5"}},
+		Services: []Service{{Protocol: "udp", ListenPort: 53}},
+		Targets:  []target{{}},
+	}}), "'networks[0].network' is required, "+
+		"'services[0].protocol' must be tcp, http or https, got \"udp\", "+
+		"'targets[0]' requires exactly one of 'server', 'label_selector' or 'ip'")
+}
+
+func TestDoc(t *testing.T) {
+	data, err := ioutil.ReadFile("../../docs/hcloud_load_balancer.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(data), doc.Markdown(argSpec), "docs/hcloud_load_balancer.md is outdated, run make docs")
+	}
+}
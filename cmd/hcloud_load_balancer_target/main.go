@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+const (
+	stateAbsent    = "absent"
+	statePresent   = "present"
+	stateHealthy   = "healthy"
+	stateUnhealthy = "unhealthy"
+
+	defaultHealthTimeout = 300
+	healthPollInterval   = 2 * time.Second
+)
+
+// Target is the module return value of a server target of an hcloud.LoadBalancer
+type Target struct {
+	LoadBalancer int            `json:"load_balancer"`
+	Server       int            `json:"server"`
+	UsePrivateIP bool           `json:"use_private_ip"`
+	HealthStatus []HealthStatus `json:"health_status"`
+}
+
+// HealthStatus is the health of a target for the service on ListenPort
+type HealthStatus struct {
+	ListenPort int    `json:"listen_port"`
+	Status     string `json:"status"`
+}
+
+type arguments struct {
+	Token string `json:"token"`
+	State string `json:"state"`
+
+	LoadBalancer  interface{} `json:"load_balancer"`
+	Server        interface{} `json:"server"`
+	UsePrivateIP  bool        `json:"use_private_ip"`
+	HealthTimeout *int        `json:"health_timeout"`
+	Wait          *bool       `json:"wait"`
+}
+
+var argSpec = ansible.ArgSpec{
+	Args: []ansible.Arg{
+		ansible.TokenArg(),
+		{Name: "state", Type: ansible.TypeStr, Default: statePresent,
+			Choices: []string{statePresent, stateAbsent, stateHealthy, stateUnhealthy},
+			Description: "`present` adds the server and waits until it is healthy for all services.\n\n" +
+				"`absent` removes the server.\n\n" +
+				"`healthy` and `unhealthy` only wait until the server reports that status for all services."},
+		{Name: "load_balancer", Type: ansible.TypeRaw, Required: true,
+			Description: "ID or name of the load balancer."},
+		{Name: "server", Type: ansible.TypeRaw, Required: true,
+			Description: "ID or name of the server."},
+		{Name: "use_private_ip", Type: ansible.TypeBool, Default: false,
+			Description: "Send traffic to the private IP of the server. The target is added again if this changes."},
+		{Name: "health_timeout", Type: ansible.TypeInt, Default: defaultHealthTimeout,
+			Description: "Maximum time in seconds to wait for the health status."},
+		{Name: "wait", Type: ansible.TypeBool, Default: true,
+			Description: "Wait for all actions and the health status. When `false`, the module returns immediately with `action_ids`, see `hcloud_action`."},
+	},
+}
+
+var doc = ansible.Doc{
+	Module:           "hcloud_load_balancer_target",
+	ShortDescription: "Add or remove single Load Balancer targets",
+	Description: "Adds or removes a single server as target of an existing Hetzner Cloud load balancer and waits for its health checks. " +
+		"Use it to take servers out of rotation during rolling deployments, `hcloud_load_balancer` manages the complete set of targets.\n\n" +
+		"Servers matched by a label selector of the load balancer count as targets, " +
+		"their health status can be awaited but they cannot be removed individually.",
+	Returns: []ansible.Return{
+		{
+			Name:        "target",
+			Description: "The target and its health status for the services of the load balancer.",
+			Returned:    "unless `state=absent`",
+			Sample: Target{
+				LoadBalancer: 1,
+				Server:       42,
+				UsePrivateIP: true,
+				HealthStatus: []HealthStatus{{ListenPort: 80, Status: "healthy"}},
+			},
+		},
+		{
+			Name:        "action_ids",
+			Description: "IDs of the pending actions, see `hcloud_action`.",
+			Returned:    "when `wait` is `false`",
+			Sample:      []int{4711},
+		},
+	},
+	Examples: `
+# rolling update of all web servers, one at a time
+- hosts: web
+  serial: 1
+  tasks:
+  - hcloud_load_balancer_target:
+      load_balancer: web
+      server: "{{ inventory_hostname }}"
+      state: absent
+    delegate_to: localhost
+
+  - hcloud_server:
+      name: "{{ inventory_hostname }}"
+      state: restarted
+    delegate_to: localhost
+
+  - hcloud_load_balancer_target:
+      load_balancer: web
+      server: "{{ inventory_hostname }}"
+      use_private_ip: true
+      health_timeout: 600
+    delegate_to: localhost
+`,
+}
+
+type module struct {
+	args         arguments
+	client       *hcloud.Client
+	waiter       util.ActionWaiter
+	pollInterval time.Duration
+}
+
+func (m *module) Args() interface{} {
+	return &m.args
+}
+
+func (m *module) ArgSpec() ansible.ArgSpec {
+	return argSpec
+}
+
+func (m *module) Doc() ansible.Doc {
+	return doc
+}
+
+func (m *module) Run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	m.client, err = hcloud.BuildClient(m.args.Token)
+	if err != nil {
+		return
+	}
+	m.waiter = util.NewActionWatcher(m.client.Action)
+	m.pollInterval = healthPollInterval
+	return m.run(ctx)
+}
+
+func (m *module) run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	if m.args.Wait != nil && !*m.args.Wait {
+		defer util.RecordActions(&m.waiter).Report(&resp, &err)
+	}
+
+	var lb *hcloud.LoadBalancer
+	if lb, err = m.loadBalancer(ctx); err != nil {
+		return
+	}
+	var server *hcloud.Server
+	if server, err = m.server(ctx); err != nil {
+		return
+	}
+
+	switch m.args.State {
+	case statePresent:
+		return m.present(ctx, lb, server)
+	case stateAbsent:
+		return m.absent(ctx, lb, server)
+	case stateHealthy, stateUnhealthy:
+		return m.health(ctx, lb, server)
+	default:
+		err = errors.New("invalid state")
+		return
+	}
+}
+
+// present adds the server as target and waits until it is healthy
+func (m *module) present(ctx context.Context, lb *hcloud.LoadBalancer, server *hcloud.Server) (resp ansible.ModuleResponse, err error) {
+	target, found := serverTarget(lb, server)
+	if found && target.Type == hcloud.LoadBalancerTargetTypeServer && target.UsePrivateIP != m.args.UsePrivateIP {
+		if err = m.removeTarget(ctx, lb, target); err != nil {
+			return
+		}
+		found = false
+	}
+	if !found {
+		// the load balancer is locked while the target is removed
+		if err = util.WaitForPending(ctx, m.waiter, hcloud.ActionResource{ID: lb.ID, Type: hcloud.ActionResourceTypeLoadBalancer}); err != nil {
+			return
+		}
+		var action *hcloud.Action
+		if action, _, err = m.client.LoadBalancer.AddTarget(ctx, lb, hcloud.LoadBalancerTarget{
+			Type:         hcloud.LoadBalancerTargetTypeServer,
+			Server:       server,
+			UsePrivateIP: m.args.UsePrivateIP,
+		}); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
+			return
+		}
+		resp.Msg(fmt.Sprintf("Server %d added to load balancer %d", server.ID, lb.ID)).Changed()
+		if lb, _, err = m.client.LoadBalancer.GetByID(ctx, lb.ID); err != nil {
+			return
+		}
+	}
+
+	if m.args.Wait == nil || *m.args.Wait {
+		if lb, err = m.waitForHealth(ctx, lb, server, hcloud.LoadBalancerTargetHealthStatusStatusHealthy); err != nil {
+			return
+		}
+	}
+	target, _ = serverTarget(lb, server)
+	resp.Set("target", toTarget(lb, server, target))
+	return
+}
+
+// absent removes the server target from the load balancer
+func (m *module) absent(ctx context.Context, lb *hcloud.LoadBalancer, server *hcloud.Server) (resp ansible.ModuleResponse, err error) {
+	target, found := serverTarget(lb, server)
+	if !found {
+		resp.Msg(fmt.Sprintf("Server %d is no target of load balancer %d, nothing to do", server.ID, lb.ID))
+		return
+	}
+	if target.Type == hcloud.LoadBalancerTargetTypeLabelSelector {
+		err = fmt.Errorf("Server %d is a target of load balancer %d by label selector %q and cannot be removed individually",
+			server.ID, lb.ID, target.LabelSelector)
+		return
+	}
+	if err = m.removeTarget(ctx, lb, target); err != nil {
+		return
+	}
+	resp.Msg(fmt.Sprintf("Server %d removed from load balancer %d", server.ID, lb.ID)).Changed()
+	return
+}
+
+// health waits until the target reports the status of the state, without changing the load balancer
+func (m *module) health(ctx context.Context, lb *hcloud.LoadBalancer, server *hcloud.Server) (resp ansible.ModuleResponse, err error) {
+	if _, found := serverTarget(lb, server); !found {
+		err = fmt.Errorf("Server %d is no target of load balancer %d", server.ID, lb.ID)
+		return
+	}
+	if lb, err = m.waitForHealth(ctx, lb, server, hcloud.LoadBalancerTargetHealthStatusStatus(m.args.State)); err != nil {
+		return
+	}
+	target, _ := serverTarget(lb, server)
+	resp.
+		Msg(fmt.Sprintf("Server %d is %s", server.ID, m.args.State)).
+		Set("target", toTarget(lb, server, target))
+	return
+}
+
+func (m *module) removeTarget(ctx context.Context, lb *hcloud.LoadBalancer, target hcloud.LoadBalancerTarget) error {
+	action, _, err := m.client.LoadBalancer.RemoveTarget(ctx, lb, target)
+	if err != nil {
+		return err
+	}
+	return m.waiter.WaitForActions(ctx, action)
+}
+
+// waitForHealth polls the load balancer until the server reports the status for all services
+func (m *module) waitForHealth(ctx context.Context, lb *hcloud.LoadBalancer, server *hcloud.Server, status hcloud.LoadBalancerTargetHealthStatusStatus) (*hcloud.LoadBalancer, error) {
+	timeout := defaultHealthTimeout
+	if m.args.HealthTimeout != nil {
+		timeout = *m.args.HealthTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	ticker := time.NewTicker(m.pollInterval)
+	defer ticker.Stop()
+	for {
+		target, _ := serverTarget(lb, server)
+		if ok, err := hasHealthStatus(lb, target, status); err != nil || ok {
+			return lb, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%v while waiting for server %d to become %s on load balancer %d",
+				ctx.Err(), server.ID, status, lb.ID)
+		case <-ticker.C:
+		}
+
+		var err error
+		var current *hcloud.LoadBalancer
+		if current, _, err = m.client.LoadBalancer.GetByID(ctx, lb.ID); err != nil {
+			return nil, err
+		}
+		if current == nil {
+			return nil, fmt.Errorf("Load balancer %d not found", lb.ID)
+		}
+		lb = current
+	}
+}
+
+// hasHealthStatus checks if the target reports the status for every service of the load balancer,
+// without services there are no health checks and the status is never reported
+func hasHealthStatus(lb *hcloud.LoadBalancer, target hcloud.LoadBalancerTarget, status hcloud.LoadBalancerTargetHealthStatusStatus) (bool, error) {
+	if len(lb.Services) == 0 {
+		return false, fmt.Errorf("Load balancer %d has no services to check the health of targets", lb.ID)
+	}
+	statuses := map[int]hcloud.LoadBalancerTargetHealthStatusStatus{}
+	for _, s := range target.HealthStatus {
+		statuses[s.ListenPort] = s.Status
+	}
+	for _, service := range lb.Services {
+		if statuses[service.ListenPort] != status {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// serverTarget returns the target of the server,
+// for servers matched by a label selector the health status is the one of the matched server
+func serverTarget(lb *hcloud.LoadBalancer, server *hcloud.Server) (hcloud.LoadBalancerTarget, bool) {
+	for _, target := range lb.Targets {
+		if target.Type == hcloud.LoadBalancerTargetTypeServer && target.Server.ID == server.ID {
+			return target, true
+		}
+	}
+	for _, target := range lb.Targets {
+		if target.Type != hcloud.LoadBalancerTargetTypeLabelSelector {
+			continue
+		}
+		for _, matched := range target.Targets {
+			if matched.Server != nil && matched.Server.ID == server.ID {
+				target.HealthStatus = matched.HealthStatus
+				return target, true
+			}
+		}
+	}
+	return hcloud.LoadBalancerTarget{}, false
+}
+
+func (m *module) loadBalancer(ctx context.Context) (lb *hcloud.LoadBalancer, err error) {
+	idOrName := util.GetIdentifier(m.args.LoadBalancer)
+	if lb, _, err = m.client.LoadBalancer.Get(ctx, idOrName); err != nil {
+		return
+	}
+	if lb == nil {
+		err = fmt.Errorf("Load balancer '%s' not found", idOrName)
+	}
+	return
+}
+
+func (m *module) server(ctx context.Context) (server *hcloud.Server, err error) {
+	id := util.GetID(m.args.Server)
+	name := util.GetName(m.args.Server)
+
+	if id != 0 {
+		server, _, err = m.client.Server.GetByID(ctx, id)
+	}
+
+	if server == nil {
+		if name != "" {
+			server, _, err = m.client.Server.GetByName(ctx, name)
+		}
+	}
+
+	if err != nil {
+		return
+	}
+	if server == nil {
+		err = fmt.Errorf("Server '%v' not found", m.args.Server)
+	}
+	return
+}
+
+func toTarget(lb *hcloud.LoadBalancer, server *hcloud.Server, target hcloud.LoadBalancerTarget) Target {
+	data := Target{
+		LoadBalancer: lb.ID,
+		Server:       server.ID,
+		UsePrivateIP: target.UsePrivateIP,
+		HealthStatus: []HealthStatus{},
+	}
+	for _, status := range target.HealthStatus {
+		data.HealthStatus = append(data.HealthStatus, HealthStatus{
+			ListenPort: status.ListenPort,
+			Status:     string(status.Status),
+		})
+	}
+	return data
+}
+
+// CheckArgs checks the constraints of the arguments the spec cannot express
+func (m *module) CheckArgs() (errs []string) {
+	if m.args.HealthTimeout != nil && *m.args.HealthTimeout <= 0 {
+		errs = append(errs, "'health_timeout' must be greater than 0")
+	}
+	return
+}
+
+var flags = pflag.NewFlagSet("hcloud_load_balancer_target", pflag.ContinueOnError)
+
+func init() {
+	flags.BoolP("version", "v", false, "Print version and exit")
+}
+
+func main() {
+	ansible.RunModule(&module{}, flags)
+}
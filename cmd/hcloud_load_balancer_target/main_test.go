@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud/hcloudtest"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+var (
+	nilResponse *hcloud.Response
+
+	noWait = util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+		return nil
+	})
+)
+
+func newLoadBalancer(targets ...hcloud.LoadBalancerTarget) *hcloud.LoadBalancer {
+	return &hcloud.LoadBalancer{
+		ID:   1,
+		Name: "web",
+		Services: []hcloud.LoadBalancerService{
+			{Protocol: hcloud.LoadBalancerServiceProtocolHTTP, ListenPort: 80},
+			{Protocol: hcloud.LoadBalancerServiceProtocolHTTPS, ListenPort: 443},
+		},
+		Targets: targets,
+	}
+}
+
+func serverTarget42(status hcloud.LoadBalancerTargetHealthStatusStatus) hcloud.LoadBalancerTarget {
+	return hcloud.LoadBalancerTarget{
+		Type:   hcloud.LoadBalancerTargetTypeServer,
+		Server: &hcloud.Server{ID: 42},
+		HealthStatus: []hcloud.LoadBalancerTargetHealthStatus{
+			{ListenPort: 80, Status: status},
+			{ListenPort: 443, Status: status},
+		},
+	}
+}
+
+func newClient(lb *hcloud.LoadBalancer) *hcloud.Client {
+	client := hcloud.NewClient()
+	client.LoadBalancer = hcloudtest.NewLoadBalancerClientMock()
+	client.Server = hcloudtest.NewServerClientMock()
+	client.LoadBalancer.(*hcloudtest.LoadBalancerClientMock).On("Get", mock.Anything, "web").Return(lb, nilResponse, nil)
+	client.Server.(*hcloudtest.ServerClientMock).On("GetByName", mock.Anything, "web1").Return(&hcloud.Server{ID: 42, Name: "web1"}, nilResponse, nil)
+	return client
+}
+
+func TestPresent(t *testing.T) {
+	t.Run("add and wait until healthy", func(t *testing.T) {
+		lb := newLoadBalancer()
+		client := newClient(lb)
+		lbMock := client.LoadBalancer.(*hcloudtest.LoadBalancerClientMock)
+		lbMock.On("AddTarget", mock.Anything, lb, hcloud.LoadBalancerTarget{
+			Type:   hcloud.LoadBalancerTargetTypeServer,
+			Server: &hcloud.Server{ID: 42, Name: "web1"},
+		}).Return(&hcloud.Action{ID: 1}, nilResponse, nil)
+		lbMock.On("GetByID", mock.Anything, 1).Return(newLoadBalancer(serverTarget42("unknown")), nilResponse, nil).Twice()
+		lbMock.On("GetByID", mock.Anything, 1).Return(newLoadBalancer(serverTarget42("healthy")), nilResponse, nil)
+
+		m := module{
+			client:       client,
+			waiter:       noWait,
+			pollInterval: time.Millisecond,
+			args:         arguments{State: statePresent, LoadBalancer: "web", Server: "web1"},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			assert.Equal(t, Target{
+				LoadBalancer: 1,
+				Server:       42,
+				HealthStatus: []HealthStatus{{ListenPort: 80, Status: "healthy"}, {ListenPort: 443, Status: "healthy"}},
+			}, resp.Data()["target"])
+			lbMock.AssertNumberOfCalls(t, "GetByID", 3)
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		client := newClient(newLoadBalancer(serverTarget42("healthy")))
+
+		m := module{
+			client:       client,
+			waiter:       noWait,
+			pollInterval: time.Millisecond,
+			args:         arguments{State: statePresent, LoadBalancer: "web", Server: "web1"},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.False(t, resp.HasChanged(), "module should not have changed")
+		}
+	})
+
+	t.Run("change use_private_ip", func(t *testing.T) {
+		target := serverTarget42("healthy")
+		lb := newLoadBalancer(target)
+		client := newClient(lb)
+		lbAction := func(id int) *hcloud.Action {
+			return &hcloud.Action{
+				ID:        id,
+				Resources: []*hcloud.ActionResource{{ID: lb.ID, Type: hcloud.ActionResourceTypeLoadBalancer}},
+			}
+		}
+		// the load balancer is locked while the target is removed,
+		// so the removal is awaited and only the new target is left pending
+		var waited []int
+		lbMock := client.LoadBalancer.(*hcloudtest.LoadBalancerClientMock)
+		lbMock.On("RemoveTarget", mock.Anything, lb, target).Return(lbAction(1), nilResponse, nil)
+		lbMock.On("AddTarget", mock.Anything, lb, hcloud.LoadBalancerTarget{
+			Type:         hcloud.LoadBalancerTargetTypeServer,
+			Server:       &hcloud.Server{ID: 42, Name: "web1"},
+			UsePrivateIP: true,
+		}).Run(func(args mock.Arguments) {
+			assert.Equal(t, []int{1}, waited, "target added before the removal finished")
+		}).Return(lbAction(2), nilResponse, nil)
+		lbMock.On("GetByID", mock.Anything, 1).Return(lb, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				for _, action := range actions {
+					waited = append(waited, action.ID)
+				}
+				return nil
+			}),
+			pollInterval: time.Millisecond,
+			args: arguments{
+				State:        statePresent,
+				LoadBalancer: "web",
+				Server:       "web1",
+				UsePrivateIP: true,
+				Wait:         hcloud.Bool(false),
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			assert.Equal(t, []int{2}, resp.Data()["action_ids"])
+		}
+	})
+
+	t.Run("matched by label selector", func(t *testing.T) {
+		client := newClient(newLoadBalancer(hcloud.LoadBalancerTarget{
+			Type:          hcloud.LoadBalancerTargetTypeLabelSelector,
+			LabelSelector: "role=web",
+			Targets:       []hcloud.LoadBalancerTarget{serverTarget42("healthy")},
+		}))
+
+		m := module{
+			client:       client,
+			waiter:       noWait,
+			pollInterval: time.Millisecond,
+			args:         arguments{State: statePresent, LoadBalancer: "web", Server: "web1", UsePrivateIP: true},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.False(t, resp.HasChanged(), "module should not have changed")
+		}
+	})
+}
+
+func TestAbsent(t *testing.T) {
+	t.Run("remove", func(t *testing.T) {
+		target := serverTarget42("healthy")
+		lb := newLoadBalancer(target)
+		client := newClient(lb)
+		lbMock := client.LoadBalancer.(*hcloudtest.LoadBalancerClientMock)
+		lbMock.On("RemoveTarget", mock.Anything, lb, target).Return(&hcloud.Action{ID: 1}, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: stateAbsent, LoadBalancer: "web", Server: "web1", Wait: hcloud.Bool(false)},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			assert.Equal(t, []int{1}, resp.Data()["action_ids"])
+		}
+	})
+
+	t.Run("matched by label selector", func(t *testing.T) {
+		client := newClient(newLoadBalancer(hcloud.LoadBalancerTarget{
+			Type:          hcloud.LoadBalancerTargetTypeLabelSelector,
+			LabelSelector: "role=web",
+			Targets:       []hcloud.LoadBalancerTarget{serverTarget42("healthy")},
+		}))
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: stateAbsent, LoadBalancer: "web", Server: "web1"},
+		}
+		_, err := m.run(context.Background())
+		assert.Error(t, err)
+	})
+}
+
+func TestHealth(t *testing.T) {
+	t.Run("unhealthy", func(t *testing.T) {
+		client := newClient(newLoadBalancer(serverTarget42("healthy")))
+		lbMock := client.LoadBalancer.(*hcloudtest.LoadBalancerClientMock)
+		lbMock.On("GetByID", mock.Anything, 1).Return(newLoadBalancer(serverTarget42("unhealthy")), nilResponse, nil)
+
+		m := module{
+			client:       client,
+			waiter:       noWait,
+			pollInterval: time.Millisecond,
+			args:         arguments{State: stateUnhealthy, LoadBalancer: "web", Server: "web1"},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.False(t, resp.HasChanged(), "module should not have changed")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		client := newClient(newLoadBalancer(serverTarget42("unhealthy")))
+		lbMock := client.LoadBalancer.(*hcloudtest.LoadBalancerClientMock)
+		lbMock.On("GetByID", mock.Anything, 1).Return(newLoadBalancer(serverTarget42("unhealthy")), nilResponse, nil)
+
+		m := module{
+			client:       client,
+			waiter:       noWait,
+			pollInterval: time.Millisecond,
+			args:         arguments{State: stateHealthy, LoadBalancer: "web", Server: "web1"},
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := m.run(ctx)
+		assert.Error(t, err)
+	})
+
+	t.Run("no services", func(t *testing.T) {
+		lb := newLoadBalancer(serverTarget42("healthy"))
+		lb.Services = nil
+		client := newClient(lb)
+
+		m := module{
+			client:       client,
+			waiter:       noWait,
+			pollInterval: time.Millisecond,
+			args:         arguments{State: stateUnhealthy, LoadBalancer: "web", Server: "web1"},
+		}
+		_, err := m.run(context.Background())
+		assert.EqualError(t, err, "Load balancer 1 has no services to check the health of targets")
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, LoadBalancer: "web", Server: float64(42)}}))
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: stateUnhealthy, LoadBalancer: float64(1), Server: "web1"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: "drained", LoadBalancer: "web", Server: "web1"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Server: "web1"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, LoadBalancer: "web"}}))
+	timeout := 0
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, LoadBalancer: "web", Server: "web1", HealthTimeout: &timeout}}))
+}
+
+func TestDoc(t *testing.T) {
+	data, err := ioutil.ReadFile("../../docs/hcloud_load_balancer_target.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(data), doc.Markdown(argSpec), "docs/hcloud_load_balancer_target.md is outdated, run make docs")
+	}
+}
@@ -0,0 +1,526 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+const (
+	stateAbsent  = "absent"
+	statePresent = "present"
+	stateList    = "list"
+)
+
+var subnetTypes = []string{
+	string(hcloud.NetworkSubnetTypeCloud),
+	string(hcloud.NetworkSubnetTypeServer),
+	string(hcloud.NetworkSubnetTypeVSwitch),
+}
+
+// Network is the module return value of an hcloud.Network
+type Network struct {
+	ID      int      `json:"id"`
+	Name    string   `json:"name"`
+	IPRange string   `json:"ip_range"`
+	Subnets []Subnet `json:"subnets"`
+	Routes  []Route  `json:"routes"`
+	Servers []int    `json:"servers"`
+}
+
+// Subnet is the module argument and return value of an hcloud.NetworkSubnet
+type Subnet struct {
+	Type        string `json:"type"`
+	NetworkZone string `json:"network_zone"`
+	IPRange     string `json:"ip_range"`
+	Gateway     string `json:"gateway,omitempty"`
+}
+
+// Route is the module argument and return value of an hcloud.NetworkRoute
+type Route struct {
+	Destination string `json:"destination"`
+	Gateway     string `json:"gateway"`
+}
+
+type arguments struct {
+	Token string `json:"token"`
+	State string `json:"state"`
+
+	ID      interface{} `json:"id"`
+	Name    string      `json:"name"`
+	IPRange string      `json:"ip_range"`
+	Subnets []Subnet    `json:"subnets"`
+	Routes  []Route     `json:"routes"`
+	Wait    *bool       `json:"wait"`
+}
+
+var argSpec = ansible.ArgSpec{
+	Args: []ansible.Arg{
+		ansible.TokenArg(),
+		{Name: "state", Type: ansible.TypeStr, Default: statePresent, Choices: []string{statePresent, stateAbsent, stateList},
+			Description: "`list` lists all existing networks."},
+		{Name: "id", Type: ansible.TypeRaw,
+			Description: "ID of the network.\n\n`id` or `name` is required when `state=present` or `state=absent`."},
+		{Name: "name", Type: ansible.TypeStr,
+			Description: "Name of the network, used to find the network when `id` is not specified. The network is renamed when both are given."},
+		{Name: "ip_range", Type: ansible.TypeStr,
+			Description: "IP range of the network in CIDR notation, e.g. `10.0.0.0/16`.\n\nRequired to create a network. The IP range can only be enlarged."},
+		{Name: "subnets", Type: ansible.TypeList, Elements: ansible.TypeDict,
+			Description: "List of subnets, see below.\n\nWhen set, subnets not in the list are deleted and changed subnets are recreated. Existing subnets are kept if not set.",
+			Options: []ansible.Arg{
+				{Name: "type", Type: ansible.TypeStr, Required: true, Choices: subnetTypes},
+				{Name: "network_zone", Type: ansible.TypeStr, Required: true, Description: "Network zone of the subnet, e.g. `eu-central`."},
+				{Name: "ip_range", Type: ansible.TypeStr, Required: true, Description: "IP range of the subnet in CIDR notation."},
+			}},
+		{Name: "routes", Type: ansible.TypeList, Elements: ansible.TypeDict,
+			Description: "List of routes, see below.\n\nWhen set, routes not in the list are deleted. Existing routes are kept if not set.",
+			Options: []ansible.Arg{
+				{Name: "destination", Type: ansible.TypeStr, Required: true, Description: "Destination of the route in CIDR notation."},
+				{Name: "gateway", Type: ansible.TypeStr, Required: true, Description: "IP the traffic is routed to."},
+			}},
+		{Name: "wait", Type: ansible.TypeBool, Default: true,
+			Description: "Wait for all actions to complete. When `false`, the module returns immediately with `action_ids`, see `hcloud_action`. " +
+				"The network is locked while an action runs, so with multiple changes to a network only the last action is not awaited."},
+	},
+	RequiredIf: []ansible.RequiredIf{
+		{Arg: "state", Value: statePresent, Required: []string{"id", "name"}, OneOf: true},
+		{Arg: "state", Value: stateAbsent, Required: []string{"id", "name"}, OneOf: true},
+	},
+}
+
+var doc = ansible.Doc{
+	Module:           "hcloud_network",
+	ShortDescription: "Manage Hetzner Cloud Networks",
+	Description: "Manages Hetzner Cloud private networks. " +
+		"This module can be used to create, modify and delete networks including their subnets and routes.",
+	Returns: []ansible.Return{
+		{
+			Name:        "networks",
+			Description: "The network, or all networks with `state=list`.",
+			Returned:    "unless `state=absent`",
+			Sample: []Network{{
+				ID:      123,
+				Name:    "internal",
+				IPRange: "10.0.0.0/16",
+				Subnets: []Subnet{{Type: "cloud", NetworkZone: "eu-central", IPRange: "10.0.1.0/24", Gateway: "10.0.0.1"}},
+				Routes:  []Route{{Destination: "10.100.0.0/16", Gateway: "10.0.1.2"}},
+				Servers: []int{42},
+			}},
+		},
+		{
+			Name:        "action_ids",
+			Description: "IDs of the pending actions, see `hcloud_action`.",
+			Returned:    "when `wait` is `false`",
+			Sample:      []int{4711},
+		},
+	},
+	Examples: `
+# create a network with a single subnet
+- hcloud_network:
+    name: internal
+    ip_range: 10.0.0.0/16
+    subnets:
+    - type: cloud
+      network_zone: eu-central
+      ip_range: 10.0.1.0/24
+
+# route a VPN range through a server and remove all other routes
+- hcloud_network:
+    name: internal
+    routes:
+    - destination: 10.100.0.0/16
+      gateway: 10.0.1.2
+
+# delete the network
+- hcloud_network:
+    name: internal
+    state: absent
+`,
+}
+
+type module struct {
+	args   arguments
+	client *hcloud.Client
+	waiter util.ActionWaiter
+}
+
+func (m *module) Args() interface{} {
+	return &m.args
+}
+
+func (m *module) ArgSpec() ansible.ArgSpec {
+	return argSpec
+}
+
+func (m *module) Doc() ansible.Doc {
+	return doc
+}
+
+func (m *module) Run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	m.client, err = hcloud.BuildClient(m.args.Token)
+	if err != nil {
+		return
+	}
+	m.waiter = util.NewActionWatcher(m.client.Action)
+	return m.run(ctx)
+}
+
+func (m *module) run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	if m.args.Wait != nil && !*m.args.Wait {
+		defer util.RecordActions(&m.waiter).Report(&resp, &err)
+	}
+
+	switch m.args.State {
+	case stateList:
+		return m.list(ctx)
+	case stateAbsent:
+		return m.absent(ctx)
+	case statePresent:
+		return m.present(ctx)
+	default:
+		err = errors.New("invalid state")
+		return
+	}
+}
+
+func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var network *hcloud.Network
+	if network, err = m.network(ctx); err != nil {
+		return
+	}
+
+	if network == nil {
+		if m.args.ID != nil {
+			err = fmt.Errorf("Network %v not found", m.args.ID)
+			return
+		}
+		opts := hcloud.NetworkCreateOpts{
+			Name:    m.args.Name,
+			IPRange: parseCIDR(m.args.IPRange),
+		}
+		for _, s := range m.args.Subnets {
+			opts.Subnets = append(opts.Subnets, toNetworkSubnet(s))
+		}
+		for _, r := range m.args.Routes {
+			opts.Routes = append(opts.Routes, toNetworkRoute(r))
+		}
+		if network, _, err = m.client.Network.Create(ctx, opts); err != nil {
+			return
+		}
+		resp.
+			Msg(fmt.Sprintf("Network %d created", network.ID)).
+			Set("networks", []Network{toNetwork(network)}).
+			Changed()
+		return
+	}
+
+	var msg []string
+	if m.args.ID != nil && m.args.Name != "" && network.Name != m.args.Name {
+		if network, _, err = m.client.Network.Update(ctx, network, hcloud.NetworkUpdateOpts{
+			Name: m.args.Name,
+		}); err != nil {
+			return
+		}
+		msg = append(msg, fmt.Sprintf("Network %d renamed", network.ID))
+		resp.Changed()
+	}
+
+	var actions []*hcloud.Action
+	if ipRange := parseCIDR(m.args.IPRange); ipRange != nil && ipRange.String() != cidr(network.IPRange) {
+		if err = m.waitForPending(ctx, network); err != nil {
+			return
+		}
+		var action *hcloud.Action
+		if action, _, err = m.client.Network.ChangeIPRange(ctx, network, ipRange); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
+			return
+		}
+		actions = append(actions, action)
+		msg = append(msg, fmt.Sprintf("Network %d IP range changed to %s", network.ID, ipRange))
+	}
+
+	// routes are removed first and added last, because their gateway must be part of a subnet
+	addRoutes, deleteRoutes := m.routeChanges(network)
+	for _, route := range deleteRoutes {
+		if err = m.waitForPending(ctx, network); err != nil {
+			return
+		}
+		var action *hcloud.Action
+		if action, _, err = m.client.Network.DeleteRoute(ctx, network, route); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
+			return
+		}
+		actions = append(actions, action)
+		msg = append(msg, fmt.Sprintf("route %s via %s deleted", route.Destination, route.Gateway))
+	}
+
+	addSubnets, deleteSubnets := m.subnetChanges(network)
+	for _, subnet := range deleteSubnets {
+		if err = m.waitForPending(ctx, network); err != nil {
+			return
+		}
+		var action *hcloud.Action
+		if action, _, err = m.client.Network.DeleteSubnet(ctx, network, subnet); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
+			return
+		}
+		actions = append(actions, action)
+		msg = append(msg, fmt.Sprintf("subnet %s deleted", subnet.IPRange))
+	}
+	for _, subnet := range addSubnets {
+		if err = m.waitForPending(ctx, network); err != nil {
+			return
+		}
+		var action *hcloud.Action
+		if action, _, err = m.client.Network.AddSubnet(ctx, network, subnet); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
+			return
+		}
+		actions = append(actions, action)
+		msg = append(msg, fmt.Sprintf("subnet %s added", subnet.IPRange))
+	}
+
+	for _, route := range addRoutes {
+		if err = m.waitForPending(ctx, network); err != nil {
+			return
+		}
+		var action *hcloud.Action
+		if action, _, err = m.client.Network.AddRoute(ctx, network, route); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
+			return
+		}
+		actions = append(actions, action)
+		msg = append(msg, fmt.Sprintf("route %s via %s added", route.Destination, route.Gateway))
+	}
+
+	if len(actions) > 0 {
+		resp.Changed()
+		if network, _, err = m.client.Network.GetByID(ctx, network.ID); err != nil {
+			return
+		}
+	}
+
+	resp.
+		Msg(strings.Join(msg, ", ")).
+		Set("networks", []Network{toNetwork(network)})
+	return
+}
+
+// waitForPending waits for the actions on the network that were not awaited because of `wait: false`,
+// the network is locked while they are running
+func (m *module) waitForPending(ctx context.Context, network *hcloud.Network) error {
+	return util.WaitForPending(ctx, m.waiter, hcloud.ActionResource{ID: network.ID, Type: hcloud.ActionResourceTypeNetwork})
+}
+
+// subnetChanges returns the subnets to add and delete,
+// changed subnets are deleted and added again
+func (m *module) subnetChanges(network *hcloud.Network) (add, del []hcloud.NetworkSubnet) {
+	if m.args.Subnets == nil {
+		return
+	}
+	desired := map[string]hcloud.NetworkSubnet{}
+	for _, s := range m.args.Subnets {
+		subnet := toNetworkSubnet(s)
+		desired[cidr(subnet.IPRange)] = subnet
+	}
+	existing := map[string]hcloud.NetworkSubnet{}
+	for _, subnet := range network.Subnets {
+		existing[cidr(subnet.IPRange)] = subnet
+		d, ok := desired[cidr(subnet.IPRange)]
+		if !ok || d.Type != subnet.Type || d.NetworkZone != subnet.NetworkZone {
+			del = append(del, subnet)
+			delete(existing, cidr(subnet.IPRange))
+		}
+	}
+	for _, s := range m.args.Subnets {
+		subnet := toNetworkSubnet(s)
+		if _, ok := existing[cidr(subnet.IPRange)]; !ok {
+			add = append(add, subnet)
+		}
+	}
+	return
+}
+
+// routeChanges returns the routes to add and delete
+func (m *module) routeChanges(network *hcloud.Network) (add, del []hcloud.NetworkRoute) {
+	if m.args.Routes == nil {
+		return
+	}
+	desired := map[string]bool{}
+	for _, r := range m.args.Routes {
+		desired[routeKey(toNetworkRoute(r))] = true
+	}
+	existing := map[string]bool{}
+	for _, route := range network.Routes {
+		existing[routeKey(route)] = true
+		if !desired[routeKey(route)] {
+			del = append(del, route)
+		}
+	}
+	for _, r := range m.args.Routes {
+		route := toNetworkRoute(r)
+		if !existing[routeKey(route)] {
+			add = append(add, route)
+		}
+	}
+	return
+}
+
+func (m *module) absent(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var network *hcloud.Network
+	if network, err = m.network(ctx); err != nil {
+		return
+	}
+	if network == nil {
+		resp.Msg("No Network found, nothing to do")
+		return
+	}
+	if _, err = m.client.Network.Delete(ctx, network); err != nil {
+		return
+	}
+	resp.Msg(fmt.Sprintf("Network %d deleted", network.ID)).Changed()
+	return
+}
+
+func (m *module) list(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var networks []*hcloud.Network
+	if networks, err = m.client.Network.All(ctx); err != nil {
+		return
+	}
+
+	list := []Network{}
+	for _, network := range networks {
+		list = append(list, toNetwork(network))
+	}
+	resp.Msg("Networks listed").Set("networks", list)
+	return
+}
+
+// network looks up the network by id or name
+func (m *module) network(ctx context.Context) (network *hcloud.Network, err error) {
+	if id := util.GetID(m.args.ID); id != 0 {
+		network, _, err = m.client.Network.GetByID(ctx, id)
+		return
+	}
+	network, _, err = m.client.Network.GetByName(ctx, m.args.Name)
+	return
+}
+
+func routeKey(route hcloud.NetworkRoute) string {
+	return cidr(route.Destination) + " " + route.Gateway.String()
+}
+
+// cidr returns the string of an IP range or an empty string
+func cidr(ipNet *net.IPNet) string {
+	if ipNet == nil {
+		return ""
+	}
+	return ipNet.String()
+}
+
+// parseCIDR returns the IP range of a CIDR string or nil if it is invalid
+func parseCIDR(s string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil
+	}
+	return ipNet
+}
+
+func toNetworkSubnet(s Subnet) hcloud.NetworkSubnet {
+	return hcloud.NetworkSubnet{
+		Type:        hcloud.NetworkSubnetType(s.Type),
+		NetworkZone: s.NetworkZone,
+		IPRange:     parseCIDR(s.IPRange),
+	}
+}
+
+func toNetworkRoute(r Route) hcloud.NetworkRoute {
+	return hcloud.NetworkRoute{
+		Destination: parseCIDR(r.Destination),
+		Gateway:     net.ParseIP(r.Gateway),
+	}
+}
+
+func toNetwork(network *hcloud.Network) Network {
+	data := Network{
+		ID:      network.ID,
+		Name:    network.Name,
+		IPRange: cidr(network.IPRange),
+		Subnets: []Subnet{},
+		Routes:  []Route{},
+		Servers: []int{},
+	}
+	for _, subnet := range network.Subnets {
+		s := Subnet{
+			Type:        string(subnet.Type),
+			NetworkZone: subnet.NetworkZone,
+			IPRange:     cidr(subnet.IPRange),
+		}
+		if subnet.Gateway != nil {
+			s.Gateway = subnet.Gateway.String()
+		}
+		data.Subnets = append(data.Subnets, s)
+	}
+	for _, route := range network.Routes {
+		data.Routes = append(data.Routes, Route{
+			Destination: cidr(route.Destination),
+			Gateway:     route.Gateway.String(),
+		})
+	}
+	for _, server := range network.Servers {
+		data.Servers = append(data.Servers, server.ID)
+	}
+	return data
+}
+
+// CheckArgs checks the constraints of the arguments the spec cannot express
+func (m *module) CheckArgs() (errs []string) {
+	if m.args.State == statePresent && m.args.ID == nil && m.args.IPRange == "" {
+		errs = append(errs, "'ip_range' is required")
+	}
+	if m.args.IPRange != "" && parseCIDR(m.args.IPRange) == nil {
+		errs = append(errs, fmt.Sprintf("'ip_range' must be a CIDR, got %q", m.args.IPRange))
+	}
+	for i, s := range m.args.Subnets {
+		if s.IPRange != "" && parseCIDR(s.IPRange) == nil {
+			errs = append(errs, fmt.Sprintf("'subnets[%d].ip_range' must be a CIDR, got %q", i, s.IPRange))
+		}
+	}
+	for i, r := range m.args.Routes {
+		if r.Destination != "" && parseCIDR(r.Destination) == nil {
+			errs = append(errs, fmt.Sprintf("'routes[%d].destination' must be a CIDR, got %q", i, r.Destination))
+		}
+		if r.Gateway != "" && net.ParseIP(r.Gateway) == nil {
+			errs = append(errs, fmt.Sprintf("'routes[%d].gateway' must be an IP, got %q", i, r.Gateway))
+		}
+	}
+	return
+}
+
+var flags = pflag.NewFlagSet("hcloud_network", pflag.ContinueOnError)
+
+func init() {
+	flags.BoolP("version", "v", false, "Print version and exit")
+}
+
+func main() {
+	ansible.RunModule(&module{}, flags)
+}
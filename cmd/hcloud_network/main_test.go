@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud/hcloudtest"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+var (
+	nilResponse *hcloud.Response
+	nilNetwork  *hcloud.Network
+
+	noWait = util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+		return nil
+	})
+)
+
+func newNetwork() *hcloud.Network {
+	return &hcloud.Network{
+		ID:      1,
+		Name:    "internal",
+		IPRange: parseCIDR("10.0.0.0/16"),
+		Subnets: []hcloud.NetworkSubnet{
+			{Type: hcloud.NetworkSubnetTypeCloud, NetworkZone: "eu-central", IPRange: parseCIDR("10.0.1.0/24"), Gateway: net.ParseIP("10.0.0.1")},
+			{Type: hcloud.NetworkSubnetTypeCloud, NetworkZone: "eu-central", IPRange: parseCIDR("10.0.2.0/24"), Gateway: net.ParseIP("10.0.0.1")},
+		},
+		Routes: []hcloud.NetworkRoute{
+			{Destination: parseCIDR("10.100.0.0/16"), Gateway: net.ParseIP("10.0.1.2")},
+		},
+		Servers: []*hcloud.Server{{ID: 42}},
+	}
+}
+
+func TestList(t *testing.T) {
+	client := hcloud.NewClient()
+	client.Network = hcloudtest.NewNetworkClientMock()
+	network := newNetwork()
+	client.Network.(*hcloudtest.NetworkClientMock).On("All", mock.Anything).Return([]*hcloud.Network{network}, nil)
+
+	m := module{client: client}
+	resp, err := m.list(context.Background())
+	if assert.NoError(t, err) {
+		assert.False(t, resp.HasChanged(), "module should not have changed")
+		assert.Equal(t, []Network{{
+			ID:      1,
+			Name:    "internal",
+			IPRange: "10.0.0.0/16",
+			Subnets: []Subnet{
+				{Type: "cloud", NetworkZone: "eu-central", IPRange: "10.0.1.0/24", Gateway: "10.0.0.1"},
+				{Type: "cloud", NetworkZone: "eu-central", IPRange: "10.0.2.0/24", Gateway: "10.0.0.1"},
+			},
+			Routes:  []Route{{Destination: "10.100.0.0/16", Gateway: "10.0.1.2"}},
+			Servers: []int{42},
+		}}, resp.Data()["networks"])
+	}
+}
+
+func TestPresent(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Network = hcloudtest.NewNetworkClientMock()
+		networkMock := client.Network.(*hcloudtest.NetworkClientMock)
+		networkMock.On("GetByName", mock.Anything, "internal").Return(nilNetwork, nilResponse, nil)
+		networkMock.On("Create", mock.Anything, hcloud.NetworkCreateOpts{
+			Name:    "internal",
+			IPRange: parseCIDR("10.0.0.0/16"),
+			Subnets: []hcloud.NetworkSubnet{
+				{Type: hcloud.NetworkSubnetTypeCloud, NetworkZone: "eu-central", IPRange: parseCIDR("10.0.1.0/24")},
+			},
+		}).Return(newNetwork(), nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args: arguments{
+				State:   statePresent,
+				Name:    "internal",
+				IPRange: "10.0.0.0/16",
+				Subnets: []Subnet{{Type: "cloud", NetworkZone: "eu-central", IPRange: "10.0.1.0/24"}},
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Network = hcloudtest.NewNetworkClientMock()
+		networkMock := client.Network.(*hcloudtest.NetworkClientMock)
+		networkMock.On("GetByName", mock.Anything, "internal").Return(newNetwork(), nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args: arguments{
+				State:   statePresent,
+				Name:    "internal",
+				IPRange: "10.0.0.0/16",
+				Subnets: []Subnet{
+					{Type: "cloud", NetworkZone: "eu-central", IPRange: "10.0.2.0/24"},
+					{Type: "cloud", NetworkZone: "eu-central", IPRange: "10.0.1.0/24"},
+				},
+				Routes: []Route{{Destination: "10.100.0.0/16", Gateway: "10.0.1.2"}},
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.False(t, resp.HasChanged(), "module should not have changed")
+		}
+	})
+
+	t.Run("reconcile subnets and routes", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Network = hcloudtest.NewNetworkClientMock()
+		network := newNetwork()
+		networkMock := client.Network.(*hcloudtest.NetworkClientMock)
+		networkMock.On("GetByID", mock.Anything, 1).Return(network, nilResponse, nil)
+		networkMock.On("DeleteRoute", mock.Anything, network, network.Routes[0]).Return(networkAction(1), nilResponse, nil)
+		networkMock.On("DeleteSubnet", mock.Anything, network, network.Subnets[1]).Return(networkAction(2), nilResponse, nil)
+		networkMock.On("AddSubnet", mock.Anything, network, hcloud.NetworkSubnet{
+			Type: hcloud.NetworkSubnetTypeCloud, NetworkZone: "eu-central", IPRange: parseCIDR("10.0.3.0/24"),
+		}).Return(networkAction(3), nilResponse, nil)
+		networkMock.On("AddRoute", mock.Anything, network, hcloud.NetworkRoute{
+			Destination: parseCIDR("10.200.0.0/16"), Gateway: net.ParseIP("10.0.3.2"),
+		}).Return(networkAction(4), nilResponse, nil)
+
+		// with wait: false each action is awaited before the next one is started,
+		// only the last one is left pending
+		var waited []int
+		m := module{
+			client: client,
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				for _, action := range actions {
+					waited = append(waited, action.ID)
+				}
+				return nil
+			}),
+			args: arguments{
+				State: statePresent,
+				ID:    1,
+				Subnets: []Subnet{
+					{Type: "cloud", NetworkZone: "eu-central", IPRange: "10.0.1.0/24"},
+					{Type: "cloud", NetworkZone: "eu-central", IPRange: "10.0.3.0/24"},
+				},
+				Routes: []Route{{Destination: "10.200.0.0/16", Gateway: "10.0.3.2"}},
+				Wait:   hcloud.Bool(false),
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			assert.Equal(t, []int{1, 2, 3}, waited)
+			assert.Equal(t, []int{4}, resp.Data()["action_ids"])
+		}
+	})
+
+	t.Run("empty list removes all routes", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Network = hcloudtest.NewNetworkClientMock()
+		network := newNetwork()
+		networkMock := client.Network.(*hcloudtest.NetworkClientMock)
+		networkMock.On("GetByName", mock.Anything, "internal").Return(network, nilResponse, nil)
+		networkMock.On("GetByID", mock.Anything, 1).Return(network, nilResponse, nil)
+		networkMock.On("DeleteRoute", mock.Anything, network, network.Routes[0]).Return(&hcloud.Action{ID: 1}, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: statePresent, Name: "internal", IPRange: "10.0.0.0/16", Routes: []Route{}},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			networkMock.AssertNotCalled(t, "DeleteSubnet", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+}
+
+func networkAction(id int) *hcloud.Action {
+	return &hcloud.Action{
+		ID:        id,
+		Status:    hcloud.ActionStatusRunning,
+		Resources: []*hcloud.ActionResource{{ID: 1, Type: hcloud.ActionResourceTypeNetwork}},
+	}
+}
+
+func TestAbsent(t *testing.T) {
+	client := hcloud.NewClient()
+	client.Network = hcloudtest.NewNetworkClientMock()
+	network := newNetwork()
+	networkMock := client.Network.(*hcloudtest.NetworkClientMock)
+	networkMock.On("GetByName", mock.Anything, "internal").Return(network, nilResponse, nil)
+	networkMock.On("Delete", mock.Anything, network).Return(nilResponse, nil)
+
+	m := module{
+		client: client,
+		waiter: noWait,
+		args:   arguments{State: stateAbsent, Name: "internal"},
+	}
+	resp, err := m.run(context.Background())
+	if assert.NoError(t, err) {
+		assert.True(t, resp.HasChanged(), "module should have changed")
+		networkMock.AssertCalled(t, "Delete", mock.Anything, network)
+	}
+}
+
+func TestValidateArgs(t *testing.T) {
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: stateList}}))
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "internal", IPRange: "10.0.0.0/16"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "internal"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "internal", IPRange: "10.0.0.0"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, ID: 1, Subnets: []Subnet{{Type: "vlan", IPRange: "10.0.1.0/24"}}}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, ID: 1, Routes: []Route{{Destination: "10.1.0.0/16", Gateway: "gw"}}}}))
+}
+
+func TestDoc(t *testing.T) {
+	data, err := ioutil.ReadFile("../../docs/hcloud_network.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(data), doc.Markdown(argSpec), "docs/hcloud_network.md is outdated, run make docs")
+	}
+}
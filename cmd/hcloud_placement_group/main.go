@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/pflag"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+const (
+	stateAbsent  = "absent"
+	statePresent = "present"
+	stateList    = "list"
+)
+
+// PlacementGroup is the module return value of an hcloud.PlacementGroup
+type PlacementGroup struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Servers []int  `json:"servers"`
+}
+
+type arguments struct {
+	Token string `json:"token"`
+	State string `json:"state"`
+
+	ID   interface{} `json:"id"`
+	Name string      `json:"name"`
+	Type string      `json:"type"`
+	Wait *bool       `json:"wait"`
+}
+
+var argSpec = ansible.ArgSpec{
+	Args: []ansible.Arg{
+		ansible.TokenArg(),
+		{Name: "state", Type: ansible.TypeStr, Default: statePresent, Choices: []string{statePresent, stateAbsent, stateList},
+			Description: "`list` lists all existing placement groups."},
+		{Name: "id", Type: ansible.TypeRaw,
+			Description: "ID of the placement group.\n\n`id` or `name` is required when `state=present` or `state=absent`."},
+		{Name: "name", Type: ansible.TypeStr,
+			Description: "Name of the placement group, used to find the group when `id` is not specified. The group is renamed when both are given."},
+		{Name: "type", Type: ansible.TypeStr, Default: string(hcloud.PlacementGroupTypeSpread),
+			Choices:     []string{string(hcloud.PlacementGroupTypeSpread)},
+			Description: "Type of the placement group, cannot be changed after creation."},
+		{Name: "wait", Type: ansible.TypeBool, Default: true,
+			Description: "Wait for all actions to complete. When `false`, the module returns immediately with `action_ids`, see `hcloud_action`."},
+	},
+	RequiredIf: []ansible.RequiredIf{
+		{Arg: "state", Value: statePresent, Required: []string{"id", "name"}, OneOf: true},
+		{Arg: "state", Value: stateAbsent, Required: []string{"id", "name"}, OneOf: true},
+	},
+}
+
+var doc = ansible.Doc{
+	Module:           "hcloud_placement_group",
+	ShortDescription: "Spread servers across physical hosts",
+	Description: "Manages Hetzner Cloud placement groups. Servers in a placement group of type `spread` are placed on different physical hosts, " +
+		"use the `placement_group` option of `hcloud_server` to add servers to a group.",
+	Returns: []ansible.Return{
+		{
+			Name:        "placement_groups",
+			Description: "The placement group, or all placement groups with `state=list`.",
+			Returned:    "unless `state=absent`",
+			Sample:      []PlacementGroup{{ID: 123, Name: "db", Type: "spread", Servers: []int{42, 43}}},
+		},
+		{
+			Name:        "action_ids",
+			Description: "IDs of the pending actions, see `hcloud_action`.",
+			Returned:    "when `wait` is `false`",
+			Sample:      []int{4711},
+		},
+	},
+	Examples: `
+# create a placement group
+- hcloud_placement_group:
+    name: db
+
+# create three servers on different hosts
+- hcloud_server:
+    name: [db1, db2, db3]
+    image: debian-9
+    server_type: cx11
+    placement_group: db
+
+# delete the placement group
+- hcloud_placement_group:
+    name: db
+    state: absent
+
+# list all placement groups
+- hcloud_placement_group:
+    state: list
+  register: placement_groups
+`,
+}
+
+type module struct {
+	args   arguments
+	client *hcloud.Client
+	waiter util.ActionWaiter
+}
+
+func (m *module) Args() interface{} {
+	return &m.args
+}
+
+func (m *module) ArgSpec() ansible.ArgSpec {
+	return argSpec
+}
+
+func (m *module) Doc() ansible.Doc {
+	return doc
+}
+
+func (m *module) Run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	m.client, err = hcloud.BuildClient(m.args.Token)
+	if err != nil {
+		return
+	}
+	m.waiter = util.NewActionWatcher(m.client.Action)
+	return m.run(ctx)
+}
+
+func (m *module) run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	if m.args.Wait != nil && !*m.args.Wait {
+		defer util.RecordActions(&m.waiter).Report(&resp, &err)
+	}
+
+	switch m.args.State {
+	case stateList:
+		return m.list(ctx)
+	case stateAbsent:
+		return m.absent(ctx)
+	case statePresent:
+		return m.present(ctx)
+	default:
+		err = errors.New("invalid state")
+		return
+	}
+}
+
+func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var placementGroup *hcloud.PlacementGroup
+	if placementGroup, err = m.placementGroup(ctx); err != nil {
+		return
+	}
+
+	if placementGroup == nil {
+		if m.args.ID != nil {
+			err = fmt.Errorf("Placement group %v not found", m.args.ID)
+			return
+		}
+		var result hcloud.PlacementGroupCreateResult
+		if result, _, err = m.client.PlacementGroup.Create(ctx, hcloud.PlacementGroupCreateOpts{
+			Name: m.args.Name,
+			Type: hcloud.PlacementGroupType(m.args.Type),
+		}); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, result.Action); err != nil {
+			return
+		}
+		resp.
+			Msg(fmt.Sprintf("Placement group %d created", result.PlacementGroup.ID)).
+			Set("placement_groups", []PlacementGroup{toPlacementGroup(result.PlacementGroup)}).
+			Changed()
+		return
+	}
+
+	if m.args.Type != "" && string(placementGroup.Type) != m.args.Type {
+		err = fmt.Errorf("Placement group %d is of type %s, the type cannot be changed", placementGroup.ID, placementGroup.Type)
+		return
+	}
+
+	if m.args.ID != nil && m.args.Name != "" && placementGroup.Name != m.args.Name {
+		if placementGroup, _, err = m.client.PlacementGroup.Update(ctx, placementGroup, hcloud.PlacementGroupUpdateOpts{
+			Name: m.args.Name,
+		}); err != nil {
+			return
+		}
+		resp.Msg(fmt.Sprintf("Placement group %d renamed", placementGroup.ID)).Changed()
+	} else {
+		resp.Msg(fmt.Sprintf("Placement group %d already exists, nothing to do", placementGroup.ID))
+	}
+	resp.Set("placement_groups", []PlacementGroup{toPlacementGroup(placementGroup)})
+	return
+}
+
+func (m *module) absent(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var placementGroup *hcloud.PlacementGroup
+	if placementGroup, err = m.placementGroup(ctx); err != nil {
+		return
+	}
+	if placementGroup == nil {
+		resp.Msg("No placement group found, nothing to do")
+		return
+	}
+	if _, err = m.client.PlacementGroup.Delete(ctx, placementGroup); err != nil {
+		return
+	}
+	resp.Msg(fmt.Sprintf("Placement group %d deleted", placementGroup.ID)).Changed()
+	return
+}
+
+func (m *module) list(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var placementGroups []*hcloud.PlacementGroup
+	if placementGroups, err = m.client.PlacementGroup.All(ctx); err != nil {
+		return
+	}
+	list := []PlacementGroup{}
+	for _, placementGroup := range placementGroups {
+		list = append(list, toPlacementGroup(placementGroup))
+	}
+	resp.Msg("Placement groups listed").Set("placement_groups", list)
+	return
+}
+
+// placementGroup looks up the placement group by id or name
+func (m *module) placementGroup(ctx context.Context) (placementGroup *hcloud.PlacementGroup, err error) {
+	if id := util.GetID(m.args.ID); id != 0 {
+		placementGroup, _, err = m.client.PlacementGroup.GetByID(ctx, id)
+		return
+	}
+	placementGroup, _, err = m.client.PlacementGroup.GetByName(ctx, m.args.Name)
+	return
+}
+
+func toPlacementGroup(placementGroup *hcloud.PlacementGroup) PlacementGroup {
+	data := PlacementGroup{
+		ID:      placementGroup.ID,
+		Name:    placementGroup.Name,
+		Type:    string(placementGroup.Type),
+		Servers: []int{},
+	}
+	data.Servers = append(data.Servers, placementGroup.Servers...)
+	return data
+}
+
+// CheckArgs checks the constraints of the arguments the spec cannot express
+func (m *module) CheckArgs() (errs []string) {
+	return
+}
+
+var flags = pflag.NewFlagSet("hcloud_placement_group", pflag.ContinueOnError)
+
+func init() {
+	flags.BoolP("version", "v", false, "Print version and exit")
+}
+
+func main() {
+	ansible.RunModule(&module{}, flags)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud/hcloudtest"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+var (
+	nilResponse       *hcloud.Response
+	nilPlacementGroup *hcloud.PlacementGroup
+
+	noWait = util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+		return nil
+	})
+)
+
+func newPlacementGroup() *hcloud.PlacementGroup {
+	return &hcloud.PlacementGroup{
+		ID:      1,
+		Name:    "db",
+		Type:    hcloud.PlacementGroupTypeSpread,
+		Servers: []int{42, 43},
+	}
+}
+
+func TestList(t *testing.T) {
+	client := hcloud.NewClient()
+	client.PlacementGroup = hcloudtest.NewPlacementGroupClientMock()
+	client.PlacementGroup.(*hcloudtest.PlacementGroupClientMock).On("All", mock.Anything).Return([]*hcloud.PlacementGroup{newPlacementGroup()}, nil)
+
+	m := module{client: client}
+	resp, err := m.list(context.Background())
+	if assert.NoError(t, err) {
+		assert.False(t, resp.HasChanged(), "module should not have changed")
+		assert.Equal(t, []PlacementGroup{
+			{ID: 1, Name: "db", Type: "spread", Servers: []int{42, 43}},
+		}, resp.Data()["placement_groups"])
+	}
+}
+
+func TestPresent(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.PlacementGroup = hcloudtest.NewPlacementGroupClientMock()
+		placementGroupMock := client.PlacementGroup.(*hcloudtest.PlacementGroupClientMock)
+		placementGroupMock.On("GetByName", mock.Anything, "db").Return(nilPlacementGroup, nilResponse, nil)
+		placementGroupMock.On("Create", mock.Anything, hcloud.PlacementGroupCreateOpts{
+			Name: "db",
+			Type: hcloud.PlacementGroupTypeSpread,
+		}).Return(hcloud.PlacementGroupCreateResult{
+			PlacementGroup: &hcloud.PlacementGroup{ID: 1, Name: "db", Type: hcloud.PlacementGroupTypeSpread},
+		}, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: statePresent, Name: "db", Type: string(hcloud.PlacementGroupTypeSpread)},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			assert.Equal(t, []PlacementGroup{
+				{ID: 1, Name: "db", Type: "spread", Servers: []int{}},
+			}, resp.Data()["placement_groups"])
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.PlacementGroup = hcloudtest.NewPlacementGroupClientMock()
+		client.PlacementGroup.(*hcloudtest.PlacementGroupClientMock).On("GetByName", mock.Anything, "db").Return(newPlacementGroup(), nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: statePresent, Name: "db", Type: "spread"},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.False(t, resp.HasChanged(), "module should not have changed")
+		}
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.PlacementGroup = hcloudtest.NewPlacementGroupClientMock()
+		placementGroupMock := client.PlacementGroup.(*hcloudtest.PlacementGroupClientMock)
+		placementGroup := newPlacementGroup()
+		placementGroupMock.On("GetByID", mock.Anything, 1).Return(placementGroup, nilResponse, nil)
+		placementGroupMock.On("Update", mock.Anything, placementGroup, hcloud.PlacementGroupUpdateOpts{Name: "database"}).
+			Return(&hcloud.PlacementGroup{ID: 1, Name: "database", Type: hcloud.PlacementGroupTypeSpread}, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: statePresent, ID: float64(1), Name: "database"},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			placementGroupMock.AssertNumberOfCalls(t, "Update", 1)
+		}
+	})
+}
+
+func TestAbsent(t *testing.T) {
+	client := hcloud.NewClient()
+	client.PlacementGroup = hcloudtest.NewPlacementGroupClientMock()
+	placementGroupMock := client.PlacementGroup.(*hcloudtest.PlacementGroupClientMock)
+	placementGroup := newPlacementGroup()
+	placementGroupMock.On("GetByName", mock.Anything, "db").Return(placementGroup, nilResponse, nil)
+	placementGroupMock.On("Delete", mock.Anything, placementGroup).Return(nilResponse, nil)
+
+	m := module{
+		client: client,
+		waiter: noWait,
+		args:   arguments{State: stateAbsent, Name: "db"},
+	}
+	resp, err := m.run(context.Background())
+	if assert.NoError(t, err) {
+		assert.True(t, resp.HasChanged(), "module should have changed")
+		placementGroupMock.AssertNumberOfCalls(t, "Delete", 1)
+	}
+}
+
+func TestValidateArgs(t *testing.T) {
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "db"}}))
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: stateList}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "db", Type: "cluster"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: "running", Name: "db"}}))
+}
+
+func TestDoc(t *testing.T) {
+	data, err := ioutil.ReadFile("../../docs/hcloud_placement_group.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(data), doc.Markdown(argSpec), "docs/hcloud_placement_group.md is outdated, run make docs")
+	}
+}
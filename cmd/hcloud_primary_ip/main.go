@@ -0,0 +1,464 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+const (
+	stateAbsent  = "absent"
+	statePresent = "present"
+	stateList    = "list"
+)
+
+// PrimaryIP is the module return value of an hcloud.PrimaryIP
+type PrimaryIP struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	IP         string `json:"ip"`
+	Datacenter string `json:"datacenter"`
+	Server     int    `json:"server"`
+	AutoDelete bool   `json:"auto_delete"`
+	Protected  bool   `json:"protected"`
+}
+
+type arguments struct {
+	Token string `json:"token"`
+	State string `json:"state"`
+
+	ID         interface{} `json:"id"`
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Datacenter string      `json:"datacenter"`
+	// Server is the id or name of the server the IP is assigned to, "" unassigns the IP
+	Server     interface{} `json:"server"`
+	AutoDelete *bool       `json:"auto_delete"`
+	Protected  *bool       `json:"protected"`
+	Wait       *bool       `json:"wait"`
+}
+
+var argSpec = ansible.ArgSpec{
+	Args: []ansible.Arg{
+		ansible.TokenArg(),
+		{Name: "state", Type: ansible.TypeStr, Default: statePresent, Choices: []string{statePresent, stateAbsent, stateList},
+			Description: "`list` lists all existing primary IPs."},
+		{Name: "id", Type: ansible.TypeRaw,
+			Description: "ID of the primary IP.\n\n`id` or `name` is required when `state=present` or `state=absent`."},
+		{Name: "name", Type: ansible.TypeStr,
+			Description: "Name of the primary IP, used to find the primary IP when `id` is not specified. The primary IP is renamed when both are given."},
+		{Name: "type", Type: ansible.TypeStr, Choices: []string{string(hcloud.PrimaryIPTypeIPv4), string(hcloud.PrimaryIPTypeIPv6)},
+			Description: "Required to create a primary IP, cannot be changed."},
+		{Name: "datacenter", Type: ansible.TypeStr,
+			Description: "Name or id of the datacenter to create the primary IP in. Required to create an unassigned primary IP, mutually exclusive with `server`. Cannot be changed."},
+		{Name: "server", Type: ansible.TypeRaw,
+			Description: "ID or name of the server to assign the primary IP to, an empty string unassigns it. " +
+				"The server must be stopped, see `hcloud_server` for assigning primary IPs to running servers. The assignment is kept if not set."},
+		{Name: "auto_delete", Type: ansible.TypeBool,
+			Description: "Delete the primary IP together with the server it is assigned to. New primary IPs are not deleted with their server if not set."},
+		{Name: "protected", Type: ansible.TypeBool,
+			Description: "Protect the primary IP against deletion."},
+		{Name: "wait", Type: ansible.TypeBool, Default: true,
+			Description: "Wait for all actions to complete. When `false`, the module returns immediately with `action_ids`, see `hcloud_action`. " +
+				"The primary IP is locked while an action runs, so with multiple changes to a primary IP only the last action is not awaited."},
+	},
+	RequiredIf: []ansible.RequiredIf{
+		{Arg: "state", Value: statePresent, Required: []string{"id", "name"}, OneOf: true},
+		{Arg: "state", Value: stateAbsent, Required: []string{"id", "name"}, OneOf: true},
+	},
+	MutuallyExclusive: [][]string{{"datacenter", "server"}},
+}
+
+var doc = ansible.Doc{
+	Module:           "hcloud_primary_ip",
+	ShortDescription: "Manage Primary IPs",
+	Description: "Manages Hetzner Cloud primary IPs, the public IPv4 addresses and IPv6 networks of servers. " +
+		"Primary IPs are bound to a datacenter and can be kept when a server is deleted, " +
+		"use the `primary_ipv4` and `primary_ipv6` options of `hcloud_server` to create servers with them.",
+	Returns: []ansible.Return{
+		{
+			Name:        "primary_ips",
+			Description: "The primary IP, or all primary IPs with `state=list`. `server` is `0` for unassigned primary IPs.",
+			Returned:    "unless `state=absent`",
+			Sample: []PrimaryIP{
+				{ID: 123, Name: "web-v4", Type: "ipv4", IP: "203.0.113.1", Datacenter: "fsn1-dc14", Server: 42, Protected: true},
+				{ID: 124, Name: "web-v6", Type: "ipv6", IP: "2001:db8::/64", Datacenter: "fsn1-dc14"},
+			},
+		},
+		{
+			Name:        "action_ids",
+			Description: "IDs of the pending actions, see `hcloud_action`.",
+			Returned:    "when `wait` is `false`",
+			Sample:      []int{4711},
+		},
+	},
+	Examples: `
+# allocate a protected IPv4 address
+- hcloud_primary_ip:
+    name: web-v4
+    type: ipv4
+    datacenter: fsn1-dc14
+    protected: true
+
+# create a server with it, the address survives recreation of the server
+- hcloud_server:
+    name: web1
+    image: debian-9
+    server_type: cx11
+    datacenter: fsn1-dc14
+    primary_ipv4: web-v4
+
+# move the address to a stopped server
+- hcloud_primary_ip:
+    name: web-v4
+    server: web2
+
+# delete the primary IP
+- hcloud_primary_ip:
+    name: web-v4
+    protected: false
+- hcloud_primary_ip:
+    name: web-v4
+    state: absent
+
+# list all primary IPs
+- hcloud_primary_ip:
+    state: list
+  register: primary_ips
+`,
+}
+
+type module struct {
+	args   arguments
+	client *hcloud.Client
+	waiter util.ActionWaiter
+}
+
+func (m *module) Args() interface{} {
+	return &m.args
+}
+
+func (m *module) ArgSpec() ansible.ArgSpec {
+	return argSpec
+}
+
+func (m *module) Doc() ansible.Doc {
+	return doc
+}
+
+func (m *module) Run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	m.client, err = hcloud.BuildClient(m.args.Token)
+	if err != nil {
+		return
+	}
+	m.waiter = util.NewActionWatcher(m.client.Action)
+	return m.run(ctx)
+}
+
+func (m *module) run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	if m.args.Wait != nil && !*m.args.Wait {
+		defer util.RecordActions(&m.waiter).Report(&resp, &err)
+	}
+
+	switch m.args.State {
+	case stateList:
+		return m.list(ctx)
+	case stateAbsent:
+		return m.absent(ctx)
+	case statePresent:
+		return m.present(ctx)
+	default:
+		err = errors.New("invalid state")
+		return
+	}
+}
+
+func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var primaryIP *hcloud.PrimaryIP
+	if primaryIP, err = m.primaryIP(ctx); err != nil {
+		return
+	}
+	var server *hcloud.Server
+	if server, err = m.server(ctx); err != nil {
+		return
+	}
+
+	var msg []string
+	if primaryIP == nil {
+		if primaryIP, err = m.create(ctx, server); err != nil {
+			return
+		}
+		msg = append(msg, fmt.Sprintf("Primary IP %d created", primaryIP.ID))
+		resp.Changed()
+	} else {
+		if m.args.Type != "" && string(primaryIP.Type) != m.args.Type {
+			err = fmt.Errorf("Primary IP %d is of type %s, the type cannot be changed", primaryIP.ID, primaryIP.Type)
+			return
+		}
+		if m.args.Datacenter != "" && primaryIP.Datacenter.Name != m.args.Datacenter {
+			err = fmt.Errorf("Primary IP %d is located in %s, the datacenter cannot be changed", primaryIP.ID, primaryIP.Datacenter.Name)
+			return
+		}
+
+		opts := hcloud.PrimaryIPUpdateOpts{}
+		if m.args.ID != nil && m.args.Name != "" && primaryIP.Name != m.args.Name {
+			opts.Name = m.args.Name
+		}
+		if m.args.AutoDelete != nil && primaryIP.AutoDelete != *m.args.AutoDelete {
+			opts.AutoDelete = m.args.AutoDelete
+		}
+		if opts.Name != "" || opts.AutoDelete != nil {
+			if primaryIP, _, err = m.client.PrimaryIP.Update(ctx, primaryIP, opts); err != nil {
+				return
+			}
+			msg = append(msg, fmt.Sprintf("Primary IP %d updated", primaryIP.ID))
+			resp.Changed()
+		}
+
+		var assigned bool
+		if assigned, err = m.ensureAssignment(ctx, primaryIP, server); err != nil {
+			return
+		}
+		if assigned {
+			msg = append(msg, fmt.Sprintf("Primary IP %d assignment changed", primaryIP.ID))
+			resp.Changed()
+		}
+	}
+
+	if m.args.Protected != nil && primaryIP.Protection.Delete != *m.args.Protected {
+		if err = m.waitForPending(ctx, primaryIP); err != nil {
+			return
+		}
+		var action *hcloud.Action
+		protection := hcloud.PrimaryIPProtection{Delete: *m.args.Protected}
+		if action, _, err = m.client.PrimaryIP.ChangeProtection(ctx, primaryIP, protection); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
+			return
+		}
+		primaryIP.Protection = protection
+		msg = append(msg, fmt.Sprintf("Primary IP %d protection changed", primaryIP.ID))
+		resp.Changed()
+	}
+
+	if len(msg) == 0 {
+		msg = append(msg, fmt.Sprintf("Primary IP %d already exists, nothing to do", primaryIP.ID))
+	}
+	resp.
+		Msg(strings.Join(msg, ", ")).
+		Set("primary_ips", []PrimaryIP{toPrimaryIP(primaryIP)})
+	return
+}
+
+func (m *module) create(ctx context.Context, server *hcloud.Server) (*hcloud.PrimaryIP, error) {
+	if m.args.ID != nil {
+		return nil, fmt.Errorf("Primary IP %v not found", m.args.ID)
+	}
+	var errs []string
+	if m.args.Type == "" {
+		errs = append(errs, "'type' is required")
+	}
+	if server == nil && m.args.Datacenter == "" {
+		errs = append(errs, "'datacenter' or 'server' is required")
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("Cannot create primary IP '%s': %s", m.args.Name, strings.Join(errs, ", "))
+	}
+	opts := hcloud.PrimaryIPCreateOpts{
+		Name: m.args.Name,
+		Type: hcloud.PrimaryIPType(m.args.Type),
+	}
+	if m.args.AutoDelete != nil {
+		opts.AutoDelete = *m.args.AutoDelete
+	}
+	if server != nil {
+		if err := checkStopped(server); err != nil {
+			return nil, err
+		}
+		opts.Assignee = server
+	} else {
+		datacenter, _, err := m.client.Datacenter.Get(ctx, m.args.Datacenter)
+		if err != nil {
+			return nil, err
+		}
+		if datacenter == nil {
+			return nil, fmt.Errorf("Datacenter '%s' not found", m.args.Datacenter)
+		}
+		opts.Datacenter = datacenter
+	}
+
+	result, _, err := m.client.PrimaryIP.Create(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.waiter.WaitForActions(ctx, result.Action); err != nil {
+		return nil, err
+	}
+	return result.PrimaryIP, nil
+}
+
+// ensureAssignment assigns the primary IP to the server or unassigns it, if the server argument is set.
+// Primary IPs can only be assigned to and unassigned from stopped servers.
+func (m *module) ensureAssignment(ctx context.Context, primaryIP *hcloud.PrimaryIP, server *hcloud.Server) (changed bool, err error) {
+	if m.args.Server == nil {
+		return
+	}
+	desired := 0
+	if server != nil {
+		desired = server.ID
+	}
+	if primaryIP.AssigneeID == desired {
+		return
+	}
+
+	if primaryIP.AssigneeID != 0 {
+		var current *hcloud.Server
+		if current, _, err = m.client.Server.GetByID(ctx, primaryIP.AssigneeID); err != nil {
+			return
+		}
+		if current != nil {
+			if err = checkStopped(current); err != nil {
+				return
+			}
+		}
+		var action *hcloud.Action
+		if action, _, err = m.client.PrimaryIP.Unassign(ctx, primaryIP); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
+			return
+		}
+		primaryIP.AssigneeID = 0
+	}
+	if server != nil {
+		if err = checkStopped(server); err != nil {
+			return
+		}
+		if err = m.waitForPending(ctx, primaryIP); err != nil {
+			return
+		}
+		var action *hcloud.Action
+		if action, _, err = m.client.PrimaryIP.Assign(ctx, primaryIP, server); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
+			return
+		}
+		primaryIP.AssigneeID = server.ID
+	}
+	return true, nil
+}
+
+func checkStopped(server *hcloud.Server) error {
+	if server.Status != hcloud.ServerStatusOff {
+		return fmt.Errorf("Server %d must be stopped to change its primary IPs", server.ID)
+	}
+	return nil
+}
+
+func (m *module) absent(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var primaryIP *hcloud.PrimaryIP
+	if primaryIP, err = m.primaryIP(ctx); err != nil {
+		return
+	}
+	if primaryIP == nil {
+		resp.Msg("No primary IP found, nothing to do")
+		return
+	}
+	if primaryIP.Protection.Delete {
+		err = fmt.Errorf("Primary IP %d is protected against deletion, set protected to false first", primaryIP.ID)
+		return
+	}
+	if _, err = m.client.PrimaryIP.Delete(ctx, primaryIP); err != nil {
+		return
+	}
+	resp.Msg(fmt.Sprintf("Primary IP %d deleted", primaryIP.ID)).Changed()
+	return
+}
+
+func (m *module) list(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var primaryIPs []*hcloud.PrimaryIP
+	if primaryIPs, err = m.client.PrimaryIP.All(ctx); err != nil {
+		return
+	}
+	list := []PrimaryIP{}
+	for _, primaryIP := range primaryIPs {
+		list = append(list, toPrimaryIP(primaryIP))
+	}
+	resp.Msg("Primary IPs listed").Set("primary_ips", list)
+	return
+}
+
+// waitForPending waits for the recorded actions on the primary IP with `wait: false`,
+// the primary IP is locked until they complete
+func (m *module) waitForPending(ctx context.Context, primaryIP *hcloud.PrimaryIP) error {
+	return util.WaitForPending(ctx, m.waiter, hcloud.ActionResource{ID: primaryIP.ID, Type: hcloud.ActionResourceTypePrimaryIP})
+}
+
+// primaryIP looks up the primary IP by id or name
+func (m *module) primaryIP(ctx context.Context) (primaryIP *hcloud.PrimaryIP, err error) {
+	if id := util.GetID(m.args.ID); id != 0 {
+		primaryIP, _, err = m.client.PrimaryIP.GetByID(ctx, id)
+		return
+	}
+	primaryIP, _, err = m.client.PrimaryIP.GetByName(ctx, m.args.Name)
+	return
+}
+
+// server looks up the server argument, nil if it is not set or empty
+func (m *module) server(ctx context.Context) (server *hcloud.Server, err error) {
+	idOrName := util.GetIdentifier(m.args.Server)
+	if idOrName == "" {
+		return
+	}
+	if server, _, err = m.client.Server.Get(ctx, idOrName); err != nil {
+		return
+	}
+	if server == nil {
+		err = fmt.Errorf("Server '%s' not found", idOrName)
+	}
+	return
+}
+
+func toPrimaryIP(primaryIP *hcloud.PrimaryIP) PrimaryIP {
+	data := PrimaryIP{
+		ID:         primaryIP.ID,
+		Name:       primaryIP.Name,
+		Type:       string(primaryIP.Type),
+		Server:     primaryIP.AssigneeID,
+		AutoDelete: primaryIP.AutoDelete,
+		Protected:  primaryIP.Protection.Delete,
+	}
+	if primaryIP.Network != nil {
+		data.IP = primaryIP.Network.String()
+	} else if primaryIP.IP != nil {
+		data.IP = primaryIP.IP.String()
+	}
+	if primaryIP.Datacenter != nil {
+		data.Datacenter = primaryIP.Datacenter.Name
+	}
+	return data
+}
+
+// CheckArgs checks the constraints of the arguments the spec cannot express
+func (m *module) CheckArgs() (errs []string) {
+	return
+}
+
+var flags = pflag.NewFlagSet("hcloud_primary_ip", pflag.ContinueOnError)
+
+func init() {
+	flags.BoolP("version", "v", false, "Print version and exit")
+}
+
+func main() {
+	ansible.RunModule(&module{}, flags)
+}
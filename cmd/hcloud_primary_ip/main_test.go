@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud/hcloudtest"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+var (
+	nilResponse  *hcloud.Response
+	nilPrimaryIP *hcloud.PrimaryIP
+
+	noWait = util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+		return nil
+	})
+)
+
+func newPrimaryIP() *hcloud.PrimaryIP {
+	return &hcloud.PrimaryIP{
+		ID:         1,
+		Name:       "web",
+		Type:       hcloud.PrimaryIPTypeIPv4,
+		IP:         net.ParseIP("203.0.113.1"),
+		Datacenter: &hcloud.Datacenter{Name: "fsn1-dc14"},
+		AssigneeID: 42,
+	}
+}
+
+func TestList(t *testing.T) {
+	client := hcloud.NewClient()
+	client.PrimaryIP = hcloudtest.NewPrimaryIPClientMock()
+	_, network, _ := net.ParseCIDR("2001:db8::/64")
+	client.PrimaryIP.(*hcloudtest.PrimaryIPClientMock).On("All", mock.Anything).Return([]*hcloud.PrimaryIP{
+		newPrimaryIP(),
+		{ID: 2, Name: "web-v6", Type: hcloud.PrimaryIPTypeIPv6, IP: network.IP, Network: network, Datacenter: &hcloud.Datacenter{Name: "fsn1-dc14"}},
+	}, nil)
+
+	m := module{client: client}
+	resp, err := m.list(context.Background())
+	if assert.NoError(t, err) {
+		assert.Equal(t, []PrimaryIP{
+			{ID: 1, Name: "web", Type: "ipv4", IP: "203.0.113.1", Datacenter: "fsn1-dc14", Server: 42},
+			{ID: 2, Name: "web-v6", Type: "ipv6", IP: "2001:db8::/64", Datacenter: "fsn1-dc14"},
+		}, resp.Data()["primary_ips"])
+	}
+}
+
+func TestPresent(t *testing.T) {
+	t.Run("create protected", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.PrimaryIP = hcloudtest.NewPrimaryIPClientMock()
+		client.Datacenter = hcloudtest.NewDatacenterClientMock()
+		datacenter := &hcloud.Datacenter{ID: 4, Name: "fsn1-dc14"}
+		client.Datacenter.(*hcloudtest.DatacenterClientMock).On("Get", mock.Anything, "fsn1-dc14").Return(datacenter, nilResponse, nil)
+
+		primaryIP := newPrimaryIP()
+		primaryIP.AssigneeID = 0
+		primaryIPMock := client.PrimaryIP.(*hcloudtest.PrimaryIPClientMock)
+		primaryIPMock.On("GetByName", mock.Anything, "web").Return(nilPrimaryIP, nilResponse, nil)
+		primaryIPMock.On("Create", mock.Anything, hcloud.PrimaryIPCreateOpts{
+			Name:       "web",
+			Type:       hcloud.PrimaryIPTypeIPv4,
+			Datacenter: datacenter,
+		}).Return(hcloud.PrimaryIPCreateResult{PrimaryIP: primaryIP}, nilResponse, nil)
+		primaryIPMock.On("ChangeProtection", mock.Anything, primaryIP, hcloud.PrimaryIPProtection{Delete: true}).
+			Return(&hcloud.Action{ID: 1}, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args: arguments{
+				State:      statePresent,
+				Name:       "web",
+				Type:       "ipv4",
+				Datacenter: "fsn1-dc14",
+				Protected:  hcloud.Bool(true),
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			assert.True(t, resp.Data()["primary_ips"].([]PrimaryIP)[0].Protected)
+		}
+	})
+
+	t.Run("create without type", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.PrimaryIP = hcloudtest.NewPrimaryIPClientMock()
+		client.PrimaryIP.(*hcloudtest.PrimaryIPClientMock).On("GetByName", mock.Anything, "web").Return(nilPrimaryIP, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: statePresent, Name: "web", Datacenter: "fsn1-dc14"},
+		}
+		_, err := m.run(context.Background())
+		assert.Error(t, err)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.PrimaryIP = hcloudtest.NewPrimaryIPClientMock()
+		client.Server = hcloudtest.NewServerClientMock()
+		client.PrimaryIP.(*hcloudtest.PrimaryIPClientMock).On("GetByName", mock.Anything, "web").Return(newPrimaryIP(), nilResponse, nil)
+		client.Server.(*hcloudtest.ServerClientMock).On("Get", mock.Anything, "web1").Return(&hcloud.Server{ID: 42, Status: hcloud.ServerStatusRunning}, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: statePresent, Name: "web", Type: "ipv4", Server: "web1"},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.False(t, resp.HasChanged(), "module should not have changed")
+		}
+	})
+
+	t.Run("reassign", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.PrimaryIP = hcloudtest.NewPrimaryIPClientMock()
+		client.Server = hcloudtest.NewServerClientMock()
+		primaryIP := newPrimaryIP()
+		old := &hcloud.Server{ID: 42, Status: hcloud.ServerStatusOff}
+		server := &hcloud.Server{ID: 43, Status: hcloud.ServerStatusOff}
+
+		primaryIPMock := client.PrimaryIP.(*hcloudtest.PrimaryIPClientMock)
+		primaryIPAction := func(id int) *hcloud.Action {
+			return &hcloud.Action{
+				ID:        id,
+				Resources: []*hcloud.ActionResource{{ID: primaryIP.ID, Type: hcloud.ActionResourceTypePrimaryIP}},
+			}
+		}
+		// the primary IP is locked while an action runs, so each action
+		// is awaited before the next one and only the last one is left pending
+		var waited []int
+		primaryIPMock.On("GetByName", mock.Anything, "web").Return(primaryIP, nilResponse, nil)
+		primaryIPMock.On("Unassign", mock.Anything, primaryIP).Return(primaryIPAction(1), nilResponse, nil)
+		primaryIPMock.On("Assign", mock.Anything, primaryIP, server).Run(func(args mock.Arguments) {
+			assert.Equal(t, []int{1}, waited, "primary IP assigned before the unassign finished")
+		}).Return(primaryIPAction(2), nilResponse, nil)
+		primaryIPMock.On("ChangeProtection", mock.Anything, primaryIP, hcloud.PrimaryIPProtection{Delete: true}).Run(func(args mock.Arguments) {
+			assert.Equal(t, []int{1, 2}, waited, "protection changed before the assign finished")
+		}).Return(primaryIPAction(3), nilResponse, nil)
+		serverMock := client.Server.(*hcloudtest.ServerClientMock)
+		serverMock.On("Get", mock.Anything, "43").Return(server, nilResponse, nil)
+		serverMock.On("GetByID", mock.Anything, 42).Return(old, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				for _, action := range actions {
+					waited = append(waited, action.ID)
+				}
+				return nil
+			}),
+			args: arguments{State: statePresent, Name: "web", Server: float64(43), Protected: hcloud.Bool(true), Wait: hcloud.Bool(false)},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			assert.Equal(t, []int{3}, resp.Data()["action_ids"])
+			assert.Equal(t, 43, resp.Data()["primary_ips"].([]PrimaryIP)[0].Server)
+		}
+	})
+
+	t.Run("assign to running server", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.PrimaryIP = hcloudtest.NewPrimaryIPClientMock()
+		client.Server = hcloudtest.NewServerClientMock()
+		primaryIP := newPrimaryIP()
+		primaryIP.AssigneeID = 0
+
+		client.PrimaryIP.(*hcloudtest.PrimaryIPClientMock).On("GetByName", mock.Anything, "web").Return(primaryIP, nilResponse, nil)
+		client.Server.(*hcloudtest.ServerClientMock).On("Get", mock.Anything, "web1").Return(&hcloud.Server{ID: 43, Status: hcloud.ServerStatusRunning}, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: statePresent, Name: "web", Server: "web1"},
+		}
+		_, err := m.run(context.Background())
+		assert.Error(t, err)
+	})
+}
+
+func TestAbsent(t *testing.T) {
+	t.Run("delete", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.PrimaryIP = hcloudtest.NewPrimaryIPClientMock()
+		primaryIP := newPrimaryIP()
+		primaryIPMock := client.PrimaryIP.(*hcloudtest.PrimaryIPClientMock)
+		primaryIPMock.On("GetByID", mock.Anything, 1).Return(primaryIP, nilResponse, nil)
+		primaryIPMock.On("Delete", mock.Anything, primaryIP).Return(nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: stateAbsent, ID: float64(1)},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+		}
+	})
+
+	t.Run("protected", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.PrimaryIP = hcloudtest.NewPrimaryIPClientMock()
+		primaryIP := newPrimaryIP()
+		primaryIP.Protection.Delete = true
+		client.PrimaryIP.(*hcloudtest.PrimaryIPClientMock).On("GetByID", mock.Anything, 1).Return(primaryIP, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: stateAbsent, ID: float64(1)},
+		}
+		_, err := m.run(context.Background())
+		assert.Error(t, err)
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", Type: "ipv6", Datacenter: "fsn1-dc14"}}))
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: stateList}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", Type: "ipv5"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "web", Datacenter: "fsn1-dc14", Server: "web1"}}))
+}
+
+func TestDoc(t *testing.T) {
+	data, err := ioutil.ReadFile("../../docs/hcloud_primary_ip.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(data), doc.Markdown(argSpec), "docs/hcloud_primary_ip.md is outdated, run make docs")
+	}
+}
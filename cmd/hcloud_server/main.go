@@ -4,13 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
 	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/hetznerdns"
 	"github.com/thetechnick/hcloud-ansible/pkg/util"
+	"golang.org/x/crypto/ssh"
 )
 
 type arguments struct {
@@ -25,8 +32,275 @@ type arguments struct {
 	Datacenter string      `json:"datacenter"`
 	Location   string      `json:"location"`
 	Rescue     string      `json:"rescue"`
-	SSHKeys    interface{} `json:"ssh_keys"`
-	ISO        interface{} `json:"iso"`
+	// SSHKeys are ids, names, public keys or paths of public key files
+	SSHKeys interface{} `json:"ssh_keys"`
+	// TemporarySSHKeys deletes uploaded public keys after the servers are created
+	TemporarySSHKeys bool        `json:"temporary_ssh_keys"`
+	ISO              interface{} `json:"iso"`
+	Networks         []network   `json:"networks"`
+	// PlacementGroup is the id or name of a placement group, "" removes the server from its group
+	PlacementGroup interface{} `json:"placement_group"`
+	// PrimaryIPv4 and PrimaryIPv6 are the id or name of a primary IP, false disables the public IP
+	PrimaryIPv4 interface{} `json:"primary_ipv4"`
+	PrimaryIPv6 interface{} `json:"primary_ipv6"`
+	// DNSName is a fully qualified name in a Hetzner DNS zone pointed at the public IPs of the servers
+	DNSName  string `json:"dns_name"`
+	DNSToken string `json:"dns_token"`
+	Wait     *bool  `json:"wait"`
+}
+
+var argSpec = ansible.ArgSpec{
+	Args: []ansible.Arg{
+		ansible.TokenArg(),
+		{Name: "state", Type: ansible.TypeStr, Default: statePresent,
+			Choices: []string{statePresent, stateAbsent, stateRunning, stateStopped, stateList, stateRestarted},
+			Description: "`running`, `stopped` and `restarted` create missing servers like `present`. " +
+				"`list` lists all existing servers."},
+		{Name: "id", Type: ansible.TypeRaw,
+			Description: "A single id or list of ids. Either `id` or `name` must be set."},
+		{Name: "name", Type: ansible.TypeRaw,
+			Description: "A single name or list of names. Either `id` or `name` must be set."},
+		{Name: "image", Type: ansible.TypeRaw,
+			Description: "Name or id of the image. Required when a server needs to be created. Servers with another image are recreated."},
+		{Name: "server_type", Type: ansible.TypeStr,
+			Description: "Required when a server needs to be created. Servers of another server type are recreated."},
+		{Name: "user_data", Type: ansible.TypeStr,
+			Description: "cloud-init userdata"},
+		{Name: "datacenter", Type: ansible.TypeStr,
+			Description: "Datacenter of new servers. Servers in another datacenter are recreated."},
+		{Name: "location", Type: ansible.TypeStr,
+			Description: "Location of new servers. Servers in another location are recreated.\n\n" +
+				"Ignored with a warning when `datacenter` is set."},
+		{Name: "rescue", Type: ansible.TypeStr,
+			Choices: []string{
+				string(hcloud.ServerRescueTypeLinux64),
+				string(hcloud.ServerRescueTypeLinux32),
+				string(hcloud.ServerRescueTypeFreeBSD64),
+			},
+			Description: "Will make sure the choosen rescue system is enabled. " +
+				"Automatically resets the server to boot into the rescue system if `state != stopped`."},
+		{Name: "ssh_keys", Type: ansible.TypeRaw,
+			Description: "List of Hetzner Cloud SSHKey ids, names or dict containing the `id` or `name`, public keys or paths of public key files. " +
+				"Entries are looked up by id or name first, other entries are public keys or paths. " +
+				"Public keys are found by fingerprint or uploaded when a server is created or the rescue system is enabled, named by their comment. " +
+				"DSA keys and RSA keys with less than 2048 bits are rejected."},
+		{Name: "temporary_ssh_keys", Type: ansible.TypeBool, Default: false,
+			Description: "Delete the public keys uploaded from `ssh_keys` after the servers are created."},
+		{Name: "iso", Type: ansible.TypeRaw,
+			Description: "`name` or `id` of the iso image to attach."},
+		{Name: "networks", Type: ansible.TypeList, Elements: ansible.TypeDict,
+			Description: "List of private networks, see below. " +
+				"The server is attached to all listed networks and detached from all others, an empty list detaches all networks. " +
+				"Changing `ip` detaches and attaches the server again. Existing attachments are kept if not set.",
+			Options: []ansible.Arg{
+				{Name: "network", Type: ansible.TypeRaw, Required: true, Description: "ID or name of the network."},
+				{Name: "ip", Type: ansible.TypeStr, Description: "IP of the server in the network, assigned automatically if not set."},
+				{Name: "alias_ips", Type: ansible.TypeList, Elements: ansible.TypeStr, Description: "Additional IPs of the server in the network."},
+			}},
+		{Name: "placement_group", Type: ansible.TypeRaw,
+			Description: "ID or name of a placement group. New servers are created in the group. " +
+				"Existing servers are stopped, moved into the group and started again if they were running; " +
+				"an empty string removes the server from its group. Servers are never moved if not set. " +
+				"Always waits for the actions, even with `wait: false`."},
+		{Name: "primary_ipv4", Type: ansible.TypeRaw,
+			Description: "ID or name of a primary IPv4 to use as public IPv4, or `false` for a server without public IPv4. " +
+				"The primary IP keeps its address when the server is recreated, its `auto_delete` (see `hcloud_primary_ip`) is disabled until the new server is created. " +
+				"Existing servers are stopped to change their primary IPs and started again if they were running. " +
+				"Not set keeps the public IPv4 of existing servers and creates a new one for new servers. " +
+				"Always waits for the actions, even with `wait: false`."},
+		{Name: "primary_ipv6", Type: ansible.TypeRaw,
+			Description: "Same as `primary_ipv4` for the public IPv6 network. Servers need at least one public IP or private network."},
+		{Name: "dns_name", Type: ansible.TypeStr,
+			Description: "Fully qualified name in a [Hetzner DNS](https://dns.hetzner.com) zone, e.g. `web.example.com`. " +
+				"A and AAAA records are pointed at the public IPv4 addresses and the first addresses of the IPv6 networks of the servers, " +
+				"records of other addresses are deleted. Multiple servers share the name round-robin. " +
+				"With `state=absent` the records of the deleted servers are removed."},
+		{Name: "dns_token", Type: ansible.TypeStr, NoLog: true,
+			Description: "Hetzner DNS API Token, required with `dns_name`. Can also be specified with `HETZNER_DNS_TOKEN` environment variable."},
+		{Name: "wait", Type: ansible.TypeBool, Default: true,
+			Description: "Wait for all actions to complete. When `false`, the module returns immediately with `action_ids`, see `hcloud_action`. " +
+				"The server is locked while an action runs, so with multiple changes to a server only the last action is not awaited."},
+	},
+}
+
+var doc = ansible.Doc{
+	Module:           "hcloud_server",
+	ShortDescription: "Manage Hetzner Cloud Servers",
+	Description:      "Manages Hetzner Cloud servers. This module can be used to create, modify, delete and reboot servers. In check mode the changes and their cost are reported without making them.",
+	Returns: []ansible.Return{
+		{
+			Name:        "servers",
+			Description: "The servers, or all servers with `state=list`.",
+			Returned:    "unless `state=absent`",
+			Sample: []Server{{
+				ID:         123,
+				Name:       "server-name",
+				Image:      "debian-9",
+				ServerType: "cx11",
+				Status:     string(hcloud.ServerStatusRunning),
+				Datacenter: "fsn1-dc8",
+				Location:   "fsn1",
+				PublicIPv4: "10.0.0.1",
+				PublicIPv6: "2001:db8::/64",
+				PrivateIPs: []PrivateIP{{
+					NetworkID: 4711,
+					IP:        "10.0.1.2",
+					AliasIPs:  []string{"10.0.1.10"},
+				}},
+				PlacementGroup: "db",
+			}},
+		},
+		{
+			Name:        "uploaded_ssh_keys",
+			Description: "The public keys uploaded from `ssh_keys`, `deleted` with `temporary_ssh_keys`.",
+			Returned:    "when public keys are uploaded",
+			Sample: []SSHKey{{
+				ID:          4711,
+				Name:        "user@example-notebook",
+				Fingerprint: "a2:94:75:0d:cf:fd:2c:fc:77:81:0e:c6:7a:8d:a2:21",
+				Deleted:     true,
+			}},
+		},
+		{
+			Name:        "action_ids",
+			Description: "IDs of the pending actions, see `hcloud_action`.",
+			Returned:    "when `wait` is `false`",
+			Sample:      []int{4711},
+		},
+		{
+			Name:        "cost",
+			Description: "The monthly and hourly cost delta of the created, recreated and deleted servers.",
+			Returned:    "in check mode",
+			Sample: CostDelta{
+				Currency: "EUR",
+				Monthly:  util.Cost{Net: 1.6, Gross: 1.904},
+				Hourly:   util.Cost{Net: 0.0032, Gross: 0.0038},
+			},
+		},
+	},
+	Examples: `
+# create a single server
+- hcloud_server:
+    name: example-server
+    image: debian-9
+    server_type: cx11
+    datacenter: nbg1-dc3
+    ssh_keys:
+    - user@example-notebook   # by name
+    - 1234                    # by id
+
+# create three servers at once
+- hcloud_server:
+    name:
+    - example-server01
+    - example-server02
+    - example-server03
+    image: debian-9
+    server_type: cx11
+    location: nbg1
+    ssh_keys:
+    - user@example-notebook   # by name
+    - 1234                    # by id
+
+# create a server with a local public key without keeping it in the project
+- hcloud_server:
+    name: example-server
+    image: debian-9
+    server_type: cx11
+    location: nbg1
+    ssh_keys:
+    - ~/.ssh/id_ed25519.pub   # by path
+    - "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGrhZ30jkQntYeeJNvC5fUVDfi/XmjSRbnOLMLzhyAuq ci@example"
+    temporary_ssh_keys: true
+
+# create a web server in the private network
+- hcloud_server:
+    name: web1
+    image: debian-9
+    server_type: cx11
+    location: fsn1
+    networks:
+    - network: internal
+      ip: 10.0.1.10
+
+# spread the database servers across different hosts
+- hcloud_placement_group:
+    name: db
+- hcloud_server:
+    name: [db1, db2, db3]
+    image: debian-9
+    server_type: cx11
+    location: fsn1
+    placement_group: db
+
+# keep the public IPv4 across recreations and create an IPv6 only server
+- hcloud_primary_ip:
+    name: web-v4
+    type: ipv4
+    datacenter: fsn1-dc14
+- hcloud_server:
+    name: web1
+    image: debian-9
+    server_type: cx11
+    datacenter: fsn1-dc14
+    primary_ipv4: web-v4
+- hcloud_server:
+    name: mail
+    image: debian-9
+    server_type: cx11
+    primary_ipv4: false
+
+# point web.example.com at the servers, HETZNER_DNS_TOKEN must be set
+- hcloud_server:
+    name: [web1, web2]
+    image: debian-9
+    server_type: cx11
+    dns_name: web.example.com
+
+# ensure server is running (if the server already exists)
+- hcloud_server:
+    name: example-server
+    state: running
+
+# create a server with cloud init user data
+- hcloud_server:
+    name: cloudinit
+    image: debian-9
+    server_type: cx11
+    user_data: |
+      #cloud-config
+      runcmd:
+      - [touch, /root/cloud-init-worked]
+
+- hcloud_server:
+    id: 123
+    state: running
+
+# ensure the servers name is "web-234" (if the server already exists)
+- hcloud_server:
+    id: 123
+    name: web-234
+
+# enable and boot into rescue system (if the server already exists)
+- hcloud_server:
+    name: example-server01
+    rescue: linux64
+    ssh_keys:                 # rescue os will be configured with these ssh keys
+    - user@example-notebook   # by name
+    - 1234                    # by id
+
+# list all servers
+- hcloud_server:
+    state: list
+  register: hcloud_servers
+`,
+}
+
+// network is the argument of a private network the server is attached to
+type network struct {
+	Network  interface{} `json:"network"`
+	IP       string      `json:"ip"`
+	AliasIPs []string    `json:"alias_ips"`
 }
 
 const (
@@ -36,6 +310,8 @@ const (
 	stateRunning   = "running"
 	stateStopped   = "stopped"
 	stateRestarted = "restarted"
+
+	deletePollInterval = time.Second
 )
 
 type config struct {
@@ -52,47 +328,141 @@ type config struct {
 	Location   *hcloud.Location
 	Rescue     string
 	SSHKeys    []*hcloud.SSHKey
+	// PublicKeys are found by fingerprint or uploaded when a server needs them
+	PublicKeys       []publicKey
+	TemporarySSHKeys bool
+	Networks         []serverNetwork
+	// PlacementGroup is the desired placement group, nil leaves the membership unchanged
+	// and a placement group with ID 0 removes the server from its group
+	PlacementGroup *hcloud.PlacementGroup
+	PrimaryIPv4    *publicIP
+	PrimaryIPv6    *publicIP
+	Wait           bool
+}
+
+// stopsServers checks if the config contains changes that require existing servers to be stopped
+func (c config) stopsServers() bool {
+	return c.PlacementGroup != nil || c.PrimaryIPv4 != nil || c.PrimaryIPv6 != nil
+}
+
+// publicIP is the desired public IP of a server, nil in the config leaves the public IPs
+// of existing servers unchanged and lets the API create new primary IPs for new servers
+type publicIP struct {
+	// Disabled servers have no public IP of this type
+	Disabled  bool
+	PrimaryIP *hcloud.PrimaryIP
+}
+
+// publicKey is a public key from the ssh_keys argument
+type publicKey struct {
+	Name        string
+	PublicKey   string
+	Fingerprint string
+}
+
+// serverNetwork is the desired attachment of a server to a private network,
+// nil Networks in the config leaves the attachments of the server unchanged
+type serverNetwork struct {
+	Network  *hcloud.Network
+	IP       net.IP
+	AliasIPs []net.IP
 }
 
 // Server is the module return value of an hcloud.Server
 type Server struct {
-	ID         int    `json:"id"`
-	Name       string `json:"name"`
-	Image      string `json:"image"`
-	ServerType string `json:"server_type"`
-	Status     string `json:"status"`
-	Datacenter string `json:"datacenter"`
-	Location   string `json:"location"`
-	ISO        string `json:"iso"`
-	PublicIPv4 string `json:"public_ipv4"`
-	PublicIPv6 string `json:"public_ipv6"`
+	ID         int         `json:"id"`
+	Name       string      `json:"name"`
+	Image      string      `json:"image"`
+	ServerType string      `json:"server_type"`
+	Status     string      `json:"status"`
+	Datacenter string      `json:"datacenter"`
+	Location   string      `json:"location"`
+	ISO        string      `json:"iso"`
+	PublicIPv4 string      `json:"public_ipv4"`
+	PublicIPv6 string      `json:"public_ipv6"`
+	PrivateIPs []PrivateIP `json:"private_ips"`
+	// PlacementGroup is the name of the placement group of the server
+	PlacementGroup string `json:"placement_group"`
+}
+
+// SSHKey is the module return value of an uploaded hcloud.SSHKey
+type SSHKey struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Fingerprint string `json:"fingerprint"`
+	Deleted     bool   `json:"deleted"`
+}
+
+// PrivateIP is the module return value of an hcloud.ServerPrivateNet
+type PrivateIP struct {
+	NetworkID int      `json:"network_id"`
+	IP        string   `json:"ip"`
+	AliasIPs  []string `json:"alias_ips"`
+}
+
+// CostDelta is the module return value of the cost change in check mode
+type CostDelta struct {
+	Currency string    `json:"currency"`
+	Monthly  util.Cost `json:"monthly"`
+	Hourly   util.Cost `json:"hourly"`
 }
 
 type module struct {
 	args     arguments
 	config   config
 	client   *hcloud.Client
-	waitFn   util.WaitFn
+	dns      *hetznerdns.Client
+	waiter   util.ActionWaiter
 	messages ansible.MessageLog
+	warnings ansible.MessageLog
+
+	pollInterval time.Duration
+
+	// sshKeys are the resolved SSH keys including uploaded public keys
+	sshKeys     []*hcloud.SSHKey
+	uploaded    []*hcloud.SSHKey
+	sshKeysLock sync.Mutex
 }
 
 func (m *module) Args() interface{} {
 	return &m.args
 }
 
-func (m *module) Run() (resp ansible.ModuleResponse, err error) {
+func (m *module) ArgSpec() ansible.ArgSpec {
+	return argSpec
+}
+
+func (m *module) Doc() ansible.Doc {
+	return doc
+}
+
+func (m *module) Run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
 	if m.client, err = hcloud.BuildClient(m.args.Token); err != nil {
 		return
 	}
-	return m.run(context.Background())
+	if m.args.DNSName != "" {
+		if m.dns, err = hetznerdns.BuildClient(m.args.DNSToken); err != nil {
+			return
+		}
+	}
+	m.waiter = util.NewActionWatcher(m.client.Action)
+	m.pollInterval = deletePollInterval
+	return m.run(ctx)
 }
 
 func (m *module) run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	defer func() {
+		for _, warning := range m.warnings.Messages() {
+			resp.Warn(warning)
+		}
+	}()
 	if m.config, err = m.argsToConfig(ctx); err != nil {
 		return
 	}
-	if m.args.State == "" {
-		m.args.State = statePresent
+	// changing the placement group or primary IPs requires the server to be stopped,
+	// so the module always waits if they are managed
+	if !m.config.Wait && !m.config.stopsServers() {
+		defer util.RecordActions(&m.waiter).Report(&resp, &err)
 	}
 
 	switch m.config.State {
@@ -114,6 +484,165 @@ func (m *module) run(ctx context.Context) (resp ansible.ModuleResponse, err erro
 	}
 }
 
+// Check reports the changes of the servers in check mode without making them,
+// the cost delta of created, recreated and deleted servers is returned as cost
+func (m *module) Check(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	if m.client, err = hcloud.BuildClient(m.args.Token); err != nil {
+		return
+	}
+	return m.check(ctx)
+}
+
+func (m *module) check(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	defer func() {
+		for _, warning := range m.warnings.Messages() {
+			resp.Warn(warning)
+		}
+	}()
+	if m.config, err = m.argsToConfig(ctx); err != nil {
+		return
+	}
+	if m.config.State == stateList {
+		return m.list(ctx)
+	}
+	if len(m.config.Name) == 0 && len(m.config.ID) == 0 {
+		err = fmt.Errorf("'name' or 'id' is required")
+		return
+	}
+
+	var pricing hcloud.Pricing
+	if pricing, _, err = m.client.Pricing.Get(ctx); err != nil {
+		return
+	}
+	var delta util.Costs
+	for _, id := range m.config.ID {
+		var server *hcloud.Server
+		if server, _, err = m.client.Server.GetByID(ctx, id); err != nil {
+			return
+		}
+		if server == nil && m.config.State != stateAbsent {
+			err = fmt.Errorf("Server with id %d not found", id)
+			return
+		}
+		var d util.Costs
+		if d, err = m.checkServer(&resp, pricing, server, ""); err != nil {
+			return
+		}
+		delta = delta.Add(d)
+	}
+	for _, name := range m.config.Name {
+		var server *hcloud.Server
+		if server, _, err = m.client.Server.GetByName(ctx, name); err != nil {
+			return
+		}
+		var d util.Costs
+		if d, err = m.checkServer(&resp, pricing, server, name); err != nil {
+			return
+		}
+		delta = delta.Add(d)
+	}
+
+	resp.Msg(m.messages.String())
+	resp.Set("cost", CostDelta{
+		Currency: pricing.Image.PerGBMonth.Currency,
+		Monthly:  delta.Round().Monthly,
+		Hourly:   delta.Round().Hourly,
+	})
+	return
+}
+
+// checkServer reports the change of a server, nil if it does not exist, and returns its cost delta
+func (m *module) checkServer(resp *ansible.ModuleResponse, pricing hcloud.Pricing, server *hcloud.Server, name string) (delta util.Costs, err error) {
+	if m.config.State == stateAbsent {
+		if server == nil {
+			return
+		}
+		var before util.Costs
+		if before, err = serverCosts(pricing, server.ServerType.Name, server.Datacenter.Location.Name, server.BackupWindow != ""); err != nil {
+			return
+		}
+		m.messages.Add(fmt.Sprintf("Server %d would be deleted", server.ID))
+		resp.Changed()
+		return delta.Sub(before), nil
+	}
+
+	if server != nil {
+		reason := recreateReason(server, m.config)
+		if reason == "" {
+			m.checkServerState(resp, server)
+			return
+		}
+		if delta, err = serverCosts(pricing, server.ServerType.Name, server.Datacenter.Location.Name, server.BackupWindow != ""); err != nil {
+			return
+		}
+		delta = util.Costs{}.Sub(delta)
+		m.messages.Add(fmt.Sprintf("Server %d would be recreated because %s", server.ID, reason))
+		name = server.Name
+	} else {
+		if m.config.ServerType == "" {
+			err = fmt.Errorf("Cannot create server '%s': 'server_type' is required", name)
+			return
+		}
+		m.messages.Add(fmt.Sprintf("Server %q would be created", name))
+	}
+	resp.Changed()
+
+	// new servers are created in the existing or configured location,
+	// without one the API picks the location and the costs are unknown
+	serverType, location := m.config.ServerType, ""
+	if server != nil {
+		serverType, location = server.ServerType.Name, server.Datacenter.Location.Name
+		if m.config.ServerType != "" {
+			serverType = m.config.ServerType
+		}
+	}
+	if m.config.Datacenter != nil && m.config.Datacenter.Location != nil {
+		location = m.config.Datacenter.Location.Name
+	} else if m.config.Location != nil {
+		location = m.config.Location.Name
+	}
+	if location == "" {
+		m.warnings.Add(fmt.Sprintf("Cost of server '%s' is unknown without 'location' or 'datacenter'", name))
+		return
+	}
+	var after util.Costs
+	if after, err = serverCosts(pricing, serverType, location, false); err != nil {
+		return
+	}
+	return delta.Add(after), nil
+}
+
+// checkServerState reports the power state changes of an existing server
+func (m *module) checkServerState(resp *ansible.ModuleResponse, server *hcloud.Server) {
+	switch m.config.State {
+	case stateRunning, stateRestarted:
+		if server.Status != hcloud.ServerStatusRunning {
+			m.messages.Add(fmt.Sprintf("Server %d would be started", server.ID))
+			resp.Changed()
+		} else if m.config.State == stateRestarted {
+			m.messages.Add(fmt.Sprintf("Server %d would be restarted", server.ID))
+			resp.Changed()
+		}
+	case stateStopped:
+		if server.Status != hcloud.ServerStatusOff {
+			m.messages.Add(fmt.Sprintf("Server %d would be stopped", server.ID))
+			resp.Changed()
+		}
+	}
+}
+
+// serverCosts returns the costs of a server including its backups
+func serverCosts(pricing hcloud.Pricing, serverType, location string, backups bool) (costs util.Costs, err error) {
+	if costs, err = util.ServerCosts(pricing, serverType, location); err != nil || !backups {
+		return
+	}
+	var backup util.Costs
+	if backup, err = util.BackupCosts(pricing, costs); err != nil {
+		return
+	}
+	return costs.Add(backup), nil
+}
+
 func (m *module) absent(ctx context.Context) (resp ansible.ModuleResponse, err error) {
 	var servers []*hcloud.Server
 	servers, err = m.servers(ctx)
@@ -123,6 +652,11 @@ func (m *module) absent(ctx context.Context) (resp ansible.ModuleResponse, err e
 		}
 		resp.Changed()
 	}
+	if m.args.DNSName != "" && len(servers) > 0 {
+		if err = m.deleteDNSRecords(ctx, &resp, servers); err != nil {
+			return
+		}
+	}
 	return
 }
 
@@ -176,11 +710,23 @@ func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err
 	}
 	wg.Wait()
 
+	if len(m.uploaded) > 0 {
+		if err = m.reportUploadedSSHKeys(ctx, &resp); err != nil {
+			return
+		}
+	}
+
 	if len(errors) > 0 {
 		err = fmt.Errorf("%s", strings.Join(errors, ", "))
 		return
 	}
 
+	if m.args.DNSName != "" {
+		if err = m.ensureDNSRecords(ctx, &resp); err != nil {
+			return
+		}
+	}
+
 	if err = m.output(ctx, &resp); err != nil {
 		return
 	}
@@ -199,7 +745,7 @@ func (m *module) servers(ctx context.Context) (servers []*hcloud.Server, err err
 			return
 		}
 		if server == nil && m.config.State != stateAbsent {
-			err = fmt.Errorf("Server with id %q not found", id)
+			err = fmt.Errorf("Server with id %d not found", id)
 			return
 		}
 		if server != nil {
@@ -227,11 +773,20 @@ func (m *module) ensureServerExists(ctx context.Context, resp *ansible.ModuleRes
 		return
 	}
 
-	if needsRecreate(server, m.config) {
+	// primary IPs of a recreated server are kept for the new one
+	var autoDelete []*hcloud.PrimaryIP
+	if reason := recreateReason(server, m.config); reason != "" {
+		if autoDelete, err = m.keepPrimaryIPs(ctx, server); err != nil {
+			return
+		}
 		if _, err = m.client.Server.Delete(ctx, server); err != nil {
 			return
 		}
+		if err = m.waitForDeletion(ctx, server); err != nil {
+			return
+		}
 		m.messages.Add(fmt.Sprintf("Server %d deleted (needs recreate)", server.ID))
+		m.warnings.Add(fmt.Sprintf("Server %d recreated because %s", server.ID, reason))
 		server = nil
 		resp.Changed()
 	}
@@ -248,6 +803,11 @@ func (m *module) ensureServerExists(ctx context.Context, resp *ansible.ModuleRes
 			return nil, fmt.Errorf("Cannot create server '%s': %s", name, strings.Join(errs, ", "))
 		}
 
+		var sshKeys []*hcloud.SSHKey
+		if sshKeys, err = m.resolveSSHKeys(ctx, resp); err != nil {
+			return
+		}
+
 		resp.Changed()
 		opts := hcloud.ServerCreateOpts{
 			Name: name,
@@ -256,7 +816,7 @@ func (m *module) ensureServerExists(ctx context.Context, resp *ansible.ModuleRes
 				Name: m.config.ServerType,
 			},
 			UserData: m.config.UserData,
-			SSHKeys:  m.config.SSHKeys,
+			SSHKeys:  sshKeys,
 			Image:    m.config.Image,
 		}
 		if m.config.State == stateStopped {
@@ -268,31 +828,83 @@ func (m *module) ensureServerExists(ctx context.Context, resp *ansible.ModuleRes
 		if m.config.Location != nil {
 			opts.Location = m.config.Location
 		}
+		if m.config.PlacementGroup != nil && m.config.PlacementGroup.ID != 0 {
+			opts.PlacementGroup = m.config.PlacementGroup
+		}
+		if m.config.PrimaryIPv4 != nil || m.config.PrimaryIPv6 != nil {
+			opts.PublicNet = &hcloud.ServerCreatePublicNet{EnableIPv4: true, EnableIPv6: true}
+			if ip := m.config.PrimaryIPv4; ip != nil {
+				opts.PublicNet.EnableIPv4 = !ip.Disabled
+				opts.PublicNet.IPv4 = ip.PrimaryIP
+			}
+			if ip := m.config.PrimaryIPv6; ip != nil {
+				opts.PublicNet.EnableIPv6 = !ip.Disabled
+				opts.PublicNet.IPv6 = ip.PrimaryIP
+			}
+		}
 
 		var res hcloud.ServerCreateResult
 		res, _, err = m.client.Server.Create(ctx, opts)
 		if err != nil {
 			return
 		}
-		if err = m.waitFn(ctx, m.client, res.Action); err != nil {
+		if err = m.waiter.WaitForActions(ctx, res.Action); err != nil {
 			return nil, err
 		}
 		server = res.Server
+
+		for _, primaryIP := range autoDelete {
+			if _, _, err = m.client.PrimaryIP.Update(ctx, primaryIP, hcloud.PrimaryIPUpdateOpts{AutoDelete: hcloud.Bool(true)}); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// keepPrimaryIPs disables auto_delete of the configured primary IPs of a server before it is deleted,
+// it returns the primary IPs to enable auto_delete again for the new server
+func (m *module) keepPrimaryIPs(ctx context.Context, server *hcloud.Server) (autoDelete []*hcloud.PrimaryIP, err error) {
+	for _, ip := range []*publicIP{m.config.PrimaryIPv4, m.config.PrimaryIPv6} {
+		if ip == nil || ip.PrimaryIP == nil || ip.PrimaryIP.AssigneeID != server.ID || !ip.PrimaryIP.AutoDelete {
+			continue
+		}
+		if _, _, err = m.client.PrimaryIP.Update(ctx, ip.PrimaryIP, hcloud.PrimaryIPUpdateOpts{AutoDelete: hcloud.Bool(false)}); err != nil {
+			return
+		}
+		autoDelete = append(autoDelete, ip.PrimaryIP)
 	}
 	return
 }
 
+// waitForDeletion polls until a deleted server is gone,
+// its name and primary IPs cannot be used for a new server before
+func (m *module) waitForDeletion(ctx context.Context, server *hcloud.Server) error {
+	ticker := time.NewTicker(m.pollInterval)
+	defer ticker.Stop()
+	for {
+		current, _, err := m.client.Server.GetByID(ctx, server.ID)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%v while waiting for server %d to be deleted", ctx.Err(), server.ID)
+		case <-ticker.C:
+		}
+	}
+}
+
 func (m *module) ensureServerState(ctx context.Context, resp *ansible.ModuleResponse, server *hcloud.Server, name string) (err error) {
 	// mount/dismount ISO BEFORE changing the power state of the server.
 	// this allowes to boot from the ISO in one step and
 	// prevents the server booting from the ISO if it is detached and restarted in one step
 	if server.ISO != nil &&
 		(m.config.ISO == nil || m.config.ISO.ID != server.ISO.ID) {
-		var action *hcloud.Action
-		if action, _, err = m.client.Server.DetachISO(ctx, server); err != nil {
-			return
-		}
-		if err = m.waitFn(ctx, m.client, action); err != nil {
+		if err = m.doAction(ctx, server, m.client.Server.DetachISO); err != nil {
 			return
 		}
 		m.messages.Add(fmt.Sprintf("Server %d ISO %d detached", server.ID, server.ISO.ID))
@@ -300,12 +912,15 @@ func (m *module) ensureServerState(ctx context.Context, resp *ansible.ModuleResp
 		server.ISO = nil
 	}
 	if server.ISO == nil && m.config.ISO != nil {
+		if err = m.waitForPending(ctx, server); err != nil {
+			return
+		}
 		var action *hcloud.Action
 		if action, _, err = m.client.Server.AttachISO(ctx, server, m.config.ISO); err != nil {
 			return
 		}
 
-		if err = m.waitFn(ctx, m.client, action); err != nil {
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
 			return
 		}
 		m.messages.Add(fmt.Sprintf("Server %d ISO %d attached", server.ID, m.config.ISO.ID))
@@ -313,25 +928,37 @@ func (m *module) ensureServerState(ctx context.Context, resp *ansible.ModuleResp
 		server.ISO = m.config.ISO
 	}
 
+	if err = m.ensureServerNetworks(ctx, resp, server); err != nil {
+		return
+	}
+
+	// placement group and primary IPs can only be changed while the server is stopped,
+	// they power off the server and it is started again here if the state does not
+	wasRunning := server.Status != hcloud.ServerStatusOff
+	if err = m.ensureServerPlacementGroup(ctx, resp, server); err != nil {
+		return
+	}
+	if err = m.ensureServerPrimaryIPs(ctx, resp, server); err != nil {
+		return
+	}
+	if wasRunning && server.Status == hcloud.ServerStatusOff && m.config.State == statePresent {
+		if err = m.doAction(ctx, server, m.client.Server.Poweron); err != nil {
+			return
+		}
+		m.messages.Add(fmt.Sprintf("Server %d started", server.ID))
+		server.Status = hcloud.ServerStatusRunning
+	}
+
 	switch m.config.State {
 	case stateRunning, stateRestarted:
 		if server.Status != hcloud.ServerStatusRunning {
-			var action *hcloud.Action
-			if action, _, err = m.client.Server.Poweron(ctx, server); err != nil {
-				return
-			}
-
-			if err = m.waitFn(ctx, m.client, action); err != nil {
+			if err = m.doAction(ctx, server, m.client.Server.Poweron); err != nil {
 				return
 			}
 			m.messages.Add(fmt.Sprintf("Server %d started", server.ID))
 			resp.Changed()
 		} else if m.config.State == stateRestarted {
-			var action *hcloud.Action
-			if action, _, err = m.client.Server.Reboot(ctx, server); err != nil {
-				return
-			}
-			if err = m.waitFn(ctx, m.client, action); err != nil {
+			if err = m.doAction(ctx, server, m.client.Server.Reboot); err != nil {
 				return
 			}
 			m.messages.Add(fmt.Sprintf("Server %d restarted", server.ID))
@@ -340,12 +967,7 @@ func (m *module) ensureServerState(ctx context.Context, resp *ansible.ModuleResp
 
 	case stateStopped:
 		if server.Status != hcloud.ServerStatusOff {
-			var action *hcloud.Action
-			if action, _, err = m.client.Server.Poweroff(ctx, server); err != nil {
-				return
-			}
-
-			if err = m.waitFn(ctx, m.client, action); err != nil {
+			if err = m.doAction(ctx, server, m.client.Server.Poweroff); err != nil {
 				return
 			}
 			m.messages.Add(fmt.Sprintf("Server %d stopped", server.ID))
@@ -364,12 +986,7 @@ func (m *module) ensureServerState(ctx context.Context, resp *ansible.ModuleResp
 
 	var rescueChanged bool
 	if server.RescueEnabled && m.config.Rescue == "" {
-		var action *hcloud.Action
-		if action, _, err = m.client.Server.DisableRescue(ctx, server); err != nil {
-			return
-		}
-
-		if err = m.waitFn(ctx, m.client, action); err != nil {
+		if err = m.doAction(ctx, server, m.client.Server.DisableRescue); err != nil {
 			return
 		}
 		m.messages.Add(fmt.Sprintf("Server %d disabled rescue mode", server.ID))
@@ -377,16 +994,24 @@ func (m *module) ensureServerState(ctx context.Context, resp *ansible.ModuleResp
 		rescueChanged = true
 	}
 	if !server.RescueEnabled && m.config.Rescue != "" {
+		var sshKeys []*hcloud.SSHKey
+		if sshKeys, err = m.resolveSSHKeys(ctx, resp); err != nil {
+			return
+		}
+
+		if err = m.waitForPending(ctx, server); err != nil {
+			return
+		}
 		var res hcloud.ServerEnableRescueResult
 		res, _, err = m.client.Server.EnableRescue(ctx, server, hcloud.ServerEnableRescueOpts{
 			Type:    hcloud.ServerRescueType(m.config.Rescue),
-			SSHKeys: m.config.SSHKeys,
+			SSHKeys: sshKeys,
 		})
 		if err != nil {
 			return
 		}
 
-		if err = m.waitFn(ctx, m.client, res.Action); err != nil {
+		if err = m.waiter.WaitForActions(ctx, res.Action); err != nil {
 			return
 		}
 		m.messages.Add(fmt.Sprintf("Server %d enabled rescue mode", server.ID))
@@ -395,48 +1020,457 @@ func (m *module) ensureServerState(ctx context.Context, resp *ansible.ModuleResp
 	}
 
 	if rescueChanged && m.config.State != stateStopped {
+		if err = m.doAction(ctx, server, m.client.Server.Reset); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// resolveSSHKeys returns the SSH keys for new servers and the rescue system, public keys
+// are found by fingerprint or uploaded the first time a server needs them
+func (m *module) resolveSSHKeys(ctx context.Context, resp *ansible.ModuleResponse) (sshKeys []*hcloud.SSHKey, err error) {
+	m.sshKeysLock.Lock()
+	defer m.sshKeysLock.Unlock()
+	if m.sshKeys != nil || len(m.config.PublicKeys) == 0 {
+		return append(m.sshKeys, m.config.SSHKeys...), nil
+	}
+
+	var resolved []*hcloud.SSHKey
+	for _, key := range m.config.PublicKeys {
+		var sshKey *hcloud.SSHKey
+		if sshKey, _, err = m.client.SSHKey.GetByFingerprint(ctx, key.Fingerprint); err != nil {
+			return
+		}
+		if sshKey == nil {
+			// the name of the key may be taken by another key
+			name := key.Name
+			var existing *hcloud.SSHKey
+			if existing, _, err = m.client.SSHKey.GetByName(ctx, name); err != nil {
+				return
+			}
+			if existing != nil {
+				name = fmt.Sprintf("%s (%s)", key.Name, key.Fingerprint)
+			}
+
+			sshKey, _, err = m.client.SSHKey.Create(ctx, hcloud.SSHKeyCreateOpts{
+				Name:      name,
+				PublicKey: key.PublicKey,
+			})
+			if err != nil {
+				return
+			}
+			m.uploaded = append(m.uploaded, sshKey)
+			m.messages.Add(fmt.Sprintf("SSHKey %d uploaded", sshKey.ID))
+			resp.Changed()
+		}
+		resolved = append(resolved, sshKey)
+	}
+	m.sshKeys = resolved
+	return append(m.sshKeys, m.config.SSHKeys...), nil
+}
+
+// reportUploadedSSHKeys returns the uploaded SSH keys and deletes them if they are temporary
+func (m *module) reportUploadedSSHKeys(ctx context.Context, resp *ansible.ModuleResponse) (err error) {
+	var uploaded []SSHKey
+	for _, sshKey := range m.uploaded {
+		key := SSHKey{ID: sshKey.ID, Name: sshKey.Name, Fingerprint: sshKey.Fingerprint}
+		if m.config.TemporarySSHKeys {
+			if _, err = m.client.SSHKey.Delete(ctx, sshKey); err != nil {
+				return
+			}
+			m.messages.Add(fmt.Sprintf("SSHKey %d deleted", sshKey.ID))
+			key.Deleted = true
+		}
+		uploaded = append(uploaded, key)
+	}
+	resp.Set("uploaded_ssh_keys", uploaded)
+	return
+}
+
+// ensureServerNetworks attaches and detaches the server to match the configured networks.
+// The primary IP of an attachment cannot be changed, the server is detached and attached again.
+func (m *module) ensureServerNetworks(ctx context.Context, resp *ansible.ModuleResponse, server *hcloud.Server) (err error) {
+	if m.config.Networks == nil {
+		return
+	}
+	var privateNets []hcloud.ServerPrivateNet
+	if privateNets, _, err = m.client.ServerNetwork.List(ctx, server); err != nil {
+		return
+	}
+	attached := map[int]hcloud.ServerPrivateNet{}
+	for _, privateNet := range privateNets {
+		attached[privateNet.Network.ID] = privateNet
+	}
+	desired := map[int]serverNetwork{}
+	for _, network := range m.config.Networks {
+		desired[network.Network.ID] = network
+	}
+
+	for _, privateNet := range privateNets {
+		network, ok := desired[privateNet.Network.ID]
+		if ok && (network.IP == nil || network.IP.Equal(privateNet.IP)) {
+			continue
+		}
+		if err = m.waitForPending(ctx, server); err != nil {
+			return
+		}
 		var action *hcloud.Action
-		if action, _, err = m.client.Server.Reset(ctx, server); err != nil {
+		if action, _, err = m.client.ServerNetwork.Detach(ctx, server, privateNet.Network); err != nil {
 			return
 		}
-		if err = m.waitFn(ctx, m.client, action); err != nil {
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
 			return
 		}
+		m.messages.Add(fmt.Sprintf("Server %d detached from network %d", server.ID, privateNet.Network.ID))
+		resp.Changed()
+		delete(attached, privateNet.Network.ID)
 	}
 
+	for _, network := range m.config.Networks {
+		privateNet, ok := attached[network.Network.ID]
+		if !ok {
+			if err = m.waitForPending(ctx, server); err != nil {
+				return
+			}
+			var action *hcloud.Action
+			if action, _, err = m.client.ServerNetwork.Attach(ctx, server, hcloud.ServerAttachToNetworkOpts{
+				Network:  network.Network,
+				IP:       network.IP,
+				AliasIPs: network.AliasIPs,
+			}); err != nil {
+				return
+			}
+			if err = m.waiter.WaitForActions(ctx, action); err != nil {
+				return
+			}
+			m.messages.Add(fmt.Sprintf("Server %d attached to network %d", server.ID, network.Network.ID))
+			resp.Changed()
+			continue
+		}
+		if network.AliasIPs != nil && !sameIPs(network.AliasIPs, privateNet.Aliases) {
+			if err = m.waitForPending(ctx, server); err != nil {
+				return
+			}
+			var action *hcloud.Action
+			if action, _, err = m.client.ServerNetwork.ChangeAliasIPs(ctx, server, network.Network, network.AliasIPs); err != nil {
+				return
+			}
+			if err = m.waiter.WaitForActions(ctx, action); err != nil {
+				return
+			}
+			m.messages.Add(fmt.Sprintf("Server %d alias IPs in network %d changed", server.ID, network.Network.ID))
+			resp.Changed()
+		}
+	}
 	return
 }
 
-func (m *module) output(ctx context.Context, resp *ansible.ModuleResponse) (err error) {
+// ensureServerPlacementGroup moves the server into the configured placement group,
+// a running server is powered off first.
+func (m *module) ensureServerPlacementGroup(ctx context.Context, resp *ansible.ModuleResponse, server *hcloud.Server) (err error) {
+	if m.config.PlacementGroup == nil {
+		return
+	}
+	var placementGroups map[int]*hcloud.PlacementGroup
+	if placementGroups, err = m.placementGroups(ctx); err != nil {
+		return
+	}
+	current := placementGroups[server.ID]
+	if current == nil && m.config.PlacementGroup.ID == 0 ||
+		current != nil && current.ID == m.config.PlacementGroup.ID {
+		return
+	}
+
+	if err = m.stopServer(ctx, server); err != nil {
+		return
+	}
+	if current != nil {
+		if err = m.doAction(ctx, server, m.client.Server.RemoveFromPlacementGroup); err != nil {
+			return
+		}
+		m.messages.Add(fmt.Sprintf("Server %d removed from placement group %d", server.ID, current.ID))
+	}
+	if m.config.PlacementGroup.ID != 0 {
+		var action *hcloud.Action
+		if action, _, err = m.client.Server.AddToPlacementGroup(ctx, server, m.config.PlacementGroup); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
+			return
+		}
+		m.messages.Add(fmt.Sprintf("Server %d added to placement group %d", server.ID, m.config.PlacementGroup.ID))
+	}
+	resp.Changed()
+	return
+}
+
+// ensureServerPrimaryIPs assigns the configured primary IPs to the server,
+// a running server is powered off first.
+func (m *module) ensureServerPrimaryIPs(ctx context.Context, resp *ansible.ModuleResponse, server *hcloud.Server) (err error) {
+	if m.config.PrimaryIPv4 == nil && m.config.PrimaryIPv6 == nil {
+		return
+	}
+	var primaryIPs []*hcloud.PrimaryIP
+	if primaryIPs, err = m.client.PrimaryIP.All(ctx); err != nil {
+		return
+	}
+	for _, desired := range []struct {
+		ipType hcloud.PrimaryIPType
+		ip     *publicIP
+	}{
+		{hcloud.PrimaryIPTypeIPv4, m.config.PrimaryIPv4},
+		{hcloud.PrimaryIPTypeIPv6, m.config.PrimaryIPv6},
+	} {
+		if desired.ip == nil {
+			continue
+		}
+		var current *hcloud.PrimaryIP
+		for _, primaryIP := range primaryIPs {
+			if primaryIP.AssigneeID == server.ID && primaryIP.Type == desired.ipType {
+				current = primaryIP
+			}
+		}
+		target := desired.ip.PrimaryIP
+		if current == nil && target == nil ||
+			current != nil && target != nil && current.ID == target.ID {
+			continue
+		}
+		if target != nil && target.AssigneeID != 0 && target.AssigneeID != server.ID {
+			return fmt.Errorf("Primary IP %d is assigned to server %d", target.ID, target.AssigneeID)
+		}
+
+		if err = m.stopServer(ctx, server); err != nil {
+			return
+		}
+		if current != nil {
+			var action *hcloud.Action
+			if action, _, err = m.client.PrimaryIP.Unassign(ctx, current); err != nil {
+				return
+			}
+			if err = m.waiter.WaitForActions(ctx, action); err != nil {
+				return
+			}
+			m.messages.Add(fmt.Sprintf("Primary IP %d unassigned from server %d", current.ID, server.ID))
+		}
+		if target != nil {
+			var action *hcloud.Action
+			if action, _, err = m.client.PrimaryIP.Assign(ctx, target, server); err != nil {
+				return
+			}
+			if err = m.waiter.WaitForActions(ctx, action); err != nil {
+				return
+			}
+			m.messages.Add(fmt.Sprintf("Primary IP %d assigned to server %d", target.ID, server.ID))
+		}
+		resp.Changed()
+	}
+	return
+}
+
+// stopServer powers off the server if it is running
+func (m *module) stopServer(ctx context.Context, server *hcloud.Server) error {
+	if server.Status == hcloud.ServerStatusOff {
+		return nil
+	}
+	if err := m.doAction(ctx, server, m.client.Server.Poweroff); err != nil {
+		return err
+	}
+	m.messages.Add(fmt.Sprintf("Server %d stopped", server.ID))
+	server.Status = hcloud.ServerStatusOff
+	return nil
+}
+
+// doAction triggers a server action and waits for it
+func (m *module) doAction(ctx context.Context, server *hcloud.Server, f func(context.Context, *hcloud.Server) (*hcloud.Action, *hcloud.Response, error)) error {
+	if err := m.waitForPending(ctx, server); err != nil {
+		return err
+	}
+	action, _, err := f(ctx, server)
+	if err != nil {
+		return err
+	}
+	return m.waiter.WaitForActions(ctx, action)
+}
+
+// waitForPending waits for the actions on the server that were not awaited because of `wait: false`,
+// the server is locked while they are running
+func (m *module) waitForPending(ctx context.Context, server *hcloud.Server) error {
+	return util.WaitForPending(ctx, m.waiter, hcloud.ActionResource{ID: server.ID, Type: hcloud.ActionResourceTypeServer})
+}
+
+// ensureDNSRecords points the A and AAAA records of dns_name at the public IPs of the servers,
+// records of other addresses are deleted
+func (m *module) ensureDNSRecords(ctx context.Context, resp *ansible.ModuleResponse) (err error) {
+	var servers []*hcloud.Server
+	if servers, err = m.servers(ctx); err != nil {
+		return
+	}
 	var (
-		servers []*hcloud.Server
-		s       []Server
+		zone *hetznerdns.Zone
+		name string
 	)
-	if servers, err = m.servers(ctx); err != nil {
+	if zone, name, err = hetznerdns.FindZone(ctx, m.dns.Zone, m.args.DNSName); err != nil {
+		return
+	}
+	ipv4, ipv6 := publicAddresses(servers)
+	for _, set := range []struct {
+		recordType string
+		values     []string
+	}{{"A", ipv4}, {"AAAA", ipv6}} {
+		var result hetznerdns.RecordSetResult
+		if result, err = hetznerdns.EnsureRecordSet(ctx, m.dns.Record, zone, hetznerdns.RecordSet{
+			Name:      name,
+			Type:      set.recordType,
+			Values:    set.values,
+			Exclusive: true,
+		}); err != nil {
+			return
+		}
+		if result.Changed() {
+			m.messages.Add(fmt.Sprintf("%s records of %s updated", set.recordType, m.args.DNSName))
+			resp.Changed()
+		}
+	}
+	return
+}
+
+// deleteDNSRecords deletes the A and AAAA records of dns_name pointing at the deleted servers
+func (m *module) deleteDNSRecords(ctx context.Context, resp *ansible.ModuleResponse, servers []*hcloud.Server) (err error) {
+	var (
+		zone *hetznerdns.Zone
+		name string
+	)
+	if zone, name, err = hetznerdns.FindZone(ctx, m.dns.Zone, m.args.DNSName); err != nil {
 		return
 	}
+	ipv4, ipv6 := publicAddresses(servers)
+	for _, set := range []struct {
+		recordType string
+		values     []string
+	}{{"A", ipv4}, {"AAAA", ipv6}} {
+		// no values would delete all records of the name
+		if len(set.values) == 0 {
+			continue
+		}
+		var deleted []*hetznerdns.Record
+		if deleted, err = hetznerdns.DeleteRecords(ctx, m.dns.Record, zone, name, set.recordType, set.values); err != nil {
+			return
+		}
+		if len(deleted) > 0 {
+			m.messages.Add(fmt.Sprintf("%s records of %s deleted", set.recordType, m.args.DNSName))
+			resp.Changed()
+		}
+	}
+	return
+}
+
+// publicAddresses returns the public IPv4 addresses and the first addresses
+// of the IPv6 networks of the servers
+func publicAddresses(servers []*hcloud.Server) (ipv4, ipv6 []string) {
 	for _, server := range servers {
-		s = append(s, toServer(server))
+		if ip := server.PublicNet.IPv4.IP; ip != nil {
+			ipv4 = append(ipv4, ip.String())
+		}
+		if network := server.PublicNet.IPv6.IP; network != nil {
+			ip := make(net.IP, len(network))
+			copy(ip, network)
+			ip[len(ip)-1]++
+			ipv6 = append(ipv6, ip.String())
+		}
+	}
+	return
+}
+
+// placementGroups returns the placement groups by the id of their servers
+func (m *module) placementGroups(ctx context.Context) (map[int]*hcloud.PlacementGroup, error) {
+	all, err := m.client.PlacementGroup.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	placementGroups := map[int]*hcloud.PlacementGroup{}
+	for _, placementGroup := range all {
+		for _, id := range placementGroup.Servers {
+			placementGroups[id] = placementGroup
+		}
+	}
+	return placementGroups, nil
+}
+
+// sameIPs checks if both lists contain the same IPs, ignoring the order
+func sameIPs(a, b []net.IP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	ips := map[string]bool{}
+	for _, ip := range a {
+		ips[ip.String()] = true
+	}
+	for _, ip := range b {
+		if !ips[ip.String()] {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *module) output(ctx context.Context, resp *ansible.ModuleResponse) (err error) {
+	var servers []*hcloud.Server
+	if servers, err = m.servers(ctx); err != nil {
+		return
+	}
+	var s []Server
+	if s, err = m.toServers(ctx, servers); err != nil {
+		return
 	}
 	resp.Set("servers", s)
 	return
 }
 
+// toServers converts the servers to module return values including their private IPs and placement groups
+func (m *module) toServers(ctx context.Context, servers []*hcloud.Server) (s []Server, err error) {
+	var placementGroups map[int]*hcloud.PlacementGroup
+	if placementGroups, err = m.placementGroups(ctx); err != nil {
+		return
+	}
+	for _, server := range servers {
+		var privateNets []hcloud.ServerPrivateNet
+		if privateNets, _, err = m.client.ServerNetwork.List(ctx, server); err != nil {
+			return
+		}
+		s = append(s, toServer(server, privateNets, placementGroups[server.ID]))
+	}
+	return
+}
+
 func (m *module) list(ctx context.Context) (resp ansible.ModuleResponse, err error) {
-	var servers []*hcloud.Server
+	var (
+		servers []*hcloud.Server
+		s       []Server
+	)
 	if len(m.config.ID) != 0 || len(m.config.Name) != 0 {
 		if servers, err = m.servers(ctx); err != nil {
 			return
 		}
+		if s, err = m.toServers(ctx, servers); err != nil {
+			return
+		}
 	} else {
 		if servers, err = m.client.Server.All(ctx); err != nil {
 			return
 		}
-	}
-
-	var s []Server
-	for _, server := range servers {
-		s = append(s, toServer(server))
+		var privateNets map[int][]hcloud.ServerPrivateNet
+		if privateNets, err = m.client.ServerNetwork.All(ctx); err != nil {
+			return
+		}
+		var placementGroups map[int]*hcloud.PlacementGroup
+		if placementGroups, err = m.placementGroups(ctx); err != nil {
+			return
+		}
+		for _, server := range servers {
+			s = append(s, toServer(server, privateNets[server.ID], placementGroups[server.ID]))
+		}
 	}
 	resp.Set("servers", s)
 	return
@@ -454,52 +1488,34 @@ func (m *module) restarted(ctx context.Context) (resp ansible.ModuleResponse, er
 	return m.present(ctx)
 }
 
-// needsRecreate checks if the server needs to be recreated
-func needsRecreate(server *hcloud.Server, config config) bool {
+// recreateReason returns why the server needs to be recreated, or "" if it does not
+func recreateReason(server *hcloud.Server, config config) string {
 	if server == nil {
-		return false
+		return ""
 	}
-	if config.Image != nil &&
+	// the image of a server is unknown when it was deleted
+	if config.Image != nil && server.Image != nil &&
 		server.Image.ID != config.Image.ID {
-		return true
+		return "image changed"
 	}
 	if config.ServerType != "" &&
 		server.ServerType.Name != config.ServerType {
-		return true
+		return "server_type changed"
 	}
 	if config.Datacenter != nil &&
 		server.Datacenter.Name != config.Datacenter.Name {
-		return true
+		return "datacenter changed"
 	}
 	if config.Location != nil &&
 		server.Datacenter.Location.Name != config.Location.Name {
-		return true
-	}
-
-	return false
-}
-
-func validateState(state string) error {
-	if state != statePresent &&
-		state != stateAbsent &&
-		state != stateList &&
-		state != stateRestarted &&
-		state != stateRunning &&
-		state != stateStopped {
-		return fmt.Errorf("'state' must be present, absent, running, stopped, list or restarted")
+		return "location changed"
 	}
-	return nil
+	return ""
 }
 
 func (m *module) argsToConfig(ctx context.Context) (c config, err error) {
 	c.Token = m.args.Token
 
-	if m.args.State == "" {
-		m.args.State = statePresent
-	}
-	if err = validateState(m.args.State); err != nil {
-		return
-	}
 	c.State = m.args.State
 
 	c.Name = util.GetNames(m.args.Name)
@@ -507,6 +1523,7 @@ func (m *module) argsToConfig(ctx context.Context) (c config, err error) {
 	c.ServerType = m.args.ServerType
 	c.UserData = m.args.UserData
 	c.Rescue = m.args.Rescue
+	c.Wait = m.args.Wait == nil || *m.args.Wait
 
 	// Image
 	if imageID := util.GetID(m.args.Image); imageID != 0 {
@@ -574,8 +1591,10 @@ func (m *module) argsToConfig(ctx context.Context) (c config, err error) {
 		}
 	}
 
-	// Location
-	if m.args.Location != "" {
+	// Location, the datacenter implies the location
+	if m.args.Datacenter != "" && m.args.Location != "" {
+		m.warnings.Add("datacenter and location both set, using datacenter")
+	} else if m.args.Location != "" {
 		c.Location, _, err = m.client.Location.Get(ctx, m.args.Location)
 		if err != nil {
 			return
@@ -586,25 +1605,166 @@ func (m *module) argsToConfig(ctx context.Context) (c config, err error) {
 		}
 	}
 
+	if m.args.Networks != nil {
+		c.Networks = []serverNetwork{}
+		for _, n := range m.args.Networks {
+			var network serverNetwork
+			if network, err = m.serverNetwork(ctx, n); err != nil {
+				return
+			}
+			c.Networks = append(c.Networks, network)
+		}
+	}
+
+	if m.args.PlacementGroup != nil {
+		c.PlacementGroup = &hcloud.PlacementGroup{}
+		if idOrName := util.GetIdentifier(m.args.PlacementGroup); idOrName != "" {
+			if c.PlacementGroup, _, err = m.client.PlacementGroup.Get(ctx, idOrName); err != nil {
+				return
+			}
+			if c.PlacementGroup == nil {
+				err = fmt.Errorf("placement group '%s' not found", idOrName)
+				return
+			}
+		}
+	}
+
+	if c.PrimaryIPv4, err = m.publicIP(ctx, m.args.PrimaryIPv4, hcloud.PrimaryIPTypeIPv4); err != nil {
+		return
+	}
+	if c.PrimaryIPv6, err = m.publicIP(ctx, m.args.PrimaryIPv6, hcloud.PrimaryIPTypeIPv6); err != nil {
+		return
+	}
+
+	c.TemporarySSHKeys = m.args.TemporarySSHKeys
 	if m.args.SSHKeys != nil {
 		ids := util.GetIdentifiers(m.args.SSHKeys)
 		for _, id := range ids {
+			// existing keys take precedence, their names may look like keys or paths
 			var sshKey *hcloud.SSHKey
 			if sshKey, _, err = m.client.SSHKey.Get(ctx, id); err != nil {
 				return
 			}
-			if sshKey == nil {
+			if sshKey != nil {
+				c.SSHKeys = append(c.SSHKeys, sshKey)
+				continue
+			}
+
+			var key *publicKey
+			if key, err = parsePublicKey(id); err != nil {
+				return
+			}
+			if key == nil {
 				err = fmt.Errorf("SSH Key %q not found", id)
 				return
 			}
-			c.SSHKeys = append(c.SSHKeys, sshKey)
+			c.PublicKeys = append(c.PublicKeys, *key)
+		}
+	}
+
+	return
+}
+
+// parsePublicKey parses an ssh_keys entry that is a public key or the path of a public key file,
+// it returns nil for other entries
+func parsePublicKey(value string) (key *publicKey, err error) {
+	data := value
+	isPath := strings.ContainsRune(value, filepath.Separator) || strings.HasSuffix(value, ".pub")
+	// a public key has the form "<type> <base64> [comment]"
+	fields := strings.Fields(value)
+	isKey := len(fields) >= 2 &&
+		(strings.HasPrefix(fields[0], "ssh-") || strings.HasPrefix(fields[0], "ecdsa-") || strings.HasPrefix(fields[0], "sk-"))
+	switch {
+	case isKey:
+	case isPath:
+		path := value
+		if strings.HasPrefix(path, "~/") {
+			path = filepath.Join(os.Getenv("HOME"), path[2:])
+		}
+		var file []byte
+		if file, err = ioutil.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("Cannot read SSH key: %v", err)
+		}
+		data = string(file)
+	default:
+		return nil, nil
+	}
+
+	pub, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(data))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid SSH key %q: %v", value, err)
+	}
+	if err = util.ValidatePublicKey(pub); err != nil {
+		return nil, err
+	}
+	key = &publicKey{
+		Name:        comment,
+		PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub))),
+		Fingerprint: ssh.FingerprintLegacyMD5(pub),
+	}
+	if key.Name == "" {
+		key.Name = key.Fingerprint
+	}
+	return
+}
+
+// publicIP parses a primary_ipv4 or primary_ipv6 argument,
+// true lets the API create a primary IP like an unset argument does for new servers
+func (m *module) publicIP(ctx context.Context, arg interface{}, ipType hcloud.PrimaryIPType) (ip *publicIP, err error) {
+	if enabled, ok := arg.(bool); ok {
+		if enabled {
+			return nil, nil
 		}
+		return &publicIP{Disabled: true}, nil
+	}
+	idOrName := util.GetIdentifier(arg)
+	if idOrName == "" {
+		return nil, nil
+	}
+	ip = &publicIP{}
+	if ip.PrimaryIP, _, err = m.client.PrimaryIP.Get(ctx, idOrName); err != nil {
+		return
+	}
+	if ip.PrimaryIP == nil {
+		err = fmt.Errorf("primary IP '%s' not found", idOrName)
+		return
 	}
+	if ip.PrimaryIP.Type != ipType {
+		err = fmt.Errorf("primary IP '%s' is no %s address", idOrName, ipType)
+	}
+	return
+}
 
+func (m *module) serverNetwork(ctx context.Context, n network) (network serverNetwork, err error) {
+	idOrName := util.GetIdentifier(n.Network)
+	if network.Network, _, err = m.client.Network.Get(ctx, idOrName); err != nil {
+		return
+	}
+	if network.Network == nil {
+		err = fmt.Errorf("network '%s' not found", idOrName)
+		return
+	}
+	if n.IP != "" {
+		if network.IP = net.ParseIP(n.IP); network.IP == nil {
+			err = fmt.Errorf("invalid IP %q in network '%s'", n.IP, idOrName)
+			return
+		}
+	}
+	if n.AliasIPs != nil {
+		network.AliasIPs = []net.IP{}
+		for _, alias := range n.AliasIPs {
+			ip := net.ParseIP(alias)
+			if ip == nil {
+				err = fmt.Errorf("invalid alias IP %q in network '%s'", alias, idOrName)
+				return
+			}
+			network.AliasIPs = append(network.AliasIPs, ip)
+		}
+	}
 	return
 }
 
-func toServer(server *hcloud.Server) Server {
+func toServer(server *hcloud.Server, privateNets []hcloud.ServerPrivateNet, placementGroup *hcloud.PlacementGroup) Server {
 	s := Server{
 		ID:         server.ID,
 		Name:       server.Name,
@@ -612,12 +1772,39 @@ func toServer(server *hcloud.Server) Server {
 		ServerType: server.ServerType.Name,
 		Datacenter: server.Datacenter.Name,
 		Location:   server.Datacenter.Location.Name,
-		PublicIPv4: server.PublicNet.IPv4.IP.String(),
-		PublicIPv6: server.PublicNet.IPv6.Network.String(),
+		PrivateIPs: []PrivateIP{},
+	}
+	// servers without public IPv4 or IPv6 have no address of that type
+	if server.PublicNet.IPv4.IP != nil {
+		s.PublicIPv4 = server.PublicNet.IPv4.IP.String()
+	}
+	if server.PublicNet.IPv6.Network != nil {
+		s.PublicIPv6 = server.PublicNet.IPv6.Network.String()
+	}
+	for _, privateNet := range privateNets {
+		aliasIPs := []string{}
+		for _, alias := range privateNet.Aliases {
+			aliasIPs = append(aliasIPs, alias.String())
+		}
+		s.PrivateIPs = append(s.PrivateIPs, PrivateIP{
+			NetworkID: privateNet.Network.ID,
+			IP:        privateNet.IP.String(),
+			AliasIPs:  aliasIPs,
+		})
+	}
+	// snapshots and backups have no name
+	if server.Image != nil {
+		s.Image = server.Image.Name
+		if s.Image == "" {
+			s.Image = server.Image.Description
+		}
 	}
 	if server.ISO != nil {
 		s.ISO = server.ISO.Name
 	}
+	if placementGroup != nil {
+		s.PlacementGroup = placementGroup.Name
+	}
 	return s
 }
 
@@ -628,7 +1815,5 @@ func init() {
 }
 
 func main() {
-	ansible.RunModule(&module{
-		waitFn: util.WaitForAction,
-	}, flags)
+	ansible.RunModule(&module{}, flags)
 }
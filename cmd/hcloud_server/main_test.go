@@ -3,13 +3,20 @@ package main
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
 	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
 	"github.com/thetechnick/hcloud-ansible/pkg/hcloud/hcloudtest"
+	"github.com/thetechnick/hcloud-ansible/pkg/hetznerdns"
+	"github.com/thetechnick/hcloud-ansible/pkg/hetznerdns/hetznerdnstest"
 	"github.com/thetechnick/hcloud-ansible/pkg/util"
 )
 
@@ -51,10 +58,30 @@ func init() {
 	}
 }
 
+// mockServerNetworks mocks servers without private networks
+func mockServerNetworks(client *hcloud.Client) {
+	client.ServerNetwork = hcloudtest.NewServerNetworkClientMock()
+	serverNetworkMock := client.ServerNetwork.(*hcloudtest.ServerNetworkClientMock)
+	serverNetworkMock.On("List", mock.Anything, mock.Anything).Return([]hcloud.ServerPrivateNet{}, nilResponse, nil)
+	serverNetworkMock.On("All", mock.Anything).Return(map[int][]hcloud.ServerPrivateNet{}, nil)
+}
+
+// mockPlacementGroups mocks the placement groups
+func mockPlacementGroups(client *hcloud.Client, placementGroups ...*hcloud.PlacementGroup) {
+	client.PlacementGroup = hcloudtest.NewPlacementGroupClientMock()
+	placementGroupMock := client.PlacementGroup.(*hcloudtest.PlacementGroupClientMock)
+	placementGroupMock.On("All", mock.Anything).Return(placementGroups, nil)
+	for _, placementGroup := range placementGroups {
+		placementGroupMock.On("Get", mock.Anything, placementGroup.Name).Return(placementGroup, nilResponse, nil)
+	}
+}
+
 func TestList(t *testing.T) {
 	t.Run("with id", func(t *testing.T) {
 		client := hcloud.NewClient()
 		client.Server = hcloudtest.NewServerClientMock()
+		mockServerNetworks(client)
+		mockPlacementGroups(client)
 
 		m := module{
 			client: client,
@@ -63,7 +90,7 @@ func TestList(t *testing.T) {
 				State: stateList,
 				ID:    "123",
 			},
-			waitFn: util.WaitFn(func(ctx context.Context, client *hcloud.Client, action *hcloud.Action) error {
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
 				return nil
 			}),
 		}
@@ -82,13 +109,15 @@ func TestList(t *testing.T) {
 		assert.False(t, resp.HasFailed(), "should not have failed")
 		serverClientMock.AssertCalled(t, "GetByID", mock.Anything, 123)
 		assert.Equal(t, map[string]interface{}{
-			"servers": []Server{toServer(server)},
+			"servers": []Server{toServer(server, nil, nil)},
 		}, resp.Data())
 	})
 
 	t.Run("without params", func(t *testing.T) {
 		client := hcloud.NewClient()
 		client.Server = hcloudtest.NewServerClientMock()
+		mockServerNetworks(client)
+		mockPlacementGroups(client)
 
 		m := module{
 			client: client,
@@ -96,7 +125,7 @@ func TestList(t *testing.T) {
 				Token: "--token--",
 				State: stateList,
 			},
-			waitFn: util.WaitFn(func(ctx context.Context, client *hcloud.Client, action *hcloud.Action) error {
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
 				return nil
 			}),
 		}
@@ -112,7 +141,7 @@ func TestList(t *testing.T) {
 		assert.False(t, resp.HasFailed(), "should not have failed")
 		serverClientMock.AssertCalled(t, "All", mock.Anything)
 		assert.Equal(t, map[string]interface{}{
-			"servers": []Server{toServer(server)},
+			"servers": []Server{toServer(server, nil, nil)},
 		}, resp.Data())
 	})
 }
@@ -120,6 +149,8 @@ func TestList(t *testing.T) {
 func TestPresent(t *testing.T) {
 	client := hcloud.NewClient()
 	client.Server = hcloudtest.NewServerClientMock()
+	mockServerNetworks(client)
+	mockPlacementGroups(client)
 	client.Image = hcloudtest.NewImageClientMock()
 	client.ServerType = hcloudtest.NewServerTypeClientMock()
 
@@ -132,7 +163,7 @@ func TestPresent(t *testing.T) {
 			Image:      "debian-9",
 			ServerType: "cx11",
 		},
-		waitFn: util.WaitFn(func(ctx context.Context, client *hcloud.Client, action *hcloud.Action) error {
+		waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
 			return nil
 		}),
 	}
@@ -155,12 +186,14 @@ func TestPresent(t *testing.T) {
 	assert.True(t, resp.HasChanged(), "should have changed")
 	assert.False(t, resp.HasFailed(), "should not have failed")
 	assert.Equal(t, map[string]interface{}{
-		"servers": []Server{toServer(server)},
+		"servers": []Server{toServer(server, nil, nil)},
 	}, resp.Data())
 
 	t.Run("attach ISO", func(t *testing.T) {
 		client := hcloud.NewClient()
 		client.Server = hcloudtest.NewServerClientMock()
+		mockServerNetworks(client)
+		mockPlacementGroups(client)
 		client.Image = hcloudtest.NewImageClientMock()
 		client.ServerType = hcloudtest.NewServerTypeClientMock()
 		client.ISO = hcloudtest.NewISOClientMock()
@@ -175,7 +208,7 @@ func TestPresent(t *testing.T) {
 				ServerType: "cx11",
 				ISO:        "test.iso",
 			},
-			waitFn: util.WaitFn(func(ctx context.Context, client *hcloud.Client, action *hcloud.Action) error {
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
 				return nil
 			}),
 		}
@@ -202,13 +235,15 @@ func TestPresent(t *testing.T) {
 		assert.True(t, resp.HasChanged(), "should have changed")
 		assert.False(t, resp.HasFailed(), "should not have failed")
 		assert.Equal(t, map[string]interface{}{
-			"servers": []Server{toServer(&server)},
+			"servers": []Server{toServer(&server, nil, nil)},
 		}, resp.Data())
 	})
 
 	t.Run("detach ISO", func(t *testing.T) {
 		client := hcloud.NewClient()
 		client.Server = hcloudtest.NewServerClientMock()
+		mockServerNetworks(client)
+		mockPlacementGroups(client)
 		client.Image = hcloudtest.NewImageClientMock()
 		client.ServerType = hcloudtest.NewServerTypeClientMock()
 		client.ISO = hcloudtest.NewISOClientMock()
@@ -222,7 +257,7 @@ func TestPresent(t *testing.T) {
 				Image:      "debian-9",
 				ServerType: "cx11",
 			},
-			waitFn: util.WaitFn(func(ctx context.Context, client *hcloud.Client, action *hcloud.Action) error {
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
 				return nil
 			}),
 		}
@@ -250,7 +285,7 @@ func TestPresent(t *testing.T) {
 		assert.True(t, resp.HasChanged(), "should have changed")
 		assert.False(t, resp.HasFailed(), "should not have failed")
 		assert.Equal(t, map[string]interface{}{
-			"servers": []Server{toServer(&server)},
+			"servers": []Server{toServer(&server, nil, nil)},
 		}, resp.Data())
 	})
 }
@@ -258,6 +293,8 @@ func TestPresent(t *testing.T) {
 func TestRunning(t *testing.T) {
 	client := hcloud.NewClient()
 	client.Server = hcloudtest.NewServerClientMock()
+	mockServerNetworks(client)
+	mockPlacementGroups(client)
 	client.Image = hcloudtest.NewImageClientMock()
 	client.ServerType = hcloudtest.NewServerTypeClientMock()
 
@@ -270,7 +307,7 @@ func TestRunning(t *testing.T) {
 			Image:      "debian-9",
 			ServerType: "cx11",
 		},
-		waitFn: util.WaitFn(func(ctx context.Context, client *hcloud.Client, action *hcloud.Action) error {
+		waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
 			return nil
 		}),
 	}
@@ -297,7 +334,7 @@ func TestRunning(t *testing.T) {
 	assert.True(t, resp.HasChanged(), "should have changed")
 	assert.False(t, resp.HasFailed(), "should not have failed")
 	assert.Equal(t, map[string]interface{}{
-		"servers": []Server{toServer(&server)},
+		"servers": []Server{toServer(&server, nil, nil)},
 	}, resp.Data())
 	serverClientMock.AssertCalled(t, "Poweron", mock.Anything, &server)
 }
@@ -306,6 +343,8 @@ func TestStopped(t *testing.T) {
 	t.Run("server absent", func(t *testing.T) {
 		client := hcloud.NewClient()
 		client.Server = hcloudtest.NewServerClientMock()
+		mockServerNetworks(client)
+		mockPlacementGroups(client)
 		client.Image = hcloudtest.NewImageClientMock()
 		client.ServerType = hcloudtest.NewServerTypeClientMock()
 
@@ -318,7 +357,7 @@ func TestStopped(t *testing.T) {
 				Image:      "debian-9",
 				ServerType: "cx11",
 			},
-			waitFn: util.WaitFn(func(ctx context.Context, client *hcloud.Client, action *hcloud.Action) error {
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
 				return nil
 			}),
 		}
@@ -345,7 +384,7 @@ func TestStopped(t *testing.T) {
 		assert.True(t, resp.HasChanged(), "should have changed")
 		assert.False(t, resp.HasFailed(), "should not have failed")
 		assert.Equal(t, map[string]interface{}{
-			"servers": []Server{toServer(&server)},
+			"servers": []Server{toServer(&server, nil, nil)},
 		}, resp.Data())
 		serverClientMock.AssertCalled(t, "Create", mock.Anything, hcloud.ServerCreateOpts{
 			Name: "test",
@@ -361,6 +400,8 @@ func TestStopped(t *testing.T) {
 	t.Run("server running", func(t *testing.T) {
 		client := hcloud.NewClient()
 		client.Server = hcloudtest.NewServerClientMock()
+		mockServerNetworks(client)
+		mockPlacementGroups(client)
 		client.Image = hcloudtest.NewImageClientMock()
 		client.ServerType = hcloudtest.NewServerTypeClientMock()
 
@@ -373,7 +414,7 @@ func TestStopped(t *testing.T) {
 				Image:      "debian-9",
 				ServerType: "cx11",
 			},
-			waitFn: util.WaitFn(func(ctx context.Context, client *hcloud.Client, action *hcloud.Action) error {
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
 				return nil
 			}),
 		}
@@ -395,7 +436,7 @@ func TestStopped(t *testing.T) {
 		assert.True(t, resp.HasChanged(), "should have changed")
 		assert.False(t, resp.HasFailed(), "should not have failed")
 		assert.Equal(t, map[string]interface{}{
-			"servers": []Server{toServer(&server)},
+			"servers": []Server{toServer(&server, nil, nil)},
 		}, resp.Data())
 		serverClientMock.AssertCalled(t, "Poweroff", mock.Anything, &server)
 	})
@@ -405,6 +446,8 @@ func TestStopped(t *testing.T) {
 func TestAbsent(t *testing.T) {
 	client := hcloud.NewClient()
 	client.Server = hcloudtest.NewServerClientMock()
+	mockServerNetworks(client)
+	mockPlacementGroups(client)
 
 	m := module{
 		client: client,
@@ -432,6 +475,8 @@ func TestAbsent(t *testing.T) {
 	t.Run("server not found", func(t *testing.T) {
 		client := hcloud.NewClient()
 		client.Server = hcloudtest.NewServerClientMock()
+		mockServerNetworks(client)
+		mockPlacementGroups(client)
 
 		m := module{
 			client: client,
@@ -461,6 +506,8 @@ func TestAbsent(t *testing.T) {
 func TestRestarted(t *testing.T) {
 	client := hcloud.NewClient()
 	client.Server = hcloudtest.NewServerClientMock()
+	mockServerNetworks(client)
+	mockPlacementGroups(client)
 
 	m := module{
 		client: client,
@@ -469,7 +516,7 @@ func TestRestarted(t *testing.T) {
 			State: stateRestarted,
 			ID:    "123",
 		},
-		waitFn: util.WaitFn(func(ctx context.Context, client *hcloud.Client, action *hcloud.Action) error {
+		waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
 			return nil
 		}),
 	}
@@ -493,11 +540,213 @@ func TestRestarted(t *testing.T) {
 	serverClientMock.AssertCalled(t, "GetByID", mock.Anything, 123)
 	serverClientMock.AssertCalled(t, "Reboot", mock.Anything, &server)
 	assert.Equal(t, map[string]interface{}{
-		"servers": []Server{toServer(&server)},
+		"servers": []Server{toServer(&server, nil, nil)},
 	}, resp.Data())
 }
 
-func TestValidateState(t *testing.T) {
+func TestNoWait(t *testing.T) {
+	client := hcloud.NewClient()
+	client.Server = hcloudtest.NewServerClientMock()
+	mockServerNetworks(client)
+	mockPlacementGroups(client)
+
+	m := module{
+		client: client,
+		args: arguments{
+			Token: "--token--",
+			State: stateRestarted,
+			ID:    "123",
+			Wait:  hcloud.Bool(false),
+		},
+		waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+			t.Error("should not wait for actions")
+			return nil
+		}),
+	}
+
+	ctx := context.Background()
+	server := *server
+	server.Status = hcloud.ServerStatusRunning
+	serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+	serverClientMock.On("GetByID", mock.Anything, mock.Anything).Return(&server, nilResponse, nil)
+	serverClientMock.On("Reboot", mock.Anything, mock.Anything).Return(&hcloud.Action{ID: 456}, nilResponse, nil)
+
+	resp, err := m.run(ctx)
+	assert.NoError(t, err)
+	assert.True(t, resp.HasChanged(), "should have changed")
+	assert.Equal(t, map[string]interface{}{
+		"servers":    []Server{toServer(&server, nil, nil)},
+		"action_ids": []int{456},
+	}, resp.Data())
+}
+
+func TestNoWaitDependentActions(t *testing.T) {
+	client := hcloud.NewClient()
+	client.Server = hcloudtest.NewServerClientMock()
+	client.ISO = hcloudtest.NewISOClientMock()
+	mockServerNetworks(client)
+	mockPlacementGroups(client)
+
+	// the server is locked while the ISO is detached, so the detach
+	// is awaited and only the attach is left pending
+	var waited []int
+	m := module{
+		client: client,
+		args: arguments{
+			Token: "--token--",
+			State: statePresent,
+			ID:    "123",
+			ISO:   "new.iso",
+			Wait:  hcloud.Bool(false),
+		},
+		waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+			for _, action := range actions {
+				waited = append(waited, action.ID)
+			}
+			return nil
+		}),
+	}
+
+	server := *server
+	server.ISO = iso
+	newISO := &hcloud.ISO{ID: 789, Name: "new.iso"}
+	serverAction := func(id int) *hcloud.Action {
+		return &hcloud.Action{
+			ID:        id,
+			Status:    hcloud.ActionStatusRunning,
+			Resources: []*hcloud.ActionResource{{ID: server.ID, Type: hcloud.ActionResourceTypeServer}},
+		}
+	}
+	serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+	serverClientMock.On("GetByID", mock.Anything, mock.Anything).Return(&server, nilResponse, nil)
+	serverClientMock.On("DetachISO", mock.Anything, &server).Return(serverAction(1), nilResponse, nil)
+	serverClientMock.On("AttachISO", mock.Anything, &server, newISO).Run(func(args mock.Arguments) {
+		assert.Equal(t, []int{1}, waited, "ISO attached before the detach finished")
+	}).Return(serverAction(2), nilResponse, nil)
+	isoClientMock := client.ISO.(*hcloudtest.ISOClientMock)
+	isoClientMock.On("GetByName", mock.Anything, "new.iso").Return(newISO, nilResponse, nil)
+
+	resp, err := m.run(context.Background())
+	if assert.NoError(t, err) {
+		assert.True(t, resp.HasChanged(), "should have changed")
+		assert.Equal(t, []int{1}, waited)
+		assert.Equal(t, []int{2}, resp.Data()["action_ids"])
+	}
+}
+
+func TestCheck(t *testing.T) {
+	fsn1 := &hcloud.Location{Name: "fsn1"}
+	pricing := hcloud.Pricing{
+		Image: hcloud.ImagePricing{
+			PerGBMonth: hcloud.Price{Currency: "EUR", Net: "0.0100", Gross: "0.0119"},
+		},
+		ServerBackup: hcloud.ServerBackupPricing{Percentage: "20"},
+		ServerTypes: []hcloud.ServerTypePricing{
+			{
+				ServerType: &hcloud.ServerType{Name: "cx11"},
+				Pricings: []hcloud.ServerTypeLocationPricing{{
+					Location: fsn1,
+					Hourly:   hcloud.Price{Net: "0.0050", Gross: "0.0060"},
+					Monthly:  hcloud.Price{Net: "3.0000", Gross: "3.5700"},
+				}},
+			},
+			{
+				ServerType: &hcloud.ServerType{Name: "cx21"},
+				Pricings: []hcloud.ServerTypeLocationPricing{{
+					Location: fsn1,
+					Hourly:   hcloud.Price{Net: "0.0100", Gross: "0.0119"},
+					Monthly:  hcloud.Price{Net: "6.0000", Gross: "7.1400"},
+				}},
+			},
+		},
+	}
+	web := &hcloud.Server{
+		ID:           1,
+		Name:         "web",
+		Status:       hcloud.ServerStatusRunning,
+		ServerType:   &hcloud.ServerType{Name: "cx11"},
+		Datacenter:   &hcloud.Datacenter{Name: "fsn1-dc8", Location: fsn1},
+		BackupWindow: "22-02",
+	}
+	newClient := func() *hcloud.Client {
+		client := hcloud.NewClient()
+		client.Server = hcloudtest.NewServerClientMock()
+		client.Pricing = hcloudtest.NewPricingClientMock()
+		client.Pricing.(*hcloudtest.PricingClientMock).On("Get", mock.Anything).Return(pricing, nilResponse, nil)
+		serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+		serverClientMock.On("GetByName", mock.Anything, "web").Return(web, nilResponse, nil)
+		serverClientMock.On("GetByName", mock.Anything, "new").Return(nilServer, nilResponse, nil)
+		return client
+	}
+
+	t.Run("recreate and create", func(t *testing.T) {
+		client := newClient()
+		client.Location = hcloudtest.NewLocationClientMock()
+		client.Location.(*hcloudtest.LocationClientMock).On("Get", mock.Anything, "fsn1").Return(fsn1, nilResponse, nil)
+		m := &module{
+			client: client,
+			args: arguments{
+				State:      statePresent,
+				Name:       "web",
+				ServerType: "cx21",
+			},
+		}
+
+		resp, err := m.check(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged())
+			assert.Equal(t, CostDelta{
+				Currency: "EUR",
+				Monthly:  util.Cost{Net: 2.4, Gross: 2.856},
+				Hourly:   util.Cost{Net: 0.004, Gross: 0.0047},
+			}, resp.Data()["cost"])
+		}
+		client.Server.(*hcloudtest.ServerClientMock).AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+		client.Server.(*hcloudtest.ServerClientMock).AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("create without location", func(t *testing.T) {
+		m := &module{
+			client: newClient(),
+			args: arguments{
+				State:      statePresent,
+				Name:       "new",
+				ServerType: "cx11",
+			},
+		}
+
+		resp, err := m.check(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged())
+			assert.Equal(t, CostDelta{Currency: "EUR"}, resp.Data()["cost"])
+			assert.Equal(t, []string{"Cost of server 'new' is unknown without 'location' or 'datacenter'"}, resp.Warnings())
+		}
+	})
+
+	t.Run("absent", func(t *testing.T) {
+		m := &module{
+			client: newClient(),
+			args: arguments{
+				State: stateAbsent,
+				Name:  []interface{}{"web", "new"},
+			},
+		}
+
+		resp, err := m.check(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged())
+			assert.Equal(t, CostDelta{
+				Currency: "EUR",
+				Monthly:  util.Cost{Net: -3.6, Gross: -4.284},
+				Hourly:   util.Cost{Net: -0.006, Gross: -0.0072},
+			}, resp.Data()["cost"])
+		}
+		client := m.client.Server.(*hcloudtest.ServerClientMock)
+		client.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
 	valid := []string{
 		statePresent,
 		stateAbsent,
@@ -512,12 +761,12 @@ func TestValidateState(t *testing.T) {
 	}
 	for _, state := range valid {
 		t.Run(fmt.Sprintf("valid - %q", state), func(t *testing.T) {
-			assert.NoError(t, validateState(state))
+			assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: state}}))
 		})
 	}
 	for _, state := range invalid {
 		t.Run(fmt.Sprintf("invalid - %q", state), func(t *testing.T) {
-			assert.Error(t, validateState(state))
+			assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: state}}))
 		})
 	}
 }
@@ -553,3 +802,624 @@ func TestArgsToConfig(t *testing.T) {
 	}
 	isoClientMock.AssertCalled(t, "GetByName", mock.Anything, "test.iso")
 }
+
+func TestDatacenterAndLocation(t *testing.T) {
+	client := hcloud.NewClient()
+	client.Server = hcloudtest.NewServerClientMock()
+	client.Datacenter = hcloudtest.NewDatacenterClientMock()
+	client.Location = hcloudtest.NewLocationClientMock()
+	mockServerNetworks(client)
+	mockPlacementGroups(client)
+	serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+	datacenterClientMock := client.Datacenter.(*hcloudtest.DatacenterClientMock)
+	locationClientMock := client.Location.(*hcloudtest.LocationClientMock)
+	datacenter := &hcloud.Datacenter{ID: 4, Name: "fsn1-dc14", Location: &hcloud.Location{Name: "fsn1"}}
+	serverClientMock.On("GetByID", mock.Anything, 123).Return(server, nilResponse, nil)
+	datacenterClientMock.On("Get", mock.Anything, "fsn1-dc14").Return(datacenter, nilResponse, nil)
+
+	m := &module{
+		client: client,
+		args: arguments{
+			State:      stateList,
+			ID:         "123",
+			Datacenter: "fsn1-dc14",
+			Location:   "nbg1",
+		},
+	}
+
+	resp, err := m.run(context.Background())
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, datacenter, m.config.Datacenter)
+		assert.Nil(t, m.config.Location)
+		assert.Equal(t, []string{"datacenter and location both set, using datacenter"}, resp.Warnings())
+	}
+	locationClientMock.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+}
+
+func TestRecreateReason(t *testing.T) {
+	debian10 := &hcloud.Image{ID: 456, Name: "debian-10"}
+
+	assert.Equal(t, "", recreateReason(nil, config{Image: debian10}))
+	assert.Equal(t, "", recreateReason(server, config{Image: image, ServerType: "cx11"}))
+	assert.Equal(t, "image changed", recreateReason(server, config{Image: debian10}))
+	assert.Equal(t, "server_type changed", recreateReason(server, config{ServerType: "cx21"}))
+
+	deleted := *server
+	deleted.Image = nil
+	assert.Equal(t, "", recreateReason(&deleted, config{Image: debian10}))
+}
+
+func TestNetworks(t *testing.T) {
+	client := hcloud.NewClient()
+	client.Server = hcloudtest.NewServerClientMock()
+	client.Network = hcloudtest.NewNetworkClientMock()
+	client.ServerNetwork = hcloudtest.NewServerNetworkClientMock()
+	mockPlacementGroups(client)
+
+	internal := &hcloud.Network{ID: 1, Name: "internal"}
+	db := &hcloud.Network{ID: 2, Name: "db"}
+	legacy := &hcloud.Network{ID: 3}
+	server := *server
+
+	m := module{
+		client: client,
+		args: arguments{
+			State: statePresent,
+			ID:    123,
+			Networks: []network{
+				{Network: "internal", IP: "10.0.1.6"},
+				{Network: float64(2), AliasIPs: []string{"10.1.0.10"}},
+			},
+		},
+		waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+			return nil
+		}),
+	}
+
+	networkClientMock := client.Network.(*hcloudtest.NetworkClientMock)
+	networkClientMock.On("Get", mock.Anything, "internal").Return(internal, nilResponse, nil)
+	networkClientMock.On("Get", mock.Anything, "2").Return(db, nilResponse, nil)
+
+	serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+	serverClientMock.On("GetByID", mock.Anything, 123).Return(&server, nilResponse, nil)
+
+	attached := []hcloud.ServerPrivateNet{
+		{Network: &hcloud.Network{ID: 1}, IP: net.ParseIP("10.0.1.5"), Aliases: []net.IP{}},
+		{Network: &hcloud.Network{ID: 2}, IP: net.ParseIP("10.1.0.2"), Aliases: []net.IP{}},
+		{Network: legacy, IP: net.ParseIP("10.2.0.2"), Aliases: []net.IP{}},
+	}
+	serverNetworkMock := client.ServerNetwork.(*hcloudtest.ServerNetworkClientMock)
+	serverNetworkMock.On("List", mock.Anything, &server).Return(attached, nilResponse, nil)
+	serverNetworkMock.On("Detach", mock.Anything, &server, attached[0].Network).Return(&hcloud.Action{ID: 1}, nilResponse, nil)
+	serverNetworkMock.On("Detach", mock.Anything, &server, legacy).Return(&hcloud.Action{ID: 2}, nilResponse, nil)
+	serverNetworkMock.On("Attach", mock.Anything, &server, hcloud.ServerAttachToNetworkOpts{
+		Network: internal,
+		IP:      net.ParseIP("10.0.1.6"),
+	}).Return(&hcloud.Action{ID: 3}, nilResponse, nil)
+	serverNetworkMock.On("ChangeAliasIPs", mock.Anything, &server, db, []net.IP{net.ParseIP("10.1.0.10")}).Return(&hcloud.Action{ID: 4}, nilResponse, nil)
+
+	resp, err := m.run(context.Background())
+	if assert.NoError(t, err) {
+		assert.True(t, resp.HasChanged(), "should have changed")
+		serverNetworkMock.AssertNumberOfCalls(t, "Detach", 2)
+		serverNetworkMock.AssertNumberOfCalls(t, "Attach", 1)
+		serverNetworkMock.AssertNumberOfCalls(t, "ChangeAliasIPs", 1)
+		assert.Equal(t, []PrivateIP{
+			{NetworkID: 1, IP: "10.0.1.5", AliasIPs: []string{}},
+			{NetworkID: 2, IP: "10.1.0.2", AliasIPs: []string{}},
+			{NetworkID: 3, IP: "10.2.0.2", AliasIPs: []string{}},
+		}, resp.Data()["servers"].([]Server)[0].PrivateIPs)
+	}
+}
+
+func TestPlacementGroup(t *testing.T) {
+	t.Run("create in placement group", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Server = hcloudtest.NewServerClientMock()
+		client.Image = hcloudtest.NewImageClientMock()
+		mockServerNetworks(client)
+		placementGroup := &hcloud.PlacementGroup{ID: 7, Name: "db", Servers: []int{server.ID}}
+		mockPlacementGroups(client, placementGroup)
+
+		m := module{
+			client: client,
+			args: arguments{
+				State:          statePresent,
+				Name:           "test",
+				Image:          "debian-9",
+				ServerType:     "cx11",
+				PlacementGroup: "db",
+			},
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				return nil
+			}),
+		}
+
+		imageClientMock := client.Image.(*hcloudtest.ImageClientMock)
+		imageClientMock.On("GetByName", mock.Anything, mock.Anything).Return(image, nilResponse, nil)
+
+		serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+		serverClientMock.On("GetByName", mock.Anything, mock.Anything).Return(nilServer, nilResponse, nil).Once()
+		serverClientMock.On("GetByName", mock.Anything, mock.Anything).Return(server, nilResponse, nil)
+		serverClientMock.On("Create", mock.Anything, mock.MatchedBy(func(opts hcloud.ServerCreateOpts) bool {
+			return opts.PlacementGroup == placementGroup
+		})).Return(hcloud.ServerCreateResult{
+			Server: server,
+			Action: &hcloud.Action{ID: 123},
+		}, nilResponse, nil)
+
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "should have changed")
+			serverClientMock.AssertNumberOfCalls(t, "Create", 1)
+			serverClientMock.AssertNotCalled(t, "AddToPlacementGroup", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("move running server", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Server = hcloudtest.NewServerClientMock()
+		client.PlacementGroup = hcloudtest.NewPlacementGroupClientMock()
+		mockServerNetworks(client)
+		server := *server
+		server.Status = hcloud.ServerStatusRunning
+		web := &hcloud.PlacementGroup{ID: 6, Name: "web", Servers: []int{server.ID}}
+		db := &hcloud.PlacementGroup{ID: 7, Name: "db", Servers: []int{}}
+
+		placementGroupMock := client.PlacementGroup.(*hcloudtest.PlacementGroupClientMock)
+		placementGroupMock.On("Get", mock.Anything, "db").Return(db, nilResponse, nil)
+		placementGroupMock.On("All", mock.Anything).Return([]*hcloud.PlacementGroup{web, db}, nil).Once()
+		placementGroupMock.On("All", mock.Anything).Return([]*hcloud.PlacementGroup{
+			{ID: 6, Name: "web", Servers: []int{}},
+			{ID: 7, Name: "db", Servers: []int{server.ID}},
+		}, nil)
+
+		serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+		serverClientMock.On("GetByID", mock.Anything, server.ID).Return(&server, nilResponse, nil)
+		serverClientMock.On("Poweroff", mock.Anything, &server).Return(&hcloud.Action{ID: 1}, nilResponse, nil)
+		serverClientMock.On("RemoveFromPlacementGroup", mock.Anything, &server).Return(&hcloud.Action{ID: 2}, nilResponse, nil)
+		serverClientMock.On("AddToPlacementGroup", mock.Anything, &server, db).Return(&hcloud.Action{ID: 3}, nilResponse, nil)
+		serverClientMock.On("Poweron", mock.Anything, &server).Return(&hcloud.Action{ID: 4}, nilResponse, nil)
+
+		m := module{
+			client: client,
+			args: arguments{
+				State:          statePresent,
+				ID:             float64(server.ID),
+				PlacementGroup: "db",
+				Wait:           hcloud.Bool(false),
+			},
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				return nil
+			}),
+		}
+
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "should have changed")
+			serverClientMock.AssertNumberOfCalls(t, "Poweroff", 1)
+			serverClientMock.AssertNumberOfCalls(t, "RemoveFromPlacementGroup", 1)
+			serverClientMock.AssertNumberOfCalls(t, "AddToPlacementGroup", 1)
+			serverClientMock.AssertNumberOfCalls(t, "Poweron", 1)
+			assert.Equal(t, "db", resp.Data()["servers"].([]Server)[0].PlacementGroup)
+			assert.Nil(t, resp.Data()["action_ids"], "should always wait")
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Server = hcloudtest.NewServerClientMock()
+		mockServerNetworks(client)
+		server := *server
+		mockPlacementGroups(client, &hcloud.PlacementGroup{ID: 7, Name: "db", Servers: []int{server.ID}})
+
+		serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+		serverClientMock.On("GetByID", mock.Anything, server.ID).Return(&server, nilResponse, nil)
+
+		m := module{
+			client: client,
+			args: arguments{
+				State:          statePresent,
+				ID:             float64(server.ID),
+				PlacementGroup: "db",
+			},
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				return nil
+			}),
+		}
+
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.False(t, resp.HasChanged(), "should not have changed")
+		}
+	})
+}
+
+func TestPrimaryIPs(t *testing.T) {
+	t.Run("create IPv6 only server", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Server = hcloudtest.NewServerClientMock()
+		client.Image = hcloudtest.NewImageClientMock()
+		client.PrimaryIP = hcloudtest.NewPrimaryIPClientMock()
+		mockServerNetworks(client)
+		mockPlacementGroups(client)
+		ipv6 := &hcloud.PrimaryIP{ID: 6, Name: "web-v6", Type: hcloud.PrimaryIPTypeIPv6, AssigneeID: server.ID}
+
+		primaryIPMock := client.PrimaryIP.(*hcloudtest.PrimaryIPClientMock)
+		primaryIPMock.On("Get", mock.Anything, "web-v6").Return(ipv6, nilResponse, nil)
+		primaryIPMock.On("All", mock.Anything).Return([]*hcloud.PrimaryIP{ipv6}, nil)
+
+		imageClientMock := client.Image.(*hcloudtest.ImageClientMock)
+		imageClientMock.On("GetByName", mock.Anything, mock.Anything).Return(image, nilResponse, nil)
+
+		serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+		serverClientMock.On("GetByName", mock.Anything, mock.Anything).Return(nilServer, nilResponse, nil).Once()
+		serverClientMock.On("GetByName", mock.Anything, mock.Anything).Return(server, nilResponse, nil)
+		serverClientMock.On("Create", mock.Anything, mock.MatchedBy(func(opts hcloud.ServerCreateOpts) bool {
+			return assert.ObjectsAreEqual(&hcloud.ServerCreatePublicNet{EnableIPv6: true, IPv6: ipv6}, opts.PublicNet)
+		})).Return(hcloud.ServerCreateResult{
+			Server: server,
+			Action: &hcloud.Action{ID: 123},
+		}, nilResponse, nil)
+
+		m := module{
+			client: client,
+			args: arguments{
+				State:       statePresent,
+				Name:        "test",
+				Image:       "debian-9",
+				ServerType:  "cx11",
+				PrimaryIPv4: false,
+				PrimaryIPv6: "web-v6",
+			},
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				return nil
+			}),
+		}
+
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "should have changed")
+			serverClientMock.AssertNumberOfCalls(t, "Create", 1)
+			primaryIPMock.AssertNotCalled(t, "Assign", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("recreate keeps IPv4", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Server = hcloudtest.NewServerClientMock()
+		client.Image = hcloudtest.NewImageClientMock()
+		client.PrimaryIP = hcloudtest.NewPrimaryIPClientMock()
+		mockServerNetworks(client)
+		mockPlacementGroups(client)
+		debian10 := &hcloud.Image{ID: 124, Name: "debian-10"}
+		recreated := *server
+		recreated.ID = 124
+		recreated.Image = debian10
+		ipv4 := &hcloud.PrimaryIP{ID: 4, Name: "web", Type: hcloud.PrimaryIPTypeIPv4, AssigneeID: server.ID, AutoDelete: true}
+
+		// the primary IP must survive the deletion and is
+		// assigned to the old server until it is gone
+		var calls []string
+		call := func(name string) func(mock.Arguments) {
+			return func(mock.Arguments) { calls = append(calls, name) }
+		}
+		primaryIPMock := client.PrimaryIP.(*hcloudtest.PrimaryIPClientMock)
+		primaryIPMock.On("Get", mock.Anything, "web").Return(ipv4, nilResponse, nil)
+		primaryIPMock.On("Update", mock.Anything, ipv4, hcloud.PrimaryIPUpdateOpts{AutoDelete: hcloud.Bool(false)}).
+			Run(call("disable auto_delete")).Return(ipv4, nilResponse, nil)
+		primaryIPMock.On("Update", mock.Anything, ipv4, hcloud.PrimaryIPUpdateOpts{AutoDelete: hcloud.Bool(true)}).
+			Run(call("enable auto_delete")).Return(ipv4, nilResponse, nil)
+		primaryIPMock.On("All", mock.Anything).Return([]*hcloud.PrimaryIP{
+			{ID: 4, Name: "web", Type: hcloud.PrimaryIPTypeIPv4, AssigneeID: recreated.ID},
+		}, nil)
+
+		imageClientMock := client.Image.(*hcloudtest.ImageClientMock)
+		imageClientMock.On("GetByName", mock.Anything, "debian-10").Return(debian10, nilResponse, nil)
+
+		serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+		serverClientMock.On("GetByName", mock.Anything, "test").Return(server, nilResponse, nil).Once()
+		serverClientMock.On("GetByName", mock.Anything, "test").Return(&recreated, nilResponse, nil)
+		serverClientMock.On("Delete", mock.Anything, server).Run(call("delete")).Return(nilResponse, nil)
+		serverClientMock.On("GetByID", mock.Anything, server.ID).Run(call("deleting")).Return(server, nilResponse, nil).Once()
+		serverClientMock.On("GetByID", mock.Anything, server.ID).Run(call("deleted")).Return(nilServer, nilResponse, nil)
+		serverClientMock.On("Create", mock.Anything, mock.MatchedBy(func(opts hcloud.ServerCreateOpts) bool {
+			return opts.PublicNet != nil && opts.PublicNet.IPv4 == ipv4
+		})).Run(call("create")).Return(hcloud.ServerCreateResult{
+			Server: &recreated,
+			Action: &hcloud.Action{ID: 123},
+		}, nilResponse, nil)
+
+		m := module{
+			client: client,
+			args: arguments{
+				State:       statePresent,
+				Name:        "test",
+				Image:       "debian-10",
+				ServerType:  "cx11",
+				PrimaryIPv4: "web",
+			},
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				return nil
+			}),
+			pollInterval: time.Millisecond,
+		}
+
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "should have changed")
+			assert.Equal(t, []string{"disable auto_delete", "delete", "deleting", "deleted", "create", "enable auto_delete"}, calls)
+			primaryIPMock.AssertNotCalled(t, "Assign", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("swap IPv4 of stopped server", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Server = hcloudtest.NewServerClientMock()
+		client.PrimaryIP = hcloudtest.NewPrimaryIPClientMock()
+		mockServerNetworks(client)
+		mockPlacementGroups(client)
+		server := *server
+		server.Status = hcloud.ServerStatusOff
+		current := &hcloud.PrimaryIP{ID: 4, Type: hcloud.PrimaryIPTypeIPv4, AssigneeID: server.ID}
+		target := &hcloud.PrimaryIP{ID: 5, Name: "web", Type: hcloud.PrimaryIPTypeIPv4}
+
+		primaryIPMock := client.PrimaryIP.(*hcloudtest.PrimaryIPClientMock)
+		primaryIPMock.On("Get", mock.Anything, "web").Return(target, nilResponse, nil)
+		primaryIPMock.On("All", mock.Anything).Return([]*hcloud.PrimaryIP{current, target}, nil)
+		primaryIPMock.On("Unassign", mock.Anything, current).Return(&hcloud.Action{ID: 1}, nilResponse, nil)
+		primaryIPMock.On("Assign", mock.Anything, target, &server).Return(&hcloud.Action{ID: 2}, nilResponse, nil)
+
+		serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+		serverClientMock.On("GetByID", mock.Anything, server.ID).Return(&server, nilResponse, nil)
+
+		m := module{
+			client: client,
+			args: arguments{
+				State:       stateStopped,
+				ID:          float64(server.ID),
+				PrimaryIPv4: "web",
+			},
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				return nil
+			}),
+		}
+
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "should have changed")
+			primaryIPMock.AssertNumberOfCalls(t, "Unassign", 1)
+			primaryIPMock.AssertNumberOfCalls(t, "Assign", 1)
+			serverClientMock.AssertNotCalled(t, "Poweroff", mock.Anything, mock.Anything)
+			serverClientMock.AssertNotCalled(t, "Poweron", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("assigned to another server", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Server = hcloudtest.NewServerClientMock()
+		client.PrimaryIP = hcloudtest.NewPrimaryIPClientMock()
+		mockServerNetworks(client)
+		mockPlacementGroups(client)
+		server := *server
+		target := &hcloud.PrimaryIP{ID: 5, Name: "web", Type: hcloud.PrimaryIPTypeIPv4, AssigneeID: 999}
+
+		primaryIPMock := client.PrimaryIP.(*hcloudtest.PrimaryIPClientMock)
+		primaryIPMock.On("Get", mock.Anything, "web").Return(target, nilResponse, nil)
+		primaryIPMock.On("All", mock.Anything).Return([]*hcloud.PrimaryIP{target}, nil)
+
+		serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+		serverClientMock.On("GetByID", mock.Anything, server.ID).Return(&server, nilResponse, nil)
+
+		m := module{
+			client: client,
+			args: arguments{
+				State:       statePresent,
+				ID:          float64(server.ID),
+				PrimaryIPv4: "web",
+			},
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				return nil
+			}),
+		}
+
+		_, err := m.run(context.Background())
+		assert.Error(t, err)
+		serverClientMock.AssertNotCalled(t, "Poweroff", mock.Anything, mock.Anything)
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.PrimaryIP = hcloudtest.NewPrimaryIPClientMock()
+		client.PrimaryIP.(*hcloudtest.PrimaryIPClientMock).On("Get", mock.Anything, "web").
+			Return(&hcloud.PrimaryIP{ID: 5, Type: hcloud.PrimaryIPTypeIPv4}, nilResponse, nil)
+
+		m := module{client: client, args: arguments{State: statePresent, Name: "test", PrimaryIPv6: "web"}}
+		_, err := m.argsToConfig(context.Background())
+		assert.Error(t, err)
+	})
+}
+
+func TestSSHKeys(t *testing.T) {
+	const (
+		alice            = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGrhZ30jkQntYeeJNvC5fUVDfi/XmjSRbnOLMLzhyAuq"
+		aliceFingerprint = "a2:94:75:0d:cf:fd:2c:fc:77:81:0e:c6:7a:8d:a2:21"
+		bob              = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAID5BqbTwUvUFB63TyHUnKuPVKcMuTJ/tV5QNXK/fQQBz"
+		bobFingerprint   = "66:aa:c6:58:c2:c8:22:ba:4d:cd:a4:4d:a4:22:88:da"
+	)
+	dir, err := ioutil.TempDir("", "hcloud_server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bobFile := filepath.Join(dir, "id_ed25519.pub")
+	assert.NoError(t, ioutil.WriteFile(bobFile, []byte(bob+" bob@laptop\n"), 0600))
+
+	client := hcloud.NewClient()
+	client.Server = hcloudtest.NewServerClientMock()
+	client.SSHKey = hcloudtest.NewSSHClientMock()
+	mockServerNetworks(client)
+	mockPlacementGroups(client)
+	client.Image = hcloudtest.NewImageClientMock()
+	client.Image.(*hcloudtest.ImageClientMock).On("GetByName", mock.Anything, mock.Anything).Return(image, nilResponse, nil)
+
+	m := module{
+		client: client,
+		args: arguments{
+			State:            statePresent,
+			Name:             "test",
+			Image:            "debian-9",
+			ServerType:       "cx11",
+			SSHKeys:          []interface{}{"admin", "ssh-deploy", alice + " alice@laptop", bobFile},
+			TemporarySSHKeys: true,
+		},
+		waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+			return nil
+		}),
+	}
+
+	admin := &hcloud.SSHKey{ID: 1, Name: "admin"}
+	deploy := &hcloud.SSHKey{ID: 5, Name: "ssh-deploy"}
+	aliceKey := &hcloud.SSHKey{ID: 2, Name: "alice", Fingerprint: aliceFingerprint}
+	bobKey := &hcloud.SSHKey{ID: 3, Name: "bob@laptop (" + bobFingerprint + ")", Fingerprint: bobFingerprint}
+	sshKeyMock := client.SSHKey.(*hcloudtest.SSHKeyClientMock)
+	sshKeyMock.On("Get", mock.Anything, "admin").Return(admin, nilResponse, nil)
+	sshKeyMock.On("Get", mock.Anything, "ssh-deploy").Return(deploy, nilResponse, nil)
+	sshKeyMock.On("Get", mock.Anything, mock.Anything).Return((*hcloud.SSHKey)(nil), nilResponse, nil)
+	sshKeyMock.On("GetByFingerprint", mock.Anything, aliceFingerprint).Return(aliceKey, nilResponse, nil)
+	sshKeyMock.On("GetByFingerprint", mock.Anything, bobFingerprint).Return((*hcloud.SSHKey)(nil), nilResponse, nil)
+	sshKeyMock.On("GetByName", mock.Anything, "bob@laptop").Return(&hcloud.SSHKey{ID: 4, Name: "bob@laptop"}, nilResponse, nil)
+	sshKeyMock.On("Create", mock.Anything, hcloud.SSHKeyCreateOpts{
+		Name:      "bob@laptop (" + bobFingerprint + ")",
+		PublicKey: bob,
+	}).Return(bobKey, nilResponse, nil)
+	sshKeyMock.On("Delete", mock.Anything, bobKey).Return(nilResponse, nil)
+
+	serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+	serverClientMock.On("GetByName", mock.Anything, mock.Anything).Return(nilServer, nilResponse, nil).Once()
+	serverClientMock.On("GetByName", mock.Anything, mock.Anything).Return(server, nilResponse, nil)
+	serverClientMock.On("Create", mock.Anything, mock.Anything).Return(hcloud.ServerCreateResult{
+		Server: server,
+		Action: &hcloud.Action{ID: 123},
+	}, nilResponse, nil)
+
+	resp, err := m.run(context.Background())
+	if assert.NoError(t, err) {
+		assert.True(t, resp.HasChanged(), "should have changed")
+		assert.Equal(t, []SSHKey{
+			{ID: 3, Name: bobKey.Name, Fingerprint: bobFingerprint, Deleted: true},
+		}, resp.Data()["uploaded_ssh_keys"])
+		serverClientMock.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(opts hcloud.ServerCreateOpts) bool {
+			return assert.ObjectsAreEqual([]*hcloud.SSHKey{aliceKey, bobKey, admin, deploy}, opts.SSHKeys)
+		}))
+		sshKeyMock.AssertCalled(t, "Delete", mock.Anything, bobKey)
+	}
+
+	t.Run("names are no keys", func(t *testing.T) {
+		for _, name := range []string{"ssh-deploy", "ecdsa-ci"} {
+			key, err := parsePublicKey(name)
+			assert.NoError(t, err)
+			assert.Nil(t, key, name)
+		}
+	})
+
+	t.Run("weak key", func(t *testing.T) {
+		_, err := parsePublicKey("ssh-dss AAAAB3NzaC1kc3MAAACBAP1/U4EddRIpUt9KnC7s5Of2EbdSPO9EAMMeP4C2USZpRV1AIlH7WT2NWPq/xfW6MPbLm1Vs14E7gB00b/JmYLdrmVClpJ+f6AR7ECLCT7up1/63xhv4O1fnxqimFQ8E+4P208UewwI1VBNaFpEy9nXzrith1yrv8iIDGZ3RSAHHAAAAFQCXYFCPFSMLzLKSuYKi64QL8Fgc9QAAAIEA9+GghdabPd7LvKtcNrhXuXmUr7v6OuqC+VdMCz0HgmdRWVeOutRZT+ZxBxCBgLRJFnEj6EwoFhO3zwkyjMim4TwWeotUfI0o4KOuHiuzpnWRbqN/C/ohNWLx+2J6ASQ7zKTxvqhRkImog9/hWuWfBpKLZl6Ae1UlZAFMO/7PSSoAAACBAKKSU2PFl/qOLxIwmBZPPIcJshVe7bVUpFvyl3BbOXsPwU8+2i+Oj2OaPGPWe8dxz97oNPLI+YaalhT0EyWJfBGX/2cLeBPnyzlbtFzYT/kclzE1jCsbR3/kE2KtQ9T8Jyc6JmTcTOCQUR7MNa6TKFWSEHFo9PqB86uoUPTjhYbS")
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "insecure")
+		}
+	})
+}
+
+func TestDNSName(t *testing.T) {
+	zone := &hetznerdns.Zone{ID: "z1", Name: "example.com"}
+	newDNSClient := func(records ...*hetznerdns.Record) *hetznerdns.Client {
+		dns := &hetznerdns.Client{
+			Zone:   hetznerdnstest.NewZoneClientMock(),
+			Record: hetznerdnstest.NewRecordClientMock(),
+		}
+		dns.Zone.(*hetznerdnstest.ZoneClientMock).On("All", mock.Anything).Return([]*hetznerdns.Zone{zone}, nil)
+		dns.Record.(*hetznerdnstest.RecordClientMock).On("List", mock.Anything, zone).Return(records, nil)
+		return dns
+	}
+
+	t.Run("present", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Server = hcloudtest.NewServerClientMock()
+		mockServerNetworks(client)
+		mockPlacementGroups(client)
+		client.Server.(*hcloudtest.ServerClientMock).On("GetByName", mock.Anything, "test").Return(server, nilResponse, nil)
+
+		stale := &hetznerdns.Record{ID: "r1", ZoneID: "z1", Name: "web", Type: "A", Value: "192.168.1.1"}
+		current := &hetznerdns.Record{ID: "r2", ZoneID: "z1", Name: "web", Type: "A", Value: "192.168.1.2"}
+		dns := newDNSClient(stale, current)
+		recordMock := dns.Record.(*hetznerdnstest.RecordClientMock)
+		recordMock.On("Delete", mock.Anything, stale).Return(nil)
+		recordMock.On("Create", mock.Anything, hetznerdns.RecordOpts{
+			ZoneID: "z1", Name: "web", Type: "AAAA", Value: "2001:db8::1",
+		}).Return(&hetznerdns.Record{ID: "r3"}, nil)
+
+		m := module{
+			client: client,
+			dns:    dns,
+			args:   arguments{State: statePresent, Name: "test", DNSName: "web.example.com"},
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				return nil
+			}),
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "should have changed")
+			recordMock.AssertNumberOfCalls(t, "Create", 1)
+			recordMock.AssertNotCalled(t, "Delete", mock.Anything, current)
+		}
+	})
+
+	t.Run("absent", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.Server = hcloudtest.NewServerClientMock()
+		serverClientMock := client.Server.(*hcloudtest.ServerClientMock)
+		serverClientMock.On("GetByName", mock.Anything, "test").Return(server, nilResponse, nil)
+		serverClientMock.On("Delete", mock.Anything, server).Return(nilResponse, nil)
+
+		other := &hetznerdns.Record{ID: "r1", ZoneID: "z1", Name: "web", Type: "A", Value: "192.168.1.1"}
+		a := &hetznerdns.Record{ID: "r2", ZoneID: "z1", Name: "web", Type: "A", Value: "192.168.1.2"}
+		aaaa := &hetznerdns.Record{ID: "r3", ZoneID: "z1", Name: "web", Type: "AAAA", Value: "2001:db8::1"}
+		dns := newDNSClient(other, a, aaaa)
+		recordMock := dns.Record.(*hetznerdnstest.RecordClientMock)
+		recordMock.On("Delete", mock.Anything, a).Return(nil)
+		recordMock.On("Delete", mock.Anything, aaaa).Return(nil)
+
+		m := module{
+			client: client,
+			dns:    dns,
+			args:   arguments{State: stateAbsent, Name: "test", DNSName: "web.example.com"},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "should have changed")
+			recordMock.AssertNumberOfCalls(t, "Delete", 2)
+			recordMock.AssertNotCalled(t, "Delete", mock.Anything, other)
+		}
+	})
+}
+
+func TestToServerImage(t *testing.T) {
+	assert.Equal(t, "debian-9", toServer(server, nil, nil).Image)
+
+	snapshot := *server
+	snapshot.Image = &hcloud.Image{ID: 456, Type: hcloud.ImageTypeSnapshot, Description: "web snapshot"}
+	assert.Equal(t, "web snapshot", toServer(&snapshot, nil, nil).Image)
+
+	deleted := *server
+	deleted.Image = nil
+	assert.Equal(t, "", toServer(&deleted, nil, nil).Image)
+}
+
+func TestDoc(t *testing.T) {
+	data, err := ioutil.ReadFile("../../docs/hcloud_server.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(data), doc.Markdown(argSpec), "docs/hcloud_server.md is outdated, run make docs")
+	}
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"strings"
 
 	"github.com/spf13/pflag"
@@ -20,6 +23,90 @@ type arguments struct {
 	ID        interface{} `json:"id"`
 	Name      string      `json:"name"`
 	PublicKey string      `json:"public_key"`
+
+	// Keys and AuthorizedKeysFile sync many keys named by their comments
+	Keys               []string `json:"keys"`
+	AuthorizedKeysFile string   `json:"authorized_keys_file"`
+	// Exclusive deletes all keys of the project not in Keys or AuthorizedKeysFile
+	Exclusive bool `json:"exclusive"`
+}
+
+var argSpec = ansible.ArgSpec{
+	Args: []ansible.Arg{
+		ansible.TokenArg(),
+		{Name: "state", Type: ansible.TypeStr, Default: statePresent, Choices: []string{statePresent, stateAbsent, stateList},
+			Description: "`list` lists all existing ssh keys."},
+		{Name: "id", Type: ansible.TypeRaw,
+			Description: "ID of the ssh key, only with `state=absent`."},
+		{Name: "name", Type: ansible.TypeStr,
+			Description: "Name of the ssh key. Required when state is `present` without `keys` or `authorized_keys_file`.\n\n" +
+				"`id` or `name` is required when `state=absent`."},
+		{Name: "public_key", Type: ansible.TypeStr,
+			Description: "Required when state is `present` without `keys` or `authorized_keys_file`."},
+		{Name: "keys", Type: ansible.TypeList, Elements: ansible.TypeStr,
+			Description: "List of public keys in `authorized_keys` format to sync, named by their comment or their fingerprint if they have none.\n\n" +
+				"Mutually exclusive with `id`, `name` and `public_key`."},
+		{Name: "authorized_keys_file", Type: ansible.TypeStr,
+			Description: "Path of an `authorized_keys` file with public keys to sync, like `keys`. Both can be combined."},
+		{Name: "exclusive", Type: ansible.TypeBool, Default: false,
+			Description: "Delete all ssh keys of the project not in `keys` or `authorized_keys_file`."},
+	},
+	RequiredIf: []ansible.RequiredIf{
+		{Arg: "state", Value: stateAbsent, Required: []string{"id", "name"}, OneOf: true},
+	},
+}
+
+var doc = ansible.Doc{
+	Module:           "hcloud_ssh_key",
+	ShortDescription: "Manage Hetzner Cloud SSH Keys",
+	Description: "Manages Hetzner Cloud SSH Keys. This module can be used to create, list and delete ssh keys.\n\n" +
+		"With `keys` or `authorized_keys_file`, all given keys are synced at once. " +
+		"Keys are named by their comment and matched with existing keys by fingerprint, so a changed comment renames the key instead of replacing it. " +
+		"Existing keys with the name of a new key are replaced.\n\n" +
+		"The same key material can only exist once in a project. " +
+		"With `name` and `public_key`, a key with the same fingerprint under another name is adopted and renamed. " +
+		"DSA keys and RSA keys with less than 2048 bits are rejected before upload.",
+	Returns: []ansible.Return{
+		{
+			Name:        "ssh_keys",
+			Description: "The ssh key, the synced keys or all ssh keys with `state=list`.",
+			Returned:    "unless `state=absent`",
+			Sample: []SSHKey{{
+				ID:                123,
+				Name:              "mykey@machine",
+				Fingerprint:       "a2:94:75:0d:cf:fd:2c:fc:77:81:0e:c6:7a:8d:a2:21",
+				FingerprintSHA256: "SHA256:B+2RpxEOBAIb/A4Uocm4q2wxTrOvglEzETf7B/CooLo",
+				Type:              "ssh-ed25519",
+				Comment:           "mykey@machine",
+			}},
+		},
+	},
+	Examples: `
+# create an ssh key
+- hcloud_ssh_key:
+    name: test key
+    public_key: "{{lookup('file', '~/.ssh/id_rsa.pub')}}"
+
+# sync the keys of the team, delete all other keys
+- hcloud_ssh_key:
+    authorized_keys_file: files/authorized_keys
+    keys:
+    - "{{lookup('file', '~/.ssh/id_ed25519.pub')}}"
+    exclusive: true
+
+# list all ssh keys in the Hetzner Cloud Project and
+# create a single server with the fetched ssh keys
+- hcloud_ssh_key:
+    state: list
+  register: hcloud_ssh_keys
+
+- hcloud_server:
+    name: example-server
+    image: debian-9
+    server_type: cx11
+    datacenter: nbg1-dc3
+    ssh_keys: "{{ hcloud_ssh_keys.ssh_keys }}"
+`,
 }
 
 const (
@@ -30,9 +117,12 @@ const (
 
 // SSHKey is the module return value of an hcloud.SSHKey
 type SSHKey struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Fingerprint string `json:"fingerprint"`
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	Fingerprint       string `json:"fingerprint"`
+	FingerprintSHA256 string `json:"fingerprint_sha256"`
+	Type              string `json:"type"`
+	Comment           string `json:"comment"`
 }
 
 type module struct {
@@ -40,25 +130,24 @@ type module struct {
 	client *hcloud.Client
 }
 
-func (m *module) Run() (resp ansible.ModuleResponse, err error) {
-	ctx := context.Background()
+func (m *module) Run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
 	m.client, err = hcloud.BuildClient(m.args.Token)
 	if err != nil {
 		return
 	}
-	if m.args.State == "" {
-		m.args.State = statePresent
-	}
-	if err = validateArgs(m.args); err != nil {
-		return
-	}
+	return m.run(ctx)
+}
 
+func (m *module) run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
 	switch m.args.State {
 	case stateList:
 		return m.list(ctx)
 	case stateAbsent:
 		return m.absent(ctx)
 	case statePresent:
+		if m.args.Keys != nil || m.args.AuthorizedKeysFile != "" {
+			return m.sync(ctx)
+		}
 		return m.present(ctx)
 	default:
 		err = errors.New("invalid state")
@@ -70,6 +159,14 @@ func (m *module) Args() interface{} {
 	return &m.args
 }
 
+func (m *module) ArgSpec() ansible.ArgSpec {
+	return argSpec
+}
+
+func (m *module) Doc() ansible.Doc {
+	return doc
+}
+
 // list handles state: list
 func (m *module) list(ctx context.Context) (resp ansible.ModuleResponse, err error) {
 	var (
@@ -107,6 +204,15 @@ func (m *module) absent(ctx context.Context) (resp ansible.ModuleResponse, err e
 
 // present handles state: present
 func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var publicKey ssh.PublicKey
+	if publicKey, _, _, _, err = ssh.ParseAuthorizedKey([]byte(m.args.PublicKey)); err != nil {
+		return
+	}
+	if err = util.ValidatePublicKey(publicKey); err != nil {
+		return
+	}
+	fingerprint := ssh.FingerprintLegacyMD5(publicKey)
+
 	var sshKey *hcloud.SSHKey
 	if sshKey, err = m.getSSHKey(ctx); err != nil {
 		return
@@ -114,12 +220,7 @@ func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err
 
 	var msg []string
 	if sshKey != nil {
-		var publicKey ssh.PublicKey
-		if publicKey, _, _, _, err = ssh.ParseAuthorizedKey([]byte(m.args.PublicKey)); err != nil {
-			return
-		}
-
-		if ssh.FingerprintLegacyMD5(publicKey) != sshKey.Fingerprint {
+		if fingerprint != sshKey.Fingerprint {
 			if _, err = m.client.SSHKey.Delete(ctx, sshKey); err != nil {
 				return
 			}
@@ -131,14 +232,24 @@ func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err
 		}
 	}
 
+	// the same key material can only be uploaded once, it is adopted under the new name
+	if sshKey == nil {
+		if sshKey, _, err = m.client.SSHKey.GetByFingerprint(ctx, fingerprint); err != nil {
+			return
+		}
+	}
+
 	if sshKey != nil {
 		if sshKey.Name != m.args.Name {
+			id, name := sshKey.ID, sshKey.Name
 			sshKey, _, err = m.client.SSHKey.Update(ctx, sshKey, hcloud.SSHKeyUpdateOpts{
 				Name: m.args.Name,
 			})
 			if err != nil {
 				return
 			}
+			msg = append(msg, fmt.Sprintf("SSHKey %d renamed from %q", id, name))
+			resp.Changed()
 		}
 	}
 
@@ -160,6 +271,141 @@ func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err
 	return
 }
 
+// authorizedKey is a key to sync, named by its comment
+type authorizedKey struct {
+	name        string
+	publicKey   string
+	fingerprint string
+}
+
+// sync handles state: present with keys or authorized_keys_file
+func (m *module) sync(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var keys []authorizedKey
+	if keys, err = m.authorizedKeys(); err != nil {
+		return
+	}
+
+	var existing []*hcloud.SSHKey
+	if existing, err = m.client.SSHKey.All(ctx); err != nil {
+		return
+	}
+	byFingerprint := map[string]*hcloud.SSHKey{}
+	for _, sshKey := range existing {
+		byFingerprint[sshKey.Fingerprint] = sshKey
+	}
+	wanted := map[string]bool{}
+	names := map[string]bool{}
+	for _, key := range keys {
+		wanted[key.fingerprint] = true
+		names[key.name] = true
+	}
+
+	// keys not in the list are deleted first, their names may be taken by new keys
+	var msg []string
+	for _, sshKey := range existing {
+		if wanted[sshKey.Fingerprint] || !m.args.Exclusive && !names[sshKey.Name] {
+			continue
+		}
+		if _, err = m.client.SSHKey.Delete(ctx, sshKey); err != nil {
+			return
+		}
+		msg = append(msg, fmt.Sprintf("SSHKey %d deleted", sshKey.ID))
+		resp.Changed()
+	}
+
+	// keys are matched by fingerprint, so a changed comment renames the key
+	var list []SSHKey
+	for _, key := range keys {
+		sshKey := byFingerprint[key.fingerprint]
+		switch {
+		case sshKey == nil:
+			sshKey, _, err = m.client.SSHKey.Create(ctx, hcloud.SSHKeyCreateOpts{
+				Name:      key.name,
+				PublicKey: key.publicKey,
+			})
+			if err != nil {
+				return
+			}
+			msg = append(msg, fmt.Sprintf("SSHKey %d created", sshKey.ID))
+			resp.Changed()
+		case sshKey.Name != key.name:
+			sshKey, _, err = m.client.SSHKey.Update(ctx, sshKey, hcloud.SSHKeyUpdateOpts{
+				Name: key.name,
+			})
+			if err != nil {
+				return
+			}
+			msg = append(msg, fmt.Sprintf("SSHKey %d renamed", sshKey.ID))
+			resp.Changed()
+		}
+		list = append(list, toSSHKeyData(sshKey))
+	}
+	if len(msg) == 0 {
+		msg = append(msg, "SSHKeys in sync")
+	}
+
+	resp.
+		Msg(strings.Join(msg, ", ")).
+		Set("ssh_keys", list)
+	return
+}
+
+// authorizedKeys parses the keys and the authorized_keys_file,
+// keys without comment are named by their fingerprint
+func (m *module) authorizedKeys() (keys []authorizedKey, err error) {
+	var data bytes.Buffer
+	for _, key := range m.args.Keys {
+		data.WriteString(key + "\n")
+	}
+	if m.args.AuthorizedKeysFile != "" {
+		var file []byte
+		if file, err = ioutil.ReadFile(m.args.AuthorizedKeysFile); err != nil {
+			return
+		}
+		data.Write(file)
+	}
+
+	seen := map[string]string{}
+	scanner := bufio.NewScanner(&data)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		publicKey, comment, _, _, parseErr := ssh.ParseAuthorizedKey([]byte(line))
+		if parseErr != nil {
+			return nil, fmt.Errorf("Invalid public key %q: %v", line, parseErr)
+		}
+		if err = util.ValidatePublicKey(publicKey); err != nil {
+			return nil, fmt.Errorf("%v: %q", err, line)
+		}
+
+		key := authorizedKey{
+			name:        comment,
+			publicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey))),
+			fingerprint: ssh.FingerprintLegacyMD5(publicKey),
+		}
+		if key.name == "" {
+			key.name = key.fingerprint
+		}
+		if name, ok := seen[key.fingerprint]; ok {
+			if name != key.name {
+				return nil, fmt.Errorf("Public key %s is listed as %q and %q", key.fingerprint, name, key.name)
+			}
+			continue
+		}
+		for _, other := range keys {
+			if other.name == key.name {
+				return nil, fmt.Errorf("Public keys %s and %s are both named %q", other.fingerprint, key.fingerprint, key.name)
+			}
+		}
+		seen[key.fingerprint] = key.name
+		keys = append(keys, key)
+	}
+	err = scanner.Err()
+	return
+}
+
 func (m *module) getSSHKey(ctx context.Context) (sshKey *hcloud.SSHKey, err error) {
 	id := util.GetID(m.args.ID)
 	if id != 0 {
@@ -175,39 +421,45 @@ func (m *module) getSSHKey(ctx context.Context) (sshKey *hcloud.SSHKey, err erro
 }
 
 func toSSHKeyData(key *hcloud.SSHKey) SSHKey {
-	return SSHKey{
+	data := SSHKey{
 		ID:          key.ID,
 		Name:        key.Name,
 		Fingerprint: key.Fingerprint,
 	}
+	if publicKey, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(key.PublicKey)); err == nil {
+		data.FingerprintSHA256 = ssh.FingerprintSHA256(publicKey)
+		data.Type = publicKey.Type()
+		data.Comment = comment
+	}
+	return data
 }
 
-func validateArgs(args arguments) error {
-	errs := []string{}
-	if args.State != stateAbsent &&
-		args.State != statePresent &&
-		args.State != stateList {
-		errs = append(errs, "'state' must be present, absent or list")
+// CheckArgs checks the constraints of the arguments the spec cannot express
+func (m *module) CheckArgs() (errs []string) {
+	sync := m.args.Keys != nil || m.args.AuthorizedKeysFile != ""
+	if sync {
+		if m.args.State != statePresent {
+			errs = append(errs, "'keys' and 'authorized_keys_file' require state 'present'")
+		}
+		if m.args.ID != nil || m.args.Name != "" || m.args.PublicKey != "" {
+			errs = append(errs, "'keys' and 'authorized_keys_file' are mutually exclusive with 'id', 'name' and 'public_key'")
+		}
+	}
+	if m.args.Exclusive && !sync {
+		errs = append(errs, "'exclusive' requires 'keys' or 'authorized_keys_file'")
 	}
-	if args.State == statePresent {
-		if args.ID != nil {
+	if m.args.State == statePresent && !sync {
+		if m.args.ID != nil {
 			errs = append(errs, "'id' has no effect")
 		}
-		if args.Name == "" {
+		if m.args.Name == "" {
 			errs = append(errs, "'name' is required")
 		}
-		if args.PublicKey == "" {
+		if m.args.PublicKey == "" {
 			errs = append(errs, "'public_key' is required")
 		}
 	}
-	if args.State == stateAbsent &&
-		args.ID == nil && args.Name == "" {
-		errs = append(errs, "'name' or 'id' is required")
-	}
-	if len(errs) > 0 {
-		return fmt.Errorf(strings.Join(errs, ", "))
-	}
-	return nil
+	return
 }
 
 var flags = pflag.NewFlagSet("hcloud_ssh_key", pflag.ContinueOnError)
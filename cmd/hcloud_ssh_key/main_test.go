@@ -2,86 +2,120 @@ package main
 
 import (
 	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
 	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
 	"github.com/thetechnick/hcloud-ansible/pkg/hcloud/hcloudtest"
 )
 
 func TestValidateArgs(t *testing.T) {
 	t.Run("invalid state", func(t *testing.T) {
-		err := validateArgs(arguments{
+		err := ansible.ValidateArgs(&module{args: arguments{
 			State: "not-a-valid-state",
-		})
+		}})
 		assert.Error(t, err)
 	})
 
 	t.Run("present", func(t *testing.T) {
 		t.Run("success", func(t *testing.T) {
-			err := validateArgs(arguments{
+			err := ansible.ValidateArgs(&module{args: arguments{
 				State:     statePresent,
 				Name:      "my ssh key",
 				PublicKey: "---123---",
-			})
+			}})
 			assert.NoError(t, err)
 		})
 
 		t.Run("id has no effect", func(t *testing.T) {
-			err := validateArgs(arguments{
+			err := ansible.ValidateArgs(&module{args: arguments{
 				ID:        123,
 				State:     statePresent,
 				Name:      "my ssh key",
 				PublicKey: "---123---",
-			})
+			}})
 			assert.Error(t, err)
 		})
 
 		t.Run("missing name", func(t *testing.T) {
-			err := validateArgs(arguments{
+			err := ansible.ValidateArgs(&module{args: arguments{
 				State:     statePresent,
 				PublicKey: "---123---",
-			})
+			}})
 			assert.Error(t, err)
 		})
 
 		t.Run("missing public_key", func(t *testing.T) {
-			err := validateArgs(arguments{
+			err := ansible.ValidateArgs(&module{args: arguments{
 				State: statePresent,
 				ID:    333,
-			})
+			}})
 			assert.Error(t, err)
 		})
 	})
 
 	t.Run("absent", func(t *testing.T) {
 		t.Run("success with name", func(t *testing.T) {
-			err := validateArgs(arguments{
+			err := ansible.ValidateArgs(&module{args: arguments{
 				State: stateAbsent,
 				Name:  "my-ssh-key",
-			})
+			}})
 			assert.NoError(t, err)
 		})
 		t.Run("success with id", func(t *testing.T) {
-			err := validateArgs(arguments{
+			err := ansible.ValidateArgs(&module{args: arguments{
 				State: stateAbsent,
 				ID:    155,
-			})
+			}})
 			assert.NoError(t, err)
 		})
 		t.Run("missing id and name", func(t *testing.T) {
-			err := validateArgs(arguments{
+			err := ansible.ValidateArgs(&module{args: arguments{
 				State: stateAbsent,
-			})
+			}})
+			assert.Error(t, err)
+		})
+	})
+
+	t.Run("sync", func(t *testing.T) {
+		t.Run("success", func(t *testing.T) {
+			err := ansible.ValidateArgs(&module{args: arguments{
+				State:     statePresent,
+				Keys:      []string{testPublicKey},
+				Exclusive: true,
+			}})
+			assert.NoError(t, err)
+		})
+
+		t.Run("keys and name", func(t *testing.T) {
+			err := ansible.ValidateArgs(&module{args: arguments{
+				State: statePresent,
+				Keys:  []string{testPublicKey},
+				Name:  "my ssh key",
+			}})
+			assert.Error(t, err)
+		})
+
+		t.Run("exclusive without keys", func(t *testing.T) {
+			err := ansible.ValidateArgs(&module{args: arguments{
+				State:     statePresent,
+				Name:      "my ssh key",
+				PublicKey: testPublicKey,
+				Exclusive: true,
+			}})
 			assert.Error(t, err)
 		})
 	})
 
 	t.Run("list success", func(t *testing.T) {
-		err := validateArgs(arguments{
+		err := ansible.ValidateArgs(&module{args: arguments{
 			State: stateList,
-		})
+		}})
 		assert.NoError(t, err)
 	})
 }
@@ -225,6 +259,7 @@ func TestPresent(t *testing.T) {
 		var r *hcloud.Response
 
 		sshKeyMock.On("GetByName", mock.Anything, mock.Anything).Return(sshKey, r, nil)
+		sshKeyMock.On("GetByFingerprint", mock.Anything, testFingerprint).Return((*hcloud.SSHKey)(nil), r, nil)
 		sshKeyMock.On("Delete", mock.Anything, mock.Anything).Return(r, nil)
 		sshKeyMock.On("Create", mock.Anything, mock.Anything).Return(sshKey, r, nil)
 
@@ -241,3 +276,164 @@ func TestPresent(t *testing.T) {
 		}
 	})
 }
+
+func TestPresentAdopt(t *testing.T) {
+	t.Run("sshkey exists under another name", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.SSHKey = hcloudtest.NewSSHClientMock()
+
+		m := module{
+			client: client,
+			args: arguments{
+				Name:      "my-ssh-key",
+				PublicKey: testPublicKey,
+			},
+		}
+
+		sshKeyMock := client.SSHKey.(*hcloudtest.SSHKeyClientMock)
+		sshKey := &hcloud.SSHKey{ID: 123, Name: "old-name", Fingerprint: testFingerprint}
+		renamed := &hcloud.SSHKey{ID: 123, Name: "my-ssh-key", Fingerprint: testFingerprint}
+		var r *hcloud.Response
+
+		sshKeyMock.On("GetByName", mock.Anything, "my-ssh-key").Return((*hcloud.SSHKey)(nil), r, nil)
+		sshKeyMock.On("GetByFingerprint", mock.Anything, testFingerprint).Return(sshKey, r, nil)
+		sshKeyMock.On("Update", mock.Anything, sshKey, hcloud.SSHKeyUpdateOpts{Name: "my-ssh-key"}).Return(renamed, r, nil)
+
+		resp, err := m.present(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			assert.Equal(t, []SSHKey{toSSHKeyData(renamed)}, resp.Data()["ssh_keys"])
+			sshKeyMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("weak key is rejected", func(t *testing.T) {
+		client := hcloud.NewClient()
+		client.SSHKey = hcloudtest.NewSSHClientMock()
+
+		m := module{
+			client: client,
+			args: arguments{
+				Name:      "my-ssh-key",
+				PublicKey: "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAAgQDZsICNlEGZPrEBM6D5+mhdNyjYYPZuAvjfJUj4XAEUFLlcAMEcd2rxaWc3zW9kbfLkfF29g/rQo6mMFjYFj37mBvnI5yfKyZDbB+iK+9i/aWSevKC+2tOidVTF8kitECXglMBdjlsLOLTvZWYILwpUP9jchSRpweJPeWU6TBHPTw==",
+			},
+		}
+
+		_, err := m.present(context.Background())
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "RSA keys need at least 2048 bits")
+		}
+	})
+}
+
+func TestToSSHKeyData(t *testing.T) {
+	data := toSSHKeyData(&hcloud.SSHKey{
+		ID:          123,
+		Name:        "my-ssh-key",
+		Fingerprint: testFingerprint,
+		PublicKey:   testPublicKey + " alice@laptop",
+	})
+	assert.Equal(t, SSHKey{
+		ID:                123,
+		Name:              "my-ssh-key",
+		Fingerprint:       testFingerprint,
+		FingerprintSHA256: "SHA256:B+2RpxEOBAIb/A4Uocm4q2wxTrOvglEzETf7B/CooLo",
+		Type:              "ssh-ed25519",
+		Comment:           "alice@laptop",
+	}, data)
+}
+
+var testPublicKey2 = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAID5BqbTwUvUFB63TyHUnKuPVKcMuTJ/tV5QNXK/fQQBz"
+var testFingerprint2 = "66:aa:c6:58:c2:c8:22:ba:4d:cd:a4:4d:a4:22:88:da"
+
+func TestSync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcloud_ssh_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	newModule := func(exclusive bool) (module, *hcloudtest.SSHKeyClientMock) {
+		client := hcloud.NewClient()
+		client.SSHKey = hcloudtest.NewSSHClientMock()
+
+		authorizedKeys := filepath.Join(dir, "authorized_keys")
+		err := ioutil.WriteFile(authorizedKeys, []byte("# team\n"+testPublicKey2+" bob@laptop\n"), 0600)
+		assert.NoError(t, err)
+
+		return module{
+			client: client,
+			args: arguments{
+				State:              statePresent,
+				Keys:               []string{testPublicKey + " alice@laptop"},
+				AuthorizedKeysFile: authorizedKeys,
+				Exclusive:          exclusive,
+			},
+		}, client.SSHKey.(*hcloudtest.SSHKeyClientMock)
+	}
+
+	var r *hcloud.Response
+	alice := &hcloud.SSHKey{ID: 1, Name: "alice", Fingerprint: testFingerprint}
+	renamed := &hcloud.SSHKey{ID: 1, Name: "alice@laptop", Fingerprint: testFingerprint}
+	bob := &hcloud.SSHKey{ID: 2, Name: "bob@laptop", Fingerprint: testFingerprint2}
+	other := &hcloud.SSHKey{ID: 3, Name: "other", Fingerprint: "00:00"}
+
+	t.Run("rename and create", func(t *testing.T) {
+		m, sshKeyMock := newModule(false)
+		sshKeyMock.On("All", mock.Anything).Return([]*hcloud.SSHKey{alice, other}, nil)
+		sshKeyMock.On("Update", mock.Anything, alice, hcloud.SSHKeyUpdateOpts{Name: "alice@laptop"}).Return(renamed, r, nil)
+		sshKeyMock.On("Create", mock.Anything, mock.Anything).Return(bob, r, nil)
+
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			assert.Equal(t, []SSHKey{toSSHKeyData(renamed), toSSHKeyData(bob)}, resp.Data()["ssh_keys"])
+			sshKeyMock.AssertCalled(t, "Create", mock.Anything, hcloud.SSHKeyCreateOpts{
+				Name:      "bob@laptop",
+				PublicKey: testPublicKey2,
+			})
+			sshKeyMock.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("exclusive", func(t *testing.T) {
+		m, sshKeyMock := newModule(true)
+		sshKeyMock.On("All", mock.Anything).Return([]*hcloud.SSHKey{renamed, bob, other}, nil)
+		sshKeyMock.On("Delete", mock.Anything, other).Return(r, nil)
+
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			sshKeyMock.AssertCalled(t, "Delete", mock.Anything, other)
+			sshKeyMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			sshKeyMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("in sync", func(t *testing.T) {
+		m, sshKeyMock := newModule(true)
+		sshKeyMock.On("All", mock.Anything).Return([]*hcloud.SSHKey{bob, renamed}, nil)
+
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.False(t, resp.HasChanged(), "module should not have changed")
+		}
+	})
+
+	t.Run("duplicate names", func(t *testing.T) {
+		m, _ := newModule(false)
+		m.args.Keys = append(m.args.Keys, testPublicKey2+" alice@laptop")
+
+		_, err := m.authorizedKeys()
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), `both named "alice@laptop"`)
+		}
+	})
+}
+
+func TestDoc(t *testing.T) {
+	data, err := ioutil.ReadFile("../../docs/hcloud_ssh_key.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(data), doc.Markdown(argSpec), "docs/hcloud_ssh_key.md is outdated, run make docs")
+	}
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+const (
+	stateAbsent  = "absent"
+	statePresent = "present"
+	stateList    = "list"
+
+	// minSize is the smallest volume size in GB
+	minSize = 10
+)
+
+var formats = []string{"ext4", "xfs"}
+
+// Volume is the module return value of an hcloud.Volume
+type Volume struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Size        int    `json:"size"`
+	Location    string `json:"location"`
+	ServerID    *int   `json:"server_id"`
+	LinuxDevice string `json:"linux_device"`
+	Format      string `json:"format"`
+	Status      string `json:"status"`
+}
+
+type arguments struct {
+	Token string `json:"token"`
+	State string `json:"state"`
+
+	ID        interface{} `json:"id"`
+	Name      string      `json:"name"`
+	Size      int         `json:"size"`
+	Server    interface{} `json:"server"`
+	Location  string      `json:"location"`
+	Automount bool        `json:"automount"`
+	Format    string      `json:"format"`
+	Wait      *bool       `json:"wait"`
+}
+
+var argSpec = ansible.ArgSpec{
+	Args: []ansible.Arg{
+		ansible.TokenArg(),
+		{Name: "state", Type: ansible.TypeStr, Default: statePresent, Choices: []string{statePresent, stateAbsent, stateList},
+			Description: "`list` lists all existing volumes.\n\n`absent` detaches the volume from its server before deleting it."},
+		{Name: "id", Type: ansible.TypeRaw,
+			Description: "ID of the volume.\n\n`id` or `name` is required when `state=present` or `state=absent`."},
+		{Name: "name", Type: ansible.TypeStr,
+			Description: "Name of the volume, used to find the volume when `id` is not specified. The volume is renamed when both are given."},
+		{Name: "size", Type: ansible.TypeInt,
+			Description: "Size of the volume in GB, at least 10.\n\nRequired to create a volume. Volumes are resized when `size` is larger, they are never shrunk."},
+		{Name: "location", Type: ansible.TypeStr,
+			Description: "Location of the volume.\n\nRequired to create a volume without `server`. Volumes cannot be moved to another location."},
+		{Name: "server", Type: ansible.TypeRaw,
+			Description: "Server to attach the volume to, by id or name. The volume is detached if not specified.\n\nThe server must be in the location of the volume."},
+		{Name: "automount", Type: ansible.TypeBool, Default: false,
+			Description: "Mount the volume on the server after attaching it."},
+		{Name: "format", Type: ansible.TypeStr, Choices: formats,
+			Description: "Filesystem to format the volume with when it is created."},
+		{Name: "wait", Type: ansible.TypeBool, Default: true,
+			Description: "Wait for all actions to complete. When `false`, the module returns immediately with `action_ids`, see `hcloud_action`. " +
+				"The volume is locked while an action runs, so with multiple changes to a volume only the last action is not awaited. " +
+				"`state=absent` always waits for the volume to be detached."},
+	},
+	RequiredIf: []ansible.RequiredIf{
+		{Arg: "state", Value: statePresent, Required: []string{"id", "name"}, OneOf: true},
+		{Arg: "state", Value: stateAbsent, Required: []string{"id", "name"}, OneOf: true},
+	},
+}
+
+var doc = ansible.Doc{
+	Module:           "hcloud_volume",
+	ShortDescription: "Manage Hetzner Cloud Volumes",
+	Description:      "Manages Hetzner Cloud volumes. This module can be used to create, resize, attach, detach and delete block storage volumes.",
+	Returns: []ansible.Return{
+		{
+			Name:        "volumes",
+			Description: "The volume, or all volumes with `state=list`.",
+			Returned:    "unless `state=absent`",
+			Sample: []Volume{{
+				ID:          123,
+				Name:        "data",
+				Size:        50,
+				Location:    "fsn1",
+				ServerID:    hcloud.Int(42),
+				LinuxDevice: "/dev/disk/by-id/scsi-0HC_Volume_123",
+				Format:      "ext4",
+				Status:      "available",
+			}},
+		},
+		{
+			Name:        "action_ids",
+			Description: "IDs of the pending actions, see `hcloud_action`.",
+			Returned:    "when `wait` is `false`",
+			Sample:      []int{4711},
+		},
+	},
+	Examples: `
+# create a 50 GB volume, format it and mount it on the database server
+- hcloud_volume:
+    name: data
+    size: 50
+    server: db1
+    format: ext4
+    automount: true
+
+# grow the volume to 100 GB
+- hcloud_volume:
+    name: data
+    size: 100
+    server: db1
+
+# create a detached volume in Nuremberg
+- hcloud_volume:
+    name: backup
+    size: 10
+    location: nbg1
+
+# delete the volume
+- hcloud_volume:
+    name: backup
+    state: absent
+`,
+}
+
+type module struct {
+	args   arguments
+	client *hcloud.Client
+	waiter util.ActionWaiter
+}
+
+func (m *module) Args() interface{} {
+	return &m.args
+}
+
+func (m *module) ArgSpec() ansible.ArgSpec {
+	return argSpec
+}
+
+func (m *module) Doc() ansible.Doc {
+	return doc
+}
+
+func (m *module) Run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	m.client, err = hcloud.BuildClient(m.args.Token)
+	if err != nil {
+		return
+	}
+	m.waiter = util.NewActionWatcher(m.client.Action)
+	return m.run(ctx)
+}
+
+func (m *module) run(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	// a volume must be detached before it can be deleted, so absent always waits
+	if m.args.Wait != nil && !*m.args.Wait && m.args.State != stateAbsent {
+		defer util.RecordActions(&m.waiter).Report(&resp, &err)
+	}
+
+	switch m.args.State {
+	case stateList:
+		return m.list(ctx)
+	case stateAbsent:
+		return m.absent(ctx)
+	case statePresent:
+		return m.present(ctx)
+	default:
+		err = errors.New("invalid state")
+		return
+	}
+}
+
+func (m *module) present(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var volume *hcloud.Volume
+	if volume, err = m.volume(ctx); err != nil {
+		return
+	}
+
+	var server *hcloud.Server
+	if server, err = m.server(ctx, m.args.Server); err != nil {
+		return
+	}
+
+	var msg []string
+	if volume == nil {
+		if m.args.ID != nil {
+			err = fmt.Errorf("Volume %v not found", m.args.ID)
+			return
+		}
+		if m.args.Size == 0 {
+			err = errors.New("'size' is required to create a volume")
+			return
+		}
+		opts := hcloud.VolumeCreateOpts{
+			Name:   m.args.Name,
+			Size:   m.args.Size,
+			Server: server,
+		}
+		if server == nil {
+			opts.Location = &hcloud.Location{Name: m.args.Location}
+		} else if m.args.Automount {
+			opts.Automount = hcloud.Bool(true)
+		}
+		if m.args.Format != "" {
+			opts.Format = hcloud.String(m.args.Format)
+		}
+
+		var r hcloud.VolumeCreateResult
+		if r, _, err = m.client.Volume.Create(ctx, opts); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, append([]*hcloud.Action{r.Action}, r.NextActions...)...); err != nil {
+			return
+		}
+		volume = r.Volume
+		msg = append(msg, fmt.Sprintf("Volume %d created", volume.ID))
+		resp.Changed()
+	}
+
+	if m.args.Location != "" && volume.Location.Name != m.args.Location {
+		err = fmt.Errorf("Volume %d is located in %s and cannot be moved to %s", volume.ID, volume.Location.Name, m.args.Location)
+		return
+	}
+	if server != nil && server.Datacenter != nil && server.Datacenter.Location.Name != volume.Location.Name {
+		err = fmt.Errorf("Volume %d is located in %s and cannot be attached to server %d in %s",
+			volume.ID, volume.Location.Name, server.ID, server.Datacenter.Location.Name)
+		return
+	}
+
+	if m.args.Name != "" && volume.Name != m.args.Name {
+		if volume, _, err = m.client.Volume.Update(ctx, volume, hcloud.VolumeUpdateOpts{
+			Name: m.args.Name,
+		}); err != nil {
+			return
+		}
+		msg = append(msg, fmt.Sprintf("Volume %d renamed", volume.ID))
+		resp.Changed()
+	}
+
+	if m.args.Size != 0 && m.args.Size < volume.Size {
+		err = fmt.Errorf("Volume %d has %d GB and cannot be shrunk to %d GB", volume.ID, volume.Size, m.args.Size)
+		return
+	}
+	if m.args.Size > volume.Size {
+		if err = m.waitForPending(ctx, volume); err != nil {
+			return
+		}
+		var action *hcloud.Action
+		if action, _, err = m.client.Volume.Resize(ctx, volume, m.args.Size); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
+			return
+		}
+		volume.Size = m.args.Size
+		msg = append(msg, fmt.Sprintf("Volume %d resized to %d GB", volume.ID, volume.Size))
+		resp.Changed()
+	}
+
+	if volume.Server != nil && (server == nil || volume.Server.ID != server.ID) {
+		if err = m.waitForPending(ctx, volume); err != nil {
+			return
+		}
+		var action *hcloud.Action
+		if action, _, err = m.client.Volume.Detach(ctx, volume); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
+			return
+		}
+		msg = append(msg, fmt.Sprintf("Volume %d detached from server %d", volume.ID, volume.Server.ID))
+		volume.Server = nil
+		resp.Changed()
+	}
+
+	if volume.Server == nil && server != nil {
+		opts := hcloud.VolumeAttachOpts{Server: server}
+		if m.args.Automount {
+			opts.Automount = hcloud.Bool(true)
+		}
+		if err = m.waitForPending(ctx, volume); err != nil {
+			return
+		}
+		var action *hcloud.Action
+		if action, _, err = m.client.Volume.Attach(ctx, volume, opts); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
+			return
+		}
+		volume.Server = server
+		msg = append(msg, fmt.Sprintf("Volume %d attached to server %d", volume.ID, server.ID))
+		resp.Changed()
+	}
+
+	resp.
+		Msg(strings.Join(msg, ", ")).
+		Set("volumes", []Volume{toVolume(volume)})
+	return
+}
+
+func (m *module) absent(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var volume *hcloud.Volume
+	if volume, err = m.volume(ctx); err != nil {
+		return
+	}
+	if volume == nil {
+		resp.Msg("No Volume found, nothing to do")
+		return
+	}
+
+	if volume.Server != nil {
+		var action *hcloud.Action
+		if action, _, err = m.client.Volume.Detach(ctx, volume); err != nil {
+			return
+		}
+		if err = m.waiter.WaitForActions(ctx, action); err != nil {
+			return
+		}
+	}
+	if _, err = m.client.Volume.Delete(ctx, volume); err != nil {
+		return
+	}
+	resp.Msg(fmt.Sprintf("Volume %d deleted", volume.ID)).Changed()
+	return
+}
+
+func (m *module) list(ctx context.Context) (resp ansible.ModuleResponse, err error) {
+	var volumes []*hcloud.Volume
+	if volumes, err = m.client.Volume.All(ctx); err != nil {
+		return
+	}
+
+	list := []Volume{}
+	for _, volume := range volumes {
+		list = append(list, toVolume(volume))
+	}
+	resp.Msg("Volumes listed").Set("volumes", list)
+	return
+}
+
+// waitForPending waits for the recorded actions on the volume with `wait: false`,
+// the volume is locked until they complete
+func (m *module) waitForPending(ctx context.Context, volume *hcloud.Volume) error {
+	return util.WaitForPending(ctx, m.waiter, hcloud.ActionResource{ID: volume.ID, Type: hcloud.ActionResourceTypeVolume})
+}
+
+// volume looks up the volume by id or name
+func (m *module) volume(ctx context.Context) (volume *hcloud.Volume, err error) {
+	if id := util.GetID(m.args.ID); id != 0 {
+		volume, _, err = m.client.Volume.GetByID(ctx, id)
+		return
+	}
+	volume, _, err = m.client.Volume.GetByName(ctx, m.args.Name)
+	return
+}
+
+func (m *module) server(ctx context.Context, serverArg interface{}) (server *hcloud.Server, err error) {
+	id := util.GetID(serverArg)
+	name := util.GetName(serverArg)
+
+	if id == 0 && name == "" {
+		return
+	}
+
+	if id != 0 {
+		server, _, err = m.client.Server.GetByID(ctx, id)
+	}
+
+	if server == nil {
+		if name != "" {
+			server, _, err = m.client.Server.GetByName(ctx, name)
+		}
+	}
+
+	if err != nil {
+		return
+	}
+	if server == nil {
+		err = fmt.Errorf("Server '%v' not found", serverArg)
+	}
+	return
+}
+
+func toVolume(volume *hcloud.Volume) Volume {
+	data := Volume{
+		ID:          volume.ID,
+		Name:        volume.Name,
+		Size:        volume.Size,
+		Location:    volume.Location.Name,
+		LinuxDevice: volume.LinuxDevice,
+		Format:      volume.Format,
+		Status:      string(volume.Status),
+	}
+	if volume.Server != nil {
+		data.ServerID = &volume.Server.ID
+	}
+	return data
+}
+
+// CheckArgs checks the constraints of the arguments the spec cannot express
+func (m *module) CheckArgs() (errs []string) {
+	if m.args.State == statePresent && m.args.ID == nil &&
+		m.args.Location == "" && m.args.Server == nil {
+		errs = append(errs, "'location' or 'server' must be set")
+	}
+	if m.args.Size != 0 && m.args.Size < minSize {
+		errs = append(errs, fmt.Sprintf("'size' must be at least %d", minSize))
+	}
+	return
+}
+
+var flags = pflag.NewFlagSet("hcloud_volume", pflag.ContinueOnError)
+
+func init() {
+	flags.BoolP("version", "v", false, "Print version and exit")
+}
+
+func main() {
+	ansible.RunModule(&module{}, flags)
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud/hcloudtest"
+	"github.com/thetechnick/hcloud-ansible/pkg/util"
+)
+
+var (
+	nilResponse *hcloud.Response
+	nilVolume   *hcloud.Volume
+
+	fsn1   = &hcloud.Location{Name: "fsn1"}
+	server = &hcloud.Server{
+		ID:         42,
+		Name:       "db1",
+		Datacenter: &hcloud.Datacenter{Name: "fsn1-dc8", Location: fsn1},
+	}
+	noWait = util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+		return nil
+	})
+)
+
+func newVolume() *hcloud.Volume {
+	return &hcloud.Volume{
+		ID:          1,
+		Name:        "data",
+		Size:        10,
+		Location:    fsn1,
+		LinuxDevice: "/dev/disk/by-id/scsi-0HC_Volume_1",
+		Status:      hcloud.VolumeStatusAvailable,
+	}
+}
+
+func newClient() *hcloud.Client {
+	client := hcloud.NewClient()
+	client.Volume = hcloudtest.NewVolumeClientMock()
+	client.Server = hcloudtest.NewServerClientMock()
+	client.Server.(*hcloudtest.ServerClientMock).On("GetByName", mock.Anything, "db1").Return(server, nilResponse, nil)
+	return client
+}
+
+func TestList(t *testing.T) {
+	client := newClient()
+	volume := newVolume()
+	volumeMock := client.Volume.(*hcloudtest.VolumeClientMock)
+	volumeMock.On("All", mock.Anything).Return([]*hcloud.Volume{volume}, nil)
+
+	m := module{client: client}
+	resp, err := m.list(context.Background())
+	if assert.NoError(t, err) {
+		assert.False(t, resp.HasChanged(), "module should not have changed")
+		assert.Equal(t, map[string]interface{}{
+			"volumes": []Volume{toVolume(volume)},
+		}, resp.Data())
+	}
+}
+
+func TestPresent(t *testing.T) {
+	t.Run("create and attach", func(t *testing.T) {
+		client := newClient()
+		volume := newVolume()
+		volume.Server = &hcloud.Server{ID: 42}
+		volumeMock := client.Volume.(*hcloudtest.VolumeClientMock)
+		volumeMock.On("GetByName", mock.Anything, "data").Return(nilVolume, nilResponse, nil)
+		volumeMock.On("Create", mock.Anything, hcloud.VolumeCreateOpts{
+			Name:      "data",
+			Size:      10,
+			Server:    server,
+			Automount: hcloud.Bool(true),
+			Format:    hcloud.String("ext4"),
+		}).Return(hcloud.VolumeCreateResult{
+			Volume:      volume,
+			Action:      &hcloud.Action{ID: 1},
+			NextActions: []*hcloud.Action{{ID: 2}},
+		}, nilResponse, nil)
+
+		var waited []*hcloud.Action
+		m := module{
+			client: client,
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				waited = append(waited, actions...)
+				return nil
+			}),
+			args: arguments{
+				State:     statePresent,
+				Name:      "data",
+				Size:      10,
+				Server:    "db1",
+				Automount: true,
+				Format:    "ext4",
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			assert.Equal(t, []*hcloud.Action{{ID: 1}, {ID: 2}}, waited)
+			assert.Equal(t, []Volume{toVolume(volume)}, resp.Data()["volumes"])
+			volumeMock.AssertNotCalled(t, "Attach", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		client := newClient()
+		volume := newVolume()
+		volumeMock := client.Volume.(*hcloudtest.VolumeClientMock)
+		volumeMock.On("GetByID", mock.Anything, 1).Return(volume, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args: arguments{
+				State:    statePresent,
+				ID:       1,
+				Size:     10,
+				Location: "fsn1",
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.False(t, resp.HasChanged(), "module should not have changed")
+		}
+	})
+
+	t.Run("resize and move to server", func(t *testing.T) {
+		client := newClient()
+		volume := newVolume()
+		volume.Server = &hcloud.Server{ID: 7}
+		volumeMock := client.Volume.(*hcloudtest.VolumeClientMock)
+		volumeAction := func(id int) *hcloud.Action {
+			return &hcloud.Action{
+				ID:        id,
+				Resources: []*hcloud.ActionResource{{ID: volume.ID, Type: hcloud.ActionResourceTypeVolume}},
+			}
+		}
+		// the volume is locked while an action runs, so each action
+		// is awaited before the next one and only the attach is left pending
+		var waited []int
+		volumeMock.On("GetByName", mock.Anything, "data").Return(volume, nilResponse, nil)
+		volumeMock.On("Resize", mock.Anything, volume, 20).Return(volumeAction(1), nilResponse, nil)
+		volumeMock.On("Detach", mock.Anything, volume).Run(func(args mock.Arguments) {
+			assert.Equal(t, []int{1}, waited, "volume detached before the resize finished")
+		}).Return(volumeAction(2), nilResponse, nil)
+		volumeMock.On("Attach", mock.Anything, volume, hcloud.VolumeAttachOpts{Server: server}).Run(func(args mock.Arguments) {
+			assert.Equal(t, []int{1, 2}, waited, "volume attached before the detach finished")
+		}).Return(volumeAction(3), nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: util.ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+				for _, action := range actions {
+					waited = append(waited, action.ID)
+				}
+				return nil
+			}),
+			args: arguments{
+				State:  statePresent,
+				Name:   "data",
+				Size:   20,
+				Server: "db1",
+				Wait:   hcloud.Bool(false),
+			},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			assert.Equal(t, []int{3}, resp.Data()["action_ids"])
+			v := resp.Data()["volumes"].([]Volume)[0]
+			assert.Equal(t, 20, v.Size)
+			assert.Equal(t, 42, *v.ServerID)
+		}
+	})
+
+	t.Run("never shrinks", func(t *testing.T) {
+		client := newClient()
+		volume := newVolume()
+		volume.Size = 50
+		volumeMock := client.Volume.(*hcloudtest.VolumeClientMock)
+		volumeMock.On("GetByName", mock.Anything, "data").Return(volume, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: statePresent, Name: "data", Size: 20, Location: "fsn1"},
+		}
+		_, err := m.run(context.Background())
+		assert.EqualError(t, err, "Volume 1 has 50 GB and cannot be shrunk to 20 GB")
+		volumeMock.AssertNotCalled(t, "Resize", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("location pinned", func(t *testing.T) {
+		client := newClient()
+		volumeMock := client.Volume.(*hcloudtest.VolumeClientMock)
+		volumeMock.On("GetByName", mock.Anything, "data").Return(newVolume(), nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: statePresent, Name: "data", Location: "nbg1"},
+		}
+		_, err := m.run(context.Background())
+		assert.EqualError(t, err, "Volume 1 is located in fsn1 and cannot be moved to nbg1")
+	})
+}
+
+func TestAbsent(t *testing.T) {
+	t.Run("attached volume", func(t *testing.T) {
+		client := newClient()
+		volume := newVolume()
+		volume.Server = &hcloud.Server{ID: 42}
+		volumeMock := client.Volume.(*hcloudtest.VolumeClientMock)
+		volumeMock.On("GetByID", mock.Anything, 1).Return(volume, nilResponse, nil)
+		volumeMock.On("Detach", mock.Anything, volume).Return(&hcloud.Action{ID: 1}, nilResponse, nil)
+		volumeMock.On("Delete", mock.Anything, volume).Return(nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: stateAbsent, ID: 1, Wait: hcloud.Bool(false)},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.True(t, resp.HasChanged(), "module should have changed")
+			volumeMock.AssertCalled(t, "Delete", mock.Anything, volume)
+		}
+	})
+
+	t.Run("volume does not exist", func(t *testing.T) {
+		client := newClient()
+		volumeMock := client.Volume.(*hcloudtest.VolumeClientMock)
+		volumeMock.On("GetByID", mock.Anything, 1).Return(nilVolume, nilResponse, nil)
+
+		m := module{
+			client: client,
+			waiter: noWait,
+			args:   arguments{State: stateAbsent, ID: 1},
+		}
+		resp, err := m.run(context.Background())
+		if assert.NoError(t, err) {
+			assert.False(t, resp.HasChanged(), "module should not have changed")
+		}
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: stateList}}))
+	assert.NoError(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "data", Size: 10, Location: "fsn1"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "data"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "data", Location: "fsn1", Size: 5}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: statePresent, Name: "data", Location: "fsn1", Format: "btrfs"}}))
+	assert.Error(t, ansible.ValidateArgs(&module{args: arguments{State: stateAbsent}}))
+}
+
+func TestDoc(t *testing.T) {
+	data, err := ioutil.ReadFile("../../docs/hcloud_volume.md")
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(data), doc.Markdown(argSpec), "docs/hcloud_volume.md is outdated, run make docs")
+	}
+}
@@ -0,0 +1,307 @@
+package ansible
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Doc documents a module, RunModule prints it with the --doc flag
+type Doc struct {
+	Module           string
+	ShortDescription string
+	// Description is markdown, paragraphs are separated by empty lines
+	Description string
+	Returns     []Return
+	// Examples is a YAML list of tasks
+	Examples string
+}
+
+// Return documents a return value of a module
+type Return struct {
+	Name string
+	// Description is markdown like the description of the module
+	Description string
+	// Returned tells when the value is returned, e.g. always
+	Returned string
+	// Sample is an example of the value, the type of the value is documented from its Go type
+	Sample interface{}
+}
+
+// DocModule is a module with documentation
+type DocModule interface {
+	ArgSpecModule
+	Doc() Doc
+}
+
+// requirements of all modules
+var requirements = []string{"ansible >= 2.2.x (binary module support)"}
+
+// Markdown formats the documentation like the docs directory of the repository
+func (d Doc) Markdown(spec ArgSpec) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\n%s\n\n", d.Module, strings.TrimSpace(d.Description))
+	b.WriteString("## Requirements (on host that executes module)\n")
+	for _, requirement := range requirements {
+		fmt.Fprintf(&b, "- %s\n", requirement)
+	}
+
+	b.WriteString("\n## Options\n")
+	writeOptionsTable(&b, docArgs(spec), false)
+	for _, arg := range spec.Args {
+		if len(arg.Options) > 0 {
+			fmt.Fprintf(&b, "\n### %s\n\n", arg.Name)
+			writeOptionsTable(&b, arg.Options, true)
+		}
+	}
+
+	if len(d.Returns) > 0 {
+		b.WriteString("\n## Return Values\n\nThese values can be used when registering the modules output.\n\n")
+		b.WriteString("|key|returned|type|comments|\n|---|--------|----|--------|\n")
+		var samples []string
+		for _, r := range d.Returns {
+			t, elements := returnType(reflect.TypeOf(r.Sample))
+			if elements != "" {
+				t += " of " + elements
+			}
+			fmt.Fprintf(&b, "|%s|%s|%s|%s|\n", r.Name, r.Returned, t, markdownCell(r.Description))
+			samples = append(samples, marshalYAML(map[string]interface{}{r.Name: r.Sample}))
+		}
+		fmt.Fprintf(&b, "\n```yaml\n%s```\n", strings.Join(samples, "\n"))
+	}
+
+	if d.Examples != "" {
+		fmt.Fprintf(&b, "\n## Examples\n\n```yaml\n%s\n```\n", strings.TrimSpace(d.Examples))
+	}
+	return b.String()
+}
+
+// writeOptionsTable writes a table of the options, sub options of sub options are listed by their path
+func writeOptionsTable(b *bytes.Buffer, args []Arg, subOptions bool) {
+	b.WriteString("|parameter|required|default|choices|comments|\n|---------|--------|-------|-------|--------|\n")
+	writeOptionRows(b, args, "", subOptions)
+}
+
+func writeOptionRows(b *bytes.Buffer, args []Arg, prefix string, subOptions bool) {
+	for _, arg := range args {
+		required := "no"
+		if arg.Required {
+			required = "yes"
+		}
+		var def, choices string
+		if arg.Default != nil {
+			def = fmt.Sprint(arg.Default)
+		}
+		if len(arg.Choices) > 0 {
+			choices = "<ul><li>" + strings.Join(arg.Choices, "</li><li>") + "</li></ul>"
+		}
+		var comments []string
+		if arg.Description != "" {
+			comments = append(comments, markdownCell(arg.Description))
+		}
+		if len(arg.Aliases) > 0 {
+			comments = append(comments, "Aliases: `"+strings.Join(arg.Aliases, "`, `")+"`")
+		}
+		if arg.Deprecated != "" {
+			comments = append(comments, "**Deprecated:** "+deprecation(arg))
+		}
+		fmt.Fprintf(b, "|%s%s|%s|%s|%s|%s|\n", prefix, arg.Name, required, def, choices, strings.Join(comments, "<br>"))
+		if subOptions {
+			writeOptionRows(b, arg.Options, prefix+arg.Name+".", true)
+		}
+	}
+}
+
+// YAML formats the documentation as DOCUMENTATION, EXAMPLES and RETURN of an Ansible module
+func (d Doc) YAML(spec ArgSpec) string {
+	options := yamlMap{}
+	for _, arg := range docArgs(spec) {
+		options = append(options, yamlField{key: arg.Name, value: optionNode(arg)})
+	}
+	documentation := yamlMap{
+		{key: "module", value: d.Module},
+		{key: "short_description", value: d.ShortDescription},
+		{key: "description", value: ansibleMarkup(d.Description)},
+		{key: "requirements", value: stringList(requirements)},
+		{key: "options", value: options},
+	}
+
+	returns := yamlMap{}
+	for _, r := range d.Returns {
+		node := yamlMap{
+			{key: "description", value: ansibleMarkup(r.Description)},
+			{key: "returned", value: ansibleLine(r.Returned)},
+		}
+		node = append(node, typeNode(reflect.TypeOf(r.Sample))...)
+		node = append(node, yamlField{key: "sample", value: yamlNode(reflect.ValueOf(r.Sample))})
+		returns = append(returns, yamlField{key: r.Name, value: node})
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "DOCUMENTATION = r'''\n---\n%s'''\n", yamlDocument(documentation))
+	if d.Examples != "" {
+		fmt.Fprintf(&b, "\nEXAMPLES = r'''\n%s\n'''\n", strings.TrimSpace(d.Examples))
+	}
+	if len(returns) > 0 {
+		fmt.Fprintf(&b, "\nRETURN = r'''\n%s'''\n", yamlDocument(returns))
+	}
+	return b.String()
+}
+
+// docArgs are the documented arguments of a spec, the timeout follows the token of the module
+func docArgs(spec ArgSpec) []Arg {
+	timeout := timeoutArg
+	if _, ok := spec.Arg("wait"); ok {
+		timeout.Description += " Pending actions are reported when exceeded."
+	}
+	if len(spec.Args) == 0 {
+		return []Arg{timeout}
+	}
+	args := []Arg{spec.Args[0], timeout}
+	return append(args, spec.Args[1:]...)
+}
+
+func optionNode(arg Arg) yamlMap {
+	description := ansibleMarkup(arg.Description)
+	if arg.Deprecated != "" {
+		description = append(description, "B(Deprecated:) "+deprecation(arg))
+	}
+	node := yamlMap{
+		{key: "description", value: description},
+		{key: "type", value: string(arg.Type)},
+	}
+	if arg.Elements != "" {
+		node = append(node, yamlField{key: "elements", value: string(arg.Elements)})
+	}
+	node = append(node, yamlField{key: "required", value: yamlPlain(fmt.Sprint(arg.Required))})
+	if arg.Default != nil {
+		node = append(node, yamlField{key: "default", value: yamlNode(reflect.ValueOf(arg.Default))})
+	}
+	if len(arg.Choices) > 0 {
+		node = append(node, yamlField{key: "choices", value: stringList(arg.Choices)})
+	}
+	if len(arg.Aliases) > 0 {
+		node = append(node, yamlField{key: "aliases", value: stringList(arg.Aliases)})
+	}
+	if len(arg.Options) > 0 {
+		options := yamlMap{}
+		for _, option := range arg.Options {
+			options = append(options, yamlField{key: option.Name, value: optionNode(option)})
+		}
+		node = append(node, yamlField{key: "suboptions", value: options})
+	}
+	return node
+}
+
+// typeNode documents the type of a return value and the keys of dicts
+func typeNode(t reflect.Type) yamlMap {
+	typ, elements := returnType(t)
+	node := yamlMap{{key: "type", value: typ}}
+	if elements != "" {
+		node = append(node, yamlField{key: "elements", value: elements})
+	}
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || t.Implements(textMarshaler) {
+		return node
+	}
+	contains := yamlMap{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if field.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		contains = append(contains, yamlField{key: name, value: typeNode(field.Type)})
+	}
+	return append(node, yamlField{key: "contains", value: contains})
+}
+
+var textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// returnType is the Ansible type of a Go type and the type of list elements
+func returnType(t reflect.Type) (typ, elements string) {
+	if t == nil {
+		return string(TypeRaw), ""
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return returnType(t.Elem())
+	case reflect.Slice, reflect.Array:
+		elements, _ = returnType(t.Elem())
+		return string(TypeList), elements
+	case reflect.Struct:
+		if t.Implements(textMarshaler) {
+			return string(TypeStr), ""
+		}
+		return string(TypeDict), ""
+	case reflect.Map:
+		return string(TypeDict), ""
+	case reflect.String:
+		return string(TypeStr), ""
+	case reflect.Bool:
+		return string(TypeBool), ""
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return string(TypeInt), ""
+	case reflect.Float32, reflect.Float64:
+		return string(TypeFloat), ""
+	}
+	return string(TypeRaw), ""
+}
+
+func deprecation(arg Arg) string {
+	if arg.RemovedInVersion == "" {
+		return arg.Deprecated
+	}
+	return fmt.Sprintf("%s, removed in version %s", arg.Deprecated, arg.RemovedInVersion)
+}
+
+// markdownCell joins the paragraphs of a description for a table cell
+func markdownCell(description string) string {
+	return strings.Replace(strings.TrimSpace(description), "\n\n", "<br>", -1)
+}
+
+var (
+	codeMarkup = regexp.MustCompile("`([^`]*)`")
+	linkMarkup = regexp.MustCompile(`\[([^\]]*)\]\(([^)]*)\)`)
+	boldMarkup = regexp.MustCompile(`\*\*([^*]*)\*\*`)
+)
+
+// ansibleMarkup converts the paragraphs of a markdown description to the markup of ansible-doc
+func ansibleMarkup(description string) []interface{} {
+	paragraphs := []interface{}{}
+	for _, paragraph := range strings.Split(strings.Replace(description, "<br>", "\n\n", -1), "\n\n") {
+		if paragraph = ansibleLine(paragraph); paragraph != "" {
+			paragraphs = append(paragraphs, paragraph)
+		}
+	}
+	return paragraphs
+}
+
+// ansibleLine converts markdown to the markup of ansible-doc on a single line
+func ansibleLine(markdown string) string {
+	markdown = codeMarkup.ReplaceAllString(markdown, "C($1)")
+	markdown = linkMarkup.ReplaceAllString(markdown, "L($1,$2)")
+	markdown = boldMarkup.ReplaceAllString(markdown, "B($1)")
+	return strings.Join(strings.Fields(markdown), " ")
+}
+
+func stringList(values []string) []interface{} {
+	list := make([]interface{}, len(values))
+	for i, value := range values {
+		list[i] = value
+	}
+	return list
+}
+
+func yamlDocument(node interface{}) string {
+	return strings.Join(yamlLines(node), "\n") + "\n"
+}
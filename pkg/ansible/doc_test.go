@@ -0,0 +1,83 @@
+package ansible
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testVolume struct {
+	ID       int      `json:"id"`
+	Name     string   `json:"name"`
+	ServerID *int     `json:"server_id"`
+	Labels   []string `json:"labels"`
+	Config   string   `json:"config,omitempty"`
+}
+
+var testDoc = Doc{
+	Module:           "hcloud_test",
+	ShortDescription: "Manage test volumes",
+	Description:      "Manages volumes.\n\nVolumes are found by `name`, see [docs](https://example.com).",
+	Returns: []Return{
+		{
+			Name:        "volumes",
+			Description: "The volumes.",
+			Returned:    "always",
+			Sample:      []testVolume{{ID: 1, Name: "data", Labels: []string{"a"}, Config: "a: 1\n"}},
+		},
+	},
+	Examples: `
+- hcloud_test:
+    name: data
+`,
+}
+
+func TestDocMarkdown(t *testing.T) {
+	doc := testDoc.Markdown(testSpec)
+	assert.Contains(t, doc, "# hcloud_test\n\nManages volumes.\n\nVolumes are found by `name`, see [docs](https://example.com).\n")
+	assert.Contains(t, doc, "|token|no|||Hetzner Cloud API Token. Can also be specified with `HCLOUD_TOKEN` environment variable.<br>Aliases: `api_token`|\n|timeout|")
+	assert.Contains(t, doc, "|state|no|present|<ul><li>present</li><li>absent</li></ul>||\n")
+	assert.Contains(t, doc, "|datacenter|no|||**Deprecated:** use 'location' instead, removed in version 2.0|\n")
+	assert.Contains(t, doc, "|volumes|always|list of dict|The volumes.|\n")
+	assert.Contains(t, doc, "```yaml\nvolumes:\n- id: 1\n  name: data\n  server_id: null\n  labels: [a]\n  config: |\n    a: 1\n```\n")
+	assert.Contains(t, doc, "## Examples\n\n```yaml\n- hcloud_test:\n    name: data\n```\n")
+}
+
+func TestDocYAML(t *testing.T) {
+	doc := testDoc.YAML(testSpec)
+	assert.Contains(t, doc, "DOCUMENTATION = r'''\n---\nmodule: hcloud_test\nshort_description: Manage test volumes\n")
+	assert.Contains(t, doc, "description:\n- Manages volumes.\n- Volumes are found by C(name), see L(docs,https://example.com).\n")
+	assert.Contains(t, doc, "  token:\n    description:\n    - Hetzner Cloud API Token. Can also be specified with C(HCLOUD_TOKEN) environment variable.\n"+
+		"    type: str\n    required: false\n    aliases: [api_token]\n")
+	assert.Contains(t, doc, "  tags:\n    description: []\n    type: list\n    elements: str\n")
+	assert.Contains(t, doc, "EXAMPLES = r'''\n- hcloud_test:\n    name: data\n'''\n")
+	assert.Contains(t, doc, "RETURN = r'''\nvolumes:\n  description:\n  - The volumes.\n  returned: always\n  type: list\n  elements: dict\n"+
+		"  contains:\n    id:\n      type: int\n    name:\n      type: str\n    server_id:\n      type: int\n"+
+		"    labels:\n      type: list\n      elements: str\n    config:\n      type: str\n  sample:\n  - id: 1\n")
+}
+
+func TestMarshalYAML(t *testing.T) {
+	assert.Equal(t, "[]\n", marshalYAML([]string{}))
+	assert.Equal(t, `id: 2
+name: "true"
+server_id: 42
+labels:
+- "1.5"
+- "key: value"
+- "-a"
+- 2001:db8::/64
+`, marshalYAML(testVolume{ID: 2, Name: "true", ServerID: intPtr(42), Labels: []string{"1.5", "key: value", "-a", "2001:db8::/64"}}))
+	assert.Equal(t, "servers: [42, 43]\nnames: [a, \"yes\"]\n", marshalYAML(struct {
+		Servers []int    `json:"servers"`
+		Names   []string `json:"names"`
+	}{[]int{42, 43}, []string{"a", "yes"}}))
+	assert.Equal(t, "started: 2018-01-01T12:00:00Z\nfinished: null\n", marshalYAML(struct {
+		Started  time.Time  `json:"started"`
+		Finished *time.Time `json:"finished"`
+	}{Started: time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)}))
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -23,3 +23,10 @@ func (l *MessageLog) String() string {
 	defer l.mux.Unlock()
 	return strings.Join(l.messages, ", ")
 }
+
+// Messages returns a copy of the messages
+func (l *MessageLog) Messages() []string {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return append([]string(nil), l.messages...)
+}
@@ -1,10 +1,16 @@
 package ansible
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/thetechnick/hcloud-ansible/pkg/version"
@@ -13,12 +19,35 @@ import (
 // Module interface for Ansible modules
 type Module interface {
 	Args() interface{}
-	Run() (ModuleResponse, error)
+	Run(ctx context.Context) (ModuleResponse, error)
+}
+
+// CheckModeModule is implemented by modules supporting Ansible's check mode,
+// in check mode Check is called instead of Run and reports the changes without making them
+type CheckModeModule interface {
+	Module
+	Check(ctx context.Context) (ModuleResponse, error)
+}
+
+// commonArgs are arguments shared by all modules
+type commonArgs struct {
+	Timeout interface{} `json:"timeout"`
+}
+
+// internalArgs are arguments Ansible passes to every module,
+// they are not part of the arguments of the module
+type internalArgs struct {
+	CheckMode bool `json:"_ansible_check_mode"`
 }
 
 // RunModule executes the module
 func RunModule(m Module, flags *pflag.FlagSet) {
 	var resp ModuleResponse
+	dm, doc := m.(DocModule)
+	if doc && flags.Lookup("doc") == nil {
+		flags.String("doc", "", "Print the documentation as markdown or yaml and exit")
+		flags.Lookup("doc").NoOptDefVal = "markdown"
+	}
 	if err := flags.Parse(os.Args[1:]); err != nil {
 		resp.Msg(fmt.Sprintf("Error parsing flags: %v", err)).
 			Failed().
@@ -27,6 +56,9 @@ func RunModule(m Module, flags *pflag.FlagSet) {
 	if v, _ := flags.GetBool("version"); v {
 		version.PrintText()
 	}
+	if format, _ := flags.GetString("doc"); doc && format != "" {
+		printDoc(dm, format)
+	}
 
 	if len(os.Args) != 2 {
 		resp.Msg("No arguments file provided").
@@ -41,28 +73,156 @@ func RunModule(m Module, flags *pflag.FlagSet) {
 			Failed().
 			exitJSON()
 	}
+	var internal internalArgs
+	if err := json.Unmarshal(argsString, &internal); err != nil {
+		resp.Msg(fmt.Sprintf("Cannot parse arguments file: %v", err)).
+			Failed().
+			exitJSON()
+	}
 
-	if err := json.Unmarshal(argsString, m.Args()); err != nil {
+	// arguments are validated against the spec and the checks of the module
+	// and parsed with aliases resolved and defaults set,
+	// the arguments are echoed as invocation with NoLog arguments masked
+	if sm, ok := m.(ArgSpecModule); ok {
+		var args map[string]interface{}
+		if err := json.Unmarshal(argsString, &args); err != nil {
+			resp.Msg(fmt.Sprintf("Cannot parse arguments file: %v", err)).
+				Failed().
+				exitJSON()
+		}
+		spec := sm.ArgSpec()
+		resp.Invocation(spec.Mask(args))
+		resp.noLog = spec.NoLogValues(args)
+		values, deprecations, errs, err := parseArgs(sm, args)
+		if len(errs) > 0 {
+			resp.Msg(fmt.Sprintf("Invalid arguments: %s", strings.Join(errs, ", "))).
+				Failed().
+				exitJSON()
+		}
+		if err != nil {
+			resp.Msg(fmt.Sprintf("Cannot parse arguments file: %v", err)).
+				Failed().
+				exitJSON()
+		}
+		resp.Invocation(spec.Mask(values))
+		for _, deprecation := range deprecations {
+			resp.Deprecate(deprecation)
+		}
+		if argsString, err = json.Marshal(values); err != nil {
+			resp.Msg(fmt.Sprintf("Cannot parse arguments file: %v", err)).
+				Failed().
+				exitJSON()
+		}
+	} else if err := json.Unmarshal(argsString, m.Args()); err != nil {
 		resp.Msg(fmt.Sprintf("Cannot parse arguments file: %v", err)).
 			Failed().
 			exitJSON()
 	}
 
-	if resp, err := m.Run(); err != nil {
+	var common commonArgs
+	if err := json.Unmarshal(argsString, &common); err != nil {
+		resp.Msg(fmt.Sprintf("Cannot parse arguments file: %v", err)).
+			Failed().
+			exitJSON()
+	}
+	timeout, err := ParseTimeout(common.Timeout)
+	if err != nil {
 		resp.Msg(err.Error()).
 			Failed().
 			exitJSON()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	run := m.Run
+	if cm, ok := m.(CheckModeModule); ok && internal.CheckMode {
+		run = cm.Check
+	}
+	result, err := run(ctx)
+	result.invocation = resp.invocation
+	result.noLog = resp.noLog
+	result.deprecations = append(resp.deprecations, result.deprecations...)
+	resp = result
+	if err != nil {
+		msg := err.Error()
+		if ctx.Err() == context.DeadlineExceeded {
+			msg = fmt.Sprintf("Timeout of %s exceeded: %s", timeout, msg)
+		} else if ctx.Err() == context.Canceled {
+			msg = fmt.Sprintf("Cancelled: %s", msg)
+		}
+		resp.Msg(msg).
+			Failed().
+			exitJSON()
 	} else {
 		resp.exitJSON()
 	}
 }
 
+// printDoc prints the documentation of the module and exits
+func printDoc(m DocModule, format string) {
+	switch format {
+	case "markdown":
+		fmt.Print(m.Doc().Markdown(m.ArgSpec()))
+	case "yaml":
+		fmt.Print(m.Doc().YAML(m.ArgSpec()))
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown documentation format %q, must be markdown or yaml\n", format)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// ParseTimeout parses the `timeout` argument,
+// either a number of seconds or a duration string like "5m"
+func ParseTimeout(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		if v < 0 {
+			break
+		}
+		return time.Duration(v * float64(time.Second)), nil
+	case string:
+		if v == "" {
+			return 0, nil
+		}
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			return ParseTimeout(seconds)
+		}
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("'timeout' must be a positive number of seconds or a duration like \"5m\", got %v", value)
+}
+
+// noLogMask replaces the values of NoLog arguments in the module output
+const noLogMask = "********"
+
 // ModuleResponse represents the reponse of the module
 type ModuleResponse struct {
-	msg     string
-	changed bool
-	failed  bool
-	data    map[string]interface{}
+	msg          string
+	changed      bool
+	failed       bool
+	warnings     []string
+	deprecations []Deprecation
+	// invocation is echoed as invocation.module_args
+	invocation map[string]interface{}
+	// noLog are the values of NoLog arguments, they are masked in the output
+	noLog []string
+	data  map[string]interface{}
 }
 
 // Msg sets the module message
@@ -83,6 +243,24 @@ func (r *ModuleResponse) Failed() *ModuleResponse {
 	return r
 }
 
+// Warn adds a warning, Ansible shows the warnings of a module after the task
+func (r *ModuleResponse) Warn(msg string) *ModuleResponse {
+	r.warnings = append(r.warnings, msg)
+	return r
+}
+
+// Deprecate adds a deprecation notice
+func (r *ModuleResponse) Deprecate(deprecation Deprecation) *ModuleResponse {
+	r.deprecations = append(r.deprecations, deprecation)
+	return r
+}
+
+// Invocation sets the module arguments echoed as invocation.module_args
+func (r *ModuleResponse) Invocation(args map[string]interface{}) *ModuleResponse {
+	r.invocation = args
+	return r
+}
+
 // Set adds data to the reponse
 func (r *ModuleResponse) Set(key string, value interface{}) *ModuleResponse {
 	if r.data == nil {
@@ -97,6 +275,16 @@ func (r *ModuleResponse) Data() map[string]interface{} {
 	return r.data
 }
 
+// Warnings returns the warnings of the response
+func (r *ModuleResponse) Warnings() []string {
+	return r.warnings
+}
+
+// Deprecations returns the deprecation notices of the response
+func (r *ModuleResponse) Deprecations() []Deprecation {
+	return r.deprecations
+}
+
 // HasChanged returns true if the module has changed
 func (r *ModuleResponse) HasChanged() bool {
 	return r.changed
@@ -118,7 +306,46 @@ func (r *ModuleResponse) MarshalJSON() ([]byte, error) {
 	for key, value := range r.data {
 		data[key] = value
 	}
-	return json.Marshal(data)
+	if len(r.warnings) > 0 {
+		data["warnings"] = r.warnings
+	}
+	if len(r.deprecations) > 0 {
+		data["deprecations"] = r.deprecations
+	}
+	if r.invocation != nil {
+		data["invocation"] = map[string]interface{}{"module_args": r.invocation}
+	}
+	response, err := json.Marshal(data)
+	if err != nil || len(r.noLog) == 0 {
+		return response, err
+	}
+
+	// values of NoLog arguments may be part of messages or data
+	var output interface{}
+	if err := json.Unmarshal(response, &output); err != nil {
+		return nil, err
+	}
+	return json.Marshal(maskValues(output, r.noLog))
+}
+
+// maskValues replaces the values of NoLog arguments in the strings of decoded JSON
+func maskValues(value interface{}, noLog []string) interface{} {
+	switch v := value.(type) {
+	case string:
+		for _, secret := range noLog {
+			v = strings.Replace(v, secret, noLogMask, -1)
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = maskValues(v[i], noLog)
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = maskValues(v[key], noLog)
+		}
+	}
+	return value
 }
 
 func (r *ModuleResponse) exitJSON() {
@@ -0,0 +1,70 @@
+package ansible
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimeout(t *testing.T) {
+	t.Run("unset", func(t *testing.T) {
+		d, err := ParseTimeout(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(0), d)
+	})
+	t.Run("seconds", func(t *testing.T) {
+		d, err := ParseTimeout(float64(90))
+		assert.NoError(t, err)
+		assert.Equal(t, 90*time.Second, d)
+	})
+	t.Run("seconds string", func(t *testing.T) {
+		d, err := ParseTimeout("30")
+		assert.NoError(t, err)
+		assert.Equal(t, 30*time.Second, d)
+	})
+	t.Run("duration", func(t *testing.T) {
+		d, err := ParseTimeout("5m")
+		assert.NoError(t, err)
+		assert.Equal(t, 5*time.Minute, d)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ParseTimeout("soon")
+		assert.Error(t, err)
+	})
+	t.Run("negative", func(t *testing.T) {
+		_, err := ParseTimeout(float64(-1))
+		assert.Error(t, err)
+	})
+}
+
+func TestModuleResponseMarshalJSON(t *testing.T) {
+	t.Run("plain", func(t *testing.T) {
+		var resp ModuleResponse
+		data, err := json.Marshal(resp.Msg("ok"))
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"changed": false, "failed": false, "msg": "ok"}`, string(data))
+	})
+
+	t.Run("warnings, deprecations and invocation", func(t *testing.T) {
+		var resp ModuleResponse
+		resp.Invocation(testSpec.Mask(map[string]interface{}{"token": "--token--", "name": "test"}))
+		resp.noLog = []string{"--token--"}
+		resp.Msg("Invalid token --token--").
+			Warn("datacenter and location both set, using datacenter").
+			Deprecate(Deprecation{Msg: "'datacenter' is deprecated", Version: "2.0"}).
+			Set("servers", []map[string]string{{"name": "test", "user_data": "token: --token--"}})
+		data, err := json.Marshal(&resp)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{
+			"changed": false,
+			"failed": false,
+			"msg": "Invalid token ********",
+			"servers": [{"name": "test", "user_data": "token: ********"}],
+			"warnings": ["datacenter and location both set, using datacenter"],
+			"deprecations": [{"msg": "'datacenter' is deprecated", "version": "2.0"}],
+			"invocation": {"module_args": {"token": "VALUE_SPECIFIED_IN_NO_LOG_PARAMETER", "name": "test"}}
+		}`, string(data))
+	})
+}
@@ -0,0 +1,507 @@
+package ansible
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ArgType is the type of a module argument
+type ArgType string
+
+// ArgTypes
+const (
+	TypeStr   ArgType = "str"
+	TypeInt   ArgType = "int"
+	TypeFloat ArgType = "float"
+	TypeBool  ArgType = "bool"
+	TypeList  ArgType = "list"
+	TypeDict  ArgType = "dict"
+	// TypeRaw accepts any value, e.g. an id, a name or a list of them
+	TypeRaw ArgType = "raw"
+)
+
+// Arg specifies a module argument
+type Arg struct {
+	Name string
+	Type ArgType
+	// Elements is the type of list elements
+	Elements ArgType
+	Required bool
+	Default  interface{}
+	Choices  []string
+	Aliases  []string
+	// Description is markdown, see Doc
+	Description string
+	// Options documents the keys of dict arguments and of dict list elements
+	Options []Arg
+	// NoLog arguments are never echoed in the module output
+	NoLog bool
+	// Deprecated is the reason the argument is deprecated, e.g. "use 'location' instead"
+	Deprecated string
+	// RemovedInVersion is the version removing a deprecated argument
+	RemovedInVersion string
+}
+
+// RequiredIf requires arguments when another argument has a value,
+// like required_if of Ansible modules
+type RequiredIf struct {
+	Arg      string
+	Value    string
+	Required []string
+	// OneOf requires one of the arguments instead of all of them
+	OneOf bool
+}
+
+// ArgSpec specifies the arguments of a module
+type ArgSpec struct {
+	Args              []Arg
+	RequiredIf        []RequiredIf
+	RequiredOneOf     [][]string
+	MutuallyExclusive [][]string
+}
+
+// Deprecation is a deprecation notice of the module output
+type Deprecation struct {
+	Msg     string `json:"msg"`
+	Version string `json:"version,omitempty"`
+}
+
+// ArgSpecModule is a module with an argument spec,
+// RunModule validates the arguments against the spec before Run
+type ArgSpecModule interface {
+	Module
+	ArgSpec() ArgSpec
+}
+
+// ArgCheckModule is a module with constraints of its arguments the spec cannot express,
+// RunModule reports the violations of CheckArgs together with those of the spec
+type ArgCheckModule interface {
+	ArgSpecModule
+	// CheckArgs checks the parsed arguments, it is called even if they violate the spec
+	CheckArgs() []string
+}
+
+// tokenArg is the Hetzner Cloud API token argument of all modules
+var tokenArg = Arg{Name: "token", Type: TypeStr, Aliases: []string{"api_token"}, NoLog: true,
+	Description: "Hetzner Cloud API Token. Can also be specified with `HCLOUD_TOKEN` environment variable."}
+
+// TokenArg returns the spec of the Hetzner Cloud API token argument
+func TokenArg() Arg {
+	return tokenArg
+}
+
+// timeoutArg is added to all specs, see commonArgs
+var timeoutArg = Arg{Name: "timeout", Type: TypeRaw,
+	Description: "Maximum run time of the module as seconds or duration string (e.g. `10m`)."}
+
+// Arg returns the spec of the argument
+func (s ArgSpec) Arg(name string) (Arg, bool) {
+	for _, arg := range s.Args {
+		if arg.Name == name {
+			return arg, true
+		}
+	}
+	if name == timeoutArg.Name {
+		return timeoutArg, true
+	}
+	return Arg{}, false
+}
+
+// noLogValue replaces the values of NoLog arguments in the invocation like in Ansible
+const noLogValue = "VALUE_SPECIFIED_IN_NO_LOG_PARAMETER"
+
+// noLogArgs maps the names and aliases of NoLog arguments to their names
+func (s ArgSpec) noLogArgs() map[string]string {
+	names := map[string]string{}
+	for _, arg := range s.Args {
+		if !arg.NoLog {
+			continue
+		}
+		names[arg.Name] = arg.Name
+		for _, alias := range arg.Aliases {
+			names[alias] = arg.Name
+		}
+	}
+	return names
+}
+
+// Mask returns a copy of the arguments of an arguments file for the invocation,
+// the values of NoLog arguments are replaced and internal arguments of Ansible are removed
+func (s ArgSpec) Mask(args map[string]interface{}) map[string]interface{} {
+	noLog := s.noLogArgs()
+	masked := map[string]interface{}{}
+	for key, value := range args {
+		if strings.HasPrefix(key, "_ansible_") {
+			continue
+		}
+		if _, ok := noLog[key]; ok && value != nil {
+			value = noLogValue
+		}
+		masked[key] = value
+	}
+	return masked
+}
+
+// NoLogValues returns the values of the NoLog arguments of an arguments file
+func (s ArgSpec) NoLogValues(args map[string]interface{}) []string {
+	noLog := s.noLogArgs()
+	var values []string
+	for key, value := range args {
+		if _, ok := noLog[key]; !ok {
+			continue
+		}
+		if v := fmt.Sprint(value); value != nil && v != "" {
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// Validate resolves aliases, converts types and sets defaults of the arguments of an arguments file.
+// It returns the normalized arguments, notices of used deprecated arguments and all violations of the spec.
+func (s ArgSpec) Validate(args map[string]interface{}) (values map[string]interface{}, deprecations []Deprecation, errs []string) {
+	values = map[string]interface{}{}
+	aliases := map[string]string{}
+	for _, arg := range s.Args {
+		for _, alias := range arg.Aliases {
+			aliases[alias] = arg.Name
+		}
+	}
+
+	var unsupported []string
+	for key, value := range args {
+		// internal arguments of Ansible like _ansible_check_mode
+		if strings.HasPrefix(key, "_ansible_") {
+			continue
+		}
+		name := key
+		if canonical, ok := aliases[key]; ok {
+			if _, ok := args[canonical]; ok {
+				errs = append(errs, fmt.Sprintf("'%s' and its alias '%s' are both set", canonical, key))
+				continue
+			}
+			name = canonical
+		}
+		if _, ok := s.Arg(name); !ok {
+			unsupported = append(unsupported, key)
+			continue
+		}
+		values[name] = value
+	}
+	sort.Strings(unsupported)
+	for _, key := range unsupported {
+		errs = append(errs, fmt.Sprintf("'%s' is not a supported parameter", key))
+	}
+
+	for _, arg := range s.Args {
+		if values[arg.Name] == nil {
+			continue
+		}
+		if arg.Deprecated != "" {
+			deprecations = append(deprecations, Deprecation{
+				Msg:     fmt.Sprintf("'%s' is deprecated, %s", arg.Name, arg.Deprecated),
+				Version: arg.RemovedInVersion,
+			})
+		}
+	}
+	return values, deprecations, append(errs, s.check(values)...)
+}
+
+// ValidateArgs validates the parsed arguments of a module like RunModule validates an arguments file,
+// against the spec and with the checks of ArgCheckModule, and parses them with defaults set.
+// Nil values and empty strings are unset arguments.
+func ValidateArgs(m ArgSpecModule) error {
+	data, err := json.Marshal(m.Args())
+	if err != nil {
+		return err
+	}
+	args := map[string]interface{}{}
+	if err := json.Unmarshal(data, &args); err != nil {
+		return err
+	}
+	unsetZeroValues(args)
+	_, _, errs, err := parseArgs(m, args)
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// parseArgs validates the arguments of an arguments file against the spec of the module
+// and parses the normalized arguments into the arguments of the module.
+// The checks of ArgCheckModule run in the same pass, so all violations are reported at once.
+func parseArgs(m ArgSpecModule, args map[string]interface{}) (values map[string]interface{}, deprecations []Deprecation, errs []string, err error) {
+	values, deprecations, errs = m.ArgSpec().Validate(args)
+	data, err := json.Marshal(values)
+	if err != nil {
+		return
+	}
+	// values violating the spec are not converted and may not parse,
+	// the checks of the module still get the other arguments
+	if err = json.Unmarshal(data, m.Args()); err != nil && len(errs) == 0 {
+		return
+	}
+	err = nil
+	if cm, ok := m.(ArgCheckModule); ok {
+		errs = append(errs, cm.CheckArgs()...)
+	}
+	return
+}
+
+// unsetZeroValues removes nil values and empty strings of parsed arguments, also of nested options
+func unsetZeroValues(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, element := range v {
+			if element == nil || element == "" {
+				delete(v, key)
+				continue
+			}
+			unsetZeroValues(element)
+		}
+	case []interface{}:
+		for _, element := range v {
+			unsetZeroValues(element)
+		}
+	}
+}
+
+// check converts the types of the values, sets defaults and checks the constraints of the spec
+func (s ArgSpec) check(values map[string]interface{}) (errs []string) {
+	errs = checkArgs("", s.Args, values)
+	for _, r := range s.RequiredIf {
+		if value, ok := values[r.Arg]; !ok || fmt.Sprint(value) != r.Value {
+			continue
+		}
+		var missing []string
+		for _, name := range r.Required {
+			if !isSet(values[name]) {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) == 0 || r.OneOf && len(missing) < len(r.Required) {
+			continue
+		}
+		if r.OneOf {
+			errs = append(errs, fmt.Sprintf("%s is required when '%s' is %s", joinArgs(r.Required, "or"), r.Arg, r.Value))
+		} else {
+			errs = append(errs, fmt.Sprintf("%s required when '%s' is %s", requiredArgs(missing), r.Arg, r.Value))
+		}
+	}
+	for _, names := range s.RequiredOneOf {
+		found := false
+		for _, name := range names {
+			found = found || isSet(values[name])
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s is required", joinArgs(names, "or")))
+		}
+	}
+	for _, names := range s.MutuallyExclusive {
+		var set []string
+		for _, name := range names {
+			if isSet(values[name]) {
+				set = append(set, name)
+			}
+		}
+		if len(set) > 1 {
+			errs = append(errs, fmt.Sprintf("%s are mutually exclusive", joinArgs(set, "and")))
+		}
+	}
+	return
+}
+
+// checkArgs converts the types of the values, sets defaults and checks choices and required arguments.
+// Options of dicts and of dict list elements are checked the same way, their names are prefixed with
+// the path of the dict, e.g. 'services[0].health_check.protocol'.
+func checkArgs(prefix string, args []Arg, values map[string]interface{}) (errs []string) {
+	for _, arg := range args {
+		name := prefix + arg.Name
+		value, ok := values[arg.Name]
+		if !ok || value == nil {
+			if arg.Default != nil {
+				values[arg.Name] = arg.Default
+			}
+			continue
+		}
+		converted, err := convert(name, arg.Type, value)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if arg.Type == TypeList && arg.Elements != "" {
+			list := converted.([]interface{})
+			for i, element := range list {
+				if list[i], err = convert(fmt.Sprintf("%s[%d]", name, i), arg.Elements, element); err != nil {
+					errs = append(errs, err.Error())
+				}
+			}
+		}
+		if len(arg.Choices) > 0 {
+			elements := []interface{}{converted}
+			if list, ok := converted.([]interface{}); ok {
+				elements = list
+			}
+			for _, element := range elements {
+				if !contains(arg.Choices, fmt.Sprint(element)) {
+					errs = append(errs, fmt.Sprintf("'%s' must be %s, got %q", name, joinChoices(arg.Choices, "or"), fmt.Sprint(element)))
+				}
+			}
+		}
+		if len(arg.Options) > 0 {
+			switch v := converted.(type) {
+			case map[string]interface{}:
+				errs = append(errs, checkArgs(name+".", arg.Options, v)...)
+			case []interface{}:
+				for i, element := range v {
+					if dict, ok := element.(map[string]interface{}); ok {
+						errs = append(errs, checkArgs(fmt.Sprintf("%s[%d].", name, i), arg.Options, dict)...)
+					}
+				}
+			}
+		}
+		values[arg.Name] = converted
+	}
+
+	for _, arg := range args {
+		if arg.Required && !isSet(values[arg.Name]) {
+			errs = append(errs, fmt.Sprintf("'%s' is required", prefix+arg.Name))
+		}
+	}
+	return
+}
+
+// convert converts a value of an arguments file to the type
+func convert(name string, t ArgType, value interface{}) (interface{}, error) {
+	switch t {
+	case TypeStr:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+		return nil, fmt.Errorf("'%s' must be a string, got %v", name, value)
+	case TypeInt:
+		switch v := value.(type) {
+		case float64:
+			if v == float64(int(v)) {
+				return v, nil
+			}
+		case string:
+			if i, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return float64(i), nil
+			}
+		}
+		return nil, fmt.Errorf("'%s' must be an integer, got %v", name, value)
+	case TypeFloat:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f, nil
+			}
+		}
+		return nil, fmt.Errorf("'%s' must be a number, got %v", name, value)
+	case TypeBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case float64:
+			if v == 0 || v == 1 {
+				return v == 1, nil
+			}
+		case string:
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "yes", "on", "true", "1", "y":
+				return true, nil
+			case "no", "off", "false", "0", "n":
+				return false, nil
+			}
+		}
+		return nil, fmt.Errorf("'%s' must be a boolean, got %v", name, value)
+	case TypeList:
+		switch v := value.(type) {
+		case []interface{}:
+			return v, nil
+		case string:
+			// comma separated lists like Ansible
+			list := []interface{}{}
+			for _, element := range strings.Split(v, ",") {
+				if element = strings.TrimSpace(element); element != "" {
+					list = append(list, element)
+				}
+			}
+			return list, nil
+		case map[string]interface{}:
+			return nil, fmt.Errorf("'%s' must be a list, got a dict", name)
+		}
+		return []interface{}{value}, nil
+	case TypeDict:
+		if v, ok := value.(map[string]interface{}); ok {
+			return v, nil
+		}
+		return nil, fmt.Errorf("'%s' must be a dict, got %v", name, value)
+	}
+	return value, nil
+}
+
+// isSet checks if an argument is set, zero values are unset
+func isSet(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case string:
+		return v != ""
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	}
+	return true
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// joinArgs joins argument names like 'a', 'b' or 'c'
+func joinArgs(names []string, conjunction string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "'" + name + "'"
+	}
+	return joinChoices(quoted, conjunction)
+}
+
+// joinChoices joins values like a, b or c
+func joinChoices(values []string, conjunction string) string {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return strings.Join(values[:len(values)-1], ", ") + " " + conjunction + " " + values[len(values)-1]
+}
+
+// requiredArgs formats missing arguments like 'a' is or 'a' and 'b' are
+func requiredArgs(names []string) string {
+	if len(names) == 1 {
+		return fmt.Sprintf("'%s' is", names[0])
+	}
+	return joinArgs(names, "and") + " are"
+}
@@ -0,0 +1,196 @@
+package ansible
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testSpec = ArgSpec{
+	Args: []Arg{
+		TokenArg(),
+		{Name: "state", Type: TypeStr, Default: "present", Choices: []string{"present", "absent"}},
+		{Name: "id", Type: TypeRaw},
+		{Name: "name", Type: TypeStr},
+		{Name: "size", Type: TypeInt},
+		{Name: "wait", Type: TypeBool, Default: true},
+		{Name: "tags", Type: TypeList, Elements: TypeStr},
+		{Name: "datacenter", Type: TypeStr, Deprecated: "use 'location' instead", RemovedInVersion: "2.0"},
+		{Name: "location", Type: TypeStr},
+	},
+	RequiredIf: []RequiredIf{
+		{Arg: "state", Value: "absent", Required: []string{"id", "name"}, OneOf: true},
+		{Arg: "state", Value: "present", Required: []string{"name", "size"}},
+	},
+	MutuallyExclusive: [][]string{{"datacenter", "location"}},
+}
+
+func TestArgSpecValidate(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		values, deprecations, errs := testSpec.Validate(map[string]interface{}{
+			"api_token":           "--token--",
+			"name":                "test",
+			"size":                "10",
+			"tags":                "a, b",
+			"datacenter":          "fsn1-dc8",
+			"timeout":             "5m",
+			"_ansible_check_mode": false,
+		})
+		assert.Empty(t, errs)
+		assert.Equal(t, map[string]interface{}{
+			"token":      "--token--",
+			"state":      "present",
+			"name":       "test",
+			"size":       float64(10),
+			"wait":       true,
+			"tags":       []interface{}{"a", "b"},
+			"datacenter": "fsn1-dc8",
+			"timeout":    "5m",
+		}, values)
+		assert.Equal(t, []Deprecation{
+			{Msg: "'datacenter' is deprecated, use 'location' instead", Version: "2.0"},
+		}, deprecations)
+	})
+
+	t.Run("all violations", func(t *testing.T) {
+		_, _, errs := testSpec.Validate(map[string]interface{}{
+			"state":      "gone",
+			"size":       1.5,
+			"wait":       "maybe",
+			"datacenter": "fsn1-dc8",
+			"location":   "fsn1",
+			"unknown":    true,
+		})
+		assert.Equal(t, []string{
+			"'unknown' is not a supported parameter",
+			"'state' must be present or absent, got \"gone\"",
+			"'size' must be an integer, got 1.5",
+			"'wait' must be a boolean, got maybe",
+			"'datacenter' and 'location' are mutually exclusive",
+		}, errs)
+	})
+
+	t.Run("required if", func(t *testing.T) {
+		_, _, errs := testSpec.Validate(map[string]interface{}{})
+		assert.Equal(t, []string{"'name' and 'size' are required when 'state' is present"}, errs)
+
+		_, _, errs = testSpec.Validate(map[string]interface{}{"state": "absent"})
+		assert.Equal(t, []string{"'id' or 'name' is required when 'state' is absent"}, errs)
+	})
+
+	t.Run("options", func(t *testing.T) {
+		spec := ArgSpec{Args: []Arg{
+			{Name: "services", Type: TypeList, Elements: TypeDict, Options: []Arg{
+				{Name: "protocol", Type: TypeStr, Required: true, Choices: []string{"http", "tcp"}},
+				{Name: "listen_port", Type: TypeInt},
+				{Name: "health_check", Type: TypeDict, Options: []Arg{
+					{Name: "protocol", Type: TypeStr, Choices: []string{"http", "tcp"}},
+					{Name: "interval", Type: TypeInt, Default: 15},
+				}},
+			}},
+		}}
+		values, _, errs := spec.Validate(map[string]interface{}{
+			"services": []interface{}{
+				map[string]interface{}{"protocol": "tcp", "listen_port": "80", "health_check": map[string]interface{}{}},
+			},
+		})
+		assert.Empty(t, errs)
+		assert.Equal(t, map[string]interface{}{
+			"services": []interface{}{
+				map[string]interface{}{
+					"protocol":     "tcp",
+					"listen_port":  float64(80),
+					"health_check": map[string]interface{}{"interval": 15},
+				},
+			},
+		}, values)
+
+		_, _, errs = spec.Validate(map[string]interface{}{
+			"services": []interface{}{
+				map[string]interface{}{"protocol": "tcp"},
+				map[string]interface{}{"listen_port": 1.5, "health_check": map[string]interface{}{"protocol": "udp"}},
+			},
+		})
+		assert.Equal(t, []string{
+			"'services[1].listen_port' must be an integer, got 1.5",
+			"'services[1].health_check.protocol' must be http or tcp, got \"udp\"",
+			"'services[1].protocol' is required",
+		}, errs)
+	})
+
+	t.Run("alias and name", func(t *testing.T) {
+		_, _, errs := testSpec.Validate(map[string]interface{}{
+			"token": "a", "api_token": "b", "name": "test", "size": 10.0,
+		})
+		assert.Equal(t, []string{"'token' and its alias 'api_token' are both set"}, errs)
+	})
+}
+
+type testArguments struct {
+	State string `json:"state"`
+	Name  string `json:"name"`
+	Size  int    `json:"size"`
+	Wait  *bool  `json:"wait"`
+}
+
+type testModule struct {
+	args testArguments
+}
+
+func (m *testModule) Args() interface{} {
+	return &m.args
+}
+
+func (m *testModule) Run(ctx context.Context) (ModuleResponse, error) {
+	return ModuleResponse{}, nil
+}
+
+func (m *testModule) ArgSpec() ArgSpec {
+	return testSpec
+}
+
+func (m *testModule) CheckArgs() (errs []string) {
+	if m.args.State == "present" && m.args.Size > 100 {
+		errs = append(errs, "'size' must be at most 100")
+	}
+	return
+}
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		m := &testModule{args: testArguments{Name: "test", Size: 10}}
+		if assert.NoError(t, ValidateArgs(m)) {
+			wait := true
+			assert.Equal(t, testArguments{State: "present", Name: "test", Size: 10, Wait: &wait}, m.args)
+		}
+
+		m = &testModule{args: testArguments{State: "absent", Name: "test", Wait: new(bool)}}
+		if assert.NoError(t, ValidateArgs(m)) {
+			assert.Equal(t, "absent", m.args.State)
+			assert.False(t, *m.args.Wait)
+		}
+	})
+
+	t.Run("violations of the spec and the module", func(t *testing.T) {
+		assert.EqualError(t, ValidateArgs(&testModule{args: testArguments{Size: 200}}),
+			"'name' is required when 'state' is present, 'size' must be at most 100")
+		assert.EqualError(t, ValidateArgs(&testModule{args: testArguments{State: "gone"}}),
+			"'state' must be present or absent, got \"gone\"")
+	})
+}
+
+func TestArgSpecMask(t *testing.T) {
+	args := map[string]interface{}{
+		"api_token":           "--token--",
+		"name":                "test",
+		"_ansible_check_mode": false,
+	}
+	assert.Equal(t, map[string]interface{}{
+		"api_token": "VALUE_SPECIFIED_IN_NO_LOG_PARAMETER",
+		"name":      "test",
+	}, testSpec.Mask(args))
+	assert.Equal(t, "--token--", args["api_token"], "should not change the arguments")
+	assert.Equal(t, []string{"--token--"}, testSpec.NoLogValues(args))
+	assert.Empty(t, testSpec.NoLogValues(map[string]interface{}{"token": ""}))
+}
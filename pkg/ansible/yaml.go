@@ -0,0 +1,224 @@
+package ansible
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// yamlField is a key of a yamlMap
+type yamlField struct {
+	key   string
+	value interface{}
+}
+
+// yamlMap keeps the order of struct fields
+type yamlMap []yamlField
+
+// yamlPlain is a formatted number or boolean
+type yamlPlain string
+
+// marshalYAML formats a value as block style YAML,
+// struct fields are written in their order with their json names
+func marshalYAML(v interface{}) string {
+	return strings.Join(yamlLines(yamlNode(reflect.ValueOf(v))), "\n") + "\n"
+}
+
+// yamlNode converts a value to nil, a string, a yamlPlain, a yamlMap or a list of nodes,
+// structs like time.Time that marshal to text are strings
+func yamlNode(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return yamlNode(v.Elem())
+	case reflect.Struct:
+		if text, ok := v.Interface().(encoding.TextMarshaler); ok {
+			b, err := text.MarshalText()
+			if err != nil {
+				return nil
+			}
+			return string(b)
+		}
+		m := yamlMap{}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			tag := strings.Split(field.Tag.Get("json"), ",")
+			name := tag[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if len(tag) > 1 && tag[1] == "omitempty" && isEmptyValue(v.Field(i)) {
+				continue
+			}
+			m = append(m, yamlField{key: name, value: yamlNode(v.Field(i))})
+		}
+		return m
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		m := yamlMap{}
+		for _, key := range keys {
+			m = append(m, yamlField{key: yamlScalar(fmt.Sprint(key.Interface())), value: yamlNode(v.MapIndex(key))})
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		list := []interface{}{}
+		for i := 0; i < v.Len(); i++ {
+			list = append(list, yamlNode(v.Index(i)))
+		}
+		return list
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return yamlPlain(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return yamlPlain(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return yamlPlain(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		return yamlPlain(strconv.FormatFloat(v.Float(), 'f', -1, 64))
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// yamlLines formats a node, the lines after the first line are indented
+// relative to the key or list item of the node
+func yamlLines(node interface{}) []string {
+	switch n := node.(type) {
+	case nil:
+		return []string{"null"}
+	case yamlPlain:
+		return []string{string(n)}
+	case yamlMap:
+		if len(n) == 0 {
+			return []string{"{}"}
+		}
+		var lines []string
+		for _, field := range n {
+			value := yamlLines(field.value)
+			switch v := field.value.(type) {
+			case yamlMap:
+				if len(v) > 0 {
+					lines = append(lines, field.key+":")
+					lines = append(lines, indentLines(value)...)
+					continue
+				}
+			case []interface{}:
+				if len(v) > 0 && !isFlowList(v) {
+					lines = append(lines, field.key+":")
+					lines = append(lines, value...)
+					continue
+				}
+			}
+			lines = append(lines, field.key+": "+value[0])
+			lines = append(lines, indentLines(value[1:])...)
+		}
+		return lines
+	case []interface{}:
+		if isFlowList(n) {
+			items := make([]string, len(n))
+			for i, item := range n {
+				items[i] = yamlLines(item)[0]
+			}
+			return []string{"[" + strings.Join(items, ", ") + "]"}
+		}
+		var lines []string
+		for _, item := range n {
+			value := yamlLines(item)
+			lines = append(lines, "- "+value[0])
+			lines = append(lines, indentLines(value[1:])...)
+		}
+		return lines
+	case string:
+		if strings.Contains(n, "\n") {
+			indicator := "|"
+			if !strings.HasSuffix(n, "\n") {
+				indicator = "|-"
+			}
+			return append([]string{indicator}, strings.Split(strings.TrimRight(n, "\n"), "\n")...)
+		}
+		return []string{yamlScalar(n)}
+	}
+	return []string{fmt.Sprint(node)}
+}
+
+// isFlowList checks if a list is written like [a, b], lists of words and numbers are
+func isFlowList(list []interface{}) bool {
+	for _, item := range list {
+		switch v := item.(type) {
+		case yamlPlain:
+		case string:
+			if strings.ContainsAny(v, " \t\n,[]{}") {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func indentLines(lines []string) []string {
+	indented := make([]string, len(lines))
+	for i, line := range lines {
+		if line != "" {
+			indented[i] = "  " + line
+		}
+	}
+	return indented
+}
+
+// yamlScalar quotes strings that are not plain YAML strings
+func yamlScalar(s string) string {
+	if s == "" || strings.TrimSpace(s) != s ||
+		strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") ||
+		strings.HasSuffix(s, ":") || strings.ContainsAny(s, "\t\n") {
+		return strconv.Quote(s)
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null", "~":
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// isEmptyValue reports empty values like the omitempty option of encoding/json
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package hcloud
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/hetznercloud/hcloud-go/hcloud"
+	"github.com/hetznercloud/hcloud-go/hcloud/schema"
+)
+
+// ListOpts alias of hcloud.ListOpts
+type ListOpts = hcloud.ListOpts
+
+// ActionListOpts specifies options for listing actions.
+// Unlike hcloud.ActionListOpts it supports filtering by action ID.
+type ActionListOpts struct {
+	ListOpts
+	ID []int
+}
+
+// actionClient extends hcloud.ActionClient with filtered listing
+type actionClient struct {
+	*hcloud.ActionClient
+	client *hcloud.Client
+}
+
+// List returns a list of actions for a specific page.
+func (c *actionClient) List(ctx context.Context, opts ActionListOpts) ([]*Action, *Response, error) {
+	values := valuesForListOpts(opts.ListOpts)
+	for _, id := range opts.ID {
+		values.Add("id", strconv.Itoa(id))
+	}
+	req, err := c.client.NewRequest(ctx, "GET", "/actions?"+values.Encode(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var body schema.ActionListResponse
+	resp, err := c.client.Do(req, &body)
+	if err != nil {
+		return nil, resp, err
+	}
+	actions := make([]*Action, 0, len(body.Actions))
+	for _, a := range body.Actions {
+		actions = append(actions, hcloud.ActionFromSchema(a))
+	}
+	return actions, resp, nil
+}
+
+func valuesForListOpts(opts ListOpts) url.Values {
+	values := url.Values{}
+	if opts.Page > 0 {
+		values.Add("page", strconv.Itoa(opts.Page))
+	}
+	if opts.PerPage > 0 {
+		values.Add("per_page", strconv.Itoa(opts.PerPage))
+	}
+	return values
+}
@@ -0,0 +1,302 @@
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/hcloud"
+	"github.com/hetznercloud/hcloud-go/hcloud/schema"
+)
+
+// Certificate represents a TLS certificate in the Hetzner Cloud,
+// hcloud-go 1.7 does not support certificates
+type Certificate struct {
+	ID             int
+	Name           string
+	Type           CertificateType
+	Certificate    string
+	DomainNames    []string
+	Fingerprint    string
+	NotValidBefore time.Time
+	NotValidAfter  time.Time
+	Status         *CertificateStatus
+	UsedBy         []CertificateUsedByRef
+	Labels         map[string]string
+	Created        time.Time
+}
+
+// CertificateType specifies if a certificate is uploaded or managed by Hetzner Cloud
+type CertificateType string
+
+// Certificate types
+const (
+	CertificateTypeUploaded CertificateType = "uploaded"
+	CertificateTypeManaged  CertificateType = "managed"
+)
+
+// CertificateStatusType is the issuance or renewal status of a managed certificate
+type CertificateStatusType string
+
+// Certificate status types
+const (
+	CertificateStatusTypePending     CertificateStatusType = "pending"
+	CertificateStatusTypeCompleted   CertificateStatusType = "completed"
+	CertificateStatusTypeFailed      CertificateStatusType = "failed"
+	CertificateStatusTypeScheduled   CertificateStatusType = "scheduled"
+	CertificateStatusTypeUnavailable CertificateStatusType = "unavailable"
+)
+
+// CertificateStatus is the status of a managed certificate, Error is set if the issuance or renewal failed
+type CertificateStatus struct {
+	Issuance CertificateStatusType
+	Renewal  CertificateStatusType
+	Error    *Error
+}
+
+// CertificateUsedByRef references a resource using the certificate
+type CertificateUsedByRef struct {
+	ID   int
+	Type string
+}
+
+// CertificateListOpts specifies options for listing certificates
+type CertificateListOpts struct {
+	ListOpts
+	Name string
+}
+
+// CertificateCreateOpts specifies parameters for creating a certificate,
+// Certificate and PrivateKey are required for uploaded and DomainNames for managed certificates
+type CertificateCreateOpts struct {
+	Name        string
+	Type        CertificateType
+	Certificate string
+	PrivateKey  string
+	DomainNames []string
+	Labels      map[string]string
+}
+
+// CertificateCreateResult is the result of creating a certificate,
+// Action is the issuance of a managed certificate
+type CertificateCreateResult struct {
+	Certificate *Certificate
+	Action      *Action
+}
+
+// CertificateUpdateOpts specifies parameters for updating a certificate
+type CertificateUpdateOpts struct {
+	Name   string
+	Labels map[string]string
+}
+
+// CertificateClient is a client for the certificates API
+type CertificateClient interface {
+	GetByID(ctx context.Context, id int) (*Certificate, *Response, error)
+	GetByName(ctx context.Context, name string) (*Certificate, *Response, error)
+	Get(ctx context.Context, idOrName string) (*Certificate, *Response, error)
+	List(ctx context.Context, opts CertificateListOpts) ([]*Certificate, *Response, error)
+	All(ctx context.Context) ([]*Certificate, error)
+	Create(ctx context.Context, opts CertificateCreateOpts) (CertificateCreateResult, *Response, error)
+	Update(ctx context.Context, certificate *Certificate, opts CertificateUpdateOpts) (*Certificate, *Response, error)
+	Delete(ctx context.Context, certificate *Certificate) (*Response, error)
+	// RetryIssuance retries the issuance or renewal of a failed managed certificate
+	RetryIssuance(ctx context.Context, certificate *Certificate) (*Action, *Response, error)
+}
+
+type certificateSchema struct {
+	ID             int       `json:"id"`
+	Name           string    `json:"name"`
+	Type           string    `json:"type"`
+	Certificate    string    `json:"certificate"`
+	DomainNames    []string  `json:"domain_names"`
+	Fingerprint    string    `json:"fingerprint"`
+	NotValidBefore time.Time `json:"not_valid_before"`
+	NotValidAfter  time.Time `json:"not_valid_after"`
+	Status         *struct {
+		Issuance string        `json:"issuance"`
+		Renewal  string        `json:"renewal"`
+		Error    *schema.Error `json:"error"`
+	} `json:"status"`
+	UsedBy []struct {
+		ID   int    `json:"id"`
+		Type string `json:"type"`
+	} `json:"used_by"`
+	Labels  map[string]string `json:"labels"`
+	Created time.Time         `json:"created"`
+}
+
+type certificateGetResponse struct {
+	Certificate certificateSchema `json:"certificate"`
+}
+
+type certificateListResponse struct {
+	Certificates []certificateSchema `json:"certificates"`
+}
+
+type certificateCreateRequest struct {
+	Name        string             `json:"name"`
+	Type        string             `json:"type"`
+	Certificate string             `json:"certificate,omitempty"`
+	PrivateKey  string             `json:"private_key,omitempty"`
+	DomainNames []string           `json:"domain_names,omitempty"`
+	Labels      *map[string]string `json:"labels,omitempty"`
+}
+
+type certificateCreateResponse struct {
+	Certificate certificateSchema `json:"certificate"`
+	Action      *schema.Action    `json:"action"`
+}
+
+type certificateUpdateRequest struct {
+	Name   string             `json:"name,omitempty"`
+	Labels *map[string]string `json:"labels,omitempty"`
+}
+
+func certificateFromSchema(s certificateSchema) *Certificate {
+	c := &Certificate{
+		ID:             s.ID,
+		Name:           s.Name,
+		Type:           CertificateType(s.Type),
+		Certificate:    s.Certificate,
+		DomainNames:    s.DomainNames,
+		Fingerprint:    s.Fingerprint,
+		NotValidBefore: s.NotValidBefore,
+		NotValidAfter:  s.NotValidAfter,
+		UsedBy:         []CertificateUsedByRef{},
+		Labels:         s.Labels,
+		Created:        s.Created,
+	}
+	if c.DomainNames == nil {
+		c.DomainNames = []string{}
+	}
+	if s.Status != nil {
+		c.Status = &CertificateStatus{
+			Issuance: CertificateStatusType(s.Status.Issuance),
+			Renewal:  CertificateStatusType(s.Status.Renewal),
+		}
+		if s.Status.Error != nil {
+			err := hcloud.ErrorFromSchema(*s.Status.Error)
+			c.Status.Error = &err
+		}
+	}
+	for _, ref := range s.UsedBy {
+		c.UsedBy = append(c.UsedBy, CertificateUsedByRef{ID: ref.ID, Type: ref.Type})
+	}
+	return c
+}
+
+type certificateClient struct {
+	client *hcloud.Client
+}
+
+func (c *certificateClient) GetByID(ctx context.Context, id int) (*Certificate, *Response, error) {
+	var body certificateGetResponse
+	resp, err := do(ctx, c.client, "GET", fmt.Sprintf("/certificates/%d", id), nil, &body)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, resp, nil
+		}
+		return nil, resp, err
+	}
+	return certificateFromSchema(body.Certificate), resp, nil
+}
+
+func (c *certificateClient) GetByName(ctx context.Context, name string) (*Certificate, *Response, error) {
+	certificates, resp, err := c.List(ctx, CertificateListOpts{Name: name})
+	if len(certificates) == 0 {
+		return nil, resp, err
+	}
+	return certificates[0], resp, err
+}
+
+func (c *certificateClient) Get(ctx context.Context, idOrName string) (*Certificate, *Response, error) {
+	if id, err := strconv.Atoi(idOrName); err == nil {
+		return c.GetByID(ctx, id)
+	}
+	return c.GetByName(ctx, idOrName)
+}
+
+func (c *certificateClient) List(ctx context.Context, opts CertificateListOpts) ([]*Certificate, *Response, error) {
+	values := valuesForListOpts(opts.ListOpts)
+	if opts.Name != "" {
+		values.Set("name", opts.Name)
+	}
+	var body certificateListResponse
+	resp, err := do(ctx, c.client, "GET", "/certificates?"+values.Encode(), nil, &body)
+	if err != nil {
+		return nil, resp, err
+	}
+	certificates := make([]*Certificate, 0, len(body.Certificates))
+	for _, s := range body.Certificates {
+		certificates = append(certificates, certificateFromSchema(s))
+	}
+	return certificates, resp, nil
+}
+
+func (c *certificateClient) All(ctx context.Context) ([]*Certificate, error) {
+	allCertificates := []*Certificate{}
+	opts := CertificateListOpts{ListOpts: ListOpts{PerPage: 50}}
+	err := all(func(page int) (*Response, error) {
+		opts.Page = page
+		certificates, resp, err := c.List(ctx, opts)
+		if err != nil {
+			return resp, err
+		}
+		allCertificates = append(allCertificates, certificates...)
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allCertificates, nil
+}
+
+func (c *certificateClient) Create(ctx context.Context, opts CertificateCreateOpts) (CertificateCreateResult, *Response, error) {
+	reqBody := certificateCreateRequest{
+		Name:        opts.Name,
+		Type:        string(opts.Type),
+		Certificate: opts.Certificate,
+		PrivateKey:  opts.PrivateKey,
+		DomainNames: opts.DomainNames,
+	}
+	if reqBody.Type == "" {
+		reqBody.Type = string(CertificateTypeUploaded)
+	}
+	if opts.Labels != nil {
+		reqBody.Labels = &opts.Labels
+	}
+
+	var respBody certificateCreateResponse
+	resp, err := do(ctx, c.client, "POST", "/certificates", reqBody, &respBody)
+	if err != nil {
+		return CertificateCreateResult{}, resp, err
+	}
+	result := CertificateCreateResult{Certificate: certificateFromSchema(respBody.Certificate)}
+	if respBody.Action != nil {
+		result.Action = hcloud.ActionFromSchema(*respBody.Action)
+	}
+	return result, resp, nil
+}
+
+func (c *certificateClient) Update(ctx context.Context, certificate *Certificate, opts CertificateUpdateOpts) (*Certificate, *Response, error) {
+	reqBody := certificateUpdateRequest{Name: opts.Name}
+	if opts.Labels != nil {
+		reqBody.Labels = &opts.Labels
+	}
+	var respBody certificateGetResponse
+	resp, err := do(ctx, c.client, "PUT", fmt.Sprintf("/certificates/%d", certificate.ID), reqBody, &respBody)
+	if err != nil {
+		return nil, resp, err
+	}
+	return certificateFromSchema(respBody.Certificate), resp, nil
+}
+
+func (c *certificateClient) Delete(ctx context.Context, certificate *Certificate) (*Response, error) {
+	return do(ctx, c.client, "DELETE", fmt.Sprintf("/certificates/%d", certificate.ID), nil, nil)
+}
+
+func (c *certificateClient) RetryIssuance(ctx context.Context, certificate *Certificate) (*Action, *Response, error) {
+	return postAction(ctx, c.client, fmt.Sprintf("/certificates/%d/actions/retry", certificate.ID), nil)
+}
@@ -0,0 +1,66 @@
+package hcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCertificateClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "GET /certificates/1":
+			fmt.Fprint(w, `{"certificate": {
+				"id": 1, "name": "web", "type": "managed", "certificate": null,
+				"domain_names": ["example.com", "www.example.com"],
+				"fingerprint": null, "not_valid_before": null, "not_valid_after": null,
+				"status": {"issuance": "failed", "renewal": "unavailable", "error": {"code": "dns_zone_not_found", "message": "DNS zone not found"}},
+				"used_by": [{"id": 4, "type": "load_balancer"}], "labels": {}
+			}}`)
+		case "POST /certificates":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, map[string]interface{}{
+				"name":         "web",
+				"type":         "managed",
+				"domain_names": []interface{}{"example.com"},
+			}, body)
+			fmt.Fprint(w, `{
+				"certificate": {"id": 2, "name": "web", "type": "managed", "domain_names": ["example.com"], "status": {"issuance": "pending", "renewal": "unavailable"}},
+				"action": {"id": 3, "command": "create_certificate", "status": "running"}
+			}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL))
+	ctx := context.Background()
+
+	certificate, _, err := client.Certificate.Get(ctx, "1")
+	if assert.NoError(t, err) {
+		assert.Equal(t, CertificateTypeManaged, certificate.Type)
+		assert.Equal(t, []string{"example.com", "www.example.com"}, certificate.DomainNames)
+		assert.Equal(t, CertificateStatusTypeFailed, certificate.Status.Issuance)
+		assert.Equal(t, "DNS zone not found", certificate.Status.Error.Message)
+		assert.Equal(t, []CertificateUsedByRef{{ID: 4, Type: "load_balancer"}}, certificate.UsedBy)
+		assert.True(t, certificate.NotValidAfter.IsZero())
+	}
+
+	result, _, err := client.Certificate.Create(ctx, CertificateCreateOpts{
+		Name:        "web",
+		Type:        CertificateTypeManaged,
+		DomainNames: []string{"example.com"},
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, result.Certificate.ID)
+		assert.Equal(t, 3, result.Action.ID)
+	}
+}
@@ -0,0 +1,429 @@
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/hcloud"
+	"github.com/hetznercloud/hcloud-go/hcloud/schema"
+)
+
+// Firewall represents a firewall in the Hetzner Cloud,
+// hcloud-go 1.7 does not support firewalls
+type Firewall struct {
+	ID        int
+	Name      string
+	Rules     []FirewallRule
+	AppliedTo []FirewallResource
+	Labels    map[string]string
+	Created   time.Time
+}
+
+// FirewallRuleDirection specifies the direction of a firewall rule
+type FirewallRuleDirection string
+
+// Firewall rule directions
+const (
+	FirewallRuleDirectionIn  FirewallRuleDirection = "in"
+	FirewallRuleDirectionOut FirewallRuleDirection = "out"
+)
+
+// FirewallRuleProtocol specifies the protocol of a firewall rule
+type FirewallRuleProtocol string
+
+// Firewall rule protocols
+const (
+	FirewallRuleProtocolTCP  FirewallRuleProtocol = "tcp"
+	FirewallRuleProtocolUDP  FirewallRuleProtocol = "udp"
+	FirewallRuleProtocolICMP FirewallRuleProtocol = "icmp"
+	FirewallRuleProtocolESP  FirewallRuleProtocol = "esp"
+	FirewallRuleProtocolGRE  FirewallRuleProtocol = "gre"
+)
+
+// FirewallRule represents a rule of a firewall
+type FirewallRule struct {
+	Direction      FirewallRuleDirection
+	Protocol       FirewallRuleProtocol
+	Port           string
+	SourceIPs      []net.IPNet
+	DestinationIPs []net.IPNet
+	Description    string
+}
+
+// FirewallResourceType specifies the type of a resource a firewall is applied to
+type FirewallResourceType string
+
+// Firewall resource types
+const (
+	FirewallResourceTypeServer        FirewallResourceType = "server"
+	FirewallResourceTypeLabelSelector FirewallResourceType = "label_selector"
+)
+
+// FirewallResource is a resource a firewall is applied to.
+// Servers matched by a label selector are listed in Servers.
+type FirewallResource struct {
+	Type          FirewallResourceType
+	Server        *Server
+	LabelSelector string
+	Servers       []*Server
+}
+
+// FirewallListOpts specifies options for listing firewalls
+type FirewallListOpts struct {
+	ListOpts
+	Name string
+}
+
+// FirewallCreateOpts specifies parameters for creating a firewall
+type FirewallCreateOpts struct {
+	Name    string
+	Rules   []FirewallRule
+	ApplyTo []FirewallResource
+	Labels  map[string]string
+}
+
+// FirewallCreateResult is the result of creating a firewall
+type FirewallCreateResult struct {
+	Firewall *Firewall
+	Actions  []*Action
+}
+
+// FirewallUpdateOpts specifies parameters for updating a firewall
+type FirewallUpdateOpts struct {
+	Name   string
+	Labels map[string]string
+}
+
+// FirewallClient is a client for the firewalls API
+type FirewallClient interface {
+	GetByID(ctx context.Context, id int) (*Firewall, *Response, error)
+	GetByName(ctx context.Context, name string) (*Firewall, *Response, error)
+	Get(ctx context.Context, idOrName string) (*Firewall, *Response, error)
+	List(ctx context.Context, opts FirewallListOpts) ([]*Firewall, *Response, error)
+	All(ctx context.Context) ([]*Firewall, error)
+	Create(ctx context.Context, opts FirewallCreateOpts) (FirewallCreateResult, *Response, error)
+	Update(ctx context.Context, firewall *Firewall, opts FirewallUpdateOpts) (*Firewall, *Response, error)
+	Delete(ctx context.Context, firewall *Firewall) (*Response, error)
+	SetRules(ctx context.Context, firewall *Firewall, rules []FirewallRule) ([]*Action, *Response, error)
+	ApplyResources(ctx context.Context, firewall *Firewall, resources []FirewallResource) ([]*Action, *Response, error)
+	RemoveResources(ctx context.Context, firewall *Firewall, resources []FirewallResource) ([]*Action, *Response, error)
+}
+
+type firewallSchema struct {
+	ID        int                      `json:"id"`
+	Name      string                   `json:"name"`
+	Rules     []firewallRuleSchema     `json:"rules"`
+	AppliedTo []firewallResourceSchema `json:"applied_to"`
+	Labels    map[string]string        `json:"labels"`
+	Created   time.Time                `json:"created"`
+}
+
+type firewallRuleSchema struct {
+	Direction      string   `json:"direction"`
+	Protocol       string   `json:"protocol"`
+	Port           *string  `json:"port,omitempty"`
+	SourceIPs      []string `json:"source_ips,omitempty"`
+	DestinationIPs []string `json:"destination_ips,omitempty"`
+	Description    *string  `json:"description,omitempty"`
+}
+
+type firewallResourceSchema struct {
+	Type   string `json:"type"`
+	Server *struct {
+		ID int `json:"id"`
+	} `json:"server,omitempty"`
+	LabelSelector *struct {
+		Selector string `json:"selector"`
+	} `json:"label_selector,omitempty"`
+	AppliedToResources []firewallResourceSchema `json:"applied_to_resources,omitempty"`
+}
+
+type firewallGetResponse struct {
+	Firewall firewallSchema `json:"firewall"`
+}
+
+type firewallListResponse struct {
+	Firewalls []firewallSchema `json:"firewalls"`
+}
+
+type firewallCreateRequest struct {
+	Name    string                   `json:"name"`
+	Rules   []firewallRuleSchema     `json:"rules,omitempty"`
+	ApplyTo []firewallResourceSchema `json:"apply_to,omitempty"`
+	Labels  *map[string]string       `json:"labels,omitempty"`
+}
+
+type firewallCreateResponse struct {
+	Firewall firewallSchema  `json:"firewall"`
+	Actions  []schema.Action `json:"actions"`
+}
+
+type firewallUpdateRequest struct {
+	Name   string             `json:"name,omitempty"`
+	Labels *map[string]string `json:"labels,omitempty"`
+}
+
+type firewallSetRulesRequest struct {
+	Rules []firewallRuleSchema `json:"rules"`
+}
+
+type firewallApplyToResourcesRequest struct {
+	ApplyTo []firewallResourceSchema `json:"apply_to"`
+}
+
+type firewallRemoveFromResourcesRequest struct {
+	RemoveFrom []firewallResourceSchema `json:"remove_from"`
+}
+
+func firewallFromSchema(s firewallSchema) *Firewall {
+	f := &Firewall{
+		ID:        s.ID,
+		Name:      s.Name,
+		Rules:     []FirewallRule{},
+		AppliedTo: []FirewallResource{},
+		Labels:    s.Labels,
+		Created:   s.Created,
+	}
+	for _, rule := range s.Rules {
+		r := FirewallRule{
+			Direction:      FirewallRuleDirection(rule.Direction),
+			Protocol:       FirewallRuleProtocol(rule.Protocol),
+			SourceIPs:      parseIPNets(rule.SourceIPs),
+			DestinationIPs: parseIPNets(rule.DestinationIPs),
+		}
+		if rule.Port != nil {
+			r.Port = *rule.Port
+		}
+		if rule.Description != nil {
+			r.Description = *rule.Description
+		}
+		f.Rules = append(f.Rules, r)
+	}
+	for _, resource := range s.AppliedTo {
+		f.AppliedTo = append(f.AppliedTo, firewallResourceFromSchema(resource))
+	}
+	return f
+}
+
+func firewallResourceFromSchema(s firewallResourceSchema) FirewallResource {
+	r := FirewallResource{Type: FirewallResourceType(s.Type)}
+	if s.Server != nil {
+		r.Server = &Server{ID: s.Server.ID}
+	}
+	if s.LabelSelector != nil {
+		r.LabelSelector = s.LabelSelector.Selector
+	}
+	for _, applied := range s.AppliedToResources {
+		if applied.Server != nil {
+			r.Servers = append(r.Servers, &Server{ID: applied.Server.ID})
+		}
+	}
+	return r
+}
+
+func firewallRuleToSchema(rule FirewallRule) firewallRuleSchema {
+	s := firewallRuleSchema{
+		Direction:      string(rule.Direction),
+		Protocol:       string(rule.Protocol),
+		SourceIPs:      ipNetStrings(rule.SourceIPs),
+		DestinationIPs: ipNetStrings(rule.DestinationIPs),
+	}
+	if rule.Port != "" {
+		s.Port = hcloud.String(rule.Port)
+	}
+	if rule.Description != "" {
+		s.Description = hcloud.String(rule.Description)
+	}
+	return s
+}
+
+func firewallRulesToSchema(rules []FirewallRule) []firewallRuleSchema {
+	s := []firewallRuleSchema{}
+	for _, rule := range rules {
+		s = append(s, firewallRuleToSchema(rule))
+	}
+	return s
+}
+
+func firewallResourcesToSchema(resources []FirewallResource) []firewallResourceSchema {
+	s := []firewallResourceSchema{}
+	for _, resource := range resources {
+		r := firewallResourceSchema{Type: string(resource.Type)}
+		if resource.Server != nil {
+			r.Server = &struct {
+				ID int `json:"id"`
+			}{ID: resource.Server.ID}
+		}
+		if resource.Type == FirewallResourceTypeLabelSelector {
+			r.LabelSelector = &struct {
+				Selector string `json:"selector"`
+			}{Selector: resource.LabelSelector}
+		}
+		s = append(s, r)
+	}
+	return s
+}
+
+func parseIPNets(s []string) []net.IPNet {
+	ipNets := []net.IPNet{}
+	for _, cidr := range s {
+		if ipNet := parseCIDR(cidr); ipNet != nil {
+			ipNets = append(ipNets, *ipNet)
+		}
+	}
+	return ipNets
+}
+
+func ipNetStrings(ipNets []net.IPNet) []string {
+	if len(ipNets) == 0 {
+		return nil
+	}
+	s := []string{}
+	for _, ipNet := range ipNets {
+		s = append(s, ipNet.String())
+	}
+	return s
+}
+
+type firewallActionsResponse struct {
+	Actions []schema.Action `json:"actions"`
+}
+
+type firewallClient struct {
+	client *hcloud.Client
+}
+
+func (c *firewallClient) GetByID(ctx context.Context, id int) (*Firewall, *Response, error) {
+	var body firewallGetResponse
+	resp, err := do(ctx, c.client, "GET", fmt.Sprintf("/firewalls/%d", id), nil, &body)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, resp, nil
+		}
+		return nil, resp, err
+	}
+	return firewallFromSchema(body.Firewall), resp, nil
+}
+
+func (c *firewallClient) GetByName(ctx context.Context, name string) (*Firewall, *Response, error) {
+	firewalls, resp, err := c.List(ctx, FirewallListOpts{Name: name})
+	if len(firewalls) == 0 {
+		return nil, resp, err
+	}
+	return firewalls[0], resp, err
+}
+
+func (c *firewallClient) Get(ctx context.Context, idOrName string) (*Firewall, *Response, error) {
+	if id, err := strconv.Atoi(idOrName); err == nil {
+		return c.GetByID(ctx, id)
+	}
+	return c.GetByName(ctx, idOrName)
+}
+
+func (c *firewallClient) List(ctx context.Context, opts FirewallListOpts) ([]*Firewall, *Response, error) {
+	values := valuesForListOpts(opts.ListOpts)
+	if opts.Name != "" {
+		values.Set("name", opts.Name)
+	}
+	var body firewallListResponse
+	resp, err := do(ctx, c.client, "GET", "/firewalls?"+values.Encode(), nil, &body)
+	if err != nil {
+		return nil, resp, err
+	}
+	firewalls := make([]*Firewall, 0, len(body.Firewalls))
+	for _, f := range body.Firewalls {
+		firewalls = append(firewalls, firewallFromSchema(f))
+	}
+	return firewalls, resp, nil
+}
+
+func (c *firewallClient) All(ctx context.Context) ([]*Firewall, error) {
+	allFirewalls := []*Firewall{}
+	opts := FirewallListOpts{ListOpts: ListOpts{PerPage: 50}}
+	err := all(func(page int) (*Response, error) {
+		opts.Page = page
+		firewalls, resp, err := c.List(ctx, opts)
+		if err != nil {
+			return resp, err
+		}
+		allFirewalls = append(allFirewalls, firewalls...)
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allFirewalls, nil
+}
+
+func (c *firewallClient) Create(ctx context.Context, opts FirewallCreateOpts) (FirewallCreateResult, *Response, error) {
+	reqBody := firewallCreateRequest{Name: opts.Name}
+	if len(opts.Rules) > 0 {
+		reqBody.Rules = firewallRulesToSchema(opts.Rules)
+	}
+	if len(opts.ApplyTo) > 0 {
+		reqBody.ApplyTo = firewallResourcesToSchema(opts.ApplyTo)
+	}
+	if opts.Labels != nil {
+		reqBody.Labels = &opts.Labels
+	}
+
+	var respBody firewallCreateResponse
+	resp, err := do(ctx, c.client, "POST", "/firewalls", reqBody, &respBody)
+	if err != nil {
+		return FirewallCreateResult{}, resp, err
+	}
+	result := FirewallCreateResult{Firewall: firewallFromSchema(respBody.Firewall)}
+	for _, a := range respBody.Actions {
+		result.Actions = append(result.Actions, hcloud.ActionFromSchema(a))
+	}
+	return result, resp, nil
+}
+
+func (c *firewallClient) Update(ctx context.Context, firewall *Firewall, opts FirewallUpdateOpts) (*Firewall, *Response, error) {
+	reqBody := firewallUpdateRequest{Name: opts.Name}
+	if opts.Labels != nil {
+		reqBody.Labels = &opts.Labels
+	}
+	var respBody firewallGetResponse
+	resp, err := do(ctx, c.client, "PUT", fmt.Sprintf("/firewalls/%d", firewall.ID), reqBody, &respBody)
+	if err != nil {
+		return nil, resp, err
+	}
+	return firewallFromSchema(respBody.Firewall), resp, nil
+}
+
+func (c *firewallClient) Delete(ctx context.Context, firewall *Firewall) (*Response, error) {
+	return do(ctx, c.client, "DELETE", fmt.Sprintf("/firewalls/%d", firewall.ID), nil, nil)
+}
+
+func (c *firewallClient) SetRules(ctx context.Context, firewall *Firewall, rules []FirewallRule) ([]*Action, *Response, error) {
+	return c.actions(ctx, firewall, "set_rules", firewallSetRulesRequest{Rules: firewallRulesToSchema(rules)})
+}
+
+func (c *firewallClient) ApplyResources(ctx context.Context, firewall *Firewall, resources []FirewallResource) ([]*Action, *Response, error) {
+	return c.actions(ctx, firewall, "apply_to_resources", firewallApplyToResourcesRequest{
+		ApplyTo: firewallResourcesToSchema(resources),
+	})
+}
+
+func (c *firewallClient) RemoveResources(ctx context.Context, firewall *Firewall, resources []FirewallResource) ([]*Action, *Response, error) {
+	return c.actions(ctx, firewall, "remove_from_resources", firewallRemoveFromResourcesRequest{
+		RemoveFrom: firewallResourcesToSchema(resources),
+	})
+}
+
+func (c *firewallClient) actions(ctx context.Context, firewall *Firewall, action string, reqBody interface{}) ([]*Action, *Response, error) {
+	var respBody firewallActionsResponse
+	resp, err := do(ctx, c.client, "POST", fmt.Sprintf("/firewalls/%d/actions/%s", firewall.ID, action), reqBody, &respBody)
+	if err != nil {
+		return nil, resp, err
+	}
+	actions := []*Action{}
+	for _, a := range respBody.Actions {
+		actions = append(actions, hcloud.ActionFromSchema(a))
+	}
+	return actions, resp, nil
+}
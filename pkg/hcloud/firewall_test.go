@@ -0,0 +1,86 @@
+package hcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFirewallClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "GET /firewalls/1":
+			fmt.Fprint(w, `{"firewall": {
+				"id": 1,
+				"name": "web",
+				"rules": [
+					{"direction": "in", "protocol": "tcp", "port": "443", "source_ips": ["0.0.0.0/0", "::/0"], "destination_ips": [], "description": null},
+					{"direction": "out", "protocol": "icmp", "port": null, "source_ips": [], "destination_ips": ["10.0.0.0/8"], "description": "ping"}
+				],
+				"applied_to": [
+					{"type": "server", "server": {"id": 42}},
+					{"type": "label_selector", "label_selector": {"selector": "role=web"}, "applied_to_resources": [{"type": "server", "server": {"id": 43}}]}
+				]
+			}}`)
+		case "POST /firewalls/1/actions/set_rules":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, map[string]interface{}{
+				"rules": []interface{}{
+					map[string]interface{}{
+						"direction":  "in",
+						"protocol":   "tcp",
+						"port":       "22",
+						"source_ips": []interface{}{"10.0.0.0/8"},
+					},
+				},
+			}, body)
+			fmt.Fprint(w, `{"actions": [{"id": 1, "command": "set_firewall_rules", "status": "running"}]}`)
+		case "POST /firewalls/1/actions/remove_from_resources":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, map[string]interface{}{
+				"remove_from": []interface{}{
+					map[string]interface{}{"type": "label_selector", "label_selector": map[string]interface{}{"selector": "role=web"}},
+				},
+			}, body)
+			fmt.Fprint(w, `{"actions": [{"id": 2, "command": "remove_firewall", "status": "running"}]}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL))
+	ctx := context.Background()
+
+	firewall, _, err := client.Firewall.GetByID(ctx, 1)
+	if assert.NoError(t, err) {
+		assert.Len(t, firewall.Rules, 2)
+		assert.Equal(t, "443", firewall.Rules[0].Port)
+		assert.Equal(t, "::/0", firewall.Rules[0].SourceIPs[1].String())
+		assert.Equal(t, "ping", firewall.Rules[1].Description)
+		assert.Equal(t, "10.0.0.0/8", firewall.Rules[1].DestinationIPs[0].String())
+		assert.Equal(t, 42, firewall.AppliedTo[0].Server.ID)
+		assert.Equal(t, "role=web", firewall.AppliedTo[1].LabelSelector)
+		assert.Equal(t, 43, firewall.AppliedTo[1].Servers[0].ID)
+	}
+
+	actions, _, err := client.Firewall.SetRules(ctx, firewall, []FirewallRule{
+		{Direction: FirewallRuleDirectionIn, Protocol: FirewallRuleProtocolTCP, Port: "22", SourceIPs: firewall.Rules[1].DestinationIPs},
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, actions[0].ID)
+	}
+
+	actions, _, err = client.Firewall.RemoveResources(ctx, firewall, firewall.AppliedTo[1:])
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, actions[0].ID)
+	}
+}
@@ -36,41 +36,64 @@ func WithToken(token string) ClientOption {
 }
 
 // WithEndpoint alias of hcloud.WithEndpoint
-func WithEndpoint(token string) ClientOption {
-	return hcloud.WithToken(token)
+func WithEndpoint(endpoint string) ClientOption {
+	return hcloud.WithEndpoint(endpoint)
 }
 
 // Response alias of hcloud.Response
 type Response = hcloud.Response
 
+// Error alias of hcloud.Error
+type Error = hcloud.Error
+
 // Client is an alias using interfaces of hcloud.Client
 type Client struct {
 	*hcloud.Client
-	Action     ActionClient
-	Datacenter DatacenterClient
-	FloatingIP FloatingIPClient
-	Image      ImageClient
-	ISO        ISOClient
-	Location   LocationClient
-	Pricing    PricingClient
-	Server     ServerClient
-	ServerType ServerTypeClient
-	SSHKey     SSHKeyClient
+	Action         ActionClient
+	Certificate    CertificateClient
+	Datacenter     DatacenterClient
+	Firewall       FirewallClient
+	FloatingIP     FloatingIPClient
+	Image          ImageClient
+	ISO            ISOClient
+	Label          LabelClient
+	LoadBalancer   LoadBalancerClient
+	Location       LocationClient
+	Network        NetworkClient
+	PlacementGroup PlacementGroupClient
+	PrimaryIP      PrimaryIPClient
+	Pricing        PricingClient
+	Server         ServerClient
+	ServerNetwork  ServerNetworkClient
+	ServerType     ServerTypeClient
+	SSHKey         SSHKeyClient
+	Volume         VolumeClient
 }
 
 // NewClient is creates a new wrapped client
 func NewClient(options ...hcloud.ClientOption) *Client {
 	c := hcloud.NewClient(options...)
 	return &Client{
-		Client:     c,
-		Action:     &c.Action,
-		Server:     &c.Server,
-		SSHKey:     &c.SSHKey,
-		Image:      &c.Image,
-		FloatingIP: &c.FloatingIP,
-		Location:   &c.Location,
-		Datacenter: &c.Datacenter,
-		ISO:        &c.ISO,
+		Client:         c,
+		Action:         &actionClient{ActionClient: &c.Action, client: c},
+		Server:         &serverClient{ServerClient: &c.Server, client: c},
+		SSHKey:         &c.SSHKey,
+		Image:          &c.Image,
+		FloatingIP:     &c.FloatingIP,
+		Location:       &c.Location,
+		Datacenter:     &c.Datacenter,
+		ISO:            &c.ISO,
+		ServerType:     &c.ServerType,
+		Pricing:        &c.Pricing,
+		Label:          &labelClient{client: c},
+		Volume:         &volumeClient{client: c},
+		Network:        &networkClient{client: c},
+		ServerNetwork:  &serverNetworkClient{client: c},
+		Firewall:       &firewallClient{client: c},
+		LoadBalancer:   &loadBalancerClient{client: c},
+		PlacementGroup: &placementGroupClient{client: c},
+		PrimaryIP:      &primaryIPClient{client: c},
+		Certificate:    &certificateClient{client: c},
 	}
 }
 
@@ -84,6 +107,11 @@ func Bool(b bool) *bool {
 	return hcloud.Bool(b)
 }
 
+// Int alias of hcloud.Int
+func Int(i int) *int {
+	return hcloud.Int(i)
+}
+
 // Action alias of hcloud.Action
 type Action = hcloud.Action
 
@@ -97,10 +125,27 @@ const (
 	ActionStatusRunning = hcloud.ActionStatusRunning
 )
 
+// ActionResource alias of hcloud.ActionResource
+type ActionResource = hcloud.ActionResource
+
+// ActionResourceType alias of hcloud.ActionResourceType
+type ActionResourceType = hcloud.ActionResourceType
+
+// Action resource types
+const (
+	ActionResourceTypeServer       = hcloud.ActionResourceTypeServer
+	ActionResourceTypeFloatingIP   = hcloud.ActionResourceTypeFloatingIP
+	ActionResourceTypeNetwork      = ActionResourceType("network")
+	ActionResourceTypeVolume       = ActionResourceType("volume")
+	ActionResourceTypeLoadBalancer = ActionResourceType("load_balancer")
+	ActionResourceTypeFirewall     = ActionResourceType("firewall")
+	ActionResourceTypePrimaryIP    = ActionResourceType("primary_ip")
+)
+
 // ActionClient interface of hcloud.ActionClient
 type ActionClient interface {
 	GetByID(ctx context.Context, id int) (*hcloud.Action, *hcloud.Response, error)
-	List(ctx context.Context, opts hcloud.ActionListOpts) ([]*hcloud.Action, *hcloud.Response, error)
+	List(ctx context.Context, opts ActionListOpts) ([]*hcloud.Action, *hcloud.Response, error)
 	All(ctx context.Context) ([]*hcloud.Action, error)
 	WatchProgress(ctx context.Context, action *hcloud.Action) (<-chan int, <-chan error)
 }
@@ -108,6 +153,9 @@ type ActionClient interface {
 // Datacenter alias of hcloud.Datacenter
 type Datacenter = hcloud.Datacenter
 
+// DatacenterListOpts alias of hcloud.DatacenterListOpts
+type DatacenterListOpts = hcloud.DatacenterListOpts
+
 // DatacenterClient interface of hcloud.DatacenterClient
 type DatacenterClient interface {
 	GetByID(ctx context.Context, id int) (*hcloud.Datacenter, *hcloud.Response, error)
@@ -159,6 +207,16 @@ type Image = hcloud.Image
 // ImageListOpts alias of hcloud.ImageListOpts
 type ImageListOpts = hcloud.ImageListOpts
 
+// ImageType alias of hcloud.ImageType
+type ImageType = hcloud.ImageType
+
+// Image types.
+const (
+	ImageTypeSnapshot = hcloud.ImageTypeSnapshot
+	ImageTypeBackup   = hcloud.ImageTypeBackup
+	ImageTypeSystem   = hcloud.ImageTypeSystem
+)
+
 // ImageClient interface of hcloud.ImageClient
 type ImageClient interface {
 	GetByID(ctx context.Context, id int) (*hcloud.Image, *hcloud.Response, error)
@@ -171,23 +229,55 @@ type ImageClient interface {
 // ISO alias of hcloud.ISO
 type ISO = hcloud.ISO
 
+// ISOListOpts alias of hcloud.ISOListOpts
+type ISOListOpts = hcloud.ISOListOpts
+
 // ISOClient interface of hcloud.ISOClient
 type ISOClient interface {
 	GetByID(ctx context.Context, id int) (*hcloud.ISO, *hcloud.Response, error)
 	GetByName(ctx context.Context, name string) (*hcloud.ISO, *hcloud.Response, error)
 	Get(ctx context.Context, idOrName string) (*hcloud.ISO, *hcloud.Response, error)
+	List(ctx context.Context, opts hcloud.ISOListOpts) ([]*hcloud.ISO, *hcloud.Response, error)
+	All(ctx context.Context) ([]*hcloud.ISO, error)
 }
 
 // Location alias of hcloud.Location
 type Location = hcloud.Location
 
+// LocationListOpts alias of hcloud.LocationListOpts
+type LocationListOpts = hcloud.LocationListOpts
+
 // LocationClient interface of hcloud.LocationClient
 type LocationClient interface {
+	GetByID(ctx context.Context, id int) (*hcloud.Location, *hcloud.Response, error)
+	GetByName(ctx context.Context, name string) (*hcloud.Location, *hcloud.Response, error)
 	Get(ctx context.Context, idOrName string) (*hcloud.Location, *hcloud.Response, error)
+	List(ctx context.Context, opts hcloud.LocationListOpts) ([]*hcloud.Location, *hcloud.Response, error)
+	All(ctx context.Context) ([]*hcloud.Location, error)
 }
 
+// Pricing alias of hcloud.Pricing
+type Pricing = hcloud.Pricing
+
+// ImagePricing alias of hcloud.ImagePricing
+type ImagePricing = hcloud.ImagePricing
+
+// FloatingIPPricing alias of hcloud.FloatingIPPricing
+type FloatingIPPricing = hcloud.FloatingIPPricing
+
+// TrafficPricing alias of hcloud.TrafficPricing
+type TrafficPricing = hcloud.TrafficPricing
+
+// ServerBackupPricing alias of hcloud.ServerBackupPricing
+type ServerBackupPricing = hcloud.ServerBackupPricing
+
+// ServerTypePricing alias of hcloud.ServerTypePricing
+type ServerTypePricing = hcloud.ServerTypePricing
+
 // PricingClient interface of hcloud.PricingClient
-type PricingClient interface{}
+type PricingClient interface {
+	Get(ctx context.Context) (Pricing, *Response, error)
+}
 
 // Server alias of hcloud.Server
 type Server = hcloud.Server
@@ -210,12 +300,16 @@ type ServerEnableRescueResult = hcloud.ServerEnableRescueResult
 // ServerEnableRescueOpts alias of hcloud.ServerEnableRescueOpts
 type ServerEnableRescueOpts = hcloud.ServerEnableRescueOpts
 
-// ServerCreateOpts alias of hcloud.ServerCreateOpts
-type ServerCreateOpts = hcloud.ServerCreateOpts
-
 // ServerRescueType alias of hcloud.ServerRescueType
 type ServerRescueType = hcloud.ServerRescueType
 
+// Server rescue types.
+const (
+	ServerRescueTypeLinux32   = hcloud.ServerRescueTypeLinux32
+	ServerRescueTypeLinux64   = hcloud.ServerRescueTypeLinux64
+	ServerRescueTypeFreeBSD64 = hcloud.ServerRescueTypeFreeBSD64
+)
+
 // ServerPublicNet alias of hcloud.ServerPublicNet
 type ServerPublicNet = hcloud.ServerPublicNet
 
@@ -256,6 +350,8 @@ type ServerClient interface {
 	DisableBackup(ctx context.Context, server *Server) (*Action, *Response, error)
 	// ChangeType(ctx context.Context, server *Server, opts ServerChangeTypeOpts) (*Action, *Response, error)
 	// ChangeDNSPtr(ctx context.Context, server *Server, ip string, ptr *string) (*Action, *Response, error)
+	AddToPlacementGroup(ctx context.Context, server *Server, placementGroup *PlacementGroup) (*Action, *Response, error)
+	RemoveFromPlacementGroup(ctx context.Context, server *Server) (*Action, *Response, error)
 }
 
 // ServerType alias of hcloud.ServerType
@@ -264,9 +360,19 @@ type ServerType = hcloud.ServerType
 // ServerTypeListOpts alias of hcloud.ServerTypeListOpts
 type ServerTypeListOpts = hcloud.ServerTypeListOpts
 
+// ServerTypeLocationPricing alias of hcloud.ServerTypeLocationPricing
+type ServerTypeLocationPricing = hcloud.ServerTypeLocationPricing
+
+// Price alias of hcloud.Price
+type Price = hcloud.Price
+
 // ServerTypeClient interface of hcloud.ServerTypeClient
 type ServerTypeClient interface {
+	GetByID(ctx context.Context, id int) (*ServerType, *Response, error)
+	GetByName(ctx context.Context, name string) (*ServerType, *Response, error)
 	Get(ctx context.Context, idOrName string) (*ServerType, *Response, error)
+	List(ctx context.Context, opts ServerTypeListOpts) ([]*ServerType, *Response, error)
+	All(ctx context.Context) ([]*ServerType, error)
 }
 
 // SSHKey alias of hcloud.SSHKey
@@ -282,6 +388,7 @@ type SSHKeyCreateOpts = hcloud.SSHKeyCreateOpts
 type SSHKeyClient interface {
 	GetByID(ctx context.Context, id int) (*SSHKey, *Response, error)
 	GetByName(ctx context.Context, name string) (*SSHKey, *Response, error)
+	GetByFingerprint(ctx context.Context, fingerprint string) (*SSHKey, *Response, error)
 	Get(ctx context.Context, idOrName string) (*SSHKey, *Response, error)
 	All(ctx context.Context) ([]*SSHKey, error)
 	Create(ctx context.Context, opts SSHKeyCreateOpts) (*SSHKey, *Response, error)
@@ -25,7 +25,7 @@ func (m *ActionClientMock) GetByID(ctx context.Context, id int) (*hcloud.Action,
 }
 
 // List mock
-func (m *ActionClientMock) List(ctx context.Context, opts hcloud.ActionListOpts) ([]*hcloud.Action, *hcloud.Response, error) {
+func (m *ActionClientMock) List(ctx context.Context, opts hcloud_wrapped.ActionListOpts) ([]*hcloud.Action, *hcloud.Response, error) {
 	args := m.Called(ctx, opts)
 	return args.Get(0).([]*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
 }
@@ -0,0 +1,72 @@
+package hcloudtest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+)
+
+// CertificateClientMock mock of hcloud.CertificateClient
+type CertificateClientMock struct {
+	mock.Mock
+}
+
+// NewCertificateClientMock creates a CertificateClientMock
+func NewCertificateClientMock() hcloud.CertificateClient {
+	return &CertificateClientMock{}
+}
+
+// GetByID mock
+func (m *CertificateClientMock) GetByID(ctx context.Context, id int) (*hcloud.Certificate, *hcloud.Response, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*hcloud.Certificate), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// GetByName mock
+func (m *CertificateClientMock) GetByName(ctx context.Context, name string) (*hcloud.Certificate, *hcloud.Response, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(*hcloud.Certificate), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Get mock
+func (m *CertificateClientMock) Get(ctx context.Context, idOrName string) (*hcloud.Certificate, *hcloud.Response, error) {
+	args := m.Called(ctx, idOrName)
+	return args.Get(0).(*hcloud.Certificate), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// List mock
+func (m *CertificateClientMock) List(ctx context.Context, opts hcloud.CertificateListOpts) ([]*hcloud.Certificate, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).([]*hcloud.Certificate), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// All mock
+func (m *CertificateClientMock) All(ctx context.Context) ([]*hcloud.Certificate, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*hcloud.Certificate), args.Error(1)
+}
+
+// Create mock
+func (m *CertificateClientMock) Create(ctx context.Context, opts hcloud.CertificateCreateOpts) (hcloud.CertificateCreateResult, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).(hcloud.CertificateCreateResult), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Update mock
+func (m *CertificateClientMock) Update(ctx context.Context, certificate *hcloud.Certificate, opts hcloud.CertificateUpdateOpts) (*hcloud.Certificate, *hcloud.Response, error) {
+	args := m.Called(ctx, certificate, opts)
+	return args.Get(0).(*hcloud.Certificate), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Delete mock
+func (m *CertificateClientMock) Delete(ctx context.Context, certificate *hcloud.Certificate) (*hcloud.Response, error) {
+	args := m.Called(ctx, certificate)
+	return args.Get(0).(*hcloud.Response), args.Error(1)
+}
+
+// RetryIssuance mock
+func (m *CertificateClientMock) RetryIssuance(ctx context.Context, certificate *hcloud.Certificate) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, certificate)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
@@ -0,0 +1,48 @@
+package hcloudtest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+)
+
+// DatacenterClientMock mocks the DatacenterClient interface
+type DatacenterClientMock struct {
+	mock.Mock
+}
+
+// NewDatacenterClientMock creates a new DatacenterClientMock
+func NewDatacenterClientMock() hcloud.DatacenterClient {
+	return &DatacenterClientMock{}
+}
+
+// GetByID mock
+func (m *DatacenterClientMock) GetByID(ctx context.Context, id int) (*hcloud.Datacenter, *hcloud.Response, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*hcloud.Datacenter), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// GetByName mock
+func (m *DatacenterClientMock) GetByName(ctx context.Context, name string) (*hcloud.Datacenter, *hcloud.Response, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(*hcloud.Datacenter), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Get mock
+func (m *DatacenterClientMock) Get(ctx context.Context, idOrName string) (*hcloud.Datacenter, *hcloud.Response, error) {
+	args := m.Called(ctx, idOrName)
+	return args.Get(0).(*hcloud.Datacenter), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// List mock
+func (m *DatacenterClientMock) List(ctx context.Context, opts hcloud.DatacenterListOpts) ([]*hcloud.Datacenter, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).([]*hcloud.Datacenter), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// All mock
+func (m *DatacenterClientMock) All(ctx context.Context) ([]*hcloud.Datacenter, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*hcloud.Datacenter), args.Error(1)
+}
@@ -0,0 +1,84 @@
+package hcloudtest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+)
+
+// FirewallClientMock mock of hcloud.FirewallClient
+type FirewallClientMock struct {
+	mock.Mock
+}
+
+// NewFirewallClientMock creates a FirewallClientMock
+func NewFirewallClientMock() hcloud.FirewallClient {
+	return &FirewallClientMock{}
+}
+
+// GetByID mock
+func (m *FirewallClientMock) GetByID(ctx context.Context, id int) (*hcloud.Firewall, *hcloud.Response, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*hcloud.Firewall), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// GetByName mock
+func (m *FirewallClientMock) GetByName(ctx context.Context, name string) (*hcloud.Firewall, *hcloud.Response, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(*hcloud.Firewall), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Get mock
+func (m *FirewallClientMock) Get(ctx context.Context, idOrName string) (*hcloud.Firewall, *hcloud.Response, error) {
+	args := m.Called(ctx, idOrName)
+	return args.Get(0).(*hcloud.Firewall), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// List mock
+func (m *FirewallClientMock) List(ctx context.Context, opts hcloud.FirewallListOpts) ([]*hcloud.Firewall, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).([]*hcloud.Firewall), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// All mock
+func (m *FirewallClientMock) All(ctx context.Context) ([]*hcloud.Firewall, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*hcloud.Firewall), args.Error(1)
+}
+
+// Create mock
+func (m *FirewallClientMock) Create(ctx context.Context, opts hcloud.FirewallCreateOpts) (hcloud.FirewallCreateResult, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).(hcloud.FirewallCreateResult), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Update mock
+func (m *FirewallClientMock) Update(ctx context.Context, firewall *hcloud.Firewall, opts hcloud.FirewallUpdateOpts) (*hcloud.Firewall, *hcloud.Response, error) {
+	args := m.Called(ctx, firewall, opts)
+	return args.Get(0).(*hcloud.Firewall), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Delete mock
+func (m *FirewallClientMock) Delete(ctx context.Context, firewall *hcloud.Firewall) (*hcloud.Response, error) {
+	args := m.Called(ctx, firewall)
+	return args.Get(0).(*hcloud.Response), args.Error(1)
+}
+
+// SetRules mock
+func (m *FirewallClientMock) SetRules(ctx context.Context, firewall *hcloud.Firewall, rules []hcloud.FirewallRule) ([]*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, firewall, rules)
+	return args.Get(0).([]*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// ApplyResources mock
+func (m *FirewallClientMock) ApplyResources(ctx context.Context, firewall *hcloud.Firewall, resources []hcloud.FirewallResource) ([]*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, firewall, resources)
+	return args.Get(0).([]*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// RemoveResources mock
+func (m *FirewallClientMock) RemoveResources(ctx context.Context, firewall *hcloud.Firewall, resources []hcloud.FirewallResource) ([]*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, firewall, resources)
+	return args.Get(0).([]*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
@@ -35,3 +35,15 @@ func (m *ISOClientMock) Get(ctx context.Context, idOrName string) (*hcloud.ISO,
 	args := m.Called(ctx, idOrName)
 	return args.Get(0).(*hcloud.ISO), args.Get(1).(*hcloud.Response), args.Error(2)
 }
+
+// List mock
+func (m *ISOClientMock) List(ctx context.Context, opts hcloud.ISOListOpts) ([]*hcloud.ISO, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).([]*hcloud.ISO), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// All mock
+func (m *ISOClientMock) All(ctx context.Context) ([]*hcloud.ISO, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*hcloud.ISO), args.Error(1)
+}
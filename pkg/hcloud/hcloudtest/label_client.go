@@ -0,0 +1,30 @@
+package hcloudtest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+)
+
+// LabelClientMock mocks the LabelClient interface
+type LabelClientMock struct {
+	mock.Mock
+}
+
+// NewLabelClientMock creates a new LabelClientMock
+func NewLabelClientMock() hcloud.LabelClient {
+	return &LabelClientMock{}
+}
+
+// All mock
+func (m *LabelClientMock) All(ctx context.Context, resource string) (map[int]hcloud.Labels, error) {
+	args := m.Called(ctx, resource)
+	return args.Get(0).(map[int]hcloud.Labels), args.Error(1)
+}
+
+// List mock
+func (m *LabelClientMock) List(ctx context.Context, resource string, selector string) (map[int]hcloud.Labels, error) {
+	args := m.Called(ctx, resource, selector)
+	return args.Get(0).(map[int]hcloud.Labels), args.Error(1)
+}
@@ -0,0 +1,120 @@
+package hcloudtest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+)
+
+// LoadBalancerClientMock mock of hcloud.LoadBalancerClient
+type LoadBalancerClientMock struct {
+	mock.Mock
+}
+
+// NewLoadBalancerClientMock creates a LoadBalancerClientMock
+func NewLoadBalancerClientMock() hcloud.LoadBalancerClient {
+	return &LoadBalancerClientMock{}
+}
+
+// GetByID mock
+func (m *LoadBalancerClientMock) GetByID(ctx context.Context, id int) (*hcloud.LoadBalancer, *hcloud.Response, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*hcloud.LoadBalancer), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// GetByName mock
+func (m *LoadBalancerClientMock) GetByName(ctx context.Context, name string) (*hcloud.LoadBalancer, *hcloud.Response, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(*hcloud.LoadBalancer), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Get mock
+func (m *LoadBalancerClientMock) Get(ctx context.Context, idOrName string) (*hcloud.LoadBalancer, *hcloud.Response, error) {
+	args := m.Called(ctx, idOrName)
+	return args.Get(0).(*hcloud.LoadBalancer), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// List mock
+func (m *LoadBalancerClientMock) List(ctx context.Context, opts hcloud.LoadBalancerListOpts) ([]*hcloud.LoadBalancer, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).([]*hcloud.LoadBalancer), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// All mock
+func (m *LoadBalancerClientMock) All(ctx context.Context) ([]*hcloud.LoadBalancer, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*hcloud.LoadBalancer), args.Error(1)
+}
+
+// Create mock
+func (m *LoadBalancerClientMock) Create(ctx context.Context, opts hcloud.LoadBalancerCreateOpts) (hcloud.LoadBalancerCreateResult, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).(hcloud.LoadBalancerCreateResult), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Update mock
+func (m *LoadBalancerClientMock) Update(ctx context.Context, loadBalancer *hcloud.LoadBalancer, opts hcloud.LoadBalancerUpdateOpts) (*hcloud.LoadBalancer, *hcloud.Response, error) {
+	args := m.Called(ctx, loadBalancer, opts)
+	return args.Get(0).(*hcloud.LoadBalancer), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Delete mock
+func (m *LoadBalancerClientMock) Delete(ctx context.Context, loadBalancer *hcloud.LoadBalancer) (*hcloud.Response, error) {
+	args := m.Called(ctx, loadBalancer)
+	return args.Get(0).(*hcloud.Response), args.Error(1)
+}
+
+// ChangeType mock
+func (m *LoadBalancerClientMock) ChangeType(ctx context.Context, loadBalancer *hcloud.LoadBalancer, loadBalancerType *hcloud.LoadBalancerType) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, loadBalancer, loadBalancerType)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// ChangeAlgorithm mock
+func (m *LoadBalancerClientMock) ChangeAlgorithm(ctx context.Context, loadBalancer *hcloud.LoadBalancer, algorithm hcloud.LoadBalancerAlgorithmType) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, loadBalancer, algorithm)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// AttachToNetwork mock
+func (m *LoadBalancerClientMock) AttachToNetwork(ctx context.Context, loadBalancer *hcloud.LoadBalancer, opts hcloud.LoadBalancerAttachToNetworkOpts) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, loadBalancer, opts)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// DetachFromNetwork mock
+func (m *LoadBalancerClientMock) DetachFromNetwork(ctx context.Context, loadBalancer *hcloud.LoadBalancer, network *hcloud.Network) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, loadBalancer, network)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// AddService mock
+func (m *LoadBalancerClientMock) AddService(ctx context.Context, loadBalancer *hcloud.LoadBalancer, service hcloud.LoadBalancerService) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, loadBalancer, service)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// UpdateService mock
+func (m *LoadBalancerClientMock) UpdateService(ctx context.Context, loadBalancer *hcloud.LoadBalancer, service hcloud.LoadBalancerService) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, loadBalancer, service)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// DeleteService mock
+func (m *LoadBalancerClientMock) DeleteService(ctx context.Context, loadBalancer *hcloud.LoadBalancer, listenPort int) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, loadBalancer, listenPort)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// AddTarget mock
+func (m *LoadBalancerClientMock) AddTarget(ctx context.Context, loadBalancer *hcloud.LoadBalancer, target hcloud.LoadBalancerTarget) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, loadBalancer, target)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// RemoveTarget mock
+func (m *LoadBalancerClientMock) RemoveTarget(ctx context.Context, loadBalancer *hcloud.LoadBalancer, target hcloud.LoadBalancerTarget) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, loadBalancer, target)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
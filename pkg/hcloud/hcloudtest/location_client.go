@@ -0,0 +1,48 @@
+package hcloudtest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+)
+
+// LocationClientMock mocks the LocationClient interface
+type LocationClientMock struct {
+	mock.Mock
+}
+
+// NewLocationClientMock creates a new LocationClientMock
+func NewLocationClientMock() hcloud.LocationClient {
+	return &LocationClientMock{}
+}
+
+// GetByID mock
+func (m *LocationClientMock) GetByID(ctx context.Context, id int) (*hcloud.Location, *hcloud.Response, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*hcloud.Location), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// GetByName mock
+func (m *LocationClientMock) GetByName(ctx context.Context, name string) (*hcloud.Location, *hcloud.Response, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(*hcloud.Location), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Get mock
+func (m *LocationClientMock) Get(ctx context.Context, idOrName string) (*hcloud.Location, *hcloud.Response, error) {
+	args := m.Called(ctx, idOrName)
+	return args.Get(0).(*hcloud.Location), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// List mock
+func (m *LocationClientMock) List(ctx context.Context, opts hcloud.LocationListOpts) ([]*hcloud.Location, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).([]*hcloud.Location), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// All mock
+func (m *LocationClientMock) All(ctx context.Context) ([]*hcloud.Location, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*hcloud.Location), args.Error(1)
+}
@@ -0,0 +1,97 @@
+package hcloudtest
+
+import (
+	"context"
+	"net"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+)
+
+// NetworkClientMock mock of hcloud.NetworkClient
+type NetworkClientMock struct {
+	mock.Mock
+}
+
+// NewNetworkClientMock creates a NetworkClientMock
+func NewNetworkClientMock() hcloud.NetworkClient {
+	return &NetworkClientMock{}
+}
+
+// GetByID mock
+func (m *NetworkClientMock) GetByID(ctx context.Context, id int) (*hcloud.Network, *hcloud.Response, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*hcloud.Network), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// GetByName mock
+func (m *NetworkClientMock) GetByName(ctx context.Context, name string) (*hcloud.Network, *hcloud.Response, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(*hcloud.Network), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Get mock
+func (m *NetworkClientMock) Get(ctx context.Context, idOrName string) (*hcloud.Network, *hcloud.Response, error) {
+	args := m.Called(ctx, idOrName)
+	return args.Get(0).(*hcloud.Network), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// List mock
+func (m *NetworkClientMock) List(ctx context.Context, opts hcloud.NetworkListOpts) ([]*hcloud.Network, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).([]*hcloud.Network), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// All mock
+func (m *NetworkClientMock) All(ctx context.Context) ([]*hcloud.Network, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*hcloud.Network), args.Error(1)
+}
+
+// Create mock
+func (m *NetworkClientMock) Create(ctx context.Context, opts hcloud.NetworkCreateOpts) (*hcloud.Network, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).(*hcloud.Network), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Update mock
+func (m *NetworkClientMock) Update(ctx context.Context, network *hcloud.Network, opts hcloud.NetworkUpdateOpts) (*hcloud.Network, *hcloud.Response, error) {
+	args := m.Called(ctx, network, opts)
+	return args.Get(0).(*hcloud.Network), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Delete mock
+func (m *NetworkClientMock) Delete(ctx context.Context, network *hcloud.Network) (*hcloud.Response, error) {
+	args := m.Called(ctx, network)
+	return args.Get(0).(*hcloud.Response), args.Error(1)
+}
+
+// ChangeIPRange mock
+func (m *NetworkClientMock) ChangeIPRange(ctx context.Context, network *hcloud.Network, ipRange *net.IPNet) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, network, ipRange)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// AddSubnet mock
+func (m *NetworkClientMock) AddSubnet(ctx context.Context, network *hcloud.Network, subnet hcloud.NetworkSubnet) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, network, subnet)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// DeleteSubnet mock
+func (m *NetworkClientMock) DeleteSubnet(ctx context.Context, network *hcloud.Network, subnet hcloud.NetworkSubnet) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, network, subnet)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// AddRoute mock
+func (m *NetworkClientMock) AddRoute(ctx context.Context, network *hcloud.Network, route hcloud.NetworkRoute) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, network, route)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// DeleteRoute mock
+func (m *NetworkClientMock) DeleteRoute(ctx context.Context, network *hcloud.Network, route hcloud.NetworkRoute) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, network, route)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
@@ -0,0 +1,66 @@
+package hcloudtest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+)
+
+// PlacementGroupClientMock mock of hcloud.PlacementGroupClient
+type PlacementGroupClientMock struct {
+	mock.Mock
+}
+
+// NewPlacementGroupClientMock creates a PlacementGroupClientMock
+func NewPlacementGroupClientMock() hcloud.PlacementGroupClient {
+	return &PlacementGroupClientMock{}
+}
+
+// GetByID mock
+func (m *PlacementGroupClientMock) GetByID(ctx context.Context, id int) (*hcloud.PlacementGroup, *hcloud.Response, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*hcloud.PlacementGroup), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// GetByName mock
+func (m *PlacementGroupClientMock) GetByName(ctx context.Context, name string) (*hcloud.PlacementGroup, *hcloud.Response, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(*hcloud.PlacementGroup), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Get mock
+func (m *PlacementGroupClientMock) Get(ctx context.Context, idOrName string) (*hcloud.PlacementGroup, *hcloud.Response, error) {
+	args := m.Called(ctx, idOrName)
+	return args.Get(0).(*hcloud.PlacementGroup), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// List mock
+func (m *PlacementGroupClientMock) List(ctx context.Context, opts hcloud.PlacementGroupListOpts) ([]*hcloud.PlacementGroup, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).([]*hcloud.PlacementGroup), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// All mock
+func (m *PlacementGroupClientMock) All(ctx context.Context) ([]*hcloud.PlacementGroup, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*hcloud.PlacementGroup), args.Error(1)
+}
+
+// Create mock
+func (m *PlacementGroupClientMock) Create(ctx context.Context, opts hcloud.PlacementGroupCreateOpts) (hcloud.PlacementGroupCreateResult, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).(hcloud.PlacementGroupCreateResult), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Update mock
+func (m *PlacementGroupClientMock) Update(ctx context.Context, placementGroup *hcloud.PlacementGroup, opts hcloud.PlacementGroupUpdateOpts) (*hcloud.PlacementGroup, *hcloud.Response, error) {
+	args := m.Called(ctx, placementGroup, opts)
+	return args.Get(0).(*hcloud.PlacementGroup), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Delete mock
+func (m *PlacementGroupClientMock) Delete(ctx context.Context, placementGroup *hcloud.PlacementGroup) (*hcloud.Response, error) {
+	args := m.Called(ctx, placementGroup)
+	return args.Get(0).(*hcloud.Response), args.Error(1)
+}
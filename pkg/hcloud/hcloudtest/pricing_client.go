@@ -0,0 +1,24 @@
+package hcloudtest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+)
+
+// PricingClientMock mocks the PricingClient interface
+type PricingClientMock struct {
+	mock.Mock
+}
+
+// NewPricingClientMock creates a new PricingClientMock
+func NewPricingClientMock() hcloud.PricingClient {
+	return &PricingClientMock{}
+}
+
+// Get mock
+func (m *PricingClientMock) Get(ctx context.Context) (hcloud.Pricing, *hcloud.Response, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(hcloud.Pricing), args.Get(1).(*hcloud.Response), args.Error(2)
+}
@@ -0,0 +1,84 @@
+package hcloudtest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+)
+
+// PrimaryIPClientMock mock of hcloud.PrimaryIPClient
+type PrimaryIPClientMock struct {
+	mock.Mock
+}
+
+// NewPrimaryIPClientMock creates a PrimaryIPClientMock
+func NewPrimaryIPClientMock() hcloud.PrimaryIPClient {
+	return &PrimaryIPClientMock{}
+}
+
+// GetByID mock
+func (m *PrimaryIPClientMock) GetByID(ctx context.Context, id int) (*hcloud.PrimaryIP, *hcloud.Response, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*hcloud.PrimaryIP), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// GetByName mock
+func (m *PrimaryIPClientMock) GetByName(ctx context.Context, name string) (*hcloud.PrimaryIP, *hcloud.Response, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(*hcloud.PrimaryIP), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Get mock
+func (m *PrimaryIPClientMock) Get(ctx context.Context, idOrName string) (*hcloud.PrimaryIP, *hcloud.Response, error) {
+	args := m.Called(ctx, idOrName)
+	return args.Get(0).(*hcloud.PrimaryIP), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// List mock
+func (m *PrimaryIPClientMock) List(ctx context.Context, opts hcloud.PrimaryIPListOpts) ([]*hcloud.PrimaryIP, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).([]*hcloud.PrimaryIP), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// All mock
+func (m *PrimaryIPClientMock) All(ctx context.Context) ([]*hcloud.PrimaryIP, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*hcloud.PrimaryIP), args.Error(1)
+}
+
+// Create mock
+func (m *PrimaryIPClientMock) Create(ctx context.Context, opts hcloud.PrimaryIPCreateOpts) (hcloud.PrimaryIPCreateResult, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).(hcloud.PrimaryIPCreateResult), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Update mock
+func (m *PrimaryIPClientMock) Update(ctx context.Context, primaryIP *hcloud.PrimaryIP, opts hcloud.PrimaryIPUpdateOpts) (*hcloud.PrimaryIP, *hcloud.Response, error) {
+	args := m.Called(ctx, primaryIP, opts)
+	return args.Get(0).(*hcloud.PrimaryIP), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Delete mock
+func (m *PrimaryIPClientMock) Delete(ctx context.Context, primaryIP *hcloud.PrimaryIP) (*hcloud.Response, error) {
+	args := m.Called(ctx, primaryIP)
+	return args.Get(0).(*hcloud.Response), args.Error(1)
+}
+
+// Assign mock
+func (m *PrimaryIPClientMock) Assign(ctx context.Context, primaryIP *hcloud.PrimaryIP, server *hcloud.Server) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, primaryIP, server)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Unassign mock
+func (m *PrimaryIPClientMock) Unassign(ctx context.Context, primaryIP *hcloud.PrimaryIP) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, primaryIP)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// ChangeProtection mock
+func (m *PrimaryIPClientMock) ChangeProtection(ctx context.Context, primaryIP *hcloud.PrimaryIP, protection hcloud.PrimaryIPProtection) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, primaryIP, protection)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
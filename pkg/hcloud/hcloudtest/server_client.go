@@ -43,7 +43,7 @@ func (m *ServerClientMock) All(ctx context.Context) ([]*hcloud.Server, error) {
 }
 
 // Create mock
-func (m *ServerClientMock) Create(ctx context.Context, opts hcloud.ServerCreateOpts) (hcloud.ServerCreateResult, *hcloud.Response, error) {
+func (m *ServerClientMock) Create(ctx context.Context, opts hcloud_wrapped.ServerCreateOpts) (hcloud.ServerCreateResult, *hcloud.Response, error) {
 	args := m.Called(ctx, opts)
 	return args.Get(0).(hcloud.ServerCreateResult), args.Get(1).(*hcloud.Response), args.Error(2)
 }
@@ -119,3 +119,15 @@ func (m *ServerClientMock) DetachISO(ctx context.Context, server *hcloud.Server)
 	args := m.Called(ctx, server)
 	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
 }
+
+// AddToPlacementGroup mock
+func (m *ServerClientMock) AddToPlacementGroup(ctx context.Context, server *hcloud.Server, placementGroup *hcloud_wrapped.PlacementGroup) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, server, placementGroup)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// RemoveFromPlacementGroup mock
+func (m *ServerClientMock) RemoveFromPlacementGroup(ctx context.Context, server *hcloud.Server) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, server)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
@@ -0,0 +1,49 @@
+package hcloudtest
+
+import (
+	"context"
+	"net"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+)
+
+// ServerNetworkClientMock mock of hcloud.ServerNetworkClient
+type ServerNetworkClientMock struct {
+	mock.Mock
+}
+
+// NewServerNetworkClientMock creates a ServerNetworkClientMock
+func NewServerNetworkClientMock() hcloud.ServerNetworkClient {
+	return &ServerNetworkClientMock{}
+}
+
+// List mock
+func (m *ServerNetworkClientMock) List(ctx context.Context, server *hcloud.Server) ([]hcloud.ServerPrivateNet, *hcloud.Response, error) {
+	args := m.Called(ctx, server)
+	return args.Get(0).([]hcloud.ServerPrivateNet), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// All mock
+func (m *ServerNetworkClientMock) All(ctx context.Context) (map[int][]hcloud.ServerPrivateNet, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(map[int][]hcloud.ServerPrivateNet), args.Error(1)
+}
+
+// Attach mock
+func (m *ServerNetworkClientMock) Attach(ctx context.Context, server *hcloud.Server, opts hcloud.ServerAttachToNetworkOpts) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, server, opts)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Detach mock
+func (m *ServerNetworkClientMock) Detach(ctx context.Context, server *hcloud.Server, network *hcloud.Network) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, server, network)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// ChangeAliasIPs mock
+func (m *ServerNetworkClientMock) ChangeAliasIPs(ctx context.Context, server *hcloud.Server, network *hcloud.Network, aliasIPs []net.IP) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, server, network, aliasIPs)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
@@ -30,6 +30,12 @@ func (m *SSHKeyClientMock) GetByName(ctx context.Context, name string) (*hcloud.
 	return args.Get(0).(*hcloud.SSHKey), args.Get(1).(*hcloud.Response), args.Error(2)
 }
 
+// GetByFingerprint mock
+func (m *SSHKeyClientMock) GetByFingerprint(ctx context.Context, fingerprint string) (*hcloud.SSHKey, *hcloud.Response, error) {
+	args := m.Called(ctx, fingerprint)
+	return args.Get(0).(*hcloud.SSHKey), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
 // Get mock
 func (m *SSHKeyClientMock) Get(ctx context.Context, idOrName string) (*hcloud.SSHKey, *hcloud.Response, error) {
 	args := m.Called(ctx, idOrName)
@@ -0,0 +1,84 @@
+package hcloudtest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+)
+
+// VolumeClientMock mock of hcloud.VolumeClient
+type VolumeClientMock struct {
+	mock.Mock
+}
+
+// NewVolumeClientMock creates a VolumeClientMock
+func NewVolumeClientMock() hcloud.VolumeClient {
+	return &VolumeClientMock{}
+}
+
+// GetByID mock
+func (m *VolumeClientMock) GetByID(ctx context.Context, id int) (*hcloud.Volume, *hcloud.Response, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*hcloud.Volume), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// GetByName mock
+func (m *VolumeClientMock) GetByName(ctx context.Context, name string) (*hcloud.Volume, *hcloud.Response, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(*hcloud.Volume), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Get mock
+func (m *VolumeClientMock) Get(ctx context.Context, idOrName string) (*hcloud.Volume, *hcloud.Response, error) {
+	args := m.Called(ctx, idOrName)
+	return args.Get(0).(*hcloud.Volume), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// List mock
+func (m *VolumeClientMock) List(ctx context.Context, opts hcloud.VolumeListOpts) ([]*hcloud.Volume, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).([]*hcloud.Volume), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// All mock
+func (m *VolumeClientMock) All(ctx context.Context) ([]*hcloud.Volume, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*hcloud.Volume), args.Error(1)
+}
+
+// Create mock
+func (m *VolumeClientMock) Create(ctx context.Context, opts hcloud.VolumeCreateOpts) (hcloud.VolumeCreateResult, *hcloud.Response, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).(hcloud.VolumeCreateResult), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Update mock
+func (m *VolumeClientMock) Update(ctx context.Context, volume *hcloud.Volume, opts hcloud.VolumeUpdateOpts) (*hcloud.Volume, *hcloud.Response, error) {
+	args := m.Called(ctx, volume, opts)
+	return args.Get(0).(*hcloud.Volume), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Delete mock
+func (m *VolumeClientMock) Delete(ctx context.Context, volume *hcloud.Volume) (*hcloud.Response, error) {
+	args := m.Called(ctx, volume)
+	return args.Get(0).(*hcloud.Response), args.Error(1)
+}
+
+// Attach mock
+func (m *VolumeClientMock) Attach(ctx context.Context, volume *hcloud.Volume, opts hcloud.VolumeAttachOpts) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, volume, opts)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Detach mock
+func (m *VolumeClientMock) Detach(ctx context.Context, volume *hcloud.Volume) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, volume)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
+
+// Resize mock
+func (m *VolumeClientMock) Resize(ctx context.Context, volume *hcloud.Volume, size int) (*hcloud.Action, *hcloud.Response, error) {
+	args := m.Called(ctx, volume, size)
+	return args.Get(0).(*hcloud.Action), args.Get(1).(*hcloud.Response), args.Error(2)
+}
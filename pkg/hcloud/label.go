@@ -0,0 +1,79 @@
+package hcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hetznercloud/hcloud-go/hcloud"
+)
+
+// Resource types supporting labels
+const (
+	LabelResourceServers     = "servers"
+	LabelResourceFloatingIPs = "floating_ips"
+	LabelResourceImages      = "images"
+)
+
+// Labels of a resource
+type Labels map[string]string
+
+// LabelClient reads the labels of resources,
+// hcloud-go 1.7 does not expose them
+type LabelClient interface {
+	// All returns the labels of all resources of a type by resource id
+	All(ctx context.Context, resource string) (map[int]Labels, error)
+	// List returns the labels of all resources of a type matching the selector by resource id
+	List(ctx context.Context, resource string, selector string) (map[int]Labels, error)
+}
+
+type labelClient struct {
+	client *hcloud.Client
+}
+
+type labelResource struct {
+	ID     int    `json:"id"`
+	Labels Labels `json:"labels"`
+}
+
+func (c *labelClient) All(ctx context.Context, resource string) (map[int]Labels, error) {
+	return c.List(ctx, resource, "")
+}
+
+func (c *labelClient) List(ctx context.Context, resource string, selector string) (map[int]Labels, error) {
+	labels := map[int]Labels{}
+	opts := ListOpts{PerPage: 50, Page: 1}
+	for {
+		values := valuesForListOpts(opts)
+		if selector != "" {
+			values.Set("label_selector", selector)
+		}
+		req, err := c.client.NewRequest(ctx, "GET", fmt.Sprintf("/%s?%s", resource, values.Encode()), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var body map[string]json.RawMessage
+		resp, err := c.client.Do(req, &body)
+		if err != nil {
+			return nil, err
+		}
+		var resources []labelResource
+		if raw, ok := body[resource]; ok {
+			if err := json.Unmarshal(raw, &resources); err != nil {
+				return nil, err
+			}
+		}
+		for _, r := range resources {
+			if r.Labels == nil {
+				r.Labels = Labels{}
+			}
+			labels[r.ID] = r.Labels
+		}
+
+		if resp.Meta.Pagination == nil || resp.Meta.Pagination.NextPage == 0 {
+			return labels, nil
+		}
+		opts.Page = resp.Meta.Pagination.NextPage
+	}
+}
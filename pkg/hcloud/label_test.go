@@ -0,0 +1,43 @@
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		assert.Equal(t, "/servers", r.URL.Path)
+		assert.Equal(t, "env=prod", r.URL.Query().Get("label_selector"))
+		switch r.URL.Query().Get("page") {
+		case "1":
+			fmt.Fprint(w, `{
+				"servers": [{"id": 1, "name": "web1", "labels": {"env": "prod"}}],
+				"meta": {"pagination": {"page": 1, "per_page": 1, "next_page": 2, "last_page": 2, "total_entries": 2}}
+			}`)
+		case "2":
+			fmt.Fprint(w, `{
+				"servers": [{"id": 2, "name": "web2", "labels": {"env": "prod", "role": "web"}}],
+				"meta": {"pagination": {"page": 2, "per_page": 1, "previous_page": 1, "last_page": 2, "total_entries": 2}}
+			}`)
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL))
+	labels, err := client.Label.List(context.Background(), LabelResourceServers, "env=prod")
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[int]Labels{
+			1: {"env": "prod"},
+			2: {"env": "prod", "role": "web"},
+		}, labels)
+	}
+}
@@ -0,0 +1,660 @@
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/hcloud"
+	"github.com/hetznercloud/hcloud-go/hcloud/schema"
+)
+
+// LoadBalancer represents a load balancer in the Hetzner Cloud,
+// hcloud-go 1.7 does not support load balancers
+type LoadBalancer struct {
+	ID               int
+	Name             string
+	LoadBalancerType *LoadBalancerType
+	Location         *Location
+	Algorithm        LoadBalancerAlgorithmType
+	PublicNet        LoadBalancerPublicNet
+	PrivateNet       []LoadBalancerPrivateNet
+	Services         []LoadBalancerService
+	Targets          []LoadBalancerTarget
+	Labels           map[string]string
+	Created          time.Time
+}
+
+// LoadBalancerType represents a load balancer type
+type LoadBalancerType struct {
+	ID   int
+	Name string
+}
+
+// LoadBalancerAlgorithmType specifies the algorithm used to distribute traffic
+type LoadBalancerAlgorithmType string
+
+// Load balancer algorithms
+const (
+	LoadBalancerAlgorithmTypeRoundRobin       LoadBalancerAlgorithmType = "round_robin"
+	LoadBalancerAlgorithmTypeLeastConnections LoadBalancerAlgorithmType = "least_connections"
+)
+
+// LoadBalancerPublicNet represents the public interface of a load balancer
+type LoadBalancerPublicNet struct {
+	Enabled bool
+	IPv4    net.IP
+	IPv6    net.IP
+}
+
+// LoadBalancerPrivateNet is the attachment of a load balancer to a private network
+type LoadBalancerPrivateNet struct {
+	Network *Network
+	IP      net.IP
+}
+
+// LoadBalancerServiceProtocol specifies the protocol of a service
+type LoadBalancerServiceProtocol string
+
+// Load balancer service protocols
+const (
+	LoadBalancerServiceProtocolTCP   LoadBalancerServiceProtocol = "tcp"
+	LoadBalancerServiceProtocolHTTP  LoadBalancerServiceProtocol = "http"
+	LoadBalancerServiceProtocolHTTPS LoadBalancerServiceProtocol = "https"
+)
+
+// LoadBalancerService represents a service of a load balancer,
+// HTTP is only used by the http and https protocols
+type LoadBalancerService struct {
+	Protocol        LoadBalancerServiceProtocol
+	ListenPort      int
+	DestinationPort int
+	Proxyprotocol   bool
+	HTTP            LoadBalancerServiceHTTP
+	HealthCheck     LoadBalancerServiceHealthCheck
+}
+
+// LoadBalancerServiceHTTP holds the HTTP options of a service
+type LoadBalancerServiceHTTP struct {
+	CookieName     string
+	CookieLifetime time.Duration
+	Certificates   []int
+	RedirectHTTP   bool
+	StickySessions bool
+}
+
+// LoadBalancerServiceHealthCheck represents the health check of a service,
+// HTTP is only used by the http and https protocols
+type LoadBalancerServiceHealthCheck struct {
+	Protocol LoadBalancerServiceProtocol
+	Port     int
+	Interval time.Duration
+	Timeout  time.Duration
+	Retries  int
+	HTTP     LoadBalancerServiceHealthCheckHTTP
+}
+
+// LoadBalancerServiceHealthCheckHTTP holds the HTTP options of a health check
+type LoadBalancerServiceHealthCheckHTTP struct {
+	Domain      string
+	Path        string
+	Response    string
+	StatusCodes []string
+	TLS         bool
+}
+
+// LoadBalancerTargetType specifies the type of a target
+type LoadBalancerTargetType string
+
+// Load balancer target types
+const (
+	LoadBalancerTargetTypeServer        LoadBalancerTargetType = "server"
+	LoadBalancerTargetTypeLabelSelector LoadBalancerTargetType = "label_selector"
+	LoadBalancerTargetTypeIP            LoadBalancerTargetType = "ip"
+)
+
+// LoadBalancerTarget represents a target of a load balancer.
+// Targets matched by a label selector are listed in Targets.
+type LoadBalancerTarget struct {
+	Type          LoadBalancerTargetType
+	Server        *Server
+	LabelSelector string
+	IP            string
+	UsePrivateIP  bool
+	HealthStatus  []LoadBalancerTargetHealthStatus
+	Targets       []LoadBalancerTarget
+}
+
+// LoadBalancerTargetHealthStatusStatus specifies the health of a target
+type LoadBalancerTargetHealthStatusStatus string
+
+// Load balancer target health statuses
+const (
+	LoadBalancerTargetHealthStatusStatusUnknown   LoadBalancerTargetHealthStatusStatus = "unknown"
+	LoadBalancerTargetHealthStatusStatusHealthy   LoadBalancerTargetHealthStatusStatus = "healthy"
+	LoadBalancerTargetHealthStatusStatusUnhealthy LoadBalancerTargetHealthStatusStatus = "unhealthy"
+)
+
+// LoadBalancerTargetHealthStatus is the health of a target for the service on ListenPort
+type LoadBalancerTargetHealthStatus struct {
+	ListenPort int
+	Status     LoadBalancerTargetHealthStatusStatus
+}
+
+// LoadBalancerListOpts specifies options for listing load balancers
+type LoadBalancerListOpts struct {
+	ListOpts
+	Name string
+}
+
+// LoadBalancerCreateOpts specifies parameters for creating a load balancer
+type LoadBalancerCreateOpts struct {
+	Name             string
+	LoadBalancerType *LoadBalancerType
+	Location         *Location
+	Algorithm        LoadBalancerAlgorithmType
+	Network          *Network
+	Services         []LoadBalancerService
+	Targets          []LoadBalancerTarget
+	Labels           map[string]string
+}
+
+// LoadBalancerCreateResult is the result of creating a load balancer
+type LoadBalancerCreateResult struct {
+	LoadBalancer *LoadBalancer
+	Action       *Action
+}
+
+// LoadBalancerUpdateOpts specifies parameters for updating a load balancer
+type LoadBalancerUpdateOpts struct {
+	Name   string
+	Labels map[string]string
+}
+
+// LoadBalancerAttachToNetworkOpts specifies parameters for attaching a load balancer to a network
+type LoadBalancerAttachToNetworkOpts struct {
+	Network *Network
+	IP      net.IP
+}
+
+// LoadBalancerClient is a client for the load balancers API
+type LoadBalancerClient interface {
+	GetByID(ctx context.Context, id int) (*LoadBalancer, *Response, error)
+	GetByName(ctx context.Context, name string) (*LoadBalancer, *Response, error)
+	Get(ctx context.Context, idOrName string) (*LoadBalancer, *Response, error)
+	List(ctx context.Context, opts LoadBalancerListOpts) ([]*LoadBalancer, *Response, error)
+	All(ctx context.Context) ([]*LoadBalancer, error)
+	Create(ctx context.Context, opts LoadBalancerCreateOpts) (LoadBalancerCreateResult, *Response, error)
+	Update(ctx context.Context, loadBalancer *LoadBalancer, opts LoadBalancerUpdateOpts) (*LoadBalancer, *Response, error)
+	Delete(ctx context.Context, loadBalancer *LoadBalancer) (*Response, error)
+	ChangeType(ctx context.Context, loadBalancer *LoadBalancer, loadBalancerType *LoadBalancerType) (*Action, *Response, error)
+	ChangeAlgorithm(ctx context.Context, loadBalancer *LoadBalancer, algorithm LoadBalancerAlgorithmType) (*Action, *Response, error)
+	AttachToNetwork(ctx context.Context, loadBalancer *LoadBalancer, opts LoadBalancerAttachToNetworkOpts) (*Action, *Response, error)
+	DetachFromNetwork(ctx context.Context, loadBalancer *LoadBalancer, network *Network) (*Action, *Response, error)
+	AddService(ctx context.Context, loadBalancer *LoadBalancer, service LoadBalancerService) (*Action, *Response, error)
+	UpdateService(ctx context.Context, loadBalancer *LoadBalancer, service LoadBalancerService) (*Action, *Response, error)
+	DeleteService(ctx context.Context, loadBalancer *LoadBalancer, listenPort int) (*Action, *Response, error)
+	AddTarget(ctx context.Context, loadBalancer *LoadBalancer, target LoadBalancerTarget) (*Action, *Response, error)
+	RemoveTarget(ctx context.Context, loadBalancer *LoadBalancer, target LoadBalancerTarget) (*Action, *Response, error)
+}
+
+type loadBalancerSchema struct {
+	ID               int                    `json:"id"`
+	Name             string                 `json:"name"`
+	LoadBalancerType loadBalancerTypeSchema `json:"load_balancer_type"`
+	Location         schema.Location        `json:"location"`
+	Algorithm        struct {
+		Type string `json:"type"`
+	} `json:"algorithm"`
+	PublicNet struct {
+		Enabled bool `json:"enabled"`
+		IPv4    struct {
+			IP string `json:"ip"`
+		} `json:"ipv4"`
+		IPv6 struct {
+			IP string `json:"ip"`
+		} `json:"ipv6"`
+	} `json:"public_net"`
+	PrivateNet []struct {
+		Network int    `json:"network"`
+		IP      string `json:"ip"`
+	} `json:"private_net"`
+	Services []loadBalancerServiceSchema `json:"services"`
+	Targets  []loadBalancerTargetSchema  `json:"targets"`
+	Labels   map[string]string           `json:"labels"`
+	Created  time.Time                   `json:"created"`
+}
+
+type loadBalancerTypeSchema struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type loadBalancerServiceSchema struct {
+	Protocol        string                                `json:"protocol"`
+	ListenPort      int                                   `json:"listen_port"`
+	DestinationPort int                                   `json:"destination_port"`
+	Proxyprotocol   bool                                  `json:"proxyprotocol"`
+	HTTP            *loadBalancerServiceHTTPSchema        `json:"http,omitempty"`
+	HealthCheck     *loadBalancerServiceHealthCheckSchema `json:"health_check,omitempty"`
+}
+
+type loadBalancerServiceHTTPSchema struct {
+	CookieName     string `json:"cookie_name,omitempty"`
+	CookieLifetime int    `json:"cookie_lifetime,omitempty"`
+	Certificates   []int  `json:"certificates,omitempty"`
+	RedirectHTTP   bool   `json:"redirect_http"`
+	StickySessions bool   `json:"sticky_sessions"`
+}
+
+type loadBalancerServiceHealthCheckSchema struct {
+	Protocol string                                    `json:"protocol"`
+	Port     int                                       `json:"port"`
+	Interval int                                       `json:"interval"`
+	Timeout  int                                       `json:"timeout"`
+	Retries  int                                       `json:"retries"`
+	HTTP     *loadBalancerServiceHealthCheckHTTPSchema `json:"http,omitempty"`
+}
+
+type loadBalancerServiceHealthCheckHTTPSchema struct {
+	Domain      *string  `json:"domain"`
+	Path        string   `json:"path"`
+	Response    string   `json:"response,omitempty"`
+	StatusCodes []string `json:"status_codes,omitempty"`
+	TLS         bool     `json:"tls"`
+}
+
+type loadBalancerTargetSchema struct {
+	Type   string `json:"type"`
+	Server *struct {
+		ID int `json:"id"`
+	} `json:"server,omitempty"`
+	LabelSelector *struct {
+		Selector string `json:"selector"`
+	} `json:"label_selector,omitempty"`
+	IP *struct {
+		IP string `json:"ip"`
+	} `json:"ip,omitempty"`
+	UsePrivateIP *bool `json:"use_private_ip,omitempty"`
+	HealthStatus []struct {
+		ListenPort int    `json:"listen_port"`
+		Status     string `json:"status"`
+	} `json:"health_status,omitempty"`
+	Targets []loadBalancerTargetSchema `json:"targets,omitempty"`
+}
+
+type loadBalancerGetResponse struct {
+	LoadBalancer loadBalancerSchema `json:"load_balancer"`
+}
+
+type loadBalancerListResponse struct {
+	LoadBalancers []loadBalancerSchema `json:"load_balancers"`
+}
+
+type loadBalancerCreateRequest struct {
+	Name             string                       `json:"name"`
+	LoadBalancerType string                       `json:"load_balancer_type"`
+	Location         string                       `json:"location,omitempty"`
+	Algorithm        *loadBalancerAlgorithmSchema `json:"algorithm,omitempty"`
+	Network          int                          `json:"network,omitempty"`
+	Services         []loadBalancerServiceSchema  `json:"services,omitempty"`
+	Targets          []loadBalancerTargetSchema   `json:"targets,omitempty"`
+	Labels           *map[string]string           `json:"labels,omitempty"`
+}
+
+type loadBalancerAlgorithmSchema struct {
+	Type string `json:"type"`
+}
+
+type loadBalancerCreateResponse struct {
+	LoadBalancer loadBalancerSchema `json:"load_balancer"`
+	Action       schema.Action      `json:"action"`
+}
+
+type loadBalancerUpdateRequest struct {
+	Name   string             `json:"name,omitempty"`
+	Labels *map[string]string `json:"labels,omitempty"`
+}
+
+type loadBalancerChangeTypeRequest struct {
+	LoadBalancerType string `json:"load_balancer_type"`
+}
+
+type loadBalancerAttachToNetworkRequest struct {
+	Network int    `json:"network"`
+	IP      string `json:"ip,omitempty"`
+}
+
+type loadBalancerDetachFromNetworkRequest struct {
+	Network int `json:"network"`
+}
+
+type loadBalancerDeleteServiceRequest struct {
+	ListenPort int `json:"listen_port"`
+}
+
+func loadBalancerFromSchema(s loadBalancerSchema) *LoadBalancer {
+	lb := &LoadBalancer{
+		ID:   s.ID,
+		Name: s.Name,
+		LoadBalancerType: &LoadBalancerType{
+			ID:   s.LoadBalancerType.ID,
+			Name: s.LoadBalancerType.Name,
+		},
+		Location:  hcloud.LocationFromSchema(s.Location),
+		Algorithm: LoadBalancerAlgorithmType(s.Algorithm.Type),
+		PublicNet: LoadBalancerPublicNet{
+			Enabled: s.PublicNet.Enabled,
+			IPv4:    net.ParseIP(s.PublicNet.IPv4.IP),
+			IPv6:    net.ParseIP(s.PublicNet.IPv6.IP),
+		},
+		PrivateNet: []LoadBalancerPrivateNet{},
+		Services:   []LoadBalancerService{},
+		Targets:    []LoadBalancerTarget{},
+		Labels:     s.Labels,
+		Created:    s.Created,
+	}
+	for _, p := range s.PrivateNet {
+		lb.PrivateNet = append(lb.PrivateNet, LoadBalancerPrivateNet{
+			Network: &Network{ID: p.Network},
+			IP:      net.ParseIP(p.IP),
+		})
+	}
+	for _, service := range s.Services {
+		lb.Services = append(lb.Services, loadBalancerServiceFromSchema(service))
+	}
+	for _, target := range s.Targets {
+		lb.Targets = append(lb.Targets, loadBalancerTargetFromSchema(target))
+	}
+	return lb
+}
+
+func loadBalancerServiceFromSchema(s loadBalancerServiceSchema) LoadBalancerService {
+	service := LoadBalancerService{
+		Protocol:        LoadBalancerServiceProtocol(s.Protocol),
+		ListenPort:      s.ListenPort,
+		DestinationPort: s.DestinationPort,
+		Proxyprotocol:   s.Proxyprotocol,
+	}
+	if s.HTTP != nil {
+		service.HTTP = LoadBalancerServiceHTTP{
+			CookieName:     s.HTTP.CookieName,
+			CookieLifetime: time.Duration(s.HTTP.CookieLifetime) * time.Second,
+			Certificates:   s.HTTP.Certificates,
+			RedirectHTTP:   s.HTTP.RedirectHTTP,
+			StickySessions: s.HTTP.StickySessions,
+		}
+	}
+	if s.HealthCheck != nil {
+		service.HealthCheck = LoadBalancerServiceHealthCheck{
+			Protocol: LoadBalancerServiceProtocol(s.HealthCheck.Protocol),
+			Port:     s.HealthCheck.Port,
+			Interval: time.Duration(s.HealthCheck.Interval) * time.Second,
+			Timeout:  time.Duration(s.HealthCheck.Timeout) * time.Second,
+			Retries:  s.HealthCheck.Retries,
+		}
+		if h := s.HealthCheck.HTTP; h != nil {
+			service.HealthCheck.HTTP = LoadBalancerServiceHealthCheckHTTP{
+				Path:        h.Path,
+				Response:    h.Response,
+				StatusCodes: h.StatusCodes,
+				TLS:         h.TLS,
+			}
+			if h.Domain != nil {
+				service.HealthCheck.HTTP.Domain = *h.Domain
+			}
+		}
+	}
+	return service
+}
+
+func loadBalancerTargetFromSchema(s loadBalancerTargetSchema) LoadBalancerTarget {
+	target := LoadBalancerTarget{Type: LoadBalancerTargetType(s.Type)}
+	if s.Server != nil {
+		target.Server = &Server{ID: s.Server.ID}
+	}
+	if s.LabelSelector != nil {
+		target.LabelSelector = s.LabelSelector.Selector
+	}
+	if s.IP != nil {
+		target.IP = s.IP.IP
+	}
+	if s.UsePrivateIP != nil {
+		target.UsePrivateIP = *s.UsePrivateIP
+	}
+	for _, status := range s.HealthStatus {
+		target.HealthStatus = append(target.HealthStatus, LoadBalancerTargetHealthStatus{
+			ListenPort: status.ListenPort,
+			Status:     LoadBalancerTargetHealthStatusStatus(status.Status),
+		})
+	}
+	for _, t := range s.Targets {
+		target.Targets = append(target.Targets, loadBalancerTargetFromSchema(t))
+	}
+	return target
+}
+
+func loadBalancerServiceToSchema(service LoadBalancerService) loadBalancerServiceSchema {
+	s := loadBalancerServiceSchema{
+		Protocol:        string(service.Protocol),
+		ListenPort:      service.ListenPort,
+		DestinationPort: service.DestinationPort,
+		Proxyprotocol:   service.Proxyprotocol,
+		HealthCheck: &loadBalancerServiceHealthCheckSchema{
+			Protocol: string(service.HealthCheck.Protocol),
+			Port:     service.HealthCheck.Port,
+			Interval: int(service.HealthCheck.Interval / time.Second),
+			Timeout:  int(service.HealthCheck.Timeout / time.Second),
+			Retries:  service.HealthCheck.Retries,
+		},
+	}
+	if service.Protocol != LoadBalancerServiceProtocolTCP {
+		s.HTTP = &loadBalancerServiceHTTPSchema{
+			CookieName:     service.HTTP.CookieName,
+			CookieLifetime: int(service.HTTP.CookieLifetime / time.Second),
+			Certificates:   service.HTTP.Certificates,
+			RedirectHTTP:   service.HTTP.RedirectHTTP,
+			StickySessions: service.HTTP.StickySessions,
+		}
+	}
+	if service.HealthCheck.Protocol != LoadBalancerServiceProtocolTCP {
+		h := service.HealthCheck.HTTP
+		s.HealthCheck.HTTP = &loadBalancerServiceHealthCheckHTTPSchema{
+			Path:        h.Path,
+			Response:    h.Response,
+			StatusCodes: h.StatusCodes,
+			TLS:         h.TLS,
+		}
+		if h.Domain != "" {
+			s.HealthCheck.HTTP.Domain = hcloud.String(h.Domain)
+		}
+	}
+	return s
+}
+
+func loadBalancerTargetToSchema(target LoadBalancerTarget) loadBalancerTargetSchema {
+	s := loadBalancerTargetSchema{Type: string(target.Type)}
+	switch target.Type {
+	case LoadBalancerTargetTypeServer:
+		s.Server = &struct {
+			ID int `json:"id"`
+		}{ID: target.Server.ID}
+		s.UsePrivateIP = hcloud.Bool(target.UsePrivateIP)
+	case LoadBalancerTargetTypeLabelSelector:
+		s.LabelSelector = &struct {
+			Selector string `json:"selector"`
+		}{Selector: target.LabelSelector}
+		s.UsePrivateIP = hcloud.Bool(target.UsePrivateIP)
+	case LoadBalancerTargetTypeIP:
+		s.IP = &struct {
+			IP string `json:"ip"`
+		}{IP: target.IP}
+	}
+	return s
+}
+
+type loadBalancerClient struct {
+	client *hcloud.Client
+}
+
+func (c *loadBalancerClient) GetByID(ctx context.Context, id int) (*LoadBalancer, *Response, error) {
+	var body loadBalancerGetResponse
+	resp, err := do(ctx, c.client, "GET", fmt.Sprintf("/load_balancers/%d", id), nil, &body)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, resp, nil
+		}
+		return nil, resp, err
+	}
+	return loadBalancerFromSchema(body.LoadBalancer), resp, nil
+}
+
+func (c *loadBalancerClient) GetByName(ctx context.Context, name string) (*LoadBalancer, *Response, error) {
+	loadBalancers, resp, err := c.List(ctx, LoadBalancerListOpts{Name: name})
+	if len(loadBalancers) == 0 {
+		return nil, resp, err
+	}
+	return loadBalancers[0], resp, err
+}
+
+func (c *loadBalancerClient) Get(ctx context.Context, idOrName string) (*LoadBalancer, *Response, error) {
+	if id, err := strconv.Atoi(idOrName); err == nil {
+		return c.GetByID(ctx, id)
+	}
+	return c.GetByName(ctx, idOrName)
+}
+
+func (c *loadBalancerClient) List(ctx context.Context, opts LoadBalancerListOpts) ([]*LoadBalancer, *Response, error) {
+	values := valuesForListOpts(opts.ListOpts)
+	if opts.Name != "" {
+		values.Set("name", opts.Name)
+	}
+	var body loadBalancerListResponse
+	resp, err := do(ctx, c.client, "GET", "/load_balancers?"+values.Encode(), nil, &body)
+	if err != nil {
+		return nil, resp, err
+	}
+	loadBalancers := make([]*LoadBalancer, 0, len(body.LoadBalancers))
+	for _, lb := range body.LoadBalancers {
+		loadBalancers = append(loadBalancers, loadBalancerFromSchema(lb))
+	}
+	return loadBalancers, resp, nil
+}
+
+func (c *loadBalancerClient) All(ctx context.Context) ([]*LoadBalancer, error) {
+	allLoadBalancers := []*LoadBalancer{}
+	opts := LoadBalancerListOpts{ListOpts: ListOpts{PerPage: 50}}
+	err := all(func(page int) (*Response, error) {
+		opts.Page = page
+		loadBalancers, resp, err := c.List(ctx, opts)
+		if err != nil {
+			return resp, err
+		}
+		allLoadBalancers = append(allLoadBalancers, loadBalancers...)
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allLoadBalancers, nil
+}
+
+func (c *loadBalancerClient) Create(ctx context.Context, opts LoadBalancerCreateOpts) (LoadBalancerCreateResult, *Response, error) {
+	reqBody := loadBalancerCreateRequest{
+		Name:             opts.Name,
+		LoadBalancerType: opts.LoadBalancerType.Name,
+	}
+	if opts.Location != nil {
+		reqBody.Location = opts.Location.Name
+	}
+	if opts.Algorithm != "" {
+		reqBody.Algorithm = &loadBalancerAlgorithmSchema{Type: string(opts.Algorithm)}
+	}
+	if opts.Network != nil {
+		reqBody.Network = opts.Network.ID
+	}
+	for _, service := range opts.Services {
+		reqBody.Services = append(reqBody.Services, loadBalancerServiceToSchema(service))
+	}
+	for _, target := range opts.Targets {
+		reqBody.Targets = append(reqBody.Targets, loadBalancerTargetToSchema(target))
+	}
+	if opts.Labels != nil {
+		reqBody.Labels = &opts.Labels
+	}
+
+	var respBody loadBalancerCreateResponse
+	resp, err := do(ctx, c.client, "POST", "/load_balancers", reqBody, &respBody)
+	if err != nil {
+		return LoadBalancerCreateResult{}, resp, err
+	}
+	return LoadBalancerCreateResult{
+		LoadBalancer: loadBalancerFromSchema(respBody.LoadBalancer),
+		Action:       hcloud.ActionFromSchema(respBody.Action),
+	}, resp, nil
+}
+
+func (c *loadBalancerClient) Update(ctx context.Context, loadBalancer *LoadBalancer, opts LoadBalancerUpdateOpts) (*LoadBalancer, *Response, error) {
+	reqBody := loadBalancerUpdateRequest{Name: opts.Name}
+	if opts.Labels != nil {
+		reqBody.Labels = &opts.Labels
+	}
+	var respBody loadBalancerGetResponse
+	resp, err := do(ctx, c.client, "PUT", fmt.Sprintf("/load_balancers/%d", loadBalancer.ID), reqBody, &respBody)
+	if err != nil {
+		return nil, resp, err
+	}
+	return loadBalancerFromSchema(respBody.LoadBalancer), resp, nil
+}
+
+func (c *loadBalancerClient) Delete(ctx context.Context, loadBalancer *LoadBalancer) (*Response, error) {
+	return do(ctx, c.client, "DELETE", fmt.Sprintf("/load_balancers/%d", loadBalancer.ID), nil, nil)
+}
+
+func (c *loadBalancerClient) ChangeType(ctx context.Context, loadBalancer *LoadBalancer, loadBalancerType *LoadBalancerType) (*Action, *Response, error) {
+	return c.action(ctx, loadBalancer, "change_type", loadBalancerChangeTypeRequest{LoadBalancerType: loadBalancerType.Name})
+}
+
+func (c *loadBalancerClient) ChangeAlgorithm(ctx context.Context, loadBalancer *LoadBalancer, algorithm LoadBalancerAlgorithmType) (*Action, *Response, error) {
+	return c.action(ctx, loadBalancer, "change_algorithm", loadBalancerAlgorithmSchema{Type: string(algorithm)})
+}
+
+func (c *loadBalancerClient) AttachToNetwork(ctx context.Context, loadBalancer *LoadBalancer, opts LoadBalancerAttachToNetworkOpts) (*Action, *Response, error) {
+	reqBody := loadBalancerAttachToNetworkRequest{Network: opts.Network.ID}
+	if opts.IP != nil {
+		reqBody.IP = opts.IP.String()
+	}
+	return c.action(ctx, loadBalancer, "attach_to_network", reqBody)
+}
+
+func (c *loadBalancerClient) DetachFromNetwork(ctx context.Context, loadBalancer *LoadBalancer, network *Network) (*Action, *Response, error) {
+	return c.action(ctx, loadBalancer, "detach_from_network", loadBalancerDetachFromNetworkRequest{Network: network.ID})
+}
+
+func (c *loadBalancerClient) AddService(ctx context.Context, loadBalancer *LoadBalancer, service LoadBalancerService) (*Action, *Response, error) {
+	return c.action(ctx, loadBalancer, "add_service", loadBalancerServiceToSchema(service))
+}
+
+func (c *loadBalancerClient) UpdateService(ctx context.Context, loadBalancer *LoadBalancer, service LoadBalancerService) (*Action, *Response, error) {
+	return c.action(ctx, loadBalancer, "update_service", loadBalancerServiceToSchema(service))
+}
+
+func (c *loadBalancerClient) DeleteService(ctx context.Context, loadBalancer *LoadBalancer, listenPort int) (*Action, *Response, error) {
+	return c.action(ctx, loadBalancer, "delete_service", loadBalancerDeleteServiceRequest{ListenPort: listenPort})
+}
+
+func (c *loadBalancerClient) AddTarget(ctx context.Context, loadBalancer *LoadBalancer, target LoadBalancerTarget) (*Action, *Response, error) {
+	return c.action(ctx, loadBalancer, "add_target", loadBalancerTargetToSchema(target))
+}
+
+func (c *loadBalancerClient) RemoveTarget(ctx context.Context, loadBalancer *LoadBalancer, target LoadBalancerTarget) (*Action, *Response, error) {
+	s := loadBalancerTargetToSchema(target)
+	s.UsePrivateIP = nil
+	return c.action(ctx, loadBalancer, "remove_target", s)
+}
+
+func (c *loadBalancerClient) action(ctx context.Context, loadBalancer *LoadBalancer, action string, reqBody interface{}) (*Action, *Response, error) {
+	return postAction(ctx, c.client, fmt.Sprintf("/load_balancers/%d/actions/%s", loadBalancer.ID, action), reqBody)
+}
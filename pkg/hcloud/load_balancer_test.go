@@ -0,0 +1,109 @@
+package hcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadBalancerClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "GET /load_balancers/1":
+			fmt.Fprint(w, `{"load_balancer": {
+				"id": 1,
+				"name": "web",
+				"load_balancer_type": {"id": 1, "name": "lb11"},
+				"location": {"name": "fsn1"},
+				"algorithm": {"type": "round_robin"},
+				"public_net": {"enabled": true, "ipv4": {"ip": "203.0.113.1"}, "ipv6": {"ip": "2001:db8::1"}},
+				"private_net": [{"network": 4, "ip": "10.0.0.2"}],
+				"services": [{
+					"protocol": "http", "listen_port": 80, "destination_port": 8080, "proxyprotocol": false,
+					"http": {"cookie_name": "HCLBSTICKY", "cookie_lifetime": 300, "certificates": [], "redirect_http": false, "sticky_sessions": true},
+					"health_check": {"protocol": "http", "port": 8080, "interval": 15, "timeout": 10, "retries": 3,
+						"http": {"domain": null, "path": "/", "response": "", "status_codes": ["2??"], "tls": false}}
+				}],
+				"targets": [{
+					"type": "label_selector", "label_selector": {"selector": "role=web"}, "use_private_ip": true,
+					"targets": [{"type": "server", "server": {"id": 42}, "use_private_ip": true,
+						"health_status": [{"listen_port": 80, "status": "healthy"}]}]
+				}]
+			}}`)
+		case "POST /load_balancers/1/actions/add_service":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, map[string]interface{}{
+				"protocol":         "tcp",
+				"listen_port":      float64(22),
+				"destination_port": float64(2222),
+				"proxyprotocol":    false,
+				"health_check": map[string]interface{}{
+					"protocol": "tcp",
+					"port":     float64(2222),
+					"interval": float64(15),
+					"timeout":  float64(10),
+					"retries":  float64(3),
+				},
+			}, body)
+			fmt.Fprint(w, `{"action": {"id": 1, "command": "add_service", "status": "running"}}`)
+		case "POST /load_balancers/1/actions/remove_target":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, map[string]interface{}{
+				"type":           "label_selector",
+				"label_selector": map[string]interface{}{"selector": "role=web"},
+			}, body)
+			fmt.Fprint(w, `{"action": {"id": 2, "command": "remove_target", "status": "running"}}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL))
+	ctx := context.Background()
+
+	lb, _, err := client.LoadBalancer.GetByID(ctx, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "lb11", lb.LoadBalancerType.Name)
+		assert.Equal(t, "fsn1", lb.Location.Name)
+		assert.Equal(t, "203.0.113.1", lb.PublicNet.IPv4.String())
+		assert.Equal(t, 4, lb.PrivateNet[0].Network.ID)
+		assert.Equal(t, 300*time.Second, lb.Services[0].HTTP.CookieLifetime)
+		assert.True(t, lb.Services[0].HTTP.StickySessions)
+		assert.Equal(t, 15*time.Second, lb.Services[0].HealthCheck.Interval)
+		assert.Equal(t, []string{"2??"}, lb.Services[0].HealthCheck.HTTP.StatusCodes)
+		assert.Equal(t, "role=web", lb.Targets[0].LabelSelector)
+		assert.Equal(t, 42, lb.Targets[0].Targets[0].Server.ID)
+		assert.Equal(t, LoadBalancerTargetHealthStatusStatusHealthy, lb.Targets[0].Targets[0].HealthStatus[0].Status)
+	}
+
+	action, _, err := client.LoadBalancer.AddService(ctx, lb, LoadBalancerService{
+		Protocol:        LoadBalancerServiceProtocolTCP,
+		ListenPort:      22,
+		DestinationPort: 2222,
+		HealthCheck: LoadBalancerServiceHealthCheck{
+			Protocol: LoadBalancerServiceProtocolTCP,
+			Port:     2222,
+			Interval: 15 * time.Second,
+			Timeout:  10 * time.Second,
+			Retries:  3,
+		},
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, action.ID)
+	}
+
+	action, _, err = client.LoadBalancer.RemoveTarget(ctx, lb, lb.Targets[0])
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, action.ID)
+	}
+}
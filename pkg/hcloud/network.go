@@ -0,0 +1,339 @@
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/hcloud"
+)
+
+// Network represents a private network in the Hetzner Cloud,
+// hcloud-go 1.7 does not support networks
+type Network struct {
+	ID         int
+	Name       string
+	IPRange    *net.IPNet
+	Subnets    []NetworkSubnet
+	Routes     []NetworkRoute
+	Servers    []*Server
+	Protection NetworkProtection
+	Labels     map[string]string
+	Created    time.Time
+}
+
+// NetworkProtection represents the protection level of a network
+type NetworkProtection struct {
+	Delete bool
+}
+
+// NetworkSubnetType specifies the type of a subnet
+type NetworkSubnetType string
+
+// Subnet types
+const (
+	NetworkSubnetTypeCloud   NetworkSubnetType = "cloud"
+	NetworkSubnetTypeServer  NetworkSubnetType = "server"
+	NetworkSubnetTypeVSwitch NetworkSubnetType = "vswitch"
+)
+
+// NetworkSubnet represents a subnet of a network
+type NetworkSubnet struct {
+	Type        NetworkSubnetType
+	IPRange     *net.IPNet
+	NetworkZone string
+	Gateway     net.IP
+}
+
+// NetworkRoute represents a route of a network
+type NetworkRoute struct {
+	Destination *net.IPNet
+	Gateway     net.IP
+}
+
+// NetworkListOpts specifies options for listing networks
+type NetworkListOpts struct {
+	ListOpts
+	Name string
+}
+
+// NetworkCreateOpts specifies parameters for creating a network
+type NetworkCreateOpts struct {
+	Name    string
+	IPRange *net.IPNet
+	Subnets []NetworkSubnet
+	Routes  []NetworkRoute
+	Labels  map[string]string
+}
+
+// NetworkUpdateOpts specifies parameters for updating a network
+type NetworkUpdateOpts struct {
+	Name   string
+	Labels map[string]string
+}
+
+// NetworkClient is a client for the networks API
+type NetworkClient interface {
+	GetByID(ctx context.Context, id int) (*Network, *Response, error)
+	GetByName(ctx context.Context, name string) (*Network, *Response, error)
+	Get(ctx context.Context, idOrName string) (*Network, *Response, error)
+	List(ctx context.Context, opts NetworkListOpts) ([]*Network, *Response, error)
+	All(ctx context.Context) ([]*Network, error)
+	Create(ctx context.Context, opts NetworkCreateOpts) (*Network, *Response, error)
+	Update(ctx context.Context, network *Network, opts NetworkUpdateOpts) (*Network, *Response, error)
+	Delete(ctx context.Context, network *Network) (*Response, error)
+	ChangeIPRange(ctx context.Context, network *Network, ipRange *net.IPNet) (*Action, *Response, error)
+	AddSubnet(ctx context.Context, network *Network, subnet NetworkSubnet) (*Action, *Response, error)
+	DeleteSubnet(ctx context.Context, network *Network, subnet NetworkSubnet) (*Action, *Response, error)
+	AddRoute(ctx context.Context, network *Network, route NetworkRoute) (*Action, *Response, error)
+	DeleteRoute(ctx context.Context, network *Network, route NetworkRoute) (*Action, *Response, error)
+}
+
+type networkSchema struct {
+	ID         int                   `json:"id"`
+	Name       string                `json:"name"`
+	IPRange    string                `json:"ip_range"`
+	Subnets    []networkSubnetSchema `json:"subnets"`
+	Routes     []networkRouteSchema  `json:"routes"`
+	Servers    []int                 `json:"servers"`
+	Protection struct {
+		Delete bool `json:"delete"`
+	} `json:"protection"`
+	Labels  map[string]string `json:"labels"`
+	Created time.Time         `json:"created"`
+}
+
+type networkSubnetSchema struct {
+	Type        string `json:"type"`
+	IPRange     string `json:"ip_range,omitempty"`
+	NetworkZone string `json:"network_zone"`
+	Gateway     string `json:"gateway,omitempty"`
+}
+
+type networkRouteSchema struct {
+	Destination string `json:"destination"`
+	Gateway     string `json:"gateway"`
+}
+
+type networkGetResponse struct {
+	Network networkSchema `json:"network"`
+}
+
+type networkListResponse struct {
+	Networks []networkSchema `json:"networks"`
+}
+
+type networkCreateRequest struct {
+	Name    string                `json:"name"`
+	IPRange string                `json:"ip_range"`
+	Subnets []networkSubnetSchema `json:"subnets,omitempty"`
+	Routes  []networkRouteSchema  `json:"routes,omitempty"`
+	Labels  *map[string]string    `json:"labels,omitempty"`
+}
+
+type networkUpdateRequest struct {
+	Name   string             `json:"name,omitempty"`
+	Labels *map[string]string `json:"labels,omitempty"`
+}
+
+type networkChangeIPRangeRequest struct {
+	IPRange string `json:"ip_range"`
+}
+
+type networkDeleteSubnetRequest struct {
+	IPRange string `json:"ip_range"`
+}
+
+func networkFromSchema(s networkSchema) *Network {
+	n := &Network{
+		ID:         s.ID,
+		Name:       s.Name,
+		IPRange:    parseCIDR(s.IPRange),
+		Subnets:    []NetworkSubnet{},
+		Routes:     []NetworkRoute{},
+		Servers:    []*Server{},
+		Protection: NetworkProtection{Delete: s.Protection.Delete},
+		Labels:     s.Labels,
+		Created:    s.Created,
+	}
+	for _, subnet := range s.Subnets {
+		n.Subnets = append(n.Subnets, NetworkSubnet{
+			Type:        NetworkSubnetType(subnet.Type),
+			IPRange:     parseCIDR(subnet.IPRange),
+			NetworkZone: subnet.NetworkZone,
+			Gateway:     net.ParseIP(subnet.Gateway),
+		})
+	}
+	for _, route := range s.Routes {
+		n.Routes = append(n.Routes, NetworkRoute{
+			Destination: parseCIDR(route.Destination),
+			Gateway:     net.ParseIP(route.Gateway),
+		})
+	}
+	for _, id := range s.Servers {
+		n.Servers = append(n.Servers, &Server{ID: id})
+	}
+	return n
+}
+
+func networkSubnetToSchema(subnet NetworkSubnet) networkSubnetSchema {
+	s := networkSubnetSchema{
+		Type:        string(subnet.Type),
+		NetworkZone: subnet.NetworkZone,
+	}
+	if subnet.IPRange != nil {
+		s.IPRange = subnet.IPRange.String()
+	}
+	return s
+}
+
+func networkRouteToSchema(route NetworkRoute) networkRouteSchema {
+	return networkRouteSchema{
+		Destination: route.Destination.String(),
+		Gateway:     route.Gateway.String(),
+	}
+}
+
+// parseCIDR returns the network of a CIDR or nil if it is invalid
+func parseCIDR(s string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil
+	}
+	return ipNet
+}
+
+type networkClient struct {
+	client *hcloud.Client
+}
+
+func (c *networkClient) GetByID(ctx context.Context, id int) (*Network, *Response, error) {
+	var body networkGetResponse
+	resp, err := do(ctx, c.client, "GET", fmt.Sprintf("/networks/%d", id), nil, &body)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, resp, nil
+		}
+		return nil, resp, err
+	}
+	return networkFromSchema(body.Network), resp, nil
+}
+
+func (c *networkClient) GetByName(ctx context.Context, name string) (*Network, *Response, error) {
+	networks, resp, err := c.List(ctx, NetworkListOpts{Name: name})
+	if len(networks) == 0 {
+		return nil, resp, err
+	}
+	return networks[0], resp, err
+}
+
+func (c *networkClient) Get(ctx context.Context, idOrName string) (*Network, *Response, error) {
+	if id, err := strconv.Atoi(idOrName); err == nil {
+		return c.GetByID(ctx, id)
+	}
+	return c.GetByName(ctx, idOrName)
+}
+
+func (c *networkClient) List(ctx context.Context, opts NetworkListOpts) ([]*Network, *Response, error) {
+	values := valuesForListOpts(opts.ListOpts)
+	if opts.Name != "" {
+		values.Set("name", opts.Name)
+	}
+	var body networkListResponse
+	resp, err := do(ctx, c.client, "GET", "/networks?"+values.Encode(), nil, &body)
+	if err != nil {
+		return nil, resp, err
+	}
+	networks := make([]*Network, 0, len(body.Networks))
+	for _, n := range body.Networks {
+		networks = append(networks, networkFromSchema(n))
+	}
+	return networks, resp, nil
+}
+
+func (c *networkClient) All(ctx context.Context) ([]*Network, error) {
+	allNetworks := []*Network{}
+	opts := NetworkListOpts{ListOpts: ListOpts{PerPage: 50}}
+	err := all(func(page int) (*Response, error) {
+		opts.Page = page
+		networks, resp, err := c.List(ctx, opts)
+		if err != nil {
+			return resp, err
+		}
+		allNetworks = append(allNetworks, networks...)
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allNetworks, nil
+}
+
+func (c *networkClient) Create(ctx context.Context, opts NetworkCreateOpts) (*Network, *Response, error) {
+	if opts.IPRange == nil {
+		return nil, nil, fmt.Errorf("missing IP range")
+	}
+	reqBody := networkCreateRequest{
+		Name:    opts.Name,
+		IPRange: opts.IPRange.String(),
+	}
+	for _, subnet := range opts.Subnets {
+		reqBody.Subnets = append(reqBody.Subnets, networkSubnetToSchema(subnet))
+	}
+	for _, route := range opts.Routes {
+		reqBody.Routes = append(reqBody.Routes, networkRouteToSchema(route))
+	}
+	if opts.Labels != nil {
+		reqBody.Labels = &opts.Labels
+	}
+
+	var respBody networkGetResponse
+	resp, err := do(ctx, c.client, "POST", "/networks", reqBody, &respBody)
+	if err != nil {
+		return nil, resp, err
+	}
+	return networkFromSchema(respBody.Network), resp, nil
+}
+
+func (c *networkClient) Update(ctx context.Context, network *Network, opts NetworkUpdateOpts) (*Network, *Response, error) {
+	reqBody := networkUpdateRequest{Name: opts.Name}
+	if opts.Labels != nil {
+		reqBody.Labels = &opts.Labels
+	}
+	var respBody networkGetResponse
+	resp, err := do(ctx, c.client, "PUT", fmt.Sprintf("/networks/%d", network.ID), reqBody, &respBody)
+	if err != nil {
+		return nil, resp, err
+	}
+	return networkFromSchema(respBody.Network), resp, nil
+}
+
+func (c *networkClient) Delete(ctx context.Context, network *Network) (*Response, error) {
+	return do(ctx, c.client, "DELETE", fmt.Sprintf("/networks/%d", network.ID), nil, nil)
+}
+
+func (c *networkClient) ChangeIPRange(ctx context.Context, network *Network, ipRange *net.IPNet) (*Action, *Response, error) {
+	return c.action(ctx, network, "change_ip_range", networkChangeIPRangeRequest{IPRange: ipRange.String()})
+}
+
+func (c *networkClient) AddSubnet(ctx context.Context, network *Network, subnet NetworkSubnet) (*Action, *Response, error) {
+	return c.action(ctx, network, "add_subnet", networkSubnetToSchema(subnet))
+}
+
+func (c *networkClient) DeleteSubnet(ctx context.Context, network *Network, subnet NetworkSubnet) (*Action, *Response, error) {
+	return c.action(ctx, network, "delete_subnet", networkDeleteSubnetRequest{IPRange: subnet.IPRange.String()})
+}
+
+func (c *networkClient) AddRoute(ctx context.Context, network *Network, route NetworkRoute) (*Action, *Response, error) {
+	return c.action(ctx, network, "add_route", networkRouteToSchema(route))
+}
+
+func (c *networkClient) DeleteRoute(ctx context.Context, network *Network, route NetworkRoute) (*Action, *Response, error) {
+	return c.action(ctx, network, "delete_route", networkRouteToSchema(route))
+}
+
+func (c *networkClient) action(ctx context.Context, network *Network, action string, reqBody interface{}) (*Action, *Response, error) {
+	return postAction(ctx, c.client, fmt.Sprintf("/networks/%d/actions/%s", network.ID, action), reqBody)
+}
@@ -0,0 +1,219 @@
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/hcloud"
+	"github.com/hetznercloud/hcloud-go/hcloud/schema"
+)
+
+// PlacementGroup represents a placement group in the Hetzner Cloud,
+// hcloud-go 1.7 does not support placement groups
+type PlacementGroup struct {
+	ID      int
+	Name    string
+	Type    PlacementGroupType
+	Servers []int
+	Labels  map[string]string
+	Created time.Time
+}
+
+// PlacementGroupType specifies how the servers of a placement group are placed
+type PlacementGroupType string
+
+// Placement group types
+const (
+	PlacementGroupTypeSpread PlacementGroupType = "spread"
+)
+
+// PlacementGroupListOpts specifies options for listing placement groups
+type PlacementGroupListOpts struct {
+	ListOpts
+	Name string
+}
+
+// PlacementGroupCreateOpts specifies parameters for creating a placement group
+type PlacementGroupCreateOpts struct {
+	Name   string
+	Type   PlacementGroupType
+	Labels map[string]string
+}
+
+// PlacementGroupCreateResult is the result of creating a placement group
+type PlacementGroupCreateResult struct {
+	PlacementGroup *PlacementGroup
+	Action         *Action
+}
+
+// PlacementGroupUpdateOpts specifies parameters for updating a placement group
+type PlacementGroupUpdateOpts struct {
+	Name   string
+	Labels map[string]string
+}
+
+// PlacementGroupClient is a client for the placement groups API
+type PlacementGroupClient interface {
+	GetByID(ctx context.Context, id int) (*PlacementGroup, *Response, error)
+	GetByName(ctx context.Context, name string) (*PlacementGroup, *Response, error)
+	Get(ctx context.Context, idOrName string) (*PlacementGroup, *Response, error)
+	List(ctx context.Context, opts PlacementGroupListOpts) ([]*PlacementGroup, *Response, error)
+	All(ctx context.Context) ([]*PlacementGroup, error)
+	Create(ctx context.Context, opts PlacementGroupCreateOpts) (PlacementGroupCreateResult, *Response, error)
+	Update(ctx context.Context, placementGroup *PlacementGroup, opts PlacementGroupUpdateOpts) (*PlacementGroup, *Response, error)
+	Delete(ctx context.Context, placementGroup *PlacementGroup) (*Response, error)
+}
+
+type placementGroupSchema struct {
+	ID      int               `json:"id"`
+	Name    string            `json:"name"`
+	Type    string            `json:"type"`
+	Servers []int             `json:"servers"`
+	Labels  map[string]string `json:"labels"`
+	Created time.Time         `json:"created"`
+}
+
+type placementGroupGetResponse struct {
+	PlacementGroup placementGroupSchema `json:"placement_group"`
+}
+
+type placementGroupListResponse struct {
+	PlacementGroups []placementGroupSchema `json:"placement_groups"`
+}
+
+type placementGroupCreateRequest struct {
+	Name   string             `json:"name"`
+	Type   string             `json:"type"`
+	Labels *map[string]string `json:"labels,omitempty"`
+}
+
+type placementGroupCreateResponse struct {
+	PlacementGroup placementGroupSchema `json:"placement_group"`
+	Action         *schema.Action       `json:"action"`
+}
+
+type placementGroupUpdateRequest struct {
+	Name   string             `json:"name,omitempty"`
+	Labels *map[string]string `json:"labels,omitempty"`
+}
+
+func placementGroupFromSchema(s placementGroupSchema) *PlacementGroup {
+	p := &PlacementGroup{
+		ID:      s.ID,
+		Name:    s.Name,
+		Type:    PlacementGroupType(s.Type),
+		Servers: s.Servers,
+		Labels:  s.Labels,
+		Created: s.Created,
+	}
+	if p.Servers == nil {
+		p.Servers = []int{}
+	}
+	return p
+}
+
+type placementGroupClient struct {
+	client *hcloud.Client
+}
+
+func (c *placementGroupClient) GetByID(ctx context.Context, id int) (*PlacementGroup, *Response, error) {
+	var body placementGroupGetResponse
+	resp, err := do(ctx, c.client, "GET", fmt.Sprintf("/placement_groups/%d", id), nil, &body)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, resp, nil
+		}
+		return nil, resp, err
+	}
+	return placementGroupFromSchema(body.PlacementGroup), resp, nil
+}
+
+func (c *placementGroupClient) GetByName(ctx context.Context, name string) (*PlacementGroup, *Response, error) {
+	placementGroups, resp, err := c.List(ctx, PlacementGroupListOpts{Name: name})
+	if len(placementGroups) == 0 {
+		return nil, resp, err
+	}
+	return placementGroups[0], resp, err
+}
+
+func (c *placementGroupClient) Get(ctx context.Context, idOrName string) (*PlacementGroup, *Response, error) {
+	if id, err := strconv.Atoi(idOrName); err == nil {
+		return c.GetByID(ctx, id)
+	}
+	return c.GetByName(ctx, idOrName)
+}
+
+func (c *placementGroupClient) List(ctx context.Context, opts PlacementGroupListOpts) ([]*PlacementGroup, *Response, error) {
+	values := valuesForListOpts(opts.ListOpts)
+	if opts.Name != "" {
+		values.Set("name", opts.Name)
+	}
+	var body placementGroupListResponse
+	resp, err := do(ctx, c.client, "GET", "/placement_groups?"+values.Encode(), nil, &body)
+	if err != nil {
+		return nil, resp, err
+	}
+	placementGroups := make([]*PlacementGroup, 0, len(body.PlacementGroups))
+	for _, p := range body.PlacementGroups {
+		placementGroups = append(placementGroups, placementGroupFromSchema(p))
+	}
+	return placementGroups, resp, nil
+}
+
+func (c *placementGroupClient) All(ctx context.Context) ([]*PlacementGroup, error) {
+	allPlacementGroups := []*PlacementGroup{}
+	opts := PlacementGroupListOpts{ListOpts: ListOpts{PerPage: 50}}
+	err := all(func(page int) (*Response, error) {
+		opts.Page = page
+		placementGroups, resp, err := c.List(ctx, opts)
+		if err != nil {
+			return resp, err
+		}
+		allPlacementGroups = append(allPlacementGroups, placementGroups...)
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allPlacementGroups, nil
+}
+
+func (c *placementGroupClient) Create(ctx context.Context, opts PlacementGroupCreateOpts) (PlacementGroupCreateResult, *Response, error) {
+	reqBody := placementGroupCreateRequest{Name: opts.Name, Type: string(opts.Type)}
+	if reqBody.Type == "" {
+		reqBody.Type = string(PlacementGroupTypeSpread)
+	}
+	if opts.Labels != nil {
+		reqBody.Labels = &opts.Labels
+	}
+
+	var respBody placementGroupCreateResponse
+	resp, err := do(ctx, c.client, "POST", "/placement_groups", reqBody, &respBody)
+	if err != nil {
+		return PlacementGroupCreateResult{}, resp, err
+	}
+	result := PlacementGroupCreateResult{PlacementGroup: placementGroupFromSchema(respBody.PlacementGroup)}
+	if respBody.Action != nil {
+		result.Action = hcloud.ActionFromSchema(*respBody.Action)
+	}
+	return result, resp, nil
+}
+
+func (c *placementGroupClient) Update(ctx context.Context, placementGroup *PlacementGroup, opts PlacementGroupUpdateOpts) (*PlacementGroup, *Response, error) {
+	reqBody := placementGroupUpdateRequest{Name: opts.Name}
+	if opts.Labels != nil {
+		reqBody.Labels = &opts.Labels
+	}
+	var respBody placementGroupGetResponse
+	resp, err := do(ctx, c.client, "PUT", fmt.Sprintf("/placement_groups/%d", placementGroup.ID), reqBody, &respBody)
+	if err != nil {
+		return nil, resp, err
+	}
+	return placementGroupFromSchema(respBody.PlacementGroup), resp, nil
+}
+
+func (c *placementGroupClient) Delete(ctx context.Context, placementGroup *PlacementGroup) (*Response, error) {
+	return do(ctx, c.client, "DELETE", fmt.Sprintf("/placement_groups/%d", placementGroup.ID), nil, nil)
+}
@@ -0,0 +1,64 @@
+package hcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlacementGroupClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "GET /placement_groups":
+			assert.Equal(t, "db", r.URL.Query().Get("name"))
+			fmt.Fprint(w, `{"placement_groups": [{"id": 1, "name": "db", "type": "spread", "servers": [42], "labels": {}}]}`)
+		case "POST /servers":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, float64(1), body["placement_group"])
+			assert.Equal(t, "cx11", body["server_type"])
+			assert.Equal(t, "fsn1", body["location"])
+			fmt.Fprint(w, `{"server": {"id": 43, "name": "db2"}, "action": {"id": 1, "command": "create_server", "status": "running"}, "root_password": null}`)
+		case "POST /servers/43/actions/add_to_placement_group":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, map[string]interface{}{"placement_group": float64(1)}, body)
+			fmt.Fprint(w, `{"action": {"id": 2, "command": "add_to_placement_group", "status": "running"}}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL))
+	ctx := context.Background()
+
+	placementGroup, _, err := client.PlacementGroup.Get(ctx, "db")
+	if assert.NoError(t, err) {
+		assert.Equal(t, PlacementGroupTypeSpread, placementGroup.Type)
+		assert.Equal(t, []int{42}, placementGroup.Servers)
+	}
+
+	result, _, err := client.Server.Create(ctx, ServerCreateOpts{
+		Name:           "db2",
+		ServerType:     &ServerType{Name: "cx11"},
+		Image:          &Image{Name: "debian-9"},
+		Location:       &Location{Name: "fsn1"},
+		PlacementGroup: placementGroup,
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 43, result.Server.ID)
+		assert.Equal(t, 1, result.Action.ID)
+	}
+
+	action, _, err := client.Server.AddToPlacementGroup(ctx, result.Server, placementGroup)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, action.ID)
+	}
+}
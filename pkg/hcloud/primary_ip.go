@@ -0,0 +1,302 @@
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/hcloud"
+	"github.com/hetznercloud/hcloud-go/hcloud/schema"
+)
+
+// PrimaryIP represents a primary IP in the Hetzner Cloud,
+// hcloud-go 1.7 does not support primary IPs
+type PrimaryIP struct {
+	ID         int
+	Name       string
+	IP         net.IP
+	Network    *net.IPNet
+	Type       PrimaryIPType
+	AssigneeID int
+	AutoDelete bool
+	Blocked    bool
+	Datacenter *Datacenter
+	Protection PrimaryIPProtection
+	Labels     map[string]string
+	Created    time.Time
+}
+
+// PrimaryIPType specifies the IP version of a primary IP
+type PrimaryIPType string
+
+// Primary IP types
+const (
+	PrimaryIPTypeIPv4 PrimaryIPType = "ipv4"
+	PrimaryIPTypeIPv6 PrimaryIPType = "ipv6"
+)
+
+// PrimaryIPProtection represents the protection level of a primary IP
+type PrimaryIPProtection struct {
+	Delete bool
+}
+
+// PrimaryIPListOpts specifies options for listing primary IPs
+type PrimaryIPListOpts struct {
+	ListOpts
+	Name string
+}
+
+// PrimaryIPCreateOpts specifies parameters for creating a primary IP,
+// either Datacenter or Assignee must be set
+type PrimaryIPCreateOpts struct {
+	Name       string
+	Type       PrimaryIPType
+	Datacenter *Datacenter
+	Assignee   *Server
+	AutoDelete bool
+	Labels     map[string]string
+}
+
+// PrimaryIPCreateResult is the result of creating a primary IP
+type PrimaryIPCreateResult struct {
+	PrimaryIP *PrimaryIP
+	Action    *Action
+}
+
+// PrimaryIPUpdateOpts specifies parameters for updating a primary IP
+type PrimaryIPUpdateOpts struct {
+	Name       string
+	AutoDelete *bool
+	Labels     map[string]string
+}
+
+// PrimaryIPClient is a client for the primary IPs API
+type PrimaryIPClient interface {
+	GetByID(ctx context.Context, id int) (*PrimaryIP, *Response, error)
+	GetByName(ctx context.Context, name string) (*PrimaryIP, *Response, error)
+	Get(ctx context.Context, idOrName string) (*PrimaryIP, *Response, error)
+	List(ctx context.Context, opts PrimaryIPListOpts) ([]*PrimaryIP, *Response, error)
+	All(ctx context.Context) ([]*PrimaryIP, error)
+	Create(ctx context.Context, opts PrimaryIPCreateOpts) (PrimaryIPCreateResult, *Response, error)
+	Update(ctx context.Context, primaryIP *PrimaryIP, opts PrimaryIPUpdateOpts) (*PrimaryIP, *Response, error)
+	Delete(ctx context.Context, primaryIP *PrimaryIP) (*Response, error)
+	// Assign assigns a primary IP to a powered off server
+	Assign(ctx context.Context, primaryIP *PrimaryIP, server *Server) (*Action, *Response, error)
+	// Unassign unassigns a primary IP from a powered off server
+	Unassign(ctx context.Context, primaryIP *PrimaryIP) (*Action, *Response, error)
+	ChangeProtection(ctx context.Context, primaryIP *PrimaryIP, protection PrimaryIPProtection) (*Action, *Response, error)
+}
+
+type primaryIPSchema struct {
+	ID           int               `json:"id"`
+	Name         string            `json:"name"`
+	IP           string            `json:"ip"`
+	Type         string            `json:"type"`
+	AssigneeID   *int              `json:"assignee_id"`
+	AssigneeType string            `json:"assignee_type"`
+	AutoDelete   bool              `json:"auto_delete"`
+	Blocked      bool              `json:"blocked"`
+	Datacenter   schema.Datacenter `json:"datacenter"`
+	Protection   struct {
+		Delete bool `json:"delete"`
+	} `json:"protection"`
+	Labels  map[string]string `json:"labels"`
+	Created time.Time         `json:"created"`
+}
+
+type primaryIPGetResponse struct {
+	PrimaryIP primaryIPSchema `json:"primary_ip"`
+}
+
+type primaryIPListResponse struct {
+	PrimaryIPs []primaryIPSchema `json:"primary_ips"`
+}
+
+type primaryIPCreateRequest struct {
+	Name         string             `json:"name"`
+	Type         string             `json:"type"`
+	AssigneeType string             `json:"assignee_type"`
+	AssigneeID   int                `json:"assignee_id,omitempty"`
+	Datacenter   string             `json:"datacenter,omitempty"`
+	AutoDelete   bool               `json:"auto_delete"`
+	Labels       *map[string]string `json:"labels,omitempty"`
+}
+
+type primaryIPCreateResponse struct {
+	PrimaryIP primaryIPSchema `json:"primary_ip"`
+	Action    *schema.Action  `json:"action"`
+}
+
+type primaryIPUpdateRequest struct {
+	Name       string             `json:"name,omitempty"`
+	AutoDelete *bool              `json:"auto_delete,omitempty"`
+	Labels     *map[string]string `json:"labels,omitempty"`
+}
+
+type primaryIPAssignRequest struct {
+	AssigneeID   int    `json:"assignee_id"`
+	AssigneeType string `json:"assignee_type"`
+}
+
+type primaryIPChangeProtectionRequest struct {
+	Delete bool `json:"delete"`
+}
+
+func primaryIPFromSchema(s primaryIPSchema) *PrimaryIP {
+	p := &PrimaryIP{
+		ID:         s.ID,
+		Name:       s.Name,
+		Type:       PrimaryIPType(s.Type),
+		AutoDelete: s.AutoDelete,
+		Blocked:    s.Blocked,
+		Datacenter: hcloud.DatacenterFromSchema(s.Datacenter),
+		Protection: PrimaryIPProtection{Delete: s.Protection.Delete},
+		Labels:     s.Labels,
+		Created:    s.Created,
+	}
+	if s.AssigneeID != nil {
+		p.AssigneeID = *s.AssigneeID
+	}
+	if p.Type == PrimaryIPTypeIPv6 {
+		p.IP, p.Network, _ = net.ParseCIDR(s.IP)
+	} else {
+		p.IP = net.ParseIP(s.IP)
+	}
+	return p
+}
+
+type primaryIPClient struct {
+	client *hcloud.Client
+}
+
+func (c *primaryIPClient) GetByID(ctx context.Context, id int) (*PrimaryIP, *Response, error) {
+	var body primaryIPGetResponse
+	resp, err := do(ctx, c.client, "GET", fmt.Sprintf("/primary_ips/%d", id), nil, &body)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, resp, nil
+		}
+		return nil, resp, err
+	}
+	return primaryIPFromSchema(body.PrimaryIP), resp, nil
+}
+
+func (c *primaryIPClient) GetByName(ctx context.Context, name string) (*PrimaryIP, *Response, error) {
+	primaryIPs, resp, err := c.List(ctx, PrimaryIPListOpts{Name: name})
+	if len(primaryIPs) == 0 {
+		return nil, resp, err
+	}
+	return primaryIPs[0], resp, err
+}
+
+func (c *primaryIPClient) Get(ctx context.Context, idOrName string) (*PrimaryIP, *Response, error) {
+	if id, err := strconv.Atoi(idOrName); err == nil {
+		return c.GetByID(ctx, id)
+	}
+	return c.GetByName(ctx, idOrName)
+}
+
+func (c *primaryIPClient) List(ctx context.Context, opts PrimaryIPListOpts) ([]*PrimaryIP, *Response, error) {
+	values := valuesForListOpts(opts.ListOpts)
+	if opts.Name != "" {
+		values.Set("name", opts.Name)
+	}
+	var body primaryIPListResponse
+	resp, err := do(ctx, c.client, "GET", "/primary_ips?"+values.Encode(), nil, &body)
+	if err != nil {
+		return nil, resp, err
+	}
+	primaryIPs := make([]*PrimaryIP, 0, len(body.PrimaryIPs))
+	for _, p := range body.PrimaryIPs {
+		primaryIPs = append(primaryIPs, primaryIPFromSchema(p))
+	}
+	return primaryIPs, resp, nil
+}
+
+func (c *primaryIPClient) All(ctx context.Context) ([]*PrimaryIP, error) {
+	allPrimaryIPs := []*PrimaryIP{}
+	opts := PrimaryIPListOpts{ListOpts: ListOpts{PerPage: 50}}
+	err := all(func(page int) (*Response, error) {
+		opts.Page = page
+		primaryIPs, resp, err := c.List(ctx, opts)
+		if err != nil {
+			return resp, err
+		}
+		allPrimaryIPs = append(allPrimaryIPs, primaryIPs...)
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allPrimaryIPs, nil
+}
+
+func (c *primaryIPClient) Create(ctx context.Context, opts PrimaryIPCreateOpts) (PrimaryIPCreateResult, *Response, error) {
+	if (opts.Datacenter == nil) == (opts.Assignee == nil) {
+		return PrimaryIPCreateResult{}, nil, fmt.Errorf("either datacenter or assignee is required")
+	}
+	reqBody := primaryIPCreateRequest{
+		Name:         opts.Name,
+		Type:         string(opts.Type),
+		AssigneeType: "server",
+		AutoDelete:   opts.AutoDelete,
+	}
+	if opts.Assignee != nil {
+		reqBody.AssigneeID = opts.Assignee.ID
+	}
+	if opts.Datacenter != nil {
+		reqBody.Datacenter = idOrName(opts.Datacenter.ID, opts.Datacenter.Name)
+	}
+	if opts.Labels != nil {
+		reqBody.Labels = &opts.Labels
+	}
+
+	var respBody primaryIPCreateResponse
+	resp, err := do(ctx, c.client, "POST", "/primary_ips", reqBody, &respBody)
+	if err != nil {
+		return PrimaryIPCreateResult{}, resp, err
+	}
+	result := PrimaryIPCreateResult{PrimaryIP: primaryIPFromSchema(respBody.PrimaryIP)}
+	if respBody.Action != nil {
+		result.Action = hcloud.ActionFromSchema(*respBody.Action)
+	}
+	return result, resp, nil
+}
+
+func (c *primaryIPClient) Update(ctx context.Context, primaryIP *PrimaryIP, opts PrimaryIPUpdateOpts) (*PrimaryIP, *Response, error) {
+	reqBody := primaryIPUpdateRequest{Name: opts.Name, AutoDelete: opts.AutoDelete}
+	if opts.Labels != nil {
+		reqBody.Labels = &opts.Labels
+	}
+	var respBody primaryIPGetResponse
+	resp, err := do(ctx, c.client, "PUT", fmt.Sprintf("/primary_ips/%d", primaryIP.ID), reqBody, &respBody)
+	if err != nil {
+		return nil, resp, err
+	}
+	return primaryIPFromSchema(respBody.PrimaryIP), resp, nil
+}
+
+func (c *primaryIPClient) Delete(ctx context.Context, primaryIP *PrimaryIP) (*Response, error) {
+	return do(ctx, c.client, "DELETE", fmt.Sprintf("/primary_ips/%d", primaryIP.ID), nil, nil)
+}
+
+func (c *primaryIPClient) Assign(ctx context.Context, primaryIP *PrimaryIP, server *Server) (*Action, *Response, error) {
+	return c.action(ctx, primaryIP, "assign", primaryIPAssignRequest{
+		AssigneeID:   server.ID,
+		AssigneeType: "server",
+	})
+}
+
+func (c *primaryIPClient) Unassign(ctx context.Context, primaryIP *PrimaryIP) (*Action, *Response, error) {
+	return c.action(ctx, primaryIP, "unassign", nil)
+}
+
+func (c *primaryIPClient) ChangeProtection(ctx context.Context, primaryIP *PrimaryIP, protection PrimaryIPProtection) (*Action, *Response, error) {
+	return c.action(ctx, primaryIP, "change_protection", primaryIPChangeProtectionRequest{Delete: protection.Delete})
+}
+
+func (c *primaryIPClient) action(ctx context.Context, primaryIP *PrimaryIP, action string, reqBody interface{}) (*Action, *Response, error) {
+	return postAction(ctx, c.client, fmt.Sprintf("/primary_ips/%d/actions/%s", primaryIP.ID, action), reqBody)
+}
@@ -0,0 +1,74 @@
+package hcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrimaryIPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "GET /primary_ips/2":
+			fmt.Fprint(w, `{"primary_ip": {
+				"id": 2, "name": "web-v6", "ip": "2001:db8::/64", "type": "ipv6",
+				"assignee_id": null, "assignee_type": "server", "auto_delete": false, "blocked": false,
+				"datacenter": {"name": "fsn1-dc14", "location": {"name": "fsn1"}},
+				"protection": {"delete": true}, "labels": {}
+			}}`)
+		case "POST /primary_ips":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, map[string]interface{}{
+				"name":          "web",
+				"type":          "ipv4",
+				"assignee_type": "server",
+				"datacenter":    "fsn1-dc14",
+				"auto_delete":   false,
+			}, body)
+			fmt.Fprint(w, `{"primary_ip": {"id": 1, "name": "web", "ip": "203.0.113.1", "type": "ipv4", "assignee_id": null,
+				"datacenter": {"name": "fsn1-dc14"}}, "action": null}`)
+		case "POST /primary_ips/1/actions/assign":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, map[string]interface{}{"assignee_id": float64(42), "assignee_type": "server"}, body)
+			fmt.Fprint(w, `{"action": {"id": 3, "command": "assign_primary_ip", "status": "running"}}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL))
+	ctx := context.Background()
+
+	ipv6, _, err := client.PrimaryIP.GetByID(ctx, 2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, PrimaryIPTypeIPv6, ipv6.Type)
+		assert.Equal(t, "2001:db8::/64", ipv6.Network.String())
+		assert.Equal(t, 0, ipv6.AssigneeID)
+		assert.Equal(t, "fsn1", ipv6.Datacenter.Location.Name)
+		assert.True(t, ipv6.Protection.Delete)
+	}
+
+	result, _, err := client.PrimaryIP.Create(ctx, PrimaryIPCreateOpts{
+		Name:       "web",
+		Type:       PrimaryIPTypeIPv4,
+		Datacenter: &Datacenter{Name: "fsn1-dc14"},
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "203.0.113.1", result.PrimaryIP.IP.String())
+		assert.Nil(t, result.Action)
+	}
+
+	action, _, err := client.PrimaryIP.Assign(ctx, result.PrimaryIP, &Server{ID: 42})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, action.ID)
+	}
+}
@@ -0,0 +1,63 @@
+package hcloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/hetznercloud/hcloud-go/hcloud"
+	"github.com/hetznercloud/hcloud-go/hcloud/schema"
+)
+
+// IsNotFound checks if the error is a not_found error of the API
+func IsNotFound(err error) bool {
+	return hcloud.IsError(err, hcloud.ErrorCodeNotFound)
+}
+
+// do sends a request to the API for resources hcloud-go does not support,
+// reqBody is encoded as JSON if set and the response is decoded into respBody
+func do(ctx context.Context, client *hcloud.Client, method, path string, reqBody, respBody interface{}) (*Response, error) {
+	var body io.Reader
+	if reqBody != nil {
+		data, err := json.Marshal(reqBody)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := client.NewRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req, respBody)
+}
+
+// all calls list for every page until the last page is reached
+func all(list func(page int) (*Response, error)) error {
+	page := 1
+	for {
+		resp, err := list(page)
+		if err != nil {
+			return err
+		}
+		if resp == nil || resp.Meta.Pagination == nil || resp.Meta.Pagination.NextPage == 0 {
+			return nil
+		}
+		page = resp.Meta.Pagination.NextPage
+	}
+}
+
+type actionResponse struct {
+	Action schema.Action `json:"action"`
+}
+
+// postAction triggers an action of a resource and returns it
+func postAction(ctx context.Context, client *hcloud.Client, path string, reqBody interface{}) (*Action, *Response, error) {
+	var respBody actionResponse
+	resp, err := do(ctx, client, "POST", path, reqBody, &respBody)
+	if err != nil {
+		return nil, resp, err
+	}
+	return hcloud.ActionFromSchema(respBody.Action), resp, nil
+}
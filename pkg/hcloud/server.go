@@ -0,0 +1,148 @@
+package hcloud
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hetznercloud/hcloud-go/hcloud"
+	"github.com/hetznercloud/hcloud-go/hcloud/schema"
+)
+
+// ServerCreateOpts specifies parameters for creating a server,
+// extends hcloud.ServerCreateOpts with options hcloud-go 1.7 does not support
+type ServerCreateOpts struct {
+	Name             string
+	ServerType       *ServerType
+	Image            *Image
+	SSHKeys          []*SSHKey
+	Location         *Location
+	Datacenter       *Datacenter
+	UserData         string
+	StartAfterCreate *bool
+	PlacementGroup   *PlacementGroup
+	PublicNet        *ServerCreatePublicNet
+}
+
+// ServerCreatePublicNet specifies the public network of a new server,
+// the API creates new primary IPs if enabled and no primary IP is given
+type ServerCreatePublicNet struct {
+	EnableIPv4 bool
+	EnableIPv6 bool
+	IPv4       *PrimaryIP
+	IPv6       *PrimaryIP
+}
+
+// Validate checks if options are valid.
+func (o ServerCreateOpts) Validate() error {
+	return hcloud.ServerCreateOpts{
+		Name:             o.Name,
+		ServerType:       o.ServerType,
+		Image:            o.Image,
+		SSHKeys:          o.SSHKeys,
+		Location:         o.Location,
+		Datacenter:       o.Datacenter,
+		UserData:         o.UserData,
+		StartAfterCreate: o.StartAfterCreate,
+	}.Validate()
+}
+
+type serverCreateRequest struct {
+	schema.ServerCreateRequest
+	PlacementGroup int                           `json:"placement_group,omitempty"`
+	PublicNet      *serverCreatePublicNetRequest `json:"public_net,omitempty"`
+}
+
+type serverCreatePublicNetRequest struct {
+	EnableIPv4 bool `json:"enable_ipv4"`
+	EnableIPv6 bool `json:"enable_ipv6"`
+	IPv4       int  `json:"ipv4,omitempty"`
+	IPv6       int  `json:"ipv6,omitempty"`
+}
+
+type serverAddToPlacementGroupRequest struct {
+	PlacementGroup int `json:"placement_group"`
+}
+
+// serverClient extends hcloud.ServerClient with placement groups and primary IPs
+type serverClient struct {
+	*hcloud.ServerClient
+	client *hcloud.Client
+}
+
+// Create creates a new server.
+func (c *serverClient) Create(ctx context.Context, opts ServerCreateOpts) (ServerCreateResult, *Response, error) {
+	if err := opts.Validate(); err != nil {
+		return ServerCreateResult{}, nil, err
+	}
+
+	var reqBody serverCreateRequest
+	reqBody.Name = opts.Name
+	reqBody.UserData = opts.UserData
+	reqBody.StartAfterCreate = opts.StartAfterCreate
+	if opts.ServerType.ID != 0 {
+		reqBody.ServerType = opts.ServerType.ID
+	} else {
+		reqBody.ServerType = opts.ServerType.Name
+	}
+	if opts.Image.ID != 0 {
+		reqBody.Image = opts.Image.ID
+	} else {
+		reqBody.Image = opts.Image.Name
+	}
+	for _, sshKey := range opts.SSHKeys {
+		reqBody.SSHKeys = append(reqBody.SSHKeys, sshKey.ID)
+	}
+	if opts.Location != nil {
+		reqBody.Location = idOrName(opts.Location.ID, opts.Location.Name)
+	}
+	if opts.Datacenter != nil {
+		reqBody.Datacenter = idOrName(opts.Datacenter.ID, opts.Datacenter.Name)
+	}
+	if opts.PlacementGroup != nil {
+		reqBody.PlacementGroup = opts.PlacementGroup.ID
+	}
+	if opts.PublicNet != nil {
+		reqBody.PublicNet = &serverCreatePublicNetRequest{
+			EnableIPv4: opts.PublicNet.EnableIPv4,
+			EnableIPv6: opts.PublicNet.EnableIPv6,
+		}
+		if opts.PublicNet.IPv4 != nil {
+			reqBody.PublicNet.IPv4 = opts.PublicNet.IPv4.ID
+		}
+		if opts.PublicNet.IPv6 != nil {
+			reqBody.PublicNet.IPv6 = opts.PublicNet.IPv6.ID
+		}
+	}
+
+	var respBody schema.ServerCreateResponse
+	resp, err := do(ctx, c.client, "POST", "/servers", reqBody, &respBody)
+	if err != nil {
+		return ServerCreateResult{}, resp, err
+	}
+	result := ServerCreateResult{
+		Server: hcloud.ServerFromSchema(respBody.Server),
+		Action: hcloud.ActionFromSchema(respBody.Action),
+	}
+	if respBody.RootPassword != nil {
+		result.RootPassword = *respBody.RootPassword
+	}
+	return result, resp, nil
+}
+
+// AddToPlacementGroup adds a stopped server to a placement group.
+func (c *serverClient) AddToPlacementGroup(ctx context.Context, server *Server, placementGroup *PlacementGroup) (*Action, *Response, error) {
+	return postAction(ctx, c.client, fmt.Sprintf("/servers/%d/actions/add_to_placement_group", server.ID),
+		serverAddToPlacementGroupRequest{PlacementGroup: placementGroup.ID})
+}
+
+// RemoveFromPlacementGroup removes a stopped server from its placement group.
+func (c *serverClient) RemoveFromPlacementGroup(ctx context.Context, server *Server) (*Action, *Response, error) {
+	return postAction(ctx, c.client, fmt.Sprintf("/servers/%d/actions/remove_from_placement_group", server.ID), nil)
+}
+
+func idOrName(id int, name string) string {
+	if id != 0 {
+		return fmt.Sprint(id)
+	}
+	return name
+}
@@ -0,0 +1,155 @@
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/hetznercloud/hcloud-go/hcloud"
+)
+
+// ServerPrivateNet is the attachment of a server to a private network,
+// hcloud-go 1.7 does not support networks
+type ServerPrivateNet struct {
+	Network    *Network
+	IP         net.IP
+	Aliases    []net.IP
+	MACAddress string
+}
+
+// ServerAttachToNetworkOpts specifies parameters for attaching a server to a network
+type ServerAttachToNetworkOpts struct {
+	Network  *Network
+	IP       net.IP
+	AliasIPs []net.IP
+}
+
+// ServerNetworkClient manages the private networks of servers
+type ServerNetworkClient interface {
+	// List returns the private networks of a server
+	List(ctx context.Context, server *Server) ([]ServerPrivateNet, *Response, error)
+	// All returns the private networks of all servers by server id
+	All(ctx context.Context) (map[int][]ServerPrivateNet, error)
+	Attach(ctx context.Context, server *Server, opts ServerAttachToNetworkOpts) (*Action, *Response, error)
+	Detach(ctx context.Context, server *Server, network *Network) (*Action, *Response, error)
+	ChangeAliasIPs(ctx context.Context, server *Server, network *Network, aliasIPs []net.IP) (*Action, *Response, error)
+}
+
+type serverPrivateNetSchema struct {
+	Network    int      `json:"network"`
+	IP         string   `json:"ip"`
+	AliasIPs   []string `json:"alias_ips"`
+	MACAddress string   `json:"mac_address"`
+}
+
+type serverPrivateNetsSchema struct {
+	ID         int                      `json:"id"`
+	PrivateNet []serverPrivateNetSchema `json:"private_net"`
+}
+
+type serverPrivateNetsGetResponse struct {
+	Server serverPrivateNetsSchema `json:"server"`
+}
+
+type serverPrivateNetsListResponse struct {
+	Servers []serverPrivateNetsSchema `json:"servers"`
+}
+
+type serverAttachToNetworkRequest struct {
+	Network  int      `json:"network"`
+	IP       string   `json:"ip,omitempty"`
+	AliasIPs []string `json:"alias_ips,omitempty"`
+}
+
+type serverDetachFromNetworkRequest struct {
+	Network int `json:"network"`
+}
+
+type serverChangeAliasIPsRequest struct {
+	Network  int      `json:"network"`
+	AliasIPs []string `json:"alias_ips"`
+}
+
+func serverPrivateNetsFromSchema(s serverPrivateNetsSchema) []ServerPrivateNet {
+	privateNets := []ServerPrivateNet{}
+	for _, p := range s.PrivateNet {
+		privateNet := ServerPrivateNet{
+			Network:    &Network{ID: p.Network},
+			IP:         net.ParseIP(p.IP),
+			Aliases:    []net.IP{},
+			MACAddress: p.MACAddress,
+		}
+		for _, alias := range p.AliasIPs {
+			privateNet.Aliases = append(privateNet.Aliases, net.ParseIP(alias))
+		}
+		privateNets = append(privateNets, privateNet)
+	}
+	return privateNets
+}
+
+func ipStrings(ips []net.IP) []string {
+	s := []string{}
+	for _, ip := range ips {
+		s = append(s, ip.String())
+	}
+	return s
+}
+
+type serverNetworkClient struct {
+	client *hcloud.Client
+}
+
+func (c *serverNetworkClient) List(ctx context.Context, server *Server) ([]ServerPrivateNet, *Response, error) {
+	var body serverPrivateNetsGetResponse
+	resp, err := do(ctx, c.client, "GET", fmt.Sprintf("/servers/%d", server.ID), nil, &body)
+	if err != nil {
+		return nil, resp, err
+	}
+	return serverPrivateNetsFromSchema(body.Server), resp, nil
+}
+
+func (c *serverNetworkClient) All(ctx context.Context) (map[int][]ServerPrivateNet, error) {
+	privateNets := map[int][]ServerPrivateNet{}
+	err := all(func(page int) (*Response, error) {
+		values := valuesForListOpts(ListOpts{Page: page, PerPage: 50})
+		var body serverPrivateNetsListResponse
+		resp, err := do(ctx, c.client, "GET", "/servers?"+values.Encode(), nil, &body)
+		if err != nil {
+			return resp, err
+		}
+		for _, s := range body.Servers {
+			privateNets[s.ID] = serverPrivateNetsFromSchema(s)
+		}
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return privateNets, nil
+}
+
+func (c *serverNetworkClient) Attach(ctx context.Context, server *Server, opts ServerAttachToNetworkOpts) (*Action, *Response, error) {
+	reqBody := serverAttachToNetworkRequest{Network: opts.Network.ID}
+	if opts.IP != nil {
+		reqBody.IP = opts.IP.String()
+	}
+	if len(opts.AliasIPs) > 0 {
+		reqBody.AliasIPs = ipStrings(opts.AliasIPs)
+	}
+	return c.action(ctx, server, "attach_to_network", reqBody)
+}
+
+func (c *serverNetworkClient) Detach(ctx context.Context, server *Server, network *Network) (*Action, *Response, error) {
+	return c.action(ctx, server, "detach_from_network", serverDetachFromNetworkRequest{Network: network.ID})
+}
+
+func (c *serverNetworkClient) ChangeAliasIPs(ctx context.Context, server *Server, network *Network, aliasIPs []net.IP) (*Action, *Response, error) {
+	return c.action(ctx, server, "change_alias_ips", serverChangeAliasIPsRequest{
+		Network:  network.ID,
+		AliasIPs: ipStrings(aliasIPs),
+	})
+}
+
+func (c *serverNetworkClient) action(ctx context.Context, server *Server, action string, reqBody interface{}) (*Action, *Response, error) {
+	return postAction(ctx, c.client, fmt.Sprintf("/servers/%d/actions/%s", server.ID, action), reqBody)
+}
@@ -0,0 +1,301 @@
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/hcloud"
+	"github.com/hetznercloud/hcloud-go/hcloud/schema"
+)
+
+// Volume represents a volume in the Hetzner Cloud,
+// hcloud-go 1.7 does not support volumes
+type Volume struct {
+	ID          int
+	Name        string
+	Status      VolumeStatus
+	Server      *Server
+	Location    *Location
+	Size        int
+	LinuxDevice string
+	Format      string
+	Protection  VolumeProtection
+	Labels      map[string]string
+	Created     time.Time
+}
+
+// VolumeProtection represents the protection level of a volume
+type VolumeProtection struct {
+	Delete bool
+}
+
+// VolumeStatus specifies the status of a volume
+type VolumeStatus string
+
+// Volume statuses
+const (
+	VolumeStatusCreating  VolumeStatus = "creating"
+	VolumeStatusAvailable VolumeStatus = "available"
+)
+
+// VolumeListOpts specifies options for listing volumes
+type VolumeListOpts struct {
+	ListOpts
+	Name string
+}
+
+// VolumeCreateOpts specifies parameters for creating a volume
+type VolumeCreateOpts struct {
+	Name      string
+	Size      int
+	Server    *Server
+	Location  *Location
+	Labels    map[string]string
+	Automount *bool
+	Format    *string
+}
+
+// VolumeCreateResult is the result of creating a volume
+type VolumeCreateResult struct {
+	Volume      *Volume
+	Action      *Action
+	NextActions []*Action
+}
+
+// VolumeUpdateOpts specifies parameters for updating a volume
+type VolumeUpdateOpts struct {
+	Name   string
+	Labels map[string]string
+}
+
+// VolumeAttachOpts specifies parameters for attaching a volume
+type VolumeAttachOpts struct {
+	Server    *Server
+	Automount *bool
+}
+
+// VolumeClient is a client for the volumes API
+type VolumeClient interface {
+	GetByID(ctx context.Context, id int) (*Volume, *Response, error)
+	GetByName(ctx context.Context, name string) (*Volume, *Response, error)
+	Get(ctx context.Context, idOrName string) (*Volume, *Response, error)
+	List(ctx context.Context, opts VolumeListOpts) ([]*Volume, *Response, error)
+	All(ctx context.Context) ([]*Volume, error)
+	Create(ctx context.Context, opts VolumeCreateOpts) (VolumeCreateResult, *Response, error)
+	Update(ctx context.Context, volume *Volume, opts VolumeUpdateOpts) (*Volume, *Response, error)
+	Delete(ctx context.Context, volume *Volume) (*Response, error)
+	Attach(ctx context.Context, volume *Volume, opts VolumeAttachOpts) (*Action, *Response, error)
+	Detach(ctx context.Context, volume *Volume) (*Action, *Response, error)
+	Resize(ctx context.Context, volume *Volume, size int) (*Action, *Response, error)
+}
+
+type volumeSchema struct {
+	ID          int             `json:"id"`
+	Name        string          `json:"name"`
+	Status      string          `json:"status"`
+	Server      *int            `json:"server"`
+	Location    schema.Location `json:"location"`
+	Size        int             `json:"size"`
+	LinuxDevice string          `json:"linux_device"`
+	Format      *string         `json:"format"`
+	Protection  struct {
+		Delete bool `json:"delete"`
+	} `json:"protection"`
+	Labels  map[string]string `json:"labels"`
+	Created time.Time         `json:"created"`
+}
+
+type volumeGetResponse struct {
+	Volume volumeSchema `json:"volume"`
+}
+
+type volumeListResponse struct {
+	Volumes []volumeSchema `json:"volumes"`
+}
+
+type volumeCreateRequest struct {
+	Name      string             `json:"name"`
+	Size      int                `json:"size"`
+	Server    *int               `json:"server,omitempty"`
+	Location  *string            `json:"location,omitempty"`
+	Labels    *map[string]string `json:"labels,omitempty"`
+	Automount *bool              `json:"automount,omitempty"`
+	Format    *string            `json:"format,omitempty"`
+}
+
+type volumeCreateResponse struct {
+	Volume      volumeSchema    `json:"volume"`
+	Action      *schema.Action  `json:"action"`
+	NextActions []schema.Action `json:"next_actions"`
+}
+
+type volumeUpdateRequest struct {
+	Name   string             `json:"name,omitempty"`
+	Labels *map[string]string `json:"labels,omitempty"`
+}
+
+type volumeAttachRequest struct {
+	Server    int   `json:"server"`
+	Automount *bool `json:"automount,omitempty"`
+}
+
+type volumeResizeRequest struct {
+	Size int `json:"size"`
+}
+
+func volumeFromSchema(s volumeSchema) *Volume {
+	v := &Volume{
+		ID:          s.ID,
+		Name:        s.Name,
+		Status:      VolumeStatus(s.Status),
+		Location:    hcloud.LocationFromSchema(s.Location),
+		Size:        s.Size,
+		LinuxDevice: s.LinuxDevice,
+		Protection:  VolumeProtection{Delete: s.Protection.Delete},
+		Labels:      s.Labels,
+		Created:     s.Created,
+	}
+	if s.Server != nil {
+		v.Server = &Server{ID: *s.Server}
+	}
+	if s.Format != nil {
+		v.Format = *s.Format
+	}
+	return v
+}
+
+type volumeClient struct {
+	client *hcloud.Client
+}
+
+func (c *volumeClient) GetByID(ctx context.Context, id int) (*Volume, *Response, error) {
+	var body volumeGetResponse
+	resp, err := do(ctx, c.client, "GET", fmt.Sprintf("/volumes/%d", id), nil, &body)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, resp, nil
+		}
+		return nil, resp, err
+	}
+	return volumeFromSchema(body.Volume), resp, nil
+}
+
+func (c *volumeClient) GetByName(ctx context.Context, name string) (*Volume, *Response, error) {
+	volumes, resp, err := c.List(ctx, VolumeListOpts{Name: name})
+	if len(volumes) == 0 {
+		return nil, resp, err
+	}
+	return volumes[0], resp, err
+}
+
+func (c *volumeClient) Get(ctx context.Context, idOrName string) (*Volume, *Response, error) {
+	if id, err := strconv.Atoi(idOrName); err == nil {
+		return c.GetByID(ctx, id)
+	}
+	return c.GetByName(ctx, idOrName)
+}
+
+func (c *volumeClient) List(ctx context.Context, opts VolumeListOpts) ([]*Volume, *Response, error) {
+	values := valuesForListOpts(opts.ListOpts)
+	if opts.Name != "" {
+		values.Set("name", opts.Name)
+	}
+	var body volumeListResponse
+	resp, err := do(ctx, c.client, "GET", "/volumes?"+values.Encode(), nil, &body)
+	if err != nil {
+		return nil, resp, err
+	}
+	volumes := make([]*Volume, 0, len(body.Volumes))
+	for _, v := range body.Volumes {
+		volumes = append(volumes, volumeFromSchema(v))
+	}
+	return volumes, resp, nil
+}
+
+func (c *volumeClient) All(ctx context.Context) ([]*Volume, error) {
+	allVolumes := []*Volume{}
+	opts := VolumeListOpts{ListOpts: ListOpts{PerPage: 50}}
+	err := all(func(page int) (*Response, error) {
+		opts.Page = page
+		volumes, resp, err := c.List(ctx, opts)
+		if err != nil {
+			return resp, err
+		}
+		allVolumes = append(allVolumes, volumes...)
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allVolumes, nil
+}
+
+func (c *volumeClient) Create(ctx context.Context, opts VolumeCreateOpts) (VolumeCreateResult, *Response, error) {
+	reqBody := volumeCreateRequest{
+		Name:      opts.Name,
+		Size:      opts.Size,
+		Automount: opts.Automount,
+		Format:    opts.Format,
+	}
+	if opts.Labels != nil {
+		reqBody.Labels = &opts.Labels
+	}
+	if opts.Server != nil {
+		reqBody.Server = hcloud.Int(opts.Server.ID)
+	}
+	if opts.Location != nil {
+		reqBody.Location = hcloud.String(opts.Location.Name)
+	}
+
+	var respBody volumeCreateResponse
+	resp, err := do(ctx, c.client, "POST", "/volumes", reqBody, &respBody)
+	if err != nil {
+		return VolumeCreateResult{}, resp, err
+	}
+	result := VolumeCreateResult{
+		Volume: volumeFromSchema(respBody.Volume),
+	}
+	if respBody.Action != nil {
+		result.Action = hcloud.ActionFromSchema(*respBody.Action)
+	}
+	for _, a := range respBody.NextActions {
+		result.NextActions = append(result.NextActions, hcloud.ActionFromSchema(a))
+	}
+	return result, resp, nil
+}
+
+func (c *volumeClient) Update(ctx context.Context, volume *Volume, opts VolumeUpdateOpts) (*Volume, *Response, error) {
+	reqBody := volumeUpdateRequest{Name: opts.Name}
+	if opts.Labels != nil {
+		reqBody.Labels = &opts.Labels
+	}
+	var respBody volumeGetResponse
+	resp, err := do(ctx, c.client, "PUT", fmt.Sprintf("/volumes/%d", volume.ID), reqBody, &respBody)
+	if err != nil {
+		return nil, resp, err
+	}
+	return volumeFromSchema(respBody.Volume), resp, nil
+}
+
+func (c *volumeClient) Delete(ctx context.Context, volume *Volume) (*Response, error) {
+	return do(ctx, c.client, "DELETE", fmt.Sprintf("/volumes/%d", volume.ID), nil, nil)
+}
+
+func (c *volumeClient) Attach(ctx context.Context, volume *Volume, opts VolumeAttachOpts) (*Action, *Response, error) {
+	reqBody := volumeAttachRequest{Server: opts.Server.ID, Automount: opts.Automount}
+	return c.action(ctx, volume, "attach", reqBody)
+}
+
+func (c *volumeClient) Detach(ctx context.Context, volume *Volume) (*Action, *Response, error) {
+	return c.action(ctx, volume, "detach", struct{}{})
+}
+
+func (c *volumeClient) Resize(ctx context.Context, volume *Volume, size int) (*Action, *Response, error) {
+	return c.action(ctx, volume, "resize", volumeResizeRequest{Size: size})
+}
+
+func (c *volumeClient) action(ctx context.Context, volume *Volume, action string, reqBody interface{}) (*Action, *Response, error) {
+	return postAction(ctx, c.client, fmt.Sprintf("/volumes/%d/actions/%s", volume.ID, action), reqBody)
+}
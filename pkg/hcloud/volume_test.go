@@ -0,0 +1,69 @@
+package hcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVolumeClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "GET /volumes/1":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error": {"code": "not_found", "message": "volume not found"}}`)
+		case "POST /volumes":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, map[string]interface{}{
+				"name":      "data",
+				"size":      float64(10),
+				"server":    float64(42),
+				"automount": true,
+			}, body)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{
+				"volume": {"id": 2, "name": "data", "server": 42, "size": 10, "location": {"name": "fsn1"}, "format": null},
+				"action": {"id": 1, "command": "create_volume", "status": "running"},
+				"next_actions": [{"id": 2, "command": "attach_volume", "status": "running"}]
+			}`)
+		case "POST /volumes/2/actions/resize":
+			fmt.Fprint(w, `{"action": {"id": 3, "command": "resize_volume", "status": "running"}}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL))
+	ctx := context.Background()
+
+	volume, _, err := client.Volume.GetByID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, volume)
+
+	result, _, err := client.Volume.Create(ctx, VolumeCreateOpts{
+		Name:      "data",
+		Size:      10,
+		Server:    &Server{ID: 42},
+		Automount: Bool(true),
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, result.Volume.ID)
+		assert.Equal(t, 42, result.Volume.Server.ID)
+		assert.Equal(t, "fsn1", result.Volume.Location.Name)
+		assert.Equal(t, 1, result.Action.ID)
+		assert.Len(t, result.NextActions, 1)
+	}
+
+	action, _, err := client.Volume.Resize(ctx, result.Volume, 20)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3, action.ID)
+	}
+}
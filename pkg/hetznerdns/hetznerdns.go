@@ -0,0 +1,178 @@
+// Package hetznerdns is a client for the Hetzner DNS API,
+// which is separate from the Hetzner Cloud API and uses its own token
+package hetznerdns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// Endpoint is the base URL of the Hetzner DNS API
+const Endpoint = "https://dns.hetzner.com/api/v1"
+
+// BuildClient creates and configures a Hetzner DNS client
+func BuildClient(token string) (*Client, error) {
+	if token == "" {
+		token = os.Getenv("HETZNER_DNS_TOKEN")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("argument `dns_token` or environment variable `HETZNER_DNS_TOKEN` is required")
+	}
+	opts := []ClientOption{
+		WithToken(token),
+	}
+
+	if endpoint := os.Getenv("HETZNER_DNS_ENDPOINT"); endpoint != "" {
+		opts = append(opts, WithEndpoint(endpoint))
+	}
+
+	return NewClient(opts...), nil
+}
+
+// ClientOption configures a Client
+type ClientOption func(c *client)
+
+// WithToken configures the API token of the client
+func WithToken(token string) ClientOption {
+	return func(c *client) {
+		c.token = token
+	}
+}
+
+// WithEndpoint configures the base URL of the API
+func WithEndpoint(endpoint string) ClientOption {
+	return func(c *client) {
+		c.endpoint = endpoint
+	}
+}
+
+// Client is a client for the Hetzner DNS API
+type Client struct {
+	Zone   ZoneClient
+	Record RecordClient
+}
+
+// NewClient creates a new client
+func NewClient(options ...ClientOption) *Client {
+	c := &client{
+		endpoint:   Endpoint,
+		httpClient: &http.Client{},
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return &Client{
+		Zone:   &zoneClient{client: c},
+		Record: &recordClient{client: c},
+	}
+}
+
+// Error is an error returned by the API
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.StatusCode)
+}
+
+// IsNotFound checks if the error is a not found error of the API
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(Error)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+type errorResponse struct {
+	Message string `json:"message"`
+	Error   *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type paginationMeta struct {
+	Meta struct {
+		Pagination *struct {
+			Page     int `json:"page"`
+			LastPage int `json:"last_page"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
+
+type client struct {
+	endpoint   string
+	token      string
+	httpClient *http.Client
+}
+
+// do sends a request to the API,
+// reqBody is encoded as JSON if set and the response is decoded into respBody
+func (c *client) do(ctx context.Context, method, path string, reqBody, respBody interface{}) error {
+	var body io.Reader
+	if reqBody != nil {
+		data, err := json.Marshal(reqBody)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Auth-API-Token", c.token)
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := Error{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		var errResp errorResponse
+		if json.Unmarshal(data, &errResp) == nil {
+			if errResp.Error != nil && errResp.Error.Message != "" {
+				apiErr.Message = errResp.Error.Message
+			} else if errResp.Message != "" {
+				apiErr.Message = errResp.Message
+			}
+		}
+		return apiErr
+	}
+	if respBody != nil && len(data) > 0 {
+		return json.Unmarshal(data, respBody)
+	}
+	return nil
+}
+
+// all calls list for every page until the last page is reached
+func (c *client) all(list func(page string) (paginationMeta, error)) error {
+	page := 1
+	for {
+		meta, err := list(strconv.Itoa(page))
+		if err != nil {
+			return err
+		}
+		pagination := meta.Meta.Pagination
+		if pagination == nil || pagination.Page >= pagination.LastPage {
+			return nil
+		}
+		page = pagination.Page + 1
+	}
+}
@@ -0,0 +1,88 @@
+package hetznerdns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token", r.Header.Get("Auth-API-Token"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "GET /zones":
+			switch r.URL.Query().Get("page") {
+			case "1":
+				fmt.Fprint(w, `{"zones": [{"id": "z1", "name": "example.com", "ttl": 86400}], "meta": {"pagination": {"page": 1, "per_page": 1, "last_page": 2}}}`)
+			case "2":
+				fmt.Fprint(w, `{"zones": [{"id": "z2", "name": "dev.example.com", "ttl": 3600}], "meta": {"pagination": {"page": 2, "per_page": 1, "last_page": 2}}}`)
+			}
+		case "GET /records":
+			assert.Equal(t, "z2", r.URL.Query().Get("zone_id"))
+			fmt.Fprint(w, `{"records": [
+				{"id": "r1", "zone_id": "z2", "name": "web", "type": "A", "value": "203.0.113.1"},
+				{"id": "r2", "zone_id": "z2", "name": "web", "type": "A", "value": "203.0.113.2", "ttl": 60},
+				{"id": "r3", "zone_id": "z2", "name": "web", "type": "AAAA", "value": "2001:db8::1"}
+			]}`)
+		case "POST /records":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, map[string]interface{}{
+				"zone_id": "z2", "name": "web", "type": "A", "value": "203.0.113.3", "ttl": float64(60),
+			}, body)
+			fmt.Fprint(w, `{"record": {"id": "r4", "zone_id": "z2", "name": "web", "type": "A", "value": "203.0.113.3", "ttl": 60}}`)
+		case "PUT /records/r1":
+			fmt.Fprint(w, `{"record": {"id": "r1", "zone_id": "z2", "name": "web", "type": "A", "value": "203.0.113.1", "ttl": 60}}`)
+		case "DELETE /records/r2":
+		case "DELETE /records/r5":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"record": {}, "error": {"message": "record not found", "code": 404}}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(WithEndpoint(server.URL), WithToken("token"))
+	ctx := context.Background()
+
+	zone, name, err := FindZone(ctx, client.Zone, "Web.dev.example.com.")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "z2", zone.ID)
+		assert.Equal(t, "web", name)
+	}
+
+	ttl := 60
+	result, err := EnsureRecordSet(ctx, client.Record, zone, RecordSet{
+		Name:      "web",
+		Type:      "A",
+		Values:    []string{"203.0.113.1", "203.0.113.3"},
+		TTL:       &ttl,
+		Exclusive: true,
+	})
+	if assert.NoError(t, err) {
+		assert.True(t, result.Changed())
+		assert.Len(t, result.Records, 2)
+		assert.Equal(t, "r4", result.Created[0].ID)
+		assert.Equal(t, "r1", result.Updated[0].ID)
+		assert.Equal(t, "r2", result.Deleted[0].ID)
+	}
+
+	err = client.Record.Delete(ctx, &Record{ID: "r5"})
+	assert.EqualError(t, err, "record not found (404)")
+	assert.True(t, IsNotFound(err))
+}
+
+func TestRelativeName(t *testing.T) {
+	zone := &Zone{Name: "example.com"}
+	assert.Equal(t, "@", RelativeName(zone, "example.com."))
+	assert.Equal(t, "@", RelativeName(zone, ""))
+	assert.Equal(t, "www", RelativeName(zone, "www.example.com"))
+	assert.Equal(t, "www", RelativeName(zone, "www"))
+}
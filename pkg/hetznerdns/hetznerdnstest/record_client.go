@@ -0,0 +1,42 @@
+package hetznerdnstest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/hetznerdns"
+)
+
+// RecordClientMock mock of hetznerdns.RecordClient
+type RecordClientMock struct {
+	mock.Mock
+}
+
+// NewRecordClientMock creates a RecordClientMock
+func NewRecordClientMock() hetznerdns.RecordClient {
+	return &RecordClientMock{}
+}
+
+// List mock
+func (m *RecordClientMock) List(ctx context.Context, zone *hetznerdns.Zone) ([]*hetznerdns.Record, error) {
+	args := m.Called(ctx, zone)
+	return args.Get(0).([]*hetznerdns.Record), args.Error(1)
+}
+
+// Create mock
+func (m *RecordClientMock) Create(ctx context.Context, opts hetznerdns.RecordOpts) (*hetznerdns.Record, error) {
+	args := m.Called(ctx, opts)
+	return args.Get(0).(*hetznerdns.Record), args.Error(1)
+}
+
+// Update mock
+func (m *RecordClientMock) Update(ctx context.Context, record *hetznerdns.Record, opts hetznerdns.RecordOpts) (*hetznerdns.Record, error) {
+	args := m.Called(ctx, record, opts)
+	return args.Get(0).(*hetznerdns.Record), args.Error(1)
+}
+
+// Delete mock
+func (m *RecordClientMock) Delete(ctx context.Context, record *hetznerdns.Record) error {
+	args := m.Called(ctx, record)
+	return args.Error(0)
+}
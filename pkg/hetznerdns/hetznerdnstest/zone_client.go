@@ -0,0 +1,30 @@
+package hetznerdnstest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/hetznerdns"
+)
+
+// ZoneClientMock mock of hetznerdns.ZoneClient
+type ZoneClientMock struct {
+	mock.Mock
+}
+
+// NewZoneClientMock creates a ZoneClientMock
+func NewZoneClientMock() hetznerdns.ZoneClient {
+	return &ZoneClientMock{}
+}
+
+// GetByName mock
+func (m *ZoneClientMock) GetByName(ctx context.Context, name string) (*hetznerdns.Zone, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(*hetznerdns.Zone), args.Error(1)
+}
+
+// All mock
+func (m *ZoneClientMock) All(ctx context.Context) ([]*hetznerdns.Zone, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*hetznerdns.Zone), args.Error(1)
+}
@@ -0,0 +1,263 @@
+package hetznerdns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Record represents a DNS record, Name is relative to the zone
+type Record struct {
+	ID     string
+	ZoneID string
+	Name   string
+	Type   string
+	Value  string
+	// TTL is nil if the record uses the TTL of the zone
+	TTL *int
+}
+
+// RecordOpts specifies parameters for creating or updating a record
+type RecordOpts struct {
+	ZoneID string
+	Name   string
+	Type   string
+	Value  string
+	TTL    *int
+}
+
+// RecordClient is a client for the records API
+type RecordClient interface {
+	List(ctx context.Context, zone *Zone) ([]*Record, error)
+	Create(ctx context.Context, opts RecordOpts) (*Record, error)
+	Update(ctx context.Context, record *Record, opts RecordOpts) (*Record, error)
+	Delete(ctx context.Context, record *Record) error
+}
+
+type recordSchema struct {
+	ID     string `json:"id,omitempty"`
+	ZoneID string `json:"zone_id"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Value  string `json:"value"`
+	TTL    *int   `json:"ttl,omitempty"`
+}
+
+type recordResponse struct {
+	Record recordSchema `json:"record"`
+}
+
+type recordListResponse struct {
+	paginationMeta
+	Records []recordSchema `json:"records"`
+}
+
+func recordFromSchema(s recordSchema) *Record {
+	return &Record{
+		ID:     s.ID,
+		ZoneID: s.ZoneID,
+		Name:   s.Name,
+		Type:   s.Type,
+		Value:  s.Value,
+		TTL:    s.TTL,
+	}
+}
+
+func recordSchemaFromOpts(opts RecordOpts) recordSchema {
+	return recordSchema{
+		ZoneID: opts.ZoneID,
+		Name:   opts.Name,
+		Type:   opts.Type,
+		Value:  opts.Value,
+		TTL:    opts.TTL,
+	}
+}
+
+type recordClient struct {
+	client *client
+}
+
+func (c *recordClient) List(ctx context.Context, zone *Zone) ([]*Record, error) {
+	records := []*Record{}
+	err := c.client.all(func(page string) (paginationMeta, error) {
+		var body recordListResponse
+		path := fmt.Sprintf("/records?zone_id=%s&per_page=100&page=%s", url.QueryEscape(zone.ID), page)
+		if err := c.client.do(ctx, "GET", path, nil, &body); err != nil {
+			return body.paginationMeta, err
+		}
+		for _, s := range body.Records {
+			records = append(records, recordFromSchema(s))
+		}
+		return body.paginationMeta, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (c *recordClient) Create(ctx context.Context, opts RecordOpts) (*Record, error) {
+	var body recordResponse
+	if err := c.client.do(ctx, "POST", "/records", recordSchemaFromOpts(opts), &body); err != nil {
+		return nil, err
+	}
+	return recordFromSchema(body.Record), nil
+}
+
+func (c *recordClient) Update(ctx context.Context, record *Record, opts RecordOpts) (*Record, error) {
+	var body recordResponse
+	if err := c.client.do(ctx, "PUT", "/records/"+url.PathEscape(record.ID), recordSchemaFromOpts(opts), &body); err != nil {
+		return nil, err
+	}
+	return recordFromSchema(body.Record), nil
+}
+
+func (c *recordClient) Delete(ctx context.Context, record *Record) error {
+	return c.client.do(ctx, "DELETE", "/records/"+url.PathEscape(record.ID), nil, nil)
+}
+
+// RecordSet specifies the records of a name and type in a zone
+type RecordSet struct {
+	Name   string
+	Type   string
+	Values []string
+	// TTL of the records, nil keeps the TTL of existing records
+	TTL *int
+	// Exclusive deletes records of the name and type with other values
+	Exclusive bool
+}
+
+// RecordSetResult is the result of ensuring a record set
+type RecordSetResult struct {
+	// Records are all records of the name and type after the change
+	Records []*Record
+	Created []*Record
+	Updated []*Record
+	Deleted []*Record
+}
+
+// Changed checks if any record was created, updated or deleted
+func (r RecordSetResult) Changed() bool {
+	return len(r.Created) > 0 || len(r.Updated) > 0 || len(r.Deleted) > 0
+}
+
+// EnsureRecordSet creates the records of the set that do not exist yet,
+// updates the TTL of existing ones and deletes other values if the set is exclusive
+func EnsureRecordSet(ctx context.Context, records RecordClient, zone *Zone, set RecordSet) (result RecordSetResult, err error) {
+	var existing []*Record
+	if existing, err = MatchingRecords(ctx, records, zone, set.Name, set.Type); err != nil {
+		return
+	}
+
+	found := make([]bool, len(set.Values))
+	for _, record := range existing {
+		i := indexOfValue(set.Type, set.Values, record.Value)
+		if i == -1 || found[i] {
+			if !set.Exclusive {
+				result.Records = append(result.Records, record)
+				continue
+			}
+			if err = records.Delete(ctx, record); err != nil {
+				return
+			}
+			result.Deleted = append(result.Deleted, record)
+			continue
+		}
+		found[i] = true
+		if set.TTL != nil && (record.TTL == nil || *record.TTL != *set.TTL) {
+			if record, err = records.Update(ctx, record, RecordOpts{
+				ZoneID: zone.ID,
+				Name:   record.Name,
+				Type:   record.Type,
+				Value:  record.Value,
+				TTL:    set.TTL,
+			}); err != nil {
+				return
+			}
+			result.Updated = append(result.Updated, record)
+		}
+		result.Records = append(result.Records, record)
+	}
+
+	for i, value := range set.Values {
+		if found[i] {
+			continue
+		}
+		var record *Record
+		if record, err = records.Create(ctx, RecordOpts{
+			ZoneID: zone.ID,
+			Name:   set.Name,
+			Type:   set.Type,
+			Value:  value,
+			TTL:    set.TTL,
+		}); err != nil {
+			return
+		}
+		result.Created = append(result.Created, record)
+		result.Records = append(result.Records, record)
+	}
+	return
+}
+
+// DeleteRecords deletes the records of the name and type with the given values,
+// all records of the name and type are deleted if values is empty
+func DeleteRecords(ctx context.Context, records RecordClient, zone *Zone, name, recordType string, values []string) (deleted []*Record, err error) {
+	var existing []*Record
+	if existing, err = MatchingRecords(ctx, records, zone, name, recordType); err != nil {
+		return
+	}
+	for _, record := range existing {
+		if len(values) > 0 && indexOfValue(recordType, values, record.Value) == -1 {
+			continue
+		}
+		if err = records.Delete(ctx, record); err != nil {
+			return
+		}
+		deleted = append(deleted, record)
+	}
+	return
+}
+
+// MatchingRecords lists the records of the name and type in the zone,
+// an empty name or type matches all names or types
+func MatchingRecords(ctx context.Context, records RecordClient, zone *Zone, name, recordType string) ([]*Record, error) {
+	all, err := records.List(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	matching := []*Record{}
+	for _, record := range all {
+		if name != "" && !strings.EqualFold(record.Name, name) {
+			continue
+		}
+		if recordType != "" && !strings.EqualFold(record.Type, recordType) {
+			continue
+		}
+		matching = append(matching, record)
+	}
+	return matching, nil
+}
+
+// indexOfValue returns the index of the value in values or -1,
+// addresses of A and AAAA records are compared in their canonical form
+func indexOfValue(recordType string, values []string, value string) int {
+	for i, v := range values {
+		if sameValue(recordType, v, value) {
+			return i
+		}
+	}
+	return -1
+}
+
+func sameValue(recordType, a, b string) bool {
+	switch strings.ToUpper(recordType) {
+	case "A", "AAAA":
+		ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+		if ipA != nil && ipB != nil {
+			return ipA.Equal(ipB)
+		}
+	}
+	return a == b
+}
@@ -0,0 +1,108 @@
+package hetznerdns
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Zone represents a DNS zone
+type Zone struct {
+	ID   string
+	Name string
+	TTL  int
+}
+
+// ZoneClient is a client for the zones API
+type ZoneClient interface {
+	GetByName(ctx context.Context, name string) (*Zone, error)
+	All(ctx context.Context) ([]*Zone, error)
+}
+
+type zoneSchema struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	TTL  int    `json:"ttl"`
+}
+
+type zoneListResponse struct {
+	paginationMeta
+	Zones []zoneSchema `json:"zones"`
+}
+
+func zoneFromSchema(s zoneSchema) *Zone {
+	return &Zone{ID: s.ID, Name: s.Name, TTL: s.TTL}
+}
+
+type zoneClient struct {
+	client *client
+}
+
+func (c *zoneClient) GetByName(ctx context.Context, name string) (*Zone, error) {
+	var body zoneListResponse
+	if err := c.client.do(ctx, "GET", "/zones?name="+url.QueryEscape(name), nil, &body); err != nil {
+		if IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for _, s := range body.Zones {
+		if s.Name == name {
+			return zoneFromSchema(s), nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *zoneClient) All(ctx context.Context) ([]*Zone, error) {
+	zones := []*Zone{}
+	err := c.client.all(func(page string) (paginationMeta, error) {
+		var body zoneListResponse
+		if err := c.client.do(ctx, "GET", "/zones?per_page=100&page="+page, nil, &body); err != nil {
+			return body.paginationMeta, err
+		}
+		for _, s := range body.Zones {
+			zones = append(zones, zoneFromSchema(s))
+		}
+		return body.paginationMeta, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+// FindZone returns the zone of a fully qualified domain name
+// and the name of the record relative to the zone, "@" for the zone apex
+func FindZone(ctx context.Context, zones ZoneClient, fqdn string) (*Zone, string, error) {
+	fqdn = strings.TrimSuffix(strings.ToLower(fqdn), ".")
+	all, err := zones.All(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	var zone *Zone
+	for _, z := range all {
+		if fqdn != z.Name && !strings.HasSuffix(fqdn, "."+z.Name) {
+			continue
+		}
+		// prefer delegated subzones
+		if zone == nil || len(z.Name) > len(zone.Name) {
+			zone = z
+		}
+	}
+	if zone == nil {
+		return nil, "", fmt.Errorf("no DNS zone found for %q", fqdn)
+	}
+	return zone, RelativeName(zone, fqdn), nil
+}
+
+// RelativeName returns the name of a record relative to the zone,
+// name may be relative already or fully qualified
+func RelativeName(zone *Zone, name string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	if name == "" || name == zone.Name {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+zone.Name)
+}
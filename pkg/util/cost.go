@@ -0,0 +1,104 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+)
+
+// Cost is a net and gross amount of money
+type Cost struct {
+	Net   float64 `json:"net"`
+	Gross float64 `json:"gross"`
+}
+
+// Add returns the sum of both costs
+func (c Cost) Add(o Cost) Cost {
+	return Cost{Net: c.Net + o.Net, Gross: c.Gross + o.Gross}
+}
+
+// Scale returns the cost multiplied by f
+func (c Cost) Scale(f float64) Cost {
+	return Cost{Net: c.Net * f, Gross: c.Gross * f}
+}
+
+// Round rounds the amounts to 4 decimal places
+func (c Cost) Round() Cost {
+	return Cost{Net: round(c.Net), Gross: round(c.Gross)}
+}
+
+// Costs are the monthly and hourly costs
+type Costs struct {
+	Monthly Cost `json:"monthly"`
+	Hourly  Cost `json:"hourly"`
+}
+
+// Add returns the sum of both costs
+func (c Costs) Add(o Costs) Costs {
+	return Costs{Monthly: c.Monthly.Add(o.Monthly), Hourly: c.Hourly.Add(o.Hourly)}
+}
+
+// Sub returns the difference of both costs
+func (c Costs) Sub(o Costs) Costs {
+	return c.Add(Costs{Monthly: o.Monthly.Scale(-1), Hourly: o.Hourly.Scale(-1)})
+}
+
+// Round rounds the amounts to 4 decimal places
+func (c Costs) Round() Costs {
+	return Costs{Monthly: c.Monthly.Round(), Hourly: c.Hourly.Round()}
+}
+
+// ServerCosts returns the costs of a server of the server type in the location
+func ServerCosts(pricing hcloud.Pricing, serverType, location string) (costs Costs, err error) {
+	for _, serverTypePricing := range pricing.ServerTypes {
+		if serverTypePricing.ServerType.Name != serverType {
+			continue
+		}
+		for _, p := range serverTypePricing.Pricings {
+			if p.Location.Name != location {
+				continue
+			}
+			if costs.Monthly, err = ParsePrice(p.Monthly); err != nil {
+				return
+			}
+			costs.Hourly, err = ParsePrice(p.Hourly)
+			return
+		}
+	}
+	err = fmt.Errorf("no price for server type %q in location %q", serverType, location)
+	return
+}
+
+// BackupCosts returns the costs of the backups of a server, a percentage of the server costs
+func BackupCosts(pricing hcloud.Pricing, server Costs) (costs Costs, err error) {
+	var percentage float64
+	if percentage, err = strconv.ParseFloat(pricing.ServerBackup.Percentage, 64); err != nil {
+		err = fmt.Errorf("invalid server backup percentage %q: %v", pricing.ServerBackup.Percentage, err)
+		return
+	}
+	costs.Monthly = server.Monthly.Scale(percentage / 100)
+	costs.Hourly = server.Hourly.Scale(percentage / 100)
+	return
+}
+
+// ParsePrice parses the net and gross amount of a price
+func ParsePrice(price hcloud.Price) (cost Cost, err error) {
+	if cost.Net, err = strconv.ParseFloat(price.Net, 64); err != nil {
+		err = fmt.Errorf("invalid price %q: %v", price.Net, err)
+		return
+	}
+	if cost.Gross, err = strconv.ParseFloat(price.Gross, 64); err != nil {
+		err = fmt.Errorf("invalid price %q: %v", price.Gross, err)
+	}
+	return
+}
+
+// round rounds to 4 decimal places
+func round(f float64) float64 {
+	if f < 0 {
+		return -round(-f)
+	}
+	return math.Floor(f*10000+0.5) / 10000
+}
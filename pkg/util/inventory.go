@@ -0,0 +1,25 @@
+package util
+
+import (
+	"fmt"
+
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+)
+
+// InventoryGroups returns the inventory groups of the server
+func InventoryGroups(server *hcloud.Server) []string {
+	return []string{
+		server.Datacenter.Name,
+		server.Datacenter.Location.Name,
+		server.ServerType.Name,
+		fmt.Sprintf("status_%s", string(server.Status)),
+		imageTag(server),
+	}
+}
+
+func imageTag(server *hcloud.Server) string {
+	if server.Image.Name != "" {
+		return server.Image.Name
+	}
+	return fmt.Sprintf("image_%d", server.Image.ID)
+}
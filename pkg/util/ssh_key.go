@@ -0,0 +1,28 @@
+package util
+
+import (
+	"crypto/rsa"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// MinRSAKeySize is the minimum size of RSA public keys in bits
+const MinRSAKeySize = 2048
+
+// ValidatePublicKey rejects DSA and RSA public keys smaller than MinRSAKeySize
+func ValidatePublicKey(publicKey ssh.PublicKey) error {
+	switch publicKey.Type() {
+	case ssh.KeyAlgoDSA:
+		return fmt.Errorf("Public key type %s is insecure, use ssh-ed25519, ecdsa or ssh-rsa", publicKey.Type())
+	case ssh.KeyAlgoRSA:
+		cryptoKey, ok := publicKey.(ssh.CryptoPublicKey)
+		if !ok {
+			return nil
+		}
+		if rsaKey, ok := cryptoKey.CryptoPublicKey().(*rsa.PublicKey); ok && rsaKey.N.BitLen() < MinRSAKeySize {
+			return fmt.Errorf("Public key size %d is insecure, RSA keys need at least %d bits", rsaKey.N.BitLen(), MinRSAKeySize)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package util
+
+import (
+	"crypto/dsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestValidatePublicKey(t *testing.T) {
+	t.Run("ed25519", func(t *testing.T) {
+		publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGrhZ30jkQntYeeJNvC5fUVDfi/XmjSRbnOLMLzhyAuq"))
+		if assert.NoError(t, err) {
+			assert.NoError(t, ValidatePublicKey(publicKey))
+		}
+	})
+
+	t.Run("rsa", func(t *testing.T) {
+		for bits, valid := range map[int]bool{1024: false, 2048: true} {
+			key, err := rsa.GenerateKey(rand.Reader, bits)
+			if !assert.NoError(t, err) {
+				continue
+			}
+			publicKey, err := ssh.NewPublicKey(&key.PublicKey)
+			if !assert.NoError(t, err) {
+				continue
+			}
+			if valid {
+				assert.NoError(t, ValidatePublicKey(publicKey))
+			} else {
+				assert.Error(t, ValidatePublicKey(publicKey))
+			}
+		}
+	})
+
+	t.Run("dsa", func(t *testing.T) {
+		var key dsa.PrivateKey
+		if !assert.NoError(t, dsa.GenerateParameters(&key.Parameters, rand.Reader, dsa.L1024N160)) {
+			return
+		}
+		if !assert.NoError(t, dsa.GenerateKey(&key, rand.Reader)) {
+			return
+		}
+		publicKey, err := ssh.NewPublicKey(&key.PublicKey)
+		if assert.NoError(t, err) {
+			assert.Error(t, ValidatePublicKey(publicKey))
+		}
+	})
+}
@@ -2,20 +2,379 @@ package util
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
 	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
 )
 
-// WaitFn waits for the competion of an action
-type WaitFn func(ctx context.Context, client *hcloud.Client, action *hcloud.Action) error
+// ActionWaiter waits for the completion of actions
+type ActionWaiter interface {
+	WaitForActions(ctx context.Context, actions ...*hcloud.Action) error
+}
+
+// ActionWaiterFunc adapts a function to the ActionWaiter interface
+type ActionWaiterFunc func(ctx context.Context, actions ...*hcloud.Action) error
+
+// WaitForActions calls f(ctx, actions...)
+func (f ActionWaiterFunc) WaitForActions(ctx context.Context, actions ...*hcloud.Action) error {
+	return f(ctx, actions...)
+}
+
+const (
+	// DefaultPollInterval is the default interval between two action status polls
+	DefaultPollInterval = 500 * time.Millisecond
+
+	// maxActionsPerRequest is the maximum page size of the actions API
+	maxActionsPerRequest = 50
+
+	// maxPollErrors is the number of consecutive failed status polls before the waiters fail
+	maxPollErrors = 3
+)
+
+// ActionWatcherOption configures an ActionWatcher
+type ActionWatcherOption func(w *ActionWatcher)
+
+// WithPollInterval sets the interval between two action status polls
+func WithPollInterval(d time.Duration) ActionWatcherOption {
+	return func(w *ActionWatcher) {
+		w.pollInterval = d
+	}
+}
+
+// WithActionTimeout sets the maximum time to wait for a single action, 0 disables the timeout
+func WithActionTimeout(d time.Duration) ActionWatcherOption {
+	return func(w *ActionWatcher) {
+		w.timeout = d
+	}
+}
+
+// ActionWatcher tracks in-flight actions and polls the status of all of them
+// in a single loop, so concurrent waits don't block each other
+type ActionWatcher struct {
+	client       hcloud.ActionClient
+	pollInterval time.Duration
+	timeout      time.Duration
+
+	mux     sync.Mutex
+	waiters map[int][]chan error
+	running bool
+	// cancelPoll cancels the running status poll when no waiter is left
+	cancelPoll context.CancelFunc
+}
+
+// NewActionWatcher creates a new ActionWatcher
+func NewActionWatcher(client hcloud.ActionClient, opts ...ActionWatcherOption) *ActionWatcher {
+	w := &ActionWatcher{
+		client:       client,
+		pollInterval: DefaultPollInterval,
+		waiters:      map[int][]chan error{},
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// WaitForActions blocks until all actions are completed,
+// one of them failed, the timeout is reached or the context is cancelled
+func (w *ActionWatcher) WaitForActions(ctx context.Context, actions ...*hcloud.Action) error {
+	if w.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.timeout)
+		defer cancel()
+	}
+
+	pending := map[int]chan error{}
+	watched := map[int]*hcloud.Action{}
+	defer func() {
+		for id, ch := range pending {
+			w.unwatch(id, ch)
+		}
+	}()
+	for _, action := range actions {
+		if action == nil {
+			continue
+		}
+		switch action.Status {
+		case hcloud.ActionStatusSuccess:
+			continue
+		case hcloud.ActionStatusError:
+			return actionError(action)
+		}
+		if _, ok := pending[action.ID]; !ok {
+			pending[action.ID] = w.watch(action.ID)
+			watched[action.ID] = action
+		}
+	}
+
+	for id, ch := range pending {
+		select {
+		case err := <-ch:
+			delete(pending, id)
+			if err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			var actions []*hcloud.Action
+			for id := range pending {
+				actions = append(actions, watched[id])
+			}
+			return pendingError(ctx.Err(), actions)
+		}
+	}
+	return nil
+}
+
+func (w *ActionWatcher) watch(id int) chan error {
+	ch := make(chan error, 1)
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.waiters[id] = append(w.waiters[id], ch)
+	if !w.running {
+		w.running = true
+		go w.loop()
+	}
+	return ch
+}
+
+func (w *ActionWatcher) unwatch(id int, ch chan error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	waiters := w.waiters[id]
+	for i, c := range waiters {
+		if c == ch {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(w.waiters, id)
+	} else {
+		w.waiters[id] = waiters
+	}
+	if len(w.waiters) == 0 && w.cancelPoll != nil {
+		w.cancelPoll()
+	}
+}
+
+// pendingIDs returns the ids of all watched actions
+// and marks the loop as stopped if there are none
+func (w *ActionWatcher) pendingIDs() (ids []int) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	for id := range w.waiters {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		w.running = false
+	}
+	return
+}
 
-var actionWaitLock sync.Mutex
+// finish notifies and removes all waiters of the action
+func (w *ActionWatcher) finish(id int, err error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	for _, ch := range w.waiters[id] {
+		ch <- err
+	}
+	delete(w.waiters, id)
+}
+
+func (w *ActionWatcher) loop() {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	// transient errors are retried, the waiters fail after maxPollErrors errors in a row
+	errs := 0
+	for range ticker.C {
+		ids := w.pendingIDs()
+		if len(ids) == 0 {
+			return
+		}
+
+		for len(ids) > 0 {
+			n := len(ids)
+			if n > maxActionsPerRequest {
+				n = maxActionsPerRequest
+			}
+			if err := w.poll(ids[:n]); err == nil {
+				errs = 0
+			} else if errs++; errs >= maxPollErrors {
+				for _, id := range ids[:n] {
+					w.finish(id, fmt.Errorf("cannot get status of action %d: %v", id, err))
+				}
+				errs = 0
+			}
+			ids = ids[n:]
+		}
+	}
+}
+
+// poll gets the status of the actions and notifies the waiters of completed actions,
+// the request is cancelled when all waiters are gone
+func (w *ActionWatcher) poll(ids []int) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w.mux.Lock()
+	if len(w.waiters) == 0 {
+		w.mux.Unlock()
+		return nil
+	}
+	w.cancelPoll = cancel
+	w.mux.Unlock()
+	defer func() {
+		w.mux.Lock()
+		w.cancelPoll = nil
+		w.mux.Unlock()
+	}()
+
+	opts := hcloud.ActionListOpts{ID: ids}
+	opts.PerPage = len(ids)
+	actions, _, err := w.client.List(ctx, opts)
+	if ctx.Err() != nil {
+		// no waiter is left
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	found := map[int]bool{}
+	for _, action := range actions {
+		found[action.ID] = true
+		switch action.Status {
+		case hcloud.ActionStatusSuccess:
+			w.finish(action.ID, nil)
+		case hcloud.ActionStatusError:
+			w.finish(action.ID, actionError(action))
+		}
+	}
+	for _, id := range ids {
+		if !found[id] {
+			w.finish(id, fmt.Errorf("action %d not found", id))
+		}
+	}
+	return nil
+}
+
+func actionError(action *hcloud.Action) error {
+	if err := action.Error(); err != nil {
+		return err
+	}
+	return fmt.Errorf("action %d (%s) failed", action.ID, action.Command)
+}
+
+// pendingError describes the actions that did not complete before ctx was done
+func pendingError(err error, actions []*hcloud.Action) error {
+	var pending []string
+	for _, action := range actions {
+		pending = append(pending, DescribeAction(action))
+	}
+	sort.Strings(pending)
+	return fmt.Errorf("%v while waiting for %s", err, strings.Join(pending, ", "))
+}
+
+// DescribeAction returns a human readable description of the action and its resources
+func DescribeAction(action *hcloud.Action) string {
+	s := fmt.Sprintf("action %d", action.ID)
+	if action.Command != "" {
+		s += fmt.Sprintf(" (%s)", action.Command)
+	}
+	var resources []string
+	for _, r := range action.Resources {
+		resources = append(resources, fmt.Sprintf("%s %d", r.Type, r.ID))
+	}
+	if len(resources) > 0 {
+		s += " on " + strings.Join(resources, ", ")
+	}
+	return s
+}
+
+// ActionRecorder records actions instead of waiting for them,
+// only actions later actions depend on are awaited, see WaitForPending
+type ActionRecorder struct {
+	waiter  ActionWaiter
+	mux     sync.Mutex
+	actions []*hcloud.Action
+}
+
+// RecordActions replaces the waiter of a module with an ActionRecorder, used for `wait: false`
+func RecordActions(waiter *ActionWaiter) *ActionRecorder {
+	recorder := &ActionRecorder{waiter: *waiter}
+	*waiter = recorder
+	return recorder
+}
+
+// WaitForPending waits for the recorded actions on the resource if the waiter is an ActionRecorder.
+// The API rejects actions on a resource while it is locked by a running action, so modules call it
+// before starting an action that depends on earlier ones and only the last action is left pending.
+func WaitForPending(ctx context.Context, waiter ActionWaiter, resource hcloud.ActionResource) error {
+	recorder, ok := waiter.(*ActionRecorder)
+	if !ok {
+		return nil
+	}
+
+	recorder.mux.Lock()
+	var pending, actions []*hcloud.Action
+	for _, action := range recorder.actions {
+		if hasResource(action, resource) {
+			pending = append(pending, action)
+		} else {
+			actions = append(actions, action)
+		}
+	}
+	recorder.actions = actions
+	recorder.mux.Unlock()
+
+	if len(pending) == 0 || recorder.waiter == nil {
+		return nil
+	}
+	return recorder.waiter.WaitForActions(ctx, pending...)
+}
+
+func hasResource(action *hcloud.Action, resource hcloud.ActionResource) bool {
+	for _, r := range action.Resources {
+		if r.ID == resource.ID && r.Type == resource.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// Report sets the ids of the recorded actions as action_ids of the response,
+// unless the module failed. It is meant to be deferred by the run method of a module.
+func (r *ActionRecorder) Report(resp *ansible.ModuleResponse, err *error) {
+	if *err == nil {
+		resp.Set("action_ids", r.ActionIDs())
+	}
+}
+
+// WaitForActions records the actions and returns immediately
+func (r *ActionRecorder) WaitForActions(ctx context.Context, actions ...*hcloud.Action) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	for _, action := range actions {
+		if action != nil {
+			r.actions = append(r.actions, action)
+		}
+	}
+	return nil
+}
 
-// WaitForAction makes sure we only watch one action at a time
-func WaitForAction(ctx context.Context, client *hcloud.Client, action *hcloud.Action) error {
-	actionWaitLock.Lock()
-	defer actionWaitLock.Unlock()
-	_, errCh := client.Action.WatchProgress(ctx, action)
-	return <-errCh
+// ActionIDs returns the ids of all recorded actions
+func (r *ActionRecorder) ActionIDs() []int {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	ids := []int{}
+	for _, action := range r.actions {
+		ids = append(ids, action.ID)
+	}
+	return ids
 }
@@ -0,0 +1,212 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thetechnick/hcloud-ansible/pkg/ansible"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud"
+	"github.com/thetechnick/hcloud-ansible/pkg/hcloud/hcloudtest"
+)
+
+func TestActionWatcher(t *testing.T) {
+	var nilResponse *hcloud.Response
+
+	t.Run("success", func(t *testing.T) {
+		actionClientMock := hcloudtest.NewActionClientMock().(*hcloudtest.ActionClientMock)
+		actionClientMock.On("List", mock.Anything, mock.Anything).Return([]*hcloud.Action{
+			{ID: 1, Status: hcloud.ActionStatusSuccess},
+			{ID: 2, Status: hcloud.ActionStatusSuccess},
+		}, nilResponse, nil)
+
+		w := NewActionWatcher(actionClientMock, WithPollInterval(time.Millisecond))
+		err := w.WaitForActions(context.Background(),
+			&hcloud.Action{ID: 1, Status: hcloud.ActionStatusRunning},
+			&hcloud.Action{ID: 2, Status: hcloud.ActionStatusRunning},
+		)
+		assert.NoError(t, err)
+		actionClientMock.AssertNumberOfCalls(t, "List", 1)
+	})
+
+	t.Run("concurrent waits share one poll", func(t *testing.T) {
+		actionClientMock := hcloudtest.NewActionClientMock().(*hcloudtest.ActionClientMock)
+		actionClientMock.On("List", mock.Anything, mock.Anything).Return([]*hcloud.Action{
+			{ID: 1, Status: hcloud.ActionStatusSuccess},
+			{ID: 2, Status: hcloud.ActionStatusSuccess},
+		}, nilResponse, nil)
+
+		w := NewActionWatcher(actionClientMock, WithPollInterval(10*time.Millisecond))
+		errs := make(chan error, 2)
+		for _, id := range []int{1, 2} {
+			go func(id int) {
+				errs <- w.WaitForActions(context.Background(), &hcloud.Action{ID: id, Status: hcloud.ActionStatusRunning})
+			}(id)
+		}
+		assert.NoError(t, <-errs)
+		assert.NoError(t, <-errs)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		actionClientMock := hcloudtest.NewActionClientMock().(*hcloudtest.ActionClientMock)
+		actionClientMock.On("List", mock.Anything, mock.Anything).Return([]*hcloud.Action{
+			{ID: 1, Status: hcloud.ActionStatusError, ErrorCode: "action_failed", ErrorMessage: "Action failed"},
+		}, nilResponse, nil)
+
+		w := NewActionWatcher(actionClientMock, WithPollInterval(time.Millisecond))
+		err := w.WaitForActions(context.Background(), &hcloud.Action{ID: 1, Status: hcloud.ActionStatusRunning})
+		assert.EqualError(t, err, "Action failed (action_failed)")
+	})
+
+	t.Run("already completed", func(t *testing.T) {
+		actionClientMock := hcloudtest.NewActionClientMock().(*hcloudtest.ActionClientMock)
+
+		w := NewActionWatcher(actionClientMock)
+		err := w.WaitForActions(context.Background(), &hcloud.Action{ID: 1, Status: hcloud.ActionStatusSuccess}, nil)
+		assert.NoError(t, err)
+		actionClientMock.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		actionClientMock := hcloudtest.NewActionClientMock().(*hcloudtest.ActionClientMock)
+		actionClientMock.On("List", mock.Anything, mock.Anything).Return([]*hcloud.Action{
+			{ID: 1, Status: hcloud.ActionStatusRunning},
+		}, nilResponse, nil)
+
+		w := NewActionWatcher(actionClientMock,
+			WithPollInterval(time.Millisecond),
+			WithActionTimeout(20*time.Millisecond),
+		)
+		err := w.WaitForActions(context.Background(), &hcloud.Action{
+			ID:        1,
+			Status:    hcloud.ActionStatusRunning,
+			Command:   "create_server",
+			Resources: []*hcloud.ActionResource{{ID: 42, Type: "server"}},
+		})
+		assert.EqualError(t, err, "context deadline exceeded while waiting for action 1 (create_server) on server 42")
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		actionClientMock := hcloudtest.NewActionClientMock().(*hcloudtest.ActionClientMock)
+		actionClientMock.On("List", mock.Anything, mock.Anything).Return([]*hcloud.Action{
+			{ID: 1, Status: hcloud.ActionStatusRunning},
+		}, nilResponse, nil)
+
+		w := NewActionWatcher(actionClientMock, WithPollInterval(time.Millisecond))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := w.WaitForActions(ctx, &hcloud.Action{ID: 1, Status: hcloud.ActionStatusRunning})
+		assert.EqualError(t, err, "context canceled while waiting for action 1")
+	})
+	t.Run("transient list errors are retried", func(t *testing.T) {
+		actionClientMock := hcloudtest.NewActionClientMock().(*hcloudtest.ActionClientMock)
+		actionClientMock.On("List", mock.Anything, mock.Anything).
+			Return([]*hcloud.Action(nil), nilResponse, errors.New("connection reset")).Times(maxPollErrors - 1)
+		actionClientMock.On("List", mock.Anything, mock.Anything).Return([]*hcloud.Action{
+			{ID: 1, Status: hcloud.ActionStatusSuccess},
+		}, nilResponse, nil)
+
+		w := NewActionWatcher(actionClientMock, WithPollInterval(time.Millisecond))
+		err := w.WaitForActions(context.Background(), &hcloud.Action{ID: 1, Status: hcloud.ActionStatusRunning})
+		assert.NoError(t, err)
+		actionClientMock.AssertNumberOfCalls(t, "List", maxPollErrors)
+	})
+
+	t.Run("persistent list errors", func(t *testing.T) {
+		actionClientMock := hcloudtest.NewActionClientMock().(*hcloudtest.ActionClientMock)
+		actionClientMock.On("List", mock.Anything, mock.Anything).
+			Return([]*hcloud.Action(nil), nilResponse, errors.New("connection reset"))
+
+		w := NewActionWatcher(actionClientMock, WithPollInterval(time.Millisecond))
+		err := w.WaitForActions(context.Background(), &hcloud.Action{ID: 1, Status: hcloud.ActionStatusRunning})
+		assert.EqualError(t, err, "cannot get status of action 1: connection reset")
+		actionClientMock.AssertNumberOfCalls(t, "List", maxPollErrors)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		actionClientMock := hcloudtest.NewActionClientMock().(*hcloudtest.ActionClientMock)
+		actionClientMock.On("List", mock.Anything, mock.Anything).Return([]*hcloud.Action{}, nilResponse, nil)
+
+		w := NewActionWatcher(actionClientMock, WithPollInterval(time.Millisecond))
+		err := w.WaitForActions(context.Background(), &hcloud.Action{ID: 1, Status: hcloud.ActionStatusRunning})
+		assert.EqualError(t, err, "action 1 not found")
+	})
+
+	t.Run("poll is cancelled with the last waiter", func(t *testing.T) {
+		cancelled := make(chan struct{})
+		actionClientMock := hcloudtest.NewActionClientMock().(*hcloudtest.ActionClientMock)
+		actionClientMock.On("List", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			<-args.Get(0).(context.Context).Done()
+			close(cancelled)
+		}).Return([]*hcloud.Action(nil), nilResponse, context.Canceled)
+
+		w := NewActionWatcher(actionClientMock, WithPollInterval(time.Millisecond))
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := w.WaitForActions(ctx, &hcloud.Action{ID: 1, Status: hcloud.ActionStatusRunning})
+		assert.EqualError(t, err, "context deadline exceeded while waiting for action 1")
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Error("status poll was not cancelled")
+		}
+	})
+}
+
+func TestRecordActions(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var waiter ActionWaiter = ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+			t.Error("actions must not be awaited")
+			return nil
+		})
+		recorder := RecordActions(&waiter)
+		assert.Equal(t, recorder, waiter)
+
+		err := waiter.WaitForActions(context.Background(), &hcloud.Action{ID: 1}, nil, &hcloud.Action{ID: 2})
+		assert.NoError(t, err)
+
+		resp := ansible.ModuleResponse{}
+		recorder.Report(&resp, &err)
+		assert.Equal(t, []int{1, 2}, resp.Data()["action_ids"])
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		var waiter ActionWaiter
+		recorder := RecordActions(&waiter)
+		waiter.WaitForActions(context.Background(), &hcloud.Action{ID: 1})
+
+		err := errors.New("failed")
+		resp := ansible.ModuleResponse{}
+		recorder.Report(&resp, &err)
+		assert.NotContains(t, resp.Data(), "action_ids")
+	})
+}
+
+func TestWaitForPending(t *testing.T) {
+	server := hcloud.ActionResource{ID: 1, Type: hcloud.ActionResourceTypeServer}
+	other := hcloud.ActionResource{ID: 2, Type: hcloud.ActionResourceTypeServer}
+
+	var waited []int
+	var waiter ActionWaiter = ActionWaiterFunc(func(ctx context.Context, actions ...*hcloud.Action) error {
+		for _, action := range actions {
+			waited = append(waited, action.ID)
+		}
+		return nil
+	})
+	recorder := RecordActions(&waiter)
+	waiter.WaitForActions(context.Background(),
+		&hcloud.Action{ID: 1, Resources: []*hcloud.ActionResource{&server}},
+		&hcloud.Action{ID: 2, Resources: []*hcloud.ActionResource{&other}},
+	)
+
+	assert.NoError(t, WaitForPending(context.Background(), waiter, server))
+	assert.Equal(t, []int{1}, waited)
+	assert.Equal(t, []int{2}, recorder.ActionIDs())
+
+	waited = nil
+	assert.NoError(t, WaitForPending(context.Background(), waiter, server))
+	assert.Nil(t, waited, "actions must be awaited once")
+}